@@ -3,7 +3,7 @@
 set -e
 set -x
 
-go test -v github.com/koinos/koinos-cli/internal/cli/... -coverprofile=./build/cli.out -coverpkg=./koinos/cli
+go test -race -v github.com/koinos/koinos-cli/internal/cli/... -coverprofile=./build/cli.out -coverpkg=./koinos/cli
 gcov2lcov -infile=./build/cli.out -outfile=./build/cli.info
 
 golangci-lint run ./...
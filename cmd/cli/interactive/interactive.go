@@ -1,9 +1,12 @@
 package interactive
 
 import (
+	"bufio"
 	"fmt"
 	"os"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/koinos/go-prompt"
 	"github.com/koinos/go-prompt/completer"
@@ -21,6 +24,12 @@ type KoinosPrompt struct {
 	unicodeSupport     bool
 
 	latestRevision int
+	historyFile    string
+
+	// Guards the execution environment, used by commands and the prompt, against the inactivity lock
+	mu         sync.Mutex
+	lockTimer  *time.Timer
+	activityID int
 
 	onlineDisplay  string
 	offlineDisplay string
@@ -29,10 +38,28 @@ type KoinosPrompt struct {
 	sessionDisplay string
 }
 
-// NewKoinosPrompt creates a new interactive prompt object
-func NewKoinosPrompt(parser *cli.CommandParser, execEnv *cli.ExecutionEnvironment, forceText bool) *KoinosPrompt {
-	kp := &KoinosPrompt{parser: parser, execEnv: execEnv, latestRevision: -1}
-	kp.gPrompt = prompt.New(kp.executor, kp.completer, prompt.OptionLivePrefix(kp.changeLivePrefix), prompt.OptionCompletionWordSeparator(completer.FilePathCompletionSeparator))
+// Commands which are never saved to the history file, since they may contain a password or private key. The body of
+// an alias may hold any of them
+var sensitiveCommands = map[string]bool{
+	"add_key":         true,
+	"alias":           true,
+	"create":          true,
+	"open":            true,
+	"unlock":          true,
+	"import":          true,
+	"import_key":      true,
+	"import_mnemonic": true,
+	"change_password": true,
+	"export_key":      true,
+	"encrypt_file":    true,
+	"decrypt_file":    true,
+}
+
+// NewKoinosPrompt creates a new interactive prompt object. Command history is persisted to historyFile, unless it is empty
+func NewKoinosPrompt(parser *cli.CommandParser, execEnv *cli.ExecutionEnvironment, forceText bool, historyFile string) *KoinosPrompt {
+	kp := &KoinosPrompt{parser: parser, execEnv: execEnv, latestRevision: -1, historyFile: historyFile}
+	kp.gPrompt = prompt.New(kp.executor, kp.completer, prompt.OptionLivePrefix(kp.changeLivePrefix), prompt.OptionCompletionWordSeparator(completer.FilePathCompletionSeparator),
+		prompt.OptionHistory(kp.loadHistory()))
 	kp.fPath = &completer.FilePathCompleter{}
 
 	// Check for terminal unicode support
@@ -72,6 +99,10 @@ func (kp *KoinosPrompt) generateSuggestions() {
 }
 
 func (kp *KoinosPrompt) changeLivePrefix() (string, bool) {
+	// The inactivity lock may close the wallet from its timer at any time
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+
 	// Calculate online status
 	onlineStatus := kp.offlineDisplay
 	if kp.execEnv.IsOnline() {
@@ -89,7 +120,13 @@ func (kp *KoinosPrompt) changeLivePrefix() (string, bool) {
 		sessionStatus = kp.sessionDisplay
 	}
 
-	return fmt.Sprintf("%s%s%s> ", onlineStatus, walletStatus, sessionStatus), true
+	// Shown only when enabled with prompt_balance, once it has been fetched
+	balanceStatus := ""
+	if balance := kp.execEnv.PromptBalance(); balance != "" {
+		balanceStatus = "[" + balance + "] "
+	}
+
+	return fmt.Sprintf("%s%s%s%s> ", onlineStatus, walletStatus, balanceStatus, sessionStatus), true
 }
 
 func (kp *KoinosPrompt) completer(d prompt.Document) []prompt.Suggest {
@@ -114,13 +151,93 @@ func (kp *KoinosPrompt) completer(d prompt.Document) []prompt.Suggest {
 }
 
 func (kp *KoinosPrompt) executor(input string) {
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+
+	// Stop the lock timer so it cannot fire during a long running command
+	if kp.lockTimer != nil {
+		kp.lockTimer.Stop()
+	}
+	kp.activityID++
+
+	kp.saveHistory(input)
+
 	results := cli.ParseAndInterpret(kp.parser, kp.execEnv, input)
-	results.Print()
+	results.Fprint(kp.execEnv.Output())
+
+	kp.resetLockTimer()
+}
+
+// resetLockTimer restarts the inactivity timer, the caller must hold kp.mu
+func (kp *KoinosPrompt) resetLockTimer() {
+	timeout := kp.execEnv.GetLockTimeout()
+	if timeout == 0 || !kp.execEnv.IsWalletOpen() {
+		return
+	}
+
+	id := kp.activityID
+	kp.lockTimer = time.AfterFunc(timeout, func() {
+		kp.mu.Lock()
+		defer kp.mu.Unlock()
+
+		// A command was entered after this timer was started
+		if id != kp.activityID || !kp.execEnv.IsWalletOpen() {
+			return
+		}
+
+		kp.execEnv.CloseWallet()
+		fmt.Printf("\nWallet closed after %v of inactivity\n", timeout)
+	})
+}
+
+// loadHistory reads the previously entered commands from the history file
+func (kp *KoinosPrompt) loadHistory() []string {
+	history := make([]string, 0)
+	if kp.historyFile == "" {
+		return history
+	}
+
+	file, err := os.Open(kp.historyFile)
+	if err != nil {
+		return history
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			history = append(history, line)
+		}
+	}
+
+	return history
+}
+
+// saveHistory appends the input to the history file, unless it contains a sensitive command
+func (kp *KoinosPrompt) saveHistory(input string) {
+	if kp.historyFile == "" || strings.TrimSpace(input) == "" {
+		return
+	}
+
+	// Check the name of every command in the input, even those after a parse error
+	for _, cmd := range strings.Split(input, ";") {
+		if fields := strings.Fields(cmd); len(fields) > 0 && sensitiveCommands[fields[0]] {
+			return
+		}
+	}
+
+	file, err := os.OpenFile(kp.historyFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	fmt.Fprintln(file, strings.ReplaceAll(input, "\n", " "))
 }
 
 // Run runs interactive mode
 func (kp *KoinosPrompt) Run() {
-	fmt.Printf("Koinos CLI %s\n", cliutil.Version)
+	fmt.Printf("Koinos CLI %s\n", cliutil.VersionString())
 	fmt.Println("Type \"list\" for a list of commands, \"help <command>\" for help on a specific command.")
 	kp.gPrompt.Run()
 }
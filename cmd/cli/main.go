@@ -1,10 +1,12 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
+	"strconv"
 	"strings"
 
 	"github.com/joho/godotenv"
@@ -23,6 +25,35 @@ const (
 	versionOption          = "version"
 	forceInteractiveOption = "force-interactive"
 	forceTextPromptOption  = "force-text-prompt"
+	jsonOption             = "json"
+	rpcRetriesOption       = "rpc-retries"
+	rpcRetryDelayOption    = "rpc-retry-delay"
+	rpcTimeoutOption       = "rpc-timeout"
+	noHistoryOption        = "no-history"
+	noContractsOption      = "no-contracts"
+	keepGoingOption        = "keep-going"
+	quietOption            = "quiet"
+	waitOption             = "wait"
+	waitIntervalOption     = "wait-interval"
+	readFormatOption       = "read-format"
+	passwordOption         = "password"
+	passwordFileOption     = "password-file"
+	dryRunOption           = "dry-run"
+	parseOnlyOption        = "parse-only"
+	confirmOption          = "confirm"
+	yesOption              = "yes"
+	verboseOption          = "verbose"
+	debugOption            = "debug"
+	configOption           = "config"
+	koinPrecisionOption    = "koin-precision"
+	koinSymbolOption       = "koin-symbol"
+	koinBalanceEntryOption = "koin-balance-entry"
+	numberFormatOption     = "number-format"
+	networkOption          = "network"
+	maxDurationOption      = "max-duration"
+	noColorOption          = "no-color"
+	outputFileOption       = "output-file"
+	appendOutputOption     = "append-output"
 )
 
 // Default options
@@ -30,9 +61,19 @@ const (
 	rpcDefault = ""
 )
 
+// Environment variable names
+const (
+	rpcEnvVar = "KOINOS_RPC_URL"
+)
+
 // Other constants
 const (
-	rcFileName = ".koinosrc"
+	rcFileName        = ".koinosrc"
+	historyFileName   = ".koinos-cli-history"
+	aliasFileName     = ".koinos-cli-aliases"
+	commandsFileName  = ".koinos-cli-commands"
+	contractsFileName = ".koinos-cli-contracts"
+	configFileName    = ".koinos-cli.json"
 )
 
 func main() {
@@ -40,20 +81,114 @@ func main() {
 	_ = godotenv.Load()
 
 	// Setup command line options
-	rpcAddress := flag.StringP(rpcOption, "r", rpcDefault, "RPC server URL")
+	rpcAddress := flag.StringP(rpcOption, "r", rpcDefault, "RPC server URL (defaults to the "+rpcEnvVar+" environment variable, or none)")
 	executeCmd := flag.StringSliceP(executeOption, "x", nil, "Command to execute")
 	fileCmd := flag.StringSliceP(fileOption, "f", nil, "File to execute")
 	versionCmd := flag.BoolP(versionOption, "v", false, "Display the version")
 	forceInteractive := flag.BoolP(forceInteractiveOption, "i", false, "Forces interactive mode. Useful for forcing a prompt when using the excute option")
 	forceTextPrompt := flag.BoolP(forceTextPromptOption, "t", false, "Forces text prompt in interactive mode, rather than unicode symbols")
+	jsonOutput := flag.BoolP(jsonOption, "j", false, "Print the results of executed commands and files as JSON")
+	rpcRetries := flag.Int(rpcRetriesOption, cliutil.DefaultRPCRetries, "Number of times to retry an RPC request that fails to reach the node")
+	rpcRetryDelay := flag.Duration(rpcRetryDelayOption, cliutil.DefaultRPCRetryDelay, "Delay before the first RPC retry, doubled for each subsequent retry")
+	noHistory := flag.Bool(noHistoryOption, false, "Do not load or save the interactive mode command history")
+	noContracts := flag.Bool(noContractsOption, false, "Do not load or save the registered smart contracts")
+	keepGoing := flag.Bool(keepGoingOption, false, "Continue executing commands or a file after a command fails")
+	quiet := flag.BoolP(quietOption, "q", false, "Do not print the results of commands executed from files")
+	rpcTimeout := flag.Duration(rpcTimeoutOption, cli.DefaultRPCTimeout, "Maximum time each RPC call may wait on the endpoint (0 disables)")
+	wait := flag.Duration(waitOption, 0, "Maximum time to wait for submitted transactions to be included in a block (0 disables)")
+	waitInterval := flag.Duration(waitIntervalOption, cli.DefaultWaitInterval, "Time between checks for a submitted transaction's inclusion in a block")
+	dryRun := flag.Bool(dryRunOption, false, "Check transactions with the node to estimate their mana cost, without broadcasting them")
+	parseOnly := flag.Bool(parseOnlyOption, false, "Show the operations of transactions without sending anything to the node")
+	confirm := flag.Bool(confirmOption, false, "Show the operations of each transaction and ask for confirmation before submitting it")
+	yes := flag.BoolP(yesOption, "y", false, "Skip transaction confirmations, even if enabled by the confirm command")
+	readFormat := flag.String(readFormatOption, cli.TextReadFormat, "Format of contract read results, either text or json")
+	verbose := flag.Bool(verboseOption, false, "Log RPC calls and their timing to stderr")
+	debug := flag.Bool(debugOption, false, "Log RPC calls with their requests and responses to stderr, implies --verbose")
+	password := flag.String(passwordOption, "", "Wallet password for commands not given one, instead of "+cliutil.WalletPassEnvVar+" or prompting (visible to other users of this machine)")
+	passwordFile := flag.String(passwordFileOption, "", "File containing the wallet password for commands not given one, instead of "+cliutil.WalletPassFileEnvVar+" or prompting")
+	koinPrecision := flag.Int(koinPrecisionOption, cliutil.KoinPrecision, "Precision KOIN amounts are shown with")
+	koinSymbol := flag.String(koinSymbolOption, cliutil.KoinSymbol, "Symbol KOIN amounts are shown with")
+	koinBalanceEntry := flag.String(koinBalanceEntryOption, fmt.Sprintf("0x%08x", cli.TokenBalanceOfEntry), "Entry point called to read KOIN balances, as 0x prefixed hex or decimal")
+	numberFormat := flag.String(numberFormatOption, cliutil.PlainNumberFormat, "How balances are shown, as words separated by commas: plain, separators, decimals=N, and scientific")
+	network := flag.String(networkOption, "", "Network preset (mainnet, testnet, or one from the config file) setting the RPC endpoint, chain id, and KOIN units not given otherwise")
+	maxDuration := flag.Duration(maxDurationOption, 0, "Maximum time executed commands and files may run, including watch_balance, before they are stopped (0 disables)")
+	noColor := flag.Bool(noColorOption, false, "Do not color output such as table headers, which is also disabled by the NO_COLOR environment variable or when output is not a terminal")
+	outputFile := flag.StringP(outputFileOption, "o", "", "File to write the results of commands to, as well as the terminal")
+	appendOutput := flag.Bool(appendOutputOption, false, "Append to the output file rather than replacing its contents")
+	configFile := flag.String(configOption, "", "Config file of option values, overridden by environment variables and the command line (defaults to ~/"+configFileName+")")
+
+	// Accept --exec as an abbreviation of --execute
+	flag.CommandLine.SetNormalizeFunc(func(f *flag.FlagSet, name string) flag.NormalizedName {
+		if name == "exec" {
+			name = executeOption
+		}
+		return flag.NormalizedName(name)
+	})
 
 	flag.Parse()
 
 	if *versionCmd {
-		fmt.Println(cliutil.Version)
+		fmt.Println(cliutil.VersionString())
 		os.Exit(0)
 	}
 
+	// Options not given on the command line are taken from the environment, and then the config file
+	configFilename := *configFile
+	if configFilename == "" {
+		configFilename = path.Join(util.GetHomeDir(), configFileName)
+	} else if _, err := os.Stat(configFilename); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	config, err := cliutil.LoadConfigFile(configFilename)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	// The password is never read from a config file, and the remaining options only make sense on the command line
+	options, err := cliutil.ResolveConfig(flag.CommandLine, config.Options, map[string]string{rpcOption: rpcEnvVar},
+		configOption, executeOption, fileOption, versionOption, passwordOption)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	// A network preset sets the options that were not given any other way
+	if *network != "" {
+		preset, err := cliutil.GetNetwork(*network, config.Networks)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		values := map[string]string{rpcOption: preset.RPC, koinPrecisionOption: strconv.Itoa(preset.Precision), koinSymbolOption: preset.Symbol}
+		err = cliutil.ApplyConfigDefaults(flag.CommandLine, options, values, "network "+*network)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	if *debug {
+		cliutil.SetLogLevel(cliutil.LogLevelDebug)
+	} else if *verbose {
+		cliutil.SetLogLevel(cliutil.LogLevelInfo)
+	}
+
+	if *password != "" {
+		os.Setenv(cliutil.WalletPassEnvVar, *password)
+	}
+
+	if *passwordFile != "" {
+		os.Setenv(cliutil.WalletPassFileEnvVar, *passwordFile)
+	}
+
+	// Apply the retry settings to every client created
+	cliutil.DefaultRPCRetries = *rpcRetries
+	cliutil.DefaultRPCRetryDelay = *rpcRetryDelay
+
 	// Setup client
 	var client *cliutil.KoinosRPCClient
 	if *rpcAddress != "" {
@@ -65,25 +200,126 @@ func main() {
 	parser := cli.NewCommandParser(commands)
 
 	cmdEnv := cli.NewExecutionEnvironment(client, parser)
+	cmdEnv.SetRPCTimeout(*rpcTimeout)
+	cmdEnv.SetWait(*wait, *waitInterval)
+	cmdEnv.SetDryRun(*dryRun)
+	cmdEnv.SetParseOnly(*parseOnly)
+	cmdEnv.SetConfirm(*confirm)
+	cmdEnv.SetAssumeYes(*yes)
+	cmdEnv.SetConfig(options)
+	cmdEnv.SetColor(!*noColor && !*jsonOutput && *outputFile == "" && os.Getenv("NO_COLOR") == "" && cliutil.IsTerminal(os.Stdout))
+
+	if *outputFile != "" {
+		err = cmdEnv.SetOutputFile(*outputFile, *appendOutput)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	err = cmdEnv.SetReadFormat(*readFormat)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	cmdEnv.SetNetworks(config.Networks)
+	if *network != "" {
+		_, err = cmdEnv.UseNetwork(*network)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	// Set after the network, which the units given as options take priority over
+	err = cmdEnv.SetKoinUnits(*koinPrecision, *koinSymbol)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	balanceEntry, err := cliutil.ParseEntryPoint(*koinBalanceEntry)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	cmdEnv.SetKoinBalanceOfEntry(balanceEntry)
+
+	format, err := cliutil.ParseNumberFormat(*numberFormat)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	cmdEnv.SetNumberFormat(format)
+
+	// A broken address book is reported, but does not stop the CLI
+	err = cmdEnv.SetAddressBookFile(path.Join(util.GetHomeDir(), aliasFileName))
+	if err != nil {
+		fmt.Println(err)
+	}
+
+	err = cmdEnv.SetCommandAliasFile(path.Join(util.GetHomeDir(), commandsFileName))
+	if err != nil {
+		fmt.Println(err)
+	}
+
+	// Saved contracts that cannot be registered are skipped with a warning
+	if !*noContracts {
+		err = cmdEnv.SetContractsFile(path.Join(util.GetHomeDir(), contractsFileName))
+		if err != nil {
+			fmt.Println(err)
+		}
+	}
+
+	// Bound the time taken by executed commands and files, but not interactive mode
+	cmdEnv.SetMaxDuration(*maxDuration)
 
 	// If the user submitted commands, execute them
 	if *executeCmd != nil {
+		var failure error
 		for _, cmd := range *executeCmd {
 			results := cli.ParseAndInterpret(parser, cmdEnv, cmd)
-			results.Print()
+			if *jsonOutput {
+				results.FprintJSON(cmdEnv.Output())
+			} else {
+				results.Fprint(cmdEnv.Output())
+			}
+
+			// Stop at the first command that fails, unless told to keep going
+			if results.HasError() {
+				if failure == nil {
+					failure = results.Err()
+				}
+				// Running out of time stops the remaining commands even when keeping going
+				if !*keepGoing || errors.Is(results.Err(), cliutil.ErrMaxDuration) {
+					break
+				}
+			}
+		}
+
+		if failure != nil && !*forceInteractive {
+			os.Exit(cli.ExitCode(failure))
 		}
 	}
 
 	// Create list of files to execute, intialize with rc files
 	files := []string{path.Join(util.GetHomeDir(), rcFileName), rcFileName}
+	rcFiles := len(files)
 
 	if *fileCmd != nil {
 		files = append(files, *fileCmd...)
 	}
 
-	for _, file := range files {
-		// Make sure file exists, silently skip if not
+	for i, file := range files {
+		isScript := i >= rcFiles
+
+		// Make sure file exists, silently skip rc files if not
 		if _, err := os.Stat(file); os.IsNotExist(err) {
+			if isScript {
+				fmt.Println(err)
+				os.Exit(1)
+			}
 			continue
 		}
 
@@ -94,26 +330,70 @@ func main() {
 		}
 
 		results := make([]string, 0)
+		outputs := cli.NewInterpretResults()
+		var failure error
+		var failureResults []string
 
 		lines := strings.Split(string(data), "\n")
 		for _, line := range lines {
+			// Skip blank lines and comments
+			trimmed := strings.TrimSpace(line)
+			if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+				continue
+			}
+
 			ir := cli.ParseAndInterpret(parser, cmdEnv, line)
 			results = append(results, ir.Results...)
+			outputs.AddOutput(ir.Outputs...)
+
+			// Stop executing a script at the first error, unless told to keep going. Running out of time stops any file
+			stop := (isScript && !*keepGoing && ir.HasError()) || errors.Is(ir.Err(), cliutil.ErrMaxDuration)
+
+			// The first error of a script sets the exit code, even when keeping going
+			if (isScript && ir.HasError()) || stop {
+				if failure == nil {
+					failure = ir.Err()
+				}
+				failureResults = append(failureResults, ir.Results...)
+			}
+
+			if stop {
+				break
+			}
 		}
 
-		for _, result := range results {
-			fmt.Println(result)
+		// Even when quiet, show the commands that failed
+		if *quiet {
+			results = failureResults
 		}
 
-		if len(results) > 0 {
-			fmt.Println()
+		if *jsonOutput {
+			outputs.FprintJSON(cmdEnv.Output())
+		} else if !*quiet || failure != nil {
+			for _, result := range results {
+				fmt.Fprintln(cmdEnv.Output(), result)
+			}
+
+			if len(results) > 0 {
+				fmt.Fprintln(cmdEnv.Output())
+			}
+		}
+
+		if failure != nil {
+			os.Exit(cli.ExitCode(failure))
 		}
 	}
 
 	// Run interactive mode if no commands given, or if forced
 	if *forceInteractive || (*executeCmd == nil && *fileCmd == nil) {
 		// Enter interactive mode
-		p := interactive.NewKoinosPrompt(parser, cmdEnv, *forceTextPrompt)
+		historyFile := path.Join(util.GetHomeDir(), historyFileName)
+		if *noHistory {
+			historyFile = ""
+		}
+
+		cmdEnv.SetMaxDuration(0)
+		p := interactive.NewKoinosPrompt(parser, cmdEnv, *forceTextPrompt, historyFile)
 		p.Run()
 	}
 }
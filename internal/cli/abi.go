@@ -4,15 +4,19 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/btcsuite/btcutil/base58"
 	"github.com/koinos/koinos-cli/internal/cliutil"
+	kjson "github.com/koinos/koinos-proto-golang/encoding/json"
 	"github.com/koinos/koinos-proto-golang/koinos"
 	"github.com/koinos/koinos-proto-golang/koinos/chain"
 	"github.com/koinos/koinos-proto-golang/koinos/protocol"
 	util "github.com/koinos/koinos-util-golang"
+	"github.com/shopspring/decimal"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protodesc"
 	"google.golang.org/protobuf/reflect/protoreflect"
@@ -109,11 +113,18 @@ func (abi *ABI) GetFiles() (*protoregistry.Files, error) {
 
 // ABIMethod represents an ABI method descriptor
 type ABIMethod struct {
-	Argument    string `json:"argument"`
-	Return      string `json:"return"`
-	EntryPoint  string `json:"entry-point"`
-	Description string `json:"description"`
-	ReadOnly    bool   `json:"read-only"`
+	Argument    string              `json:"argument"`
+	Return      string              `json:"return"`
+	EntryPoint  string              `json:"entry-point"`
+	Description string              `json:"description"`
+	ReadOnly    bool                `json:"read-only"`
+	Units       map[string]*ABIUnit `json:"units,omitempty"` // Units of integer return fields, by dotted field name
+}
+
+// ABIUnit describes how an integer field is displayed as a decimal amount
+type ABIUnit struct {
+	Precision int    `json:"precision"`
+	Symbol    string `json:"symbol"`
 }
 
 // ContractInfo represents the information about a contract
@@ -122,52 +133,142 @@ type ContractInfo struct {
 	Address  string // []byte?
 	ABI      *ABI
 	Registry *protoregistry.Files
+	Units    *ABIUnit // How amounts of a registered token are displayed, nil for other contracts
+}
+
+// GetAddress returns the contract's address, which can be changed while commands are running
+func (ci *ContractInfo) GetAddress() string {
+	contractsLock.RLock()
+	defer contractsLock.RUnlock()
+
+	return ci.Address
 }
 
 // Contracts is a map of contract names to ContractInfo
 type Contracts map[string]*ContractInfo
 
+// contractsLock guards every Contracts map, along with the names and addresses of the contracts in it, so that
+// contracts can be registered while other commands look them up
+var contractsLock sync.RWMutex
+
 // GetFromMethodName returns contract info from method name
 func (c Contracts) GetFromMethodName(methodName string) *ContractInfo {
+	contractsLock.RLock()
+	defer contractsLock.RUnlock()
+
+	return c.getFromMethodName(methodName)
+}
+
+func (c Contracts) getFromMethodName(methodName string) *ContractInfo {
 	s := strings.Split(methodName, ".")
 	if len(s) != 2 {
 		return nil
 	}
 
-	if !c.Contains(s[0]) {
-		return nil
-	}
-
 	return c[s[0]]
 }
 
 // GetMethod returns the ABI method with the given name
 func (c Contracts) GetMethod(methodName string) *ABIMethod {
+	contractsLock.RLock()
+	defer contractsLock.RUnlock()
+
+	return c.getMethod(methodName)
+}
+
+func (c Contracts) getMethod(methodName string) *ABIMethod {
 	s := strings.Split(methodName, ".")
 	if len(s) != 2 {
 		return nil
 	}
 
-	if !c.Contains(s[0]) {
+	// Tokens registered with register_token have no ABI
+	contract, ok := c[s[0]]
+	if !ok || contract.ABI == nil {
 		return nil
 	}
 
-	contract := c[s[0]]
+	return contract.ABI.GetMethod(s[1])
+}
 
-	if contract.ABI.GetMethod(s[1]) == nil {
-		return nil
+// GetMethodByEntryPoint returns the full name of the registered method with the given contract address and entry point,
+// or an empty string if no registered contract has it
+func (c Contracts) GetMethodByEntryPoint(address string, entryPoint uint32) string {
+	contractsLock.RLock()
+	defer contractsLock.RUnlock()
+
+	// Check the contracts in order of name, so the result is the same if an address is registered more than once
+	for _, name := range c.names() {
+		contract := c[name]
+		if contract.Address != address || contract.ABI == nil {
+			continue
+		}
+
+		for methodName, method := range contract.ABI.Methods {
+			ep, err := cliutil.ParseEntryPoint(method.EntryPoint)
+			if err == nil && ep == entryPoint {
+				return name + "." + methodName
+			}
+		}
 	}
 
-	return contract.ABI.GetMethod(s[1])
+	return ""
+}
+
+// DecodeEvent decodes the data of an event emitted by a registered contract as JSON, returning the name of the contract.
+// The event name is looked up as a message type in the contract's ABI
+func (c Contracts) DecodeEvent(event *protocol.EventData) (string, string, error) {
+	contractsLock.RLock()
+	defer contractsLock.RUnlock()
+
+	address := base58.Encode(event.GetSource())
+
+	for _, name := range c.names() {
+		contract := c[name]
+		if contract.Address != address || contract.Registry == nil {
+			continue
+		}
+
+		d, err := contract.Registry.FindDescriptorByName(protoreflect.FullName(event.GetName()))
+		if err != nil {
+			continue
+		}
+
+		md, ok := d.(protoreflect.MessageDescriptor)
+		if !ok {
+			continue
+		}
+
+		msg := dynamicpb.NewMessage(md)
+		err = proto.Unmarshal(event.GetData(), msg)
+		if err != nil {
+			return "", "", fmt.Errorf("%w: could not decode event %s, %s", cliutil.ErrInvalidABI, event.GetName(), err)
+		}
+
+		b, err := kjson.Marshal(msg)
+		if err != nil {
+			return "", "", err
+		}
+
+		return name, string(b), nil
+	}
+
+	return "", "", fmt.Errorf("%w: no registered contract at %s defines event %s", cliutil.ErrContract, address, event.GetName())
 }
 
 // GetMethodArguments returns the message descriptor of the method arguments
 func (c Contracts) GetMethodArguments(methodName string) (protoreflect.MessageDescriptor, error) {
+	contractsLock.RLock()
+	defer contractsLock.RUnlock()
+
 	return c.getMethodData(methodName, true)
 }
 
 // GetMethodReturn returns the message descriptor of the method return
 func (c Contracts) GetMethodReturn(methodName string) (protoreflect.MessageDescriptor, error) {
+	contractsLock.RLock()
+	defer contractsLock.RUnlock()
+
 	return c.getMethodData(methodName, false)
 }
 
@@ -177,12 +278,12 @@ func (c Contracts) getMethodData(methodName string, getArguments bool) (protoref
 		return nil, fmt.Errorf("invalid method name: %s", methodName)
 	}
 
-	if !c.Contains(s[0]) {
+	contract, ok := c[s[0]]
+	if !ok {
 		return nil, fmt.Errorf("contract %s does not exist", s[0])
 	}
 
-	contract := c[s[0]]
-	method := c.GetMethod(methodName)
+	method := c.getMethod(methodName)
 
 	var name string
 	if getArguments {
@@ -205,15 +306,96 @@ func (c Contracts) getMethodData(methodName string, getArguments bool) (protoref
 	return md, nil
 }
 
+// GetFieldDescription returns the comment attached to a field of the method arguments, if the ABI includes one.
+// Nested fields are given as dotted names, as in the generated command arguments
+func (c Contracts) GetFieldDescription(methodName string, fieldName string) string {
+	contractsLock.RLock()
+	defer contractsLock.RUnlock()
+
+	contract := c.getFromMethodName(methodName)
+	if contract == nil || contract.ABI == nil || c.getMethod(methodName) == nil {
+		return ""
+	}
+
+	md, err := c.getMethodData(methodName, true)
+	if err != nil {
+		return ""
+	}
+
+	// Walk down to the field, through any nested messages
+	var fd protoreflect.FieldDescriptor
+	for _, part := range strings.Split(fieldName, ".") {
+		if md == nil {
+			return ""
+		}
+
+		fd = md.Fields().ByName(protoreflect.Name(part))
+		if fd == nil {
+			return ""
+		}
+
+		md = fd.Message()
+	}
+
+	loc := fd.ParentFile().SourceLocations().ByDescriptor(fd)
+	return strings.TrimSpace(loc.LeadingComments + loc.TrailingComments)
+}
+
 // Contains returns true if the contract exists
 func (c Contracts) Contains(name string) bool {
+	contractsLock.RLock()
+	defer contractsLock.RUnlock()
+
 	_, ok := c[name]
 	return ok
 }
 
+// Get returns the contract with the given name, or false if it does not exist
+func (c Contracts) Get(name string) (*ContractInfo, bool) {
+	contractsLock.RLock()
+	defer contractsLock.RUnlock()
+
+	contract, ok := c[name]
+	return contract, ok
+}
+
+// Names returns the names of the contracts in sorted order
+func (c Contracts) Names() []string {
+	contractsLock.RLock()
+	defer contractsLock.RUnlock()
+
+	return c.names()
+}
+
+func (c Contracts) names() []string {
+	names := make([]string, 0, len(c))
+	for name := range c {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Remove removes a contract, returning false if it does not exist
+func (c Contracts) Remove(name string) bool {
+	contractsLock.Lock()
+	defer contractsLock.Unlock()
+
+	if _, ok := c[name]; !ok {
+		return false
+	}
+
+	delete(c, name)
+	return true
+}
+
 // Add adds a new contract
 func (c Contracts) Add(name string, address string, abi *ABI, files *protoregistry.Files) error {
-	if c.Contains(name) {
+	contractsLock.Lock()
+	defer contractsLock.Unlock()
+
+	if _, ok := c[name]; ok {
 		return fmt.Errorf("contract %s already exists", name)
 	}
 
@@ -227,13 +409,101 @@ func (c Contracts) Add(name string, address string, abi *ABI, files *protoregist
 	return nil
 }
 
+// SetUnits sets how amounts of a registered token are displayed
+func (c Contracts) SetUnits(name string, units *ABIUnit) error {
+	contractsLock.Lock()
+	defer contractsLock.Unlock()
+
+	contract, ok := c[name]
+	if !ok {
+		return fmt.Errorf("%w: contract %s is not registered", cliutil.ErrContract, name)
+	}
+
+	contract.Units = units
+	return nil
+}
+
+// GetTokenUnits returns the units of the registered token at an address, or nil if there is none
+func (c Contracts) GetTokenUnits(address string) *ABIUnit {
+	contractsLock.RLock()
+	defer contractsLock.RUnlock()
+
+	for _, name := range c.names() {
+		if contract := c[name]; contract.Address == address && contract.Units != nil {
+			return contract.Units
+		}
+	}
+
+	return nil
+}
+
+// Rename gives a contract a new name
+func (c Contracts) Rename(oldName string, newName string) error {
+	contractsLock.Lock()
+	defer contractsLock.Unlock()
+
+	contract, ok := c[oldName]
+	if !ok {
+		return fmt.Errorf("%w: contract %s is not registered", cliutil.ErrContract, oldName)
+	}
+
+	if _, ok := c[newName]; ok {
+		return fmt.Errorf("%w: contract %s already exists", cliutil.ErrContract, newName)
+	}
+
+	delete(c, oldName)
+	contract.Name = newName
+	c[newName] = contract
+
+	return nil
+}
+
+// SetAddress changes the address of a contract, returning its previous address
+func (c Contracts) SetAddress(name string, address string) (string, error) {
+	contractsLock.Lock()
+	defer contractsLock.Unlock()
+
+	contract, ok := c[name]
+	if !ok {
+		return "", fmt.Errorf("%w: contract %s is not registered", cliutil.ErrContract, name)
+	}
+
+	previous := contract.Address
+	contract.Address = address
+
+	return previous, nil
+}
+
+// MaxABINestingDepth is the deepest level of nested messages that can be given as dotted command arguments
+const MaxABINestingDepth = 8
+
+// supportedABITypes lists the protobuf field types that can be given as command arguments, for error messages
+const supportedABITypes = "bool, int32, int64, sint32, sint64, uint32, uint64, float, double, string, bytes, enum, repeated fields of those, and nested messages"
+
 // ParseABIFields takes a message decriptor and returns a slice of command arguments
 func ParseABIFields(md protoreflect.MessageDescriptor) ([]CommandArg, error) {
-	return parseABIFields(md, "")
+	params, err := parseABIFields(md, "", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// A repeated field consumes the rest of the command, so it must come last
+	for i, param := range params {
+		if param.Variadic && i != len(params)-1 {
+			return nil, fmt.Errorf("%w: repeated field %s must be the last argument", cliutil.ErrUnsupportedType, param.Name)
+		}
+	}
+
+	return params, nil
 }
 
-// ParseABIFields takes a message decriptor and returns a slice of command arguments
-func parseABIFields(md protoreflect.MessageDescriptor, root string) ([]CommandArg, error) {
+// parseABIFields takes a message decriptor and returns a slice of command arguments, naming nested fields from the root
+func parseABIFields(md protoreflect.MessageDescriptor, root string, depth int) ([]CommandArg, error) {
+	// This also stops messages that contain themselves from recursing forever
+	if depth > MaxABINestingDepth {
+		return nil, fmt.Errorf("%w: %s is nested more than %d messages deep", cliutil.ErrUnsupportedType, root, MaxABINestingDepth)
+	}
+
 	params := make([]CommandArg, 0)
 	l := md.Fields().Len()
 	for i := 0; i < l; i++ {
@@ -249,12 +519,15 @@ func parseABIFields(md protoreflect.MessageDescriptor, root string) ([]CommandAr
 		case protoreflect.BoolKind:
 			t = BoolArg
 
-		case protoreflect.Int32Kind, protoreflect.Int64Kind:
+		case protoreflect.Int32Kind, protoreflect.Int64Kind, protoreflect.Sint32Kind, protoreflect.Sint64Kind:
 			t = IntArg
 
 		case protoreflect.Uint32Kind, protoreflect.Uint64Kind:
 			t = UIntArg
 
+		case protoreflect.FloatKind, protoreflect.DoubleKind:
+			t = FloatArg
+
 		case protoreflect.StringKind:
 			t = StringArg
 
@@ -270,8 +543,10 @@ func parseABIFields(md protoreflect.MessageDescriptor, root string) ([]CommandAr
 				switch koinos.BytesType(enum) {
 				case koinos.BytesType_HEX, koinos.BytesType_BLOCK_ID, koinos.BytesType_TRANSACTION_ID:
 					t = HexArg
-				case koinos.BytesType_BASE58, koinos.BytesType_CONTRACT_ID, koinos.BytesType_ADDRESS:
+				case koinos.BytesType_CONTRACT_ID, koinos.BytesType_ADDRESS:
 					t = AddressArg
+				case koinos.BytesType_BASE58:
+					t = StringArg
 				}
 			}
 
@@ -279,7 +554,15 @@ func parseABIFields(md protoreflect.MessageDescriptor, root string) ([]CommandAr
 			t = StringArg
 
 		case protoreflect.MessageKind:
-			cmds, err := parseABIFields(fd.Message(), name)
+			if fd.IsMap() {
+				return nil, fmt.Errorf("%w: field %s is a map, supported types are %s", cliutil.ErrUnsupportedType, name, supportedABITypes)
+			}
+
+			if fd.IsList() {
+				return nil, fmt.Errorf("%w: field %s is a repeated message, supported types are %s", cliutil.ErrUnsupportedType, name, supportedABITypes)
+			}
+
+			cmds, err := parseABIFields(fd.Message(), name, depth+1)
 			if err != nil {
 				return nil, err
 			}
@@ -287,21 +570,26 @@ func parseABIFields(md protoreflect.MessageDescriptor, root string) ([]CommandAr
 			continue
 
 		default:
-			return nil, fmt.Errorf("%w: %s", cliutil.ErrUnsupportedType, fd.Kind().String())
+			return nil, fmt.Errorf("%w: field %s has type %s, supported types are %s", cliutil.ErrUnsupportedType, name, fd.Kind().String(), supportedABITypes)
 		}
 
-		params = append(params, *NewCommandArg(name, t))
+		if fd.IsList() {
+			params = append(params, *NewOptionalVariadicCommandArg(name, t))
+		} else {
+			params = append(params, *NewCommandArg(name, t))
+		}
 	}
 
 	return params, nil
 }
 
-// DataToMessage takes a map of parsed command data and a message descriptor, and returns a message
-func DataToMessage(data map[string]*string, md protoreflect.MessageDescriptor) (proto.Message, error) {
-	return dataToMessage(data, md, "")
+// DataToMessage takes a map of parsed command data and a message descriptor, and returns a message.
+// Repeated fields take their values from lists, as parsed for variadic arguments
+func DataToMessage(data map[string]*string, lists map[string][]string, md protoreflect.MessageDescriptor) (proto.Message, error) {
+	return dataToMessage(data, lists, md, "")
 }
 
-func dataToMessage(data map[string]*string, md protoreflect.MessageDescriptor, root string) (proto.Message, error) {
+func dataToMessage(data map[string]*string, lists map[string][]string, md protoreflect.MessageDescriptor, root string) (proto.Message, error) {
 	msg := dynamicpb.NewMessage(md)
 	l := md.Fields().Len()
 	for i := 0; i < l; i++ {
@@ -311,108 +599,169 @@ func dataToMessage(data map[string]*string, md protoreflect.MessageDescriptor, r
 			name = root + "." + name
 		}
 
-		inputValue := ""
-		if fd.Kind() != protoreflect.MessageKind {
-			inputValue = *data[name]
+		// Append each value of a repeated field
+		if fd.IsList() {
+			list := msg.Mutable(fd).List()
+			for _, inputValue := range lists[name] {
+				value, err := fieldValue(fd, name, inputValue)
+				if err != nil {
+					return nil, err
+				}
+				list.Append(value)
+			}
+			continue
 		}
 
 		var value protoreflect.Value
-		switch fd.Kind() {
-		case protoreflect.BoolKind:
-			if inputValue == "true" {
-				value = protoreflect.ValueOfBool(true)
-			} else {
-				value = protoreflect.ValueOfBool(false)
-			}
-
-		case protoreflect.Int32Kind:
-			iv, err := strconv.Atoi(inputValue)
+		if fd.Kind() == protoreflect.MessageKind {
+			subMsg, err := dataToMessage(data, lists, fd.Message(), name)
 			if err != nil {
 				return nil, err
 			}
-			value = protoreflect.ValueOfInt32(int32(iv))
-
-		case protoreflect.Int64Kind:
-			iv, err := strconv.Atoi(inputValue)
+			value = protoreflect.ValueOf(subMsg)
+		} else {
+			var err error
+			value, err = fieldValue(fd, name, *data[name])
 			if err != nil {
 				return nil, err
 			}
-			value = protoreflect.ValueOfInt64(int64(iv))
+		}
 
-		case protoreflect.Uint32Kind:
-			iv, err := strconv.Atoi(inputValue)
-			if err != nil {
-				return nil, err
-			}
-			value = protoreflect.ValueOfUint32(uint32(iv))
+		// Set the value on the message
+		msg.Set(fd, value)
+	}
 
-		case protoreflect.Uint64Kind:
-			iv, err := strconv.Atoi(inputValue)
-			if err != nil {
-				return nil, err
-			}
-			value = protoreflect.ValueOfUint64(uint64(iv))
+	return msg, nil
+}
 
-		case protoreflect.StringKind:
-			value = protoreflect.ValueOfString(inputValue)
+// fieldValue converts a single parsed command value to the value of a non-message field
+func fieldValue(fd protoreflect.FieldDescriptor, name string, inputValue string) (protoreflect.Value, error) {
+	var value protoreflect.Value
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		if inputValue == "true" {
+			value = protoreflect.ValueOfBool(true)
+		} else {
+			value = protoreflect.ValueOfBool(false)
+		}
 
-		case protoreflect.BytesKind:
-			var b []byte
-			var err error
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind:
+		iv, err := strconv.ParseInt(inputValue, 10, 32)
+		if err != nil {
+			return value, fmt.Errorf("%w: %s", cliutil.ErrInvalidParam, err)
+		}
+		value = protoreflect.ValueOfInt32(int32(iv))
 
-			opts := fd.Options()
-			if opts != nil {
-				fieldOpts := opts.(*descriptorpb.FieldOptions)
-				ext := koinos.E_Btype.TypeDescriptor()
-				enum := fieldOpts.ProtoReflect().Get(ext).Enum()
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind:
+		iv, err := strconv.ParseInt(inputValue, 10, 64)
+		if err != nil {
+			return value, fmt.Errorf("%w: %s", cliutil.ErrInvalidParam, err)
+		}
+		value = protoreflect.ValueOfInt64(iv)
 
-				switch koinos.BytesType(enum) {
-				case koinos.BytesType_HEX, koinos.BytesType_BLOCK_ID, koinos.BytesType_TRANSACTION_ID:
-					b, err = util.HexStringToBytes(inputValue)
-				case koinos.BytesType_BASE58, koinos.BytesType_CONTRACT_ID, koinos.BytesType_ADDRESS:
-					b = base58.Decode(inputValue)
-					if len(b) == 0 && len(inputValue) != 0 {
-						err = errors.New("error decoding base58")
-					}
-				case koinos.BytesType_BASE64:
-					fallthrough
-				default:
-					b, err = base64.URLEncoding.DecodeString(inputValue)
+	case protoreflect.Uint32Kind:
+		iv, err := strconv.ParseUint(strings.TrimPrefix(inputValue, "+"), 10, 32)
+		if err != nil {
+			return value, fmt.Errorf("%w: %s", cliutil.ErrInvalidParam, err)
+		}
+		value = protoreflect.ValueOfUint32(uint32(iv))
+
+	case protoreflect.Uint64Kind:
+		iv, err := strconv.ParseUint(strings.TrimPrefix(inputValue, "+"), 10, 64)
+		if err != nil {
+			return value, fmt.Errorf("%w: %s", cliutil.ErrInvalidParam, err)
+		}
+		value = protoreflect.ValueOfUint64(iv)
+
+	case protoreflect.FloatKind:
+		fv, err := parseFloatField(name, inputValue, 32)
+		if err != nil {
+			return value, err
+		}
+		value = protoreflect.ValueOfFloat32(float32(fv))
+
+	case protoreflect.DoubleKind:
+		fv, err := parseFloatField(name, inputValue, 64)
+		if err != nil {
+			return value, err
+		}
+		value = protoreflect.ValueOfFloat64(fv)
+
+	case protoreflect.StringKind:
+		value = protoreflect.ValueOfString(inputValue)
+
+	case protoreflect.BytesKind:
+		var b []byte
+		var err error
+
+		opts := fd.Options()
+		if opts != nil {
+			fieldOpts := opts.(*descriptorpb.FieldOptions)
+			ext := koinos.E_Btype.TypeDescriptor()
+			enum := fieldOpts.ProtoReflect().Get(ext).Enum()
+
+			switch koinos.BytesType(enum) {
+			case koinos.BytesType_HEX, koinos.BytesType_BLOCK_ID, koinos.BytesType_TRANSACTION_ID:
+				b, err = util.HexStringToBytes(inputValue)
+			case koinos.BytesType_BASE58, koinos.BytesType_CONTRACT_ID, koinos.BytesType_ADDRESS:
+				b = base58.Decode(inputValue)
+				if len(b) == 0 && len(inputValue) != 0 {
+					err = errors.New("error decoding base58")
 				}
-			} else {
+			case koinos.BytesType_BASE64:
+				fallthrough
+			default:
 				b, err = base64.URLEncoding.DecodeString(inputValue)
 			}
+		} else {
+			b, err = base64.URLEncoding.DecodeString(inputValue)
+		}
 
-			if err != nil {
-				return nil, err
-			}
+		if err != nil {
+			return value, fmt.Errorf("%w: could not decode bytes for %s, %s", cliutil.ErrInvalidParam, name, err)
+		}
 
-			value = protoreflect.ValueOfBytes(b)
+		value = protoreflect.ValueOfBytes(b)
 
-		case protoreflect.EnumKind:
-			enum := fd.Enum().Values().ByName(protoreflect.Name(inputValue))
-			if enum == nil {
-				return nil, fmt.Errorf("enum value for '%s' not found", inputValue)
-			}
+	case protoreflect.EnumKind:
+		enum := fd.Enum().Values().ByName(protoreflect.Name(inputValue))
+		if enum == nil {
+			return value, fmt.Errorf("enum value for '%s' not found", inputValue)
+		}
 
-			value = protoreflect.ValueOfEnum(enum.Number())
+		value = protoreflect.ValueOfEnum(enum.Number())
 
-		case protoreflect.MessageKind:
-			subMsg, err := dataToMessage(data, fd.Message(), name)
-			if err != nil {
-				return nil, err
-			}
-			value = protoreflect.ValueOf(subMsg)
+	default:
+		return value, fmt.Errorf("%w: %s", cliutil.ErrUnsupportedType, fd.Kind().String())
+	}
 
-		default:
-			return nil, fmt.Errorf("%w: %s", cliutil.ErrUnsupportedType, fd.Kind().String())
-		}
+	return value, nil
+}
 
-		// Set the value on the message
-		msg.Set(fd, value)
+// parseFloatField parses the value of a float or double field with the given bit size. A value that the field cannot
+// hold exactly, such as one with more significant digits than a float keeps, is refused rather than silently rounded
+func parseFloatField(name string, inputValue string, bitSize int) (float64, error) {
+	exact, err := decimal.NewFromString(strings.TrimPrefix(inputValue, "+"))
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s is not a number", cliutil.ErrInvalidParam, inputValue)
 	}
 
-	return msg, nil
+	fv, err := strconv.ParseFloat(inputValue, bitSize)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s is out of range for %d-bit field %s", cliutil.ErrInvalidParam, inputValue, bitSize, name)
+	}
+
+	// The shortest decimal that reads back as the value shows what would be stored
+	stored := decimal.NewFromFloat(fv)
+	if bitSize == 32 {
+		stored = decimal.NewFromFloat32(float32(fv))
+	}
+
+	if !stored.Equal(exact) {
+		return 0, fmt.Errorf("%w: %s cannot be stored exactly in %d-bit field %s, it would become %s", cliutil.ErrInvalidParam, inputValue, bitSize, name, stored)
+	}
+
+	return fv, nil
 }
 
 // ParseResultToMessage takes a ParseResult and a message descriptor, and returns a message
@@ -422,5 +771,5 @@ func ParseResultToMessage(cmd *CommandParseResult, contracts Contracts) (proto.M
 		return nil, err
 	}
 
-	return DataToMessage(cmd.Args, md)
+	return DataToMessage(cmd.Args, cmd.VariadicArgs, md)
 }
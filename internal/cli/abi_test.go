@@ -4,7 +4,12 @@ import (
 	"encoding/json"
 	"testing"
 
+	"github.com/koinos/koinos-cli/internal/cliutil"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
 )
 
 var (
@@ -80,3 +85,69 @@ func TestABI(t *testing.T) {
 	testMethod(t, contracts, "abi_test.nested", []string{"name", "data.name", "data.a.value", "data.a.name", "data.a.num",
 		"data.value", "data.b.active", "data.b.name", "value"})
 }
+
+func TestParseABISignedAndFloatFields(t *testing.T) {
+	field := func(name string, number int32, fieldType descriptorpb.FieldDescriptorProto_Type) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{Name: proto.String(name), Number: proto.Int32(number), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(), Type: fieldType.Enum()}
+	}
+
+	fdProto := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("numbers_test.proto"),
+		Package: proto.String("test"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("numbers"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("small", 1, descriptorpb.FieldDescriptorProto_TYPE_SINT32),
+				field("large", 2, descriptorpb.FieldDescriptorProto_TYPE_SINT64),
+				field("ratio", 3, descriptorpb.FieldDescriptorProto_TYPE_FLOAT),
+				field("precise", 4, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE),
+			}},
+		},
+	}
+
+	fd, err := protodesc.NewFile(fdProto, nil)
+	assert.NoError(t, err)
+
+	md := fd.Messages().ByName("numbers")
+	params, err := ParseABIFields(md)
+	assert.NoError(t, err)
+	assert.Equal(t, []CommandArgType{IntArg, IntArg, FloatArg, FloatArg}, []CommandArgType{params[0].ArgType, params[1].ArgType, params[2].ArgType, params[3].ArgType})
+
+	toMessage := func(small, large, ratio, precise string) (protoreflect.Message, error) {
+		msg, err := DataToMessage(map[string]*string{"small": &small, "large": &large, "ratio": &ratio, "precise": &precise}, nil, md)
+		if err != nil {
+			return nil, err
+		}
+		return msg.ProtoReflect(), nil
+	}
+
+	msg, err := toMessage("-5", "-9000000000", "0.1", "-1.5e-7")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-5), msg.Get(md.Fields().ByName("small")).Int())
+	assert.Equal(t, int64(-9000000000), msg.Get(md.Fields().ByName("large")).Int())
+	assert.Equal(t, float64(float32(0.1)), msg.Get(md.Fields().ByName("ratio")).Float())
+	assert.Equal(t, -1.5e-7, msg.Get(md.Fields().ByName("precise")).Float())
+
+	// Values out of range for the field are refused
+	_, err = toMessage("3000000000", "0", "0", "0")
+	assert.ErrorIs(t, err, cliutil.ErrInvalidParam)
+	_, err = toMessage("0", "0", "1e39", "0")
+	assert.ErrorIs(t, err, cliutil.ErrInvalidParam)
+
+	// Values that would lose precision are refused, naming what would be stored
+	_, err = toMessage("0", "0", "16777217", "0")
+	assert.ErrorIs(t, err, cliutil.ErrInvalidParam)
+	assert.Contains(t, err.Error(), "16777217 cannot be stored exactly in 32-bit field ratio, it would become 16777216")
+	_, err = toMessage("0", "0", "0", "0.10000000000000000001")
+	assert.ErrorIs(t, err, cliutil.ErrInvalidParam)
+
+	// Floats are parsed as command arguments
+	parser := NewCommandParser(NewKoinosCommandSet())
+	parser.Commands.AddCommand(NewCommandDeclaration("test.scale", "", false, nil, params...))
+	inv, err := parser.Parse("test.scale -1 2 +2.5 6.02e23")
+	assert.NoError(t, err)
+	assert.Equal(t, "6.02e23", *inv.CommandResults[0].Args["precise"])
+
+	_, err = parser.Parse("test.scale -1 2 2.5x 1")
+	assert.ErrorIs(t, err, cliutil.ErrInvalidParam)
+}
@@ -2,15 +2,42 @@ package cli
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcutil/base58"
 	"github.com/koinos/koinos-cli/internal/cliutil"
+	"github.com/koinos/koinos-proto-golang/koinos/contracts/token"
+	"github.com/koinos/koinos-proto-golang/koinos/protocol"
 	util "github.com/koinos/koinos-util-golang"
+	"github.com/minio/sio"
 	"github.com/shopspring/decimal"
+	flag "github.com/spf13/pflag"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
 )
 
 func TestSatoshiToDecimal(t *testing.T) {
@@ -60,6 +87,11 @@ func makeTestParser() *CommandParser {
 	cs.AddCommand(NewCommandDeclaration("test_transfer", "Test command which looks like transfer", false, nil, *NewCommandArg("amount", AmountArg),
 		*NewCommandArg("amount", AddressArg)))
 	cs.AddCommand(NewCommandDeclaration("test_hex", "Test command which takes a hex argument", false, nil, *NewCommandArg("hex", HexArg)))
+	cs.AddCommand(NewCommandDeclaration("test_int", "Test command which takes integer arguments", false, nil, *NewCommandArg("int", IntArg), *NewCommandArg("uint", UIntArg)))
+	cs.AddCommand(NewCommandDeclaration("test_variadic", "Test command which takes a variadic argument", false, nil, *NewCommandArg("uint", UIntArg), *NewVariadicCommandArg("addresses", AddressArg)))
+	cs.AddCommand(NewCommandDeclaration("test_default", "Test command which takes arguments with default values", false, nil, *NewCommandArg("string", StringArg),
+		*NewDefaultCommandArg("uint", UIntArg, "10"), *NewDefaultCommandArg("format", StringArg, "text")))
+	cs.AddCommand(NewCommandDeclaration("test_optional_variadic", "Test command which takes an optional variadic argument", false, nil, *NewOptionalVariadicCommandArg("uints", UIntArg)))
 
 	parser := NewCommandParser(cs)
 
@@ -194,6 +226,37 @@ func TestOptionalArguments(t *testing.T) {
 	checkParseResults(t, parser, "optional abcd efgh ijkl mnop", nil, []string{"arg0", "arg1", "arg2", "arg3"}, []interface{}{"abcd", "efgh", "ijkl", "mnop"})
 }
 
+func TestDefaultArguments(t *testing.T) {
+	parser := makeTestParser()
+
+	// Arguments that are not given take their default values
+	checkParseResults(t, parser, "test_default abcd", nil, []string{"string", "uint", "format"}, []interface{}{"abcd", "10", "text"})
+	checkParseResults(t, parser, "test_default abcd 5", nil, []string{"string", "uint", "format"}, []interface{}{"abcd", "5", "text"})
+	checkParseResults(t, parser, "test_default abcd 5 json", nil, []string{"string", "uint", "format"}, []interface{}{"abcd", "5", "json"})
+	checkParseResults(t, parser, "test_default abcd; test_none", nil, []string{"string", "uint", "format"}, []interface{}{"abcd", "10", "text"})
+	checkParseResults(t, parser, "test_default", cliutil.ErrMissingParam, []string{}, []interface{}{})
+
+	// Each parse gets its own copy of the default
+	results, err := parser.Parse("test_default abcd")
+	assert.NoError(t, err)
+	*results.CommandResults[0].Args["uint"] = "20"
+	checkParseResults(t, parser, "test_default abcd", nil, []string{"uint"}, []interface{}{"10"})
+
+	// Defaults are shown in the usage and help
+	decl := parser.Commands.Name2Command["test_default"]
+	assert.Equal(t, "test_default <string:string> [uint:uint=10] [format:string=text]", decl.String())
+
+	ee := NewExecutionEnvironment(nil, parser)
+	result, err := (&HelpCommand{Command: "test_default"}).Execute(context.Background(), ee)
+	assert.NoError(t, err)
+	assert.Contains(t, result.Message, "  uint   - uint, optional, default 10")
+
+	// Required arguments cannot follow optional ones, and only optional single values can have defaults
+	assert.Nil(t, NewCommandDeclaration("bad", "", false, nil, *NewDefaultCommandArg("a", UIntArg, "1"), *NewCommandArg("b", UIntArg)))
+	assert.Nil(t, NewCommandDeclaration("bad", "", false, nil, CommandArg{Name: "a", ArgType: UIntArg, Default: proto.String("1")}))
+	assert.Nil(t, NewCommandDeclaration("bad", "", false, nil, CommandArg{Name: "a", ArgType: UIntArg, Optional: true, Variadic: true, Default: proto.String("1")}))
+}
+
 func TestParseBool(t *testing.T) {
 	// Construct the command parser
 	parser := makeTestParser()
@@ -214,6 +277,9 @@ func TestParseBool(t *testing.T) {
 
 	// Test invalid value
 	checkParseResults(t, parser, "test_bool abcd ghjkg 123.345", cliutil.ErrInvalidParam, []string{"string", "bool", "amount"}, []interface{}{"abcd", nil, "123.345"})
+	checkParseResults(t, parser, "test_bool abcd g1 123.345", cliutil.ErrInvalidParam, []string{}, []interface{}{})
+	checkParseResults(t, parser, "test_bool abcd truest 123.345", cliutil.ErrInvalidParam, []string{}, []interface{}{})
+	checkParseResults(t, parser, "test_bool abcd 10 123.345", cliutil.ErrInvalidParam, []string{}, []interface{}{})
 
 }
 
@@ -273,6 +339,37 @@ func TestArgumentSplitting(t *testing.T) {
 	checkMetrics("test_transfer 1.4 1GbiqgoMhvkztWytizNPn8g5SvXrrYHQQg", parser, t, false, 0, 1, AddressArg)
 }
 
+func TestStringEscapes(t *testing.T) {
+	parser := makeTestParser()
+
+	// Quoted strings may contain spaces, and escaped quotes and backslashes
+	checkParseResults(t, parser, `optional "my wallet.koin" 'pass word'`, nil, []string{"arg0", "arg1"}, []interface{}{"my wallet.koin", "pass word"})
+	checkParseResults(t, parser, `optional "say \"hi\"" 'it\'s \\ ok'`, nil, []string{"arg0", "arg1"}, []interface{}{`say "hi"`, `it's \ ok`})
+	checkParseResults(t, parser, `optional "" ''`, nil, []string{"arg0", "arg1"}, []interface{}{"", ""})
+
+	// Backslashes outside quotes are kept as they are, so Windows and UNC paths need no escaping
+	checkParseResults(t, parser, `optional C:\wallets\main.koin trailing\`, nil, []string{"arg0", "arg1"}, []interface{}{`C:\wallets\main.koin`, `trailing\`})
+	checkParseResults(t, parser, `optional \\server\share\main.koin a\\b`, nil, []string{"arg0", "arg1"}, []interface{}{`\\server\share\main.koin`, `a\\b`})
+
+	// A quoted semicolon does not end the command, and neither does a semicolon after an escaped quote
+	results, err := parser.Parse(`test_string "a;b"; test_none`)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, results.Len())
+	assert.Equal(t, "a;b", *results.CommandResults[0].Args["string"])
+	assert.Equal(t, []string{"test_string", "test_none"}, parser.commandLineNames(`test_string "a;b"; test_none`))
+	assert.Equal(t, []string{"test_string", "test_none"}, parser.commandLineNames(`test_string "a\";b"; test_none`))
+
+	// Outside quotes, a backslash does not escape the semicolon after it
+	assert.Equal(t, []string{"test_string", "test_none"}, parser.commandLineNames(`test_string a\; test_none`))
+
+	// An unterminated quote is an invalid string
+	for _, input := range []string{`test_string "my wallet.koin`, `test_string 'abc`, `test_string "abc\"`} {
+		_, err := parser.Parse(input)
+		assert.ErrorIs(t, err, cliutil.ErrInvalidString, input)
+		assert.Equal(t, ExitParseError, ExitCode(err))
+	}
+}
+
 func TestWalletFile(t *testing.T) {
 	testKey := []byte{0x03, 0x02, 0x01, 0x0A, 0x0B, 0x0C}
 
@@ -373,3 +470,3126 @@ func checkMetrics(input string, parser *CommandParser, t *testing.T, expectError
 	assert.Equal(t, arg, metrics.CurrentArg)
 	assert.Equal(t, pType, metrics.CurrentParamType)
 }
+
+func TestParseAmount(t *testing.T) {
+	parser := makeTestParser()
+
+	// Test valid amounts
+	checkParseResults(t, parser, "test_bool abcd true 1", nil, []string{"string", "bool", "amount"}, []interface{}{"abcd", "true", "1"})
+	checkParseResults(t, parser, "test_bool abcd true 1.5", nil, []string{"string", "bool", "amount"}, []interface{}{"abcd", "true", "1.5"})
+	checkParseResults(t, parser, "test_bool abcd true .25", nil, []string{"string", "bool", "amount"}, []interface{}{"abcd", "true", ".25"})
+	checkParseResults(t, parser, "test_bool abcd true 1.5;", nil, []string{"string", "bool", "amount"}, []interface{}{"abcd", "true", "1.5"})
+
+	// Test malformed amounts
+	checkParseResults(t, parser, "test_bool abcd true 1.2.3", cliutil.ErrInvalidParam, []string{}, []interface{}{})
+	checkParseResults(t, parser, "test_bool abcd true 5abc", cliutil.ErrInvalidParam, []string{}, []interface{}{})
+	checkParseResults(t, parser, "test_bool abcd true -1", cliutil.ErrInvalidParam, []string{}, []interface{}{})
+	checkParseResults(t, parser, "test_bool abcd true abc", cliutil.ErrInvalidParam, []string{}, []interface{}{})
+}
+
+func TestRemoveCommand(t *testing.T) {
+	parser := makeTestParser()
+	revision := parser.Commands.Revision
+
+	if !parser.Commands.RemoveCommand("test_none") {
+		t.Error("Expected test_none to be removed")
+	}
+
+	if parser.Commands.Revision == revision {
+		t.Error("Expected revision to change after removing a command")
+	}
+
+	if _, ok := parser.Commands.Name2Command["test_none"]; ok {
+		t.Error("Expected test_none to be absent from the command map")
+	}
+
+	for _, c := range parser.Commands.Commands {
+		if c.Name == "test_none" {
+			t.Error("Expected test_none to be absent from the command list")
+		}
+	}
+
+	if parser.Commands.RemoveCommand("test_none") {
+		t.Error("Expected removing a missing command to fail")
+	}
+
+	checkParseResults(t, parser, "test_none", cliutil.ErrUnknownCommand, []string{}, []interface{}{})
+	checkParseResults(t, parser, "test_none2", nil, []string{}, []interface{}{})
+}
+
+func TestMnemonic(t *testing.T) {
+	for _, words := range []int{12, 24} {
+		mnemonic, err := cliutil.GenerateMnemonic(words)
+		assert.NoError(t, err)
+		assert.Len(t, strings.Fields(mnemonic), words)
+
+		// The same mnemonic should always derive the same key
+		key1, err := cliutil.KeyFromMnemonic(mnemonic)
+		assert.NoError(t, err)
+
+		key2, err := cliutil.KeyFromMnemonic(mnemonic)
+		assert.NoError(t, err)
+
+		assert.True(t, bytes.Equal(key1.PrivateBytes(), key2.PrivateBytes()), "mnemonic derived keys mismatch")
+	}
+
+	_, err := cliutil.GenerateMnemonic(15)
+	assert.ErrorIs(t, err, cliutil.ErrInvalidMnemonic)
+
+	// Wrong word count
+	_, err = cliutil.KeyFromMnemonic("abandon abandon abandon")
+	assert.ErrorIs(t, err, cliutil.ErrInvalidMnemonic)
+
+	// Bad checksum
+	_, err = cliutil.KeyFromMnemonic("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon")
+	assert.ErrorIs(t, err, cliutil.ErrInvalidMnemonic)
+
+	// Unknown word
+	_, err = cliutil.KeyFromMnemonic("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon koinos")
+	assert.ErrorIs(t, err, cliutil.ErrInvalidMnemonic)
+}
+
+func TestDerivationPath(t *testing.T) {
+	// The default path and account 0 are the same
+	path, err := cliutil.ParseDerivationPath("m/44'/659'/0'/0/0")
+	assert.NoError(t, err)
+	assert.Equal(t, cliutil.KoinosDerivationPath, path)
+
+	path, err = cliutil.ParseDerivationPath("0")
+	assert.NoError(t, err)
+	assert.Equal(t, cliutil.KoinosDerivationPath, path)
+
+	// An account index and its full path are the same, with either hardened marker
+	path, err = cliutil.ParseDerivationPath("3")
+	assert.NoError(t, err)
+	assert.Equal(t, "m/44'/659'/3'/0/0", cliutil.FormatDerivationPath(path))
+
+	path2, err := cliutil.ParseDerivationPath("m/44h/659h/3h/0/0")
+	assert.NoError(t, err)
+	assert.Equal(t, path, path2)
+
+	// Different accounts derive different keys from the same mnemonic
+	mnemonic := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	key0, err := cliutil.KeyFromMnemonic(mnemonic)
+	assert.NoError(t, err)
+
+	key3, err := cliutil.KeyFromMnemonicPath(mnemonic, path)
+	assert.NoError(t, err)
+	assert.False(t, bytes.Equal(key0.PrivateBytes(), key3.PrivateBytes()), "account keys should differ")
+
+	// Invalid paths
+	for _, p := range []string{"", "m", "44'/659'", "m/44'/x", "m/44''", "m//0"} {
+		_, err = cliutil.ParseDerivationPath(p)
+		assert.ErrorIs(t, err, cliutil.ErrInvalidParam, p)
+	}
+}
+
+func TestParseEntryPoint(t *testing.T) {
+	for _, s := range []string{"0x82a3537f", "0X82A3537F", "82a3537f", "2191741823", " 0x82a3537f "} {
+		entryPoint, err := cliutil.ParseEntryPoint(s)
+		assert.NoError(t, err, s)
+		assert.Equal(t, uint32(0x82a3537f), entryPoint, s)
+	}
+
+	// Digits alone are decimal
+	entryPoint, err := cliutil.ParseEntryPoint("10")
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(10), entryPoint)
+
+	// Malformed and out of range values
+	for _, s := range []string{"", "0x", "x10", "0xzz", "-1", "0x100000000", "4294967296"} {
+		_, err = cliutil.ParseEntryPoint(s)
+		assert.ErrorIs(t, err, cliutil.ErrInvalidParam, s)
+	}
+}
+
+func TestParseIntegers(t *testing.T) {
+	parser := makeTestParser()
+
+	// Test valid integers
+	checkParseResults(t, parser, "test_int -5 5", nil, []string{"int", "uint"}, []interface{}{"-5", "5"})
+	checkParseResults(t, parser, "test_int +5 +5", nil, []string{"int", "uint"}, []interface{}{"+5", "+5"})
+	checkParseResults(t, parser, "test_int -9223372036854775808 18446744073709551615", nil, []string{"int", "uint"}, []interface{}{"-9223372036854775808", "18446744073709551615"})
+	checkParseResults(t, parser, "test_int 0 0;", nil, []string{"int", "uint"}, []interface{}{"0", "0"})
+
+	// Test out of range integers
+	checkParseResults(t, parser, "test_int 9223372036854775808 5", cliutil.ErrInvalidParam, []string{}, []interface{}{})
+	checkParseResults(t, parser, "test_int 5 18446744073709551616", cliutil.ErrInvalidParam, []string{}, []interface{}{})
+
+	// Test non-numeric integers
+	checkParseResults(t, parser, "test_int 5abc 5", cliutil.ErrInvalidParam, []string{}, []interface{}{})
+	checkParseResults(t, parser, "test_int 5 1.5", cliutil.ErrInvalidParam, []string{}, []interface{}{})
+	checkParseResults(t, parser, "test_int 5 -5", cliutil.ErrInvalidParam, []string{}, []interface{}{})
+}
+
+func TestParseHex(t *testing.T) {
+	parser := makeTestParser()
+
+	// Test valid hex, the prefix is optional
+	checkParseResults(t, parser, "test_hex 0x0123456789abcdef", nil, []string{"hex"}, []interface{}{"0x0123456789abcdef"})
+	checkParseResults(t, parser, "test_hex 0X0123456789ABCDEF", nil, []string{"hex"}, []interface{}{"0x0123456789ABCDEF"})
+	checkParseResults(t, parser, "test_hex 0123456789abcdef", nil, []string{"hex"}, []interface{}{"0x0123456789abcdef"})
+
+	// Test invalid hex
+	checkParseResults(t, parser, "test_hex 0x12zz", cliutil.ErrInvalidParam, []string{}, []interface{}{})
+	checkParseResults(t, parser, "test_hex 0x", cliutil.ErrInvalidParam, []string{}, []interface{}{})
+	checkParseResults(t, parser, "test_hex xyz", cliutil.ErrInvalidParam, []string{}, []interface{}{})
+
+	// Test odd-length hex, which is not a whole number of bytes
+	checkParseResults(t, parser, "test_hex 0x123", cliutil.ErrInvalidParam, []string{}, []interface{}{})
+	checkParseResults(t, parser, "test_hex abc", cliutil.ErrInvalidParam, []string{}, []interface{}{})
+}
+
+func TestParseAddress(t *testing.T) {
+	parser := makeTestParser()
+
+	checkParseResults(t, parser, "test_address 1GbiqgoMhvkztWytizNPn8g5SvXrrYHQQg", nil, []string{"address"}, []interface{}{"1GbiqgoMhvkztWytizNPn8g5SvXrrYHQQg"})
+
+	// Bad checksum
+	checkParseResults(t, parser, "test_address 1GbiqgoMhvkztWytizNPn8g5SvXrrYHQQh", cliutil.ErrInvalidParam, []string{}, []interface{}{})
+
+	// Truncated address
+	checkParseResults(t, parser, "test_address 1GbiqgoMhvkztWytizNPn8g5SvXrrYHQ", cliutil.ErrInvalidParam, []string{}, []interface{}{})
+
+	// Invalid base58 characters
+	checkParseResults(t, parser, "test_address 1GbiqgoMhvkztWytizNPn8g5SvXrrYHQQ0", cliutil.ErrInvalidParam, []string{}, []interface{}{})
+	checkParseResults(t, parser, "test_address abcd", cliutil.ErrInvalidParam, []string{}, []interface{}{})
+
+	// The keywords for the open wallet are resolved when the command runs
+	checkParseResults(t, parser, "test_address self", nil, []string{"address"}, []interface{}{SelfKeyword})
+	checkParseResults(t, parser, "test_address @me", nil, []string{"address"}, []interface{}{SelfKeyword})
+	checkParseResults(t, parser, "test_address selfish", cliutil.ErrInvalidParam, []string{}, []interface{}{})
+}
+
+func TestWalletKeysFile(t *testing.T) {
+	key1, err := util.GenerateKoinosKey()
+	assert.NoError(t, err)
+
+	key2, err := util.GenerateKoinosKey()
+	assert.NoError(t, err)
+
+	// A single key wallet file should be read as a legacy file with the default label
+	file, err := ioutil.TempFile("", "wallet_test_*")
+	defer os.Remove(file.Name())
+	assert.NoError(t, err)
+
+	err = cliutil.CreateWalletFile(file, "my_password", key1.PrivateBytes())
+	assert.NoError(t, err)
+	file.Close()
+
+	file, err = os.Open(file.Name())
+	assert.NoError(t, err)
+
+	keys, legacy, err := cliutil.ReadWalletKeysFile(file, "my_password")
+	assert.NoError(t, err)
+	assert.True(t, legacy)
+	assert.Len(t, keys, 1)
+	assert.Equal(t, cliutil.DefaultKeyLabel, keys[0].Label)
+	assert.True(t, bytes.Equal(key1.PrivateBytes(), keys[0].PrivateKey))
+	file.Close()
+
+	// A labeled key wallet file should round trip
+	file, err = os.Create(file.Name())
+	assert.NoError(t, err)
+
+	keys = append(keys, cliutil.WalletKey{Label: "second", PrivateKey: key2.PrivateBytes()})
+	err = cliutil.CreateWalletKeysFile(file, "my_password", keys)
+	assert.NoError(t, err)
+	file.Close()
+
+	file, err = os.Open(file.Name())
+	assert.NoError(t, err)
+
+	keys, legacy, err = cliutil.ReadWalletKeysFile(file, "my_password")
+	assert.NoError(t, err)
+	assert.False(t, legacy)
+	assert.Len(t, keys, 2)
+	assert.Equal(t, "second", keys[1].Label)
+	assert.True(t, bytes.Equal(key2.PrivateBytes(), keys[1].PrivateKey))
+	file.Close()
+}
+
+func TestWriteWalletKeysFile(t *testing.T) {
+	key1, err := util.GenerateKoinosKey()
+	assert.NoError(t, err)
+
+	key2, err := util.GenerateKoinosKey()
+	assert.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "wallet_test_")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	filename := dir + "/test.wallet"
+	keys := []cliutil.WalletKey{{Label: cliutil.DefaultKeyLabel, PrivateKey: key1.PrivateBytes()}}
+	err = cliutil.WriteWalletKeysFile(filename, "my_password", keys)
+	assert.NoError(t, err)
+
+	// A failed write should leave the original file intact and no temporary files behind
+	err = cliutil.WriteWalletKeysFile(filename, "", append(keys, cliutil.WalletKey{Label: "second", PrivateKey: key2.PrivateBytes()}))
+	assert.True(t, errors.Is(err, cliutil.ErrEmptyPassphrase))
+
+	entries, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	file, err := os.Open(filename)
+	assert.NoError(t, err)
+
+	keys, _, err = cliutil.ReadWalletKeysFile(file, "my_password")
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1)
+	assert.True(t, bytes.Equal(key1.PrivateBytes(), keys[0].PrivateKey))
+	file.Close()
+}
+
+func TestWalletKDF(t *testing.T) {
+	dir := t.TempDir()
+
+	parser, ee := newTestEnvironment()
+
+	// New wallets use the default cost
+	filename := filepath.Join(dir, "default.wallet")
+	ir := ParseAndInterpret(parser, ee, "create "+filename+" password")
+	assert.False(t, ir.HasError())
+	params, err := cliutil.ReadWalletFileKDF(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, cliutil.DefaultKDFParams, *params)
+
+	// Weak or unusable costs are rejected
+	for _, cost := range []string{"1024", "65536 4", "65536 8 0", "65537", "1048576 16"} {
+		ir = ParseAndInterpret(parser, ee, "wallet_kdf "+cost)
+		assert.ErrorIs(t, ir.Err(), cliutil.ErrInvalidParam, cost)
+	}
+
+	// A stronger cost applies when the password is changed, and the wallet still opens
+	ir = ParseAndInterpret(parser, ee, "wallet_kdf 65536")
+	assert.False(t, ir.HasError())
+	assert.Equal(t, "Wallet "+filename+" uses scrypt N=32768 r=8 p=1 (32 MiB)", ir.Outputs[0].Messages[1])
+
+	ir = ParseAndInterpret(parser, ee, "change_password password stronger")
+	assert.False(t, ir.HasError())
+	params, err = cliutil.ReadWalletFileKDF(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, cliutil.KDFParams{N: 65536, R: 8, P: 1}, *params)
+
+	ir = ParseAndInterpret(parser, ee, "close; open "+filename+" stronger")
+	assert.False(t, ir.HasError())
+
+	// Adding a key keeps the file's cost
+	ir = ParseAndInterpret(parser, ee, "wallet_kdf 16384")
+	assert.False(t, ir.HasError())
+	ir = ParseAndInterpret(parser, ee, "add_key second stronger")
+	assert.False(t, ir.HasError())
+	params, err = cliutil.ReadWalletFileKDF(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, 65536, params.N)
+
+	// Legacy files, keyed by the hash of the password, still open and get the default cost when rewritten
+	legacyFile := filepath.Join(dir, "legacy.wallet")
+	key, err := util.GenerateKoinosKey()
+	assert.NoError(t, err)
+	out, err := os.Create(legacyFile)
+	assert.NoError(t, err)
+	hash := sha256.Sum256([]byte("password"))
+	_, err = sio.Encrypt(out, bytes.NewReader(key.PrivateBytes()), sio.Config{MinVersion: sio.Version20, MaxVersion: sio.Version20, CipherSuites: []byte{sio.AES_256_GCM, sio.CHACHA20_POLY1305}, Key: hash[:]})
+	assert.NoError(t, err)
+	assert.NoError(t, out.Close())
+
+	params, err = cliutil.ReadWalletFileKDF(legacyFile)
+	assert.NoError(t, err)
+	assert.Nil(t, params)
+
+	ir = ParseAndInterpret(parser, ee, "open "+legacyFile+" password")
+	assert.False(t, ir.HasError())
+	assert.Equal(t, key.AddressBytes(), ee.Key.AddressBytes())
+	params, err = cliutil.ReadWalletFileKDF(legacyFile)
+	assert.NoError(t, err)
+	assert.Equal(t, cliutil.DefaultKDFParams, *params)
+
+	// A header asking for too much memory is refused before deriving the key
+	crafted := filepath.Join(dir, "crafted.wallet")
+	assert.NoError(t, ioutil.WriteFile(crafted, []byte("KOINOS-KDF {\"kdf\":\"scrypt\",\"n\":1073741824,\"r\":8,\"p\":1,\"salt\":\"AAAA\"}\n"), 0600))
+	_, err = cliutil.ReadWalletFileKDF(crafted)
+	assert.ErrorIs(t, err, cliutil.ErrWalletDecrypt)
+}
+
+func TestDecodePrivateKey(t *testing.T) {
+	key, err := util.GenerateKoinosKey()
+	assert.NoError(t, err)
+
+	keyBytes, err := cliutil.DecodePrivateKey(key.Private())
+	assert.NoError(t, err)
+	assert.True(t, bytes.Equal(key.PrivateBytes(), keyBytes))
+
+	hexKey := hex.EncodeToString(key.PrivateBytes())
+	keyBytes, err = cliutil.DecodePrivateKey(hexKey)
+	assert.NoError(t, err)
+	assert.True(t, bytes.Equal(key.PrivateBytes(), keyBytes))
+
+	keyBytes, err = cliutil.DecodePrivateKey("0x" + hexKey)
+	assert.NoError(t, err)
+	assert.True(t, bytes.Equal(key.PrivateBytes(), keyBytes))
+
+	_, err = cliutil.DecodePrivateKey(hexKey[:62])
+	assert.True(t, errors.Is(err, cliutil.ErrInvalidPrivateKey))
+
+	_, err = cliutil.DecodePrivateKey("zz" + hexKey[2:])
+	assert.True(t, errors.Is(err, cliutil.ErrInvalidPrivateKey))
+
+	_, err = cliutil.DecodePrivateKey("not a key")
+	assert.True(t, errors.Is(err, cliutil.ErrInvalidPrivateKey))
+}
+
+func TestVariadicArguments(t *testing.T) {
+	parser := makeTestParser()
+
+	results, err := parser.Parse("test_variadic 5 1GbiqgoMhvkztWytizNPn8g5SvXrrYHQQg 1GbiqgoMhvkztWytizNPn8g5SvXrrYHQQg; test_none")
+	assert.NoError(t, err)
+	assert.Len(t, results.CommandResults, 2)
+	assert.Equal(t, CommandTermination, results.CommandResults[0].Termination)
+	assert.Equal(t, "5", *results.CommandResults[0].Args["uint"])
+	assert.Equal(t, []string{"1GbiqgoMhvkztWytizNPn8g5SvXrrYHQQg", "1GbiqgoMhvkztWytizNPn8g5SvXrrYHQQg"}, results.CommandResults[0].VariadicArgs["addresses"])
+
+	results, err = parser.Parse("test_optional_variadic 1 2 3  ")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1", "2", "3"}, results.CommandResults[0].VariadicArgs["uints"])
+
+	results, err = parser.Parse("test_optional_variadic")
+	assert.NoError(t, err)
+	assert.Nil(t, results.CommandResults[0].Args["uints"])
+	assert.Len(t, results.CommandResults[0].VariadicArgs["uints"], 0)
+
+	// A required variadic argument needs at least one value, and every value must be valid
+	checkParseResults(t, parser, "test_variadic 5", cliutil.ErrMissingParam, []string{}, []interface{}{})
+	checkParseResults(t, parser, "test_variadic 5 1GbiqgoMhvkztWytizNPn8g5SvXrrYHQQg 1GbiqgoMhvkztWytizNPn8g5SvXrrYHQQ0", cliutil.ErrInvalidParam, []string{}, []interface{}{})
+	checkParseResults(t, parser, "test_optional_variadic 1 2 x", cliutil.ErrInvalidParam, []string{}, []interface{}{})
+
+	// Only the last argument may be variadic
+	assert.Nil(t, NewCommandDeclaration("test_bad", "", false, nil, *NewVariadicCommandArg("a", StringArg), *NewCommandArg("b", StringArg)))
+}
+
+func TestParseABIFieldsNesting(t *testing.T) {
+	field := func(name string, number int32, fieldType descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+		f := &descriptorpb.FieldDescriptorProto{
+			Name:   proto.String(name),
+			Number: proto.Int32(number),
+			Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:   fieldType.Enum(),
+		}
+		if typeName != "" {
+			f.TypeName = proto.String(typeName)
+		}
+		return f
+	}
+
+	fdProto := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("nesting_test.proto"),
+		Package: proto.String("test"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("inner"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("value", 1, descriptorpb.FieldDescriptorProto_TYPE_UINT64, ""),
+			}},
+			{Name: proto.String("outer"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+				field("inner", 2, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".test.inner"),
+			}},
+			{Name: proto.String("loop"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("next", 1, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".test.loop"),
+			}},
+		},
+	}
+
+	fd, err := protodesc.NewFile(fdProto, nil)
+	assert.NoError(t, err)
+
+	// Nested fields are flattened into dotted arguments, and filled back in from them
+	md := fd.Messages().ByName("outer")
+	params, err := ParseABIFields(md)
+	assert.NoError(t, err)
+	assert.Len(t, params, 2)
+	assert.Equal(t, "name", params[0].Name)
+	assert.Equal(t, "inner.value", params[1].Name)
+	assert.Equal(t, UIntArg, params[1].ArgType)
+
+	name := "abc"
+	value := "42"
+	msg, err := DataToMessage(map[string]*string{"name": &name, "inner.value": &value}, nil, md)
+	assert.NoError(t, err)
+
+	inner := msg.ProtoReflect().Get(md.Fields().ByName("inner")).Message()
+	assert.Equal(t, uint64(42), inner.Get(inner.Descriptor().Fields().ByName("value")).Uint())
+
+	// A message that contains itself cannot be flattened
+	_, err = ParseABIFields(fd.Messages().ByName("loop"))
+	assert.ErrorIs(t, err, cliutil.ErrUnsupportedType)
+}
+
+func TestParseABIFieldsUnsupported(t *testing.T) {
+	fdProto := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("unsupported_test.proto"),
+		Package: proto.String("test"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("floating"), Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("to"), Number: proto.Int32(1), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()},
+				{Name: proto.String("ratio"), Number: proto.Int32(2), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(), Type: descriptorpb.FieldDescriptorProto_TYPE_FIXED64.Enum()},
+			}},
+		},
+	}
+
+	fd, err := protodesc.NewFile(fdProto, nil)
+	assert.NoError(t, err)
+
+	// The error names the field and its type, and lists the supported types
+	_, err = ParseABIFields(fd.Messages().ByName("floating"))
+	assert.ErrorIs(t, err, cliutil.ErrUnsupportedType)
+	assert.Contains(t, err.Error(), "field ratio has type fixed64")
+	assert.Contains(t, err.Error(), "supported types are bool")
+}
+
+func TestUnitAmounts(t *testing.T) {
+	fdProto := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("units_test.proto"),
+		Package: proto.String("test"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("result"), Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("value"), Number: proto.Int32(1), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(), Type: descriptorpb.FieldDescriptorProto_TYPE_UINT64.Enum()},
+				{Name: proto.String("delta"), Number: proto.Int32(2), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(), Type: descriptorpb.FieldDescriptorProto_TYPE_INT64.Enum()},
+				{Name: proto.String("name"), Number: proto.Int32(3), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()},
+				{Name: proto.String("balances"), Number: proto.Int32(4), Label: descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum(), Type: descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(), TypeName: proto.String(".test.result.BalancesEntry")},
+			}, NestedType: []*descriptorpb.DescriptorProto{
+				{Name: proto.String("BalancesEntry"), Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)}, Field: []*descriptorpb.FieldDescriptorProto{
+					{Name: proto.String("key"), Number: proto.Int32(1), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()},
+					{Name: proto.String("value"), Number: proto.Int32(2), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(), Type: descriptorpb.FieldDescriptorProto_TYPE_UINT64.Enum()},
+				}},
+			}},
+		},
+	}
+
+	fd, err := protodesc.NewFile(fdProto, nil)
+	assert.NoError(t, err)
+
+	md := fd.Messages().ByName("result")
+	msg := dynamicpb.NewMessage(md)
+	msg.Set(md.Fields().ByName("value"), protoreflect.ValueOfUint64(150000000))
+	msg.Set(md.Fields().ByName("delta"), protoreflect.ValueOfInt64(-25))
+
+	units := map[string]*ABIUnit{
+		"value":   {Precision: 8, Symbol: "KOIN"},
+		"delta":   {Precision: 2},
+		"name":    {Precision: 8, Symbol: "KOIN"},
+		"missing": {Precision: 8, Symbol: "KOIN"},
+	}
+
+	// Only integer fields that exist are converted
+	amounts, err := unitAmounts(msg, units)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"value": "1.5 KOIN", "delta": "-0.25"}, amounts)
+
+	// Map fields cannot be looked into
+	for _, name := range []string{"balances", "balances.value"} {
+		_, err = unitAmounts(msg, map[string]*ABIUnit{name: {Precision: 8}})
+		assert.ErrorIs(t, err, cliutil.ErrInvalidABI, name)
+	}
+}
+
+func TestReadField(t *testing.T) {
+	dir := t.TempDir()
+
+	abiFilename := writeTestABI(t, dir, "test.abi", nil)
+
+	parser, ee := newTestEnvironment()
+	ir := ParseAndInterpret(parser, ee, "register test 1AdzuXSpC6K9qtXdCBgD5NUpDNwHjMgrc9 "+abiFilename)
+	assert.NoError(t, ir.Err())
+
+	// The field is given with each read, and applies only to it
+	results, err := parser.Parse("test.get_value abc --field header.rc_limit; test.get_value abc")
+	assert.NoError(t, err)
+	path, err := parseFieldPath(results.CommandResults[0])
+	assert.NoError(t, err)
+	assert.Equal(t, "header.rc_limit", path)
+	path, err = parseFieldPath(results.CommandResults[1])
+	assert.NoError(t, err)
+	assert.Equal(t, "", path)
+
+	results, err = parser.Parse("test.get_value abc --field header..rc_limit")
+	assert.NoError(t, err)
+	_, err = parseFieldPath(results.CommandResults[0])
+	assert.True(t, errors.Is(err, cliutil.ErrInvalidParam))
+
+	// Write commands do not take it
+	results, err = parser.Parse("test.set_value abc --field value")
+	assert.NoError(t, err)
+	assert.Nil(t, results.CommandResults[0].Options[FieldOption])
+
+	md := (&protocol.Transaction{}).ProtoReflect().Descriptor()
+	result := []byte(`{"id":"0x1220","header":{"rc_limit":"100","payer":"1abc"}}`)
+
+	value, err := selectResultField(md, result, "header.rc_limit")
+	assert.NoError(t, err)
+	assert.Equal(t, "100", formatFieldValue(value, false))
+	assert.Equal(t, `"100"`, formatFieldValue(value, true))
+
+	value, err = selectResultField(md, result, "header")
+	assert.NoError(t, err)
+	assert.Equal(t, "{\n  \"rc_limit\": \"100\",\n  \"payer\": \"1abc\"\n}", formatFieldValue(value, false))
+
+	// Unset fields are omitted from the JSON, and read as their zero value
+	value, err = selectResultField(md, result, "header.nonce")
+	assert.NoError(t, err)
+	assert.Equal(t, `""`, string(value))
+
+	value, err = selectResultField(md, []byte(`{}`), "header.rc_limit")
+	assert.NoError(t, err)
+	assert.Equal(t, `"0"`, string(value))
+
+	_, err = selectResultField(md, result, "header.missing")
+	assert.True(t, errors.Is(err, cliutil.ErrInvalidParam))
+
+	_, err = selectResultField(md, result, "id.digest")
+	assert.True(t, errors.Is(err, cliutil.ErrInvalidParam))
+}
+
+func TestExitCode(t *testing.T) {
+	assert.Equal(t, ExitSuccess, ExitCode(nil))
+	assert.Equal(t, ExitFailure, ExitCode(errors.New("something else")))
+	assert.Equal(t, ExitParseError, ExitCode(fmt.Errorf("%w: foo", cliutil.ErrUnknownCommand)))
+	assert.Equal(t, ExitWalletError, ExitCode(fmt.Errorf("%w: bad password", cliutil.ErrWalletDecrypt)))
+	assert.Equal(t, ExitWalletError, ExitCode(cliutil.ErrWalletClosed))
+	assert.Equal(t, ExitNetworkError, ExitCode(fmt.Errorf("%w: no response", context.DeadlineExceeded)))
+	assert.Equal(t, ExitNetworkError, ExitCode(fmt.Errorf("calling node: %w", cliutil.KoinosRPCError{})))
+	assert.Equal(t, ExitNetworkError, ExitCode(cliutil.ErrOffline))
+
+	// Parse errors are reported through the interpret results
+	parser, ee := newTestEnvironment()
+	ir := ParseAndInterpret(parser, ee, "not_a_command")
+	assert.True(t, ir.HasError())
+	assert.Equal(t, ExitParseError, ExitCode(ir.Err()))
+}
+
+func TestLogRedaction(t *testing.T) {
+	redacted := cliutil.Redact([]byte(`{"contract_id":"abc","args":{"private_key":"secret","nested":[{"Password":"hunter2"}]}}`))
+	assert.NotContains(t, redacted, "secret")
+	assert.NotContains(t, redacted, "hunter2")
+	assert.Contains(t, redacted, `"contract_id":"abc"`)
+	assert.Equal(t, cliutil.RedactedValue, cliutil.Redact([]byte("not json")))
+
+	var buf bytes.Buffer
+	cliutil.SetLogOutput(&buf)
+	defer cliutil.SetLogOutput(os.Stderr)
+	defer cliutil.SetLogLevel(cliutil.LogLevelNone)
+
+	cliutil.SetLogLevel(cliutil.LogLevelInfo)
+	cliutil.Infof("visible")
+	cliutil.Debugf("hidden")
+	assert.Contains(t, buf.String(), "visible")
+	assert.NotContains(t, buf.String(), "hidden")
+
+	cliutil.SetLogLevel(cliutil.LogLevelDebug)
+	cliutil.Debugf("shown")
+	assert.Contains(t, buf.String(), "shown")
+}
+
+func TestDecodeTransaction(t *testing.T) {
+	fdProto := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("decode_test.proto"),
+		Package: proto.String("test"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("set_value_arguments"), Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("value"), Number: proto.Int32(1), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(), Type: descriptorpb.FieldDescriptorProto_TYPE_UINT32.Enum()},
+			}},
+		},
+	}
+
+	fd, err := protodesc.NewFile(fdProto, nil)
+	assert.NoError(t, err)
+
+	files := &protoregistry.Files{}
+	assert.NoError(t, files.RegisterFile(fd))
+
+	address := "1AdzuXSpC6K9qtXdCBgD5NUpDNwHjMgrc9"
+	abi := &ABI{Methods: map[string]*ABIMethod{"set_value": {Argument: "test.set_value_arguments", EntryPoint: "0x1234abcd"}}}
+	contracts := Contracts{}
+	assert.NoError(t, contracts.Add("test", address, abi, files))
+
+	args := dynamicpb.NewMessage(fd.Messages().ByName("set_value_arguments"))
+	args.Set(args.Descriptor().Fields().ByName("value"), protoreflect.ValueOfUint32(42))
+	argBytes, err := proto.Marshal(args)
+	assert.NoError(t, err)
+
+	transaction := &protocol.Transaction{
+		Id:     []byte{1, 2, 3},
+		Header: &protocol.TransactionHeader{RcLimit: 100000000},
+		Operations: []*protocol.Operation{
+			{Op: &protocol.Operation_CallContract{CallContract: &protocol.CallContractOperation{ContractId: base58.Decode(address), EntryPoint: 0x1234abcd, Args: argBytes}}},
+			{Op: &protocol.Operation_CallContract{CallContract: &protocol.CallContractOperation{ContractId: base58.Decode(address), EntryPoint: 0x01, Args: []byte{0xff}}}},
+		},
+	}
+
+	data, err := proto.Marshal(transaction)
+	assert.NoError(t, err)
+
+	// Hex, with or without 0x, and base64 are accepted
+	for _, encoded := range []string{hex.EncodeToString(data), "0x" + hex.EncodeToString(data), base64.URLEncoding.EncodeToString(data)} {
+		decoded, err := decodeTransaction(encoded)
+		assert.NoError(t, err)
+		assert.True(t, proto.Equal(transaction, decoded))
+	}
+
+	_, err = decodeTransaction("not a transaction")
+	assert.ErrorIs(t, err, cliutil.ErrInvalidParam)
+
+	// Calls to registered methods are decoded, other calls are shown raw
+	assert.Equal(t, `Call test.set_value {"value":42}`, describeOperation(transaction.Operations[0], contracts))
+	assert.Equal(t, "Call contract "+address+" entry point 0x00000001 with arguments 0xff", describeOperation(transaction.Operations[1], contracts))
+}
+
+func TestInvalidContractAddress(t *testing.T) {
+	parser, ee := newTestEnvironment()
+
+	// Registration rejects an invalid address instead of failing later
+	for _, address := range []string{"", "notanaddress", "1AdzuXSpC6K9qtXdCBgD5NUpDNwHjMgrc8"} {
+		_, err := (&RegisterCommand{Name: "test", Address: address}).Execute(context.Background(), ee)
+		assert.ErrorIs(t, err, cliutil.ErrContract, address)
+
+		_, err = (&RegisterTokenCommand{Name: "test", Address: address}).Execute(context.Background(), ee)
+		assert.ErrorIs(t, err, cliutil.ErrContract, address)
+	}
+	assert.False(t, ee.Contracts.Contains("test"))
+
+	// A contract already holding a bad address gives an error when used
+	_, err := contractAddressBytes(&ContractInfo{Name: "test", Address: "notanaddress"})
+	assert.ErrorIs(t, err, cliutil.ErrContract)
+
+	address, err := contractAddressBytes(&ContractInfo{Name: "test", Address: "1AdzuXSpC6K9qtXdCBgD5NUpDNwHjMgrc9"})
+	assert.NoError(t, err)
+	assert.Len(t, address, 25)
+}
+
+func TestSetContractAddress(t *testing.T) {
+	parser, ee := newTestEnvironment()
+
+	oldAddress := "1AdzuXSpC6K9qtXdCBgD5NUpDNwHjMgrc9"
+	newAddress := cliutil.KoinContractID
+
+	ir := ParseAndInterpret(parser, ee, "register_token tkn "+oldAddress+" TKN 8")
+	assert.False(t, ir.HasError())
+
+	ir = ParseAndInterpret(parser, ee, "set_contract_address tkn "+newAddress)
+	assert.False(t, ir.HasError())
+	assert.Equal(t, newAddress, ee.Contracts["tkn"].Address)
+
+	// The existing commands use the new address
+	results, err := parser.Parse("tkn.balance_of " + oldAddress)
+	assert.NoError(t, err)
+	cmd := results.CommandResults[0].Instantiate().(*TokenBalanceCommand)
+	assert.Equal(t, base58.Decode(newAddress), cmd.ContractID)
+
+	// Only registered contracts can be moved, to a valid address
+	ir = ParseAndInterpret(parser, ee, "set_contract_address other "+newAddress)
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrContract)
+
+	_, err = (&SetContractAddressCommand{Name: "tkn", Address: "notanaddress"}).Execute(context.Background(), ee)
+	assert.ErrorIs(t, err, cliutil.ErrContract)
+	assert.Equal(t, newAddress, ee.Contracts["tkn"].Address)
+}
+
+func TestContractsConcurrency(t *testing.T) {
+	contracts := Contracts{}
+	address := "1AdzuXSpC6K9qtXdCBgD5NUpDNwHjMgrc9"
+
+	// Register, move, and remove contracts while others look them up. Run with -race to check the locking
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		name := fmt.Sprintf("contract%d", i)
+
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				assert.NoError(t, contracts.Add(name, address, nil, nil))
+				_, err := contracts.SetAddress(name, cliutil.KoinContractID)
+				assert.NoError(t, err)
+				assert.True(t, contracts.Remove(name))
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				contracts.Contains(name)
+				contracts.GetFromMethodName(name + ".method")
+				contracts.GetMethodByEntryPoint(address, 0x27f576ca)
+				for _, other := range contracts.Names() {
+					if contract, ok := contracts.Get(other); ok {
+						contract.GetAddress()
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Empty(t, contracts.Names())
+}
+
+func TestDecodeEvents(t *testing.T) {
+	fdProto := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("events_test.proto"),
+		Package: proto.String("test"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("value_event"), Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("value"), Number: proto.Int32(1), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(), Type: descriptorpb.FieldDescriptorProto_TYPE_UINT32.Enum()},
+			}},
+		},
+	}
+
+	fd, err := protodesc.NewFile(fdProto, nil)
+	assert.NoError(t, err)
+
+	files := &protoregistry.Files{}
+	assert.NoError(t, files.RegisterFile(fd))
+
+	address := "1AdzuXSpC6K9qtXdCBgD5NUpDNwHjMgrc9"
+	parser, ee := newTestEnvironment()
+	assert.NoError(t, ee.Contracts.Add("test", address, &ABI{Methods: map[string]*ABIMethod{}}, files))
+
+	event := dynamicpb.NewMessage(fd.Messages().ByName("value_event"))
+	event.Set(event.Descriptor().Fields().ByName("value"), protoreflect.ValueOfUint32(7))
+	data, err := proto.Marshal(event)
+	assert.NoError(t, err)
+
+	events := []*protocol.EventData{
+		{Source: base58.Decode(address), Name: "test.value_event", Data: data},
+		{Source: base58.Decode(address), Name: "test.unknown_event", Data: []byte{0xab}},
+	}
+
+	result := NewExecutionResult()
+	ee.addEvents(events, result)
+
+	assert.Equal(t, []string{
+		"Events:",
+		`0: test test.value_event {"value":7}`,
+		"1: " + address + " test.unknown_event 0xab",
+	}, result.Message)
+
+	outputs := result.Fields["events"].([]*EventOutput)
+	assert.Equal(t, "test", outputs[0].Contract)
+	assert.Equal(t, "0xab", outputs[1].Raw)
+}
+
+func TestAddressBookAliases(t *testing.T) {
+	dir := t.TempDir()
+
+	filename := dir + "/aliases"
+	address := "1AdzuXSpC6K9qtXdCBgD5NUpDNwHjMgrc9"
+
+	parser, ee := newTestEnvironment()
+	assert.NoError(t, ee.SetAddressBookFile(filename))
+
+	ir := ParseAndInterpret(parser, ee, "alias_add alice "+address)
+	assert.False(t, ir.HasError())
+
+	// Aliases that are addresses, and aliases for invalid addresses, are rejected
+	ir = ParseAndInterpret(parser, ee, "alias_add "+address+" "+address)
+	assert.Equal(t, ExitParseError, ExitCode(ir.Err()))
+	ir = ParseAndInterpret(parser, ee, "alias_add bob 1AdzuXSpC6K9qtXdCBgD5NUpDNwHjMgrc8")
+	assert.True(t, ir.HasError())
+
+	// Aliases resolve to their address in address arguments
+	results, err := parser.Parse("balance alice")
+	assert.NoError(t, err)
+	assert.Equal(t, address, *results.CommandResults[0].Args["address"])
+
+	// The address book is saved, and loaded by a new environment
+	_, other := newTestEnvironment()
+	assert.NoError(t, other.SetAddressBookFile(filename))
+	assert.Equal(t, map[string]string{"alice": address}, other.Parser.Aliases)
+
+	ir = ParseAndInterpret(parser, ee, "alias_remove alice")
+	assert.False(t, ir.HasError())
+	_, err = parser.Parse("balance alice")
+	assert.ErrorIs(t, err, cliutil.ErrInvalidParam)
+}
+
+func TestCommandAliases(t *testing.T) {
+	dir := t.TempDir()
+
+	filename := dir + "/commands"
+	address := "1AdzuXSpC6K9qtXdCBgD5NUpDNwHjMgrc9"
+
+	parser, ee := newTestEnvironment()
+	assert.NoError(t, ee.SetCommandAliasFile(filename))
+
+	ir := ParseAndInterpret(parser, ee, "alias bal balance "+address)
+	assert.False(t, ir.HasError())
+
+	// The alias expands to its command line, followed by any further arguments
+	results, err := parser.Parse("bal")
+	assert.NoError(t, err)
+	assert.Equal(t, "balance", results.CommandResults[0].CommandName)
+	assert.Equal(t, address, *results.CommandResults[0].Args["address"])
+
+	ir = ParseAndInterpret(parser, ee, "alias b balance")
+	assert.False(t, ir.HasError())
+	results, err = parser.Parse("b " + address + "; nonce")
+	assert.NoError(t, err)
+	assert.Len(t, results.CommandResults, 2)
+	assert.Equal(t, address, *results.CommandResults[0].Args["address"])
+
+	// Aliases may expand to other aliases, and quoted values are kept intact
+	ir = ParseAndInterpret(parser, ee, "alias bb b")
+	assert.False(t, ir.HasError())
+	ir = ParseAndInterpret(parser, ee, "alias note sign_message \"hello; world\"")
+	assert.False(t, ir.HasError())
+	results, err = parser.Parse("note")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello; world", *results.CommandResults[0].Args["message"])
+
+	// Built in commands cannot be hidden, and aliases cannot expand to themselves
+	ir = ParseAndInterpret(parser, ee, "alias balance nonce")
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrInvalidCommandName)
+	ir = ParseAndInterpret(parser, ee, "alias b bb")
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrInvalidCommandName)
+	assert.Equal(t, "balance", parser.CommandAliases["b"])
+
+	// Loops through any of the commands of an alias are refused, not only through the first
+	ir = ParseAndInterpret(parser, ee, `alias loop "nonce; loop"`)
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrInvalidCommandName)
+	_, ok := parser.CommandAliases["loop"]
+	assert.False(t, ok)
+	ir = ParseAndInterpret(parser, ee, `alias first "nonce; second"`)
+	assert.NoError(t, ir.Err())
+	ir = ParseAndInterpret(parser, ee, `alias second "balance; first"`)
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrInvalidCommandName)
+	ir = ParseAndInterpret(parser, ee, `alias quoted "sign_message 'first; second'; nonce"`)
+	assert.NoError(t, ir.Err())
+
+	// Parsing still ends for a loop that was not refused
+	parser.CommandAliases["loop"] = "nonce; loop"
+	_, err = parser.Parse("loop")
+	assert.ErrorIs(t, err, cliutil.ErrInvalidCommandName)
+	delete(parser.CommandAliases, "loop")
+
+	// The aliases are saved, and loaded by a new environment
+	_, other := newTestEnvironment()
+	assert.NoError(t, other.SetCommandAliasFile(filename))
+	assert.Equal(t, parser.CommandAliases, other.Parser.CommandAliases)
+
+	ir = ParseAndInterpret(parser, ee, "unalias bal")
+	assert.False(t, ir.HasError())
+	_, err = parser.Parse("bal")
+	assert.ErrorIs(t, err, cliutil.ErrUnknownCommand)
+}
+
+func TestCommandSuggestions(t *testing.T) {
+	parser, ee := newTestEnvironment()
+
+	// Typos suggest the closest commands
+	_, err := parser.Parse("balnce")
+	assert.ErrorIs(t, err, cliutil.ErrUnknownCommand)
+	assert.Equal(t, "unknown command: balnce, did you mean balance or balances?", err.Error())
+
+	assert.Equal(t, []string{"balance", "balances"}, parser.Commands.Suggest("balanse"))
+
+	// Part of a command name matches the commands containing it
+	assert.Equal(t, []string{"list", "list_keys", "list_wallets"}, parser.Commands.Suggest("list_"))
+	assert.Equal(t, []string{"use_wallet", "list_wallets"}, parser.Commands.Suggest("wallet"))
+
+	// Hidden commands and unrelated names are not suggested
+	assert.NotContains(t, parser.Commands.Suggest("unlok"), "unlock")
+	_, err = parser.Parse("xyzzy")
+	assert.Equal(t, "unknown command: xyzzy", err.Error())
+
+	// A mistyped method of a registered contract is matched among the contract's commands
+	parser.Commands.AddCommand(NewCommandDeclaration("koin.transfer", "", false, nil))
+	parser.Commands.AddCommand(NewCommandDeclaration("koin.balance_of", "", false, nil))
+	parser.Commands.AddCommand(NewCommandDeclaration("koin.total_supply", "", false, nil))
+	assert.Equal(t, []string{"koin.transfer"}, parser.Commands.Suggest("koin.tranfser"))
+	assert.Equal(t, []string{"koin.balance_of"}, parser.Commands.Suggest("koin.balance"))
+
+	ir := ParseAndInterpret(parser, ee, "help koin.totl_supply")
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrUnknownCommand)
+	assert.Contains(t, ir.Err().Error(), "did you mean koin.total_supply?")
+
+	assert.Equal(t, 0, cliutil.EditDistance("balance", "balance"))
+	assert.Equal(t, 2, cliutil.EditDistance("transfer", "tranfser"))
+	assert.Equal(t, 3, cliutil.EditDistance("", "abc"))
+}
+
+func TestHelpPrefix(t *testing.T) {
+	parser, ee := newTestEnvironment()
+	parser.Commands.AddCommand(NewCommandDeclaration("koin.transfer", "Transfer tokens", false, nil))
+	parser.Commands.AddCommand(NewCommandDeclaration("koin.balance_of", "Get a balance", false, nil))
+	parser.Commands.AddCommand(NewCommandDeclaration("vhp.balance_of", "Get a balance", false, nil))
+
+	// A prefix lists the matching commands, and hidden commands are excluded
+	ir := ParseAndInterpret(parser, ee, "help list_")
+	assert.NoError(t, ir.Err())
+	messages := ir.Outputs[0].Messages
+	assert.Len(t, messages, 3)
+	assert.True(t, strings.HasPrefix(messages[0], "list_contracts "), messages[0])
+	assert.True(t, strings.HasPrefix(messages[1], "list_keys "), messages[1])
+	assert.True(t, strings.HasPrefix(messages[2], "list_wallets "), messages[2])
+
+	ir = ParseAndInterpret(parser, ee, "help qui")
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrUnknownCommand)
+
+	// Contract commands are grouped under their contract
+	ir = ParseAndInterpret(parser, ee, "help koin")
+	assert.NoError(t, ir.Err())
+	messages = ir.Outputs[0].Messages
+	assert.Len(t, messages, 3)
+	assert.Equal(t, "koin:", messages[0])
+	assert.Equal(t, "  koin.balance_of - Get a balance", messages[1])
+	assert.Equal(t, "  koin.transfer   - Transfer tokens", messages[2])
+
+	// Without a prefix, every command is listed, with the contracts last
+	ir = ParseAndInterpret(parser, ee, "help")
+	assert.NoError(t, ir.Err())
+	messages = ir.Outputs[0].Messages
+	assert.Equal(t, "vhp:", messages[len(messages)-2])
+	for _, line := range messages {
+		assert.False(t, strings.HasPrefix(line, "quit "), line)
+	}
+
+	// An exact name still shows the command's help
+	ir = ParseAndInterpret(parser, ee, "help list")
+	assert.NoError(t, ir.Err())
+	assert.Equal(t, "Usage: list", ir.Outputs[0].Messages[1])
+}
+
+func TestChainID(t *testing.T) {
+	parser, ee := newTestEnvironment()
+
+	// An automatic chain id cannot be fetched offline
+	_, err := ee.GetChainID(context.Background())
+	assert.ErrorIs(t, err, cliutil.ErrOffline)
+
+	// A manual chain id is used as given, and must not be empty
+	ir := ParseAndInterpret(parser, ee, "chain_id EiBZK_GGVP0H_fXVAM3j6EAuz3-B-l3ejxRSewi7qIBfSA==")
+	assert.False(t, ir.HasError())
+
+	chainID, err := ee.GetChainID(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, chainID, 34)
+
+	ir = ParseAndInterpret(parser, ee, "chain_id ''")
+	assert.Equal(t, ExitParseError, ExitCode(ir.Err()))
+}
+
+func TestPasswordFile(t *testing.T) {
+	dir := t.TempDir()
+
+	filename := dir + "/password"
+	assert.NoError(t, ioutil.WriteFile(filename, []byte("hunter2\n"), 0600))
+
+	// Trailing line endings are trimmed
+	pass, err := cliutil.ReadPasswordFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2", pass)
+
+	assert.NoError(t, ioutil.WriteFile(filename, []byte("hunter 2\r\n\n"), 0600))
+	pass, err = cliutil.ReadPasswordFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter 2", pass)
+	assert.NoError(t, ioutil.WriteFile(filename, []byte("hunter2\n"), 0600))
+
+	// The file is used when no password is given
+	defer os.Setenv(cliutil.WalletPassEnvVar, os.Getenv(cliutil.WalletPassEnvVar))
+	os.Unsetenv(cliutil.WalletPassEnvVar)
+	defer os.Unsetenv(cliutil.WalletPassFileEnvVar)
+	os.Setenv(cliutil.WalletPassFileEnvVar, filename)
+	pass, err = cliutil.GetPassword(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2", pass)
+
+	// A wallet can be created and opened with a password file, instead of a password
+	parser, ee := newTestEnvironment()
+	ir := ParseAndInterpret(parser, ee, "create "+dir+"/main.wallet --password-file "+filename)
+	assert.NoError(t, ir.Err())
+	ee.CloseWallet()
+
+	ir = ParseAndInterpret(parser, ee, "open "+dir+"/main.wallet --password-file "+filename)
+	assert.NoError(t, ir.Err())
+	assert.True(t, ee.IsWalletOpen())
+
+	ir = ParseAndInterpret(parser, ee, "open "+dir+"/main.wallet hunter2 --password-file "+filename)
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrInvalidParam)
+
+	assert.NoError(t, ioutil.WriteFile(dir+"/wrong", []byte("hunter3"), 0600))
+	ir = ParseAndInterpret(parser, ee, "open "+dir+"/main.wallet --password-file "+dir+"/wrong")
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrWalletDecrypt)
+
+	// A file other users can read is refused
+	if runtime.GOOS != "windows" {
+		assert.NoError(t, os.Chmod(filename, 0604))
+		_, err = cliutil.GetPassword(nil)
+		assert.Error(t, err)
+	}
+}
+
+func TestWatchBalanceCommand(t *testing.T) {
+	parser := NewCommandParser(NewKoinosCommandSet())
+
+	results, err := parser.Parse("watch_balance 2.5 1AdzuXSpC6K9qtXdCBgD5NUpDNwHjMgrc9")
+	assert.NoError(t, err)
+
+	cmd, ok := results.CommandResults[0].Instantiate().(*BalanceCommand)
+	assert.True(t, ok)
+	assert.Equal(t, 2500*time.Millisecond, cmd.Watch)
+	assert.Equal(t, "1AdzuXSpC6K9qtXdCBgD5NUpDNwHjMgrc9", *cmd.Address)
+	assert.Nil(t, cmd.Contract)
+}
+
+func TestParseOnly(t *testing.T) {
+	parser, ee := newTestEnvironment()
+
+	key, err := util.GenerateKoinosKey()
+	assert.NoError(t, err)
+	ee.OpenWallet(key)
+
+	// Without a connection, transactions cannot be submitted
+	ir := ParseAndInterpret(parser, ee, "transfer 1.5 1AdzuXSpC6K9qtXdCBgD5NUpDNwHjMgrc9")
+	assert.Equal(t, ExitNetworkError, ExitCode(ir.Err()))
+
+	// In parse only mode, the operations are shown instead
+	ir = ParseAndInterpret(parser, ee, "parse_only true; transfer 1.5 1AdzuXSpC6K9qtXdCBgD5NUpDNwHjMgrc9")
+	assert.False(t, ir.HasError())
+
+	fields := ir.Outputs[1].Fields
+	assert.Equal(t, true, fields["parse_only"])
+	operations := fields["operations"].([]string)
+	assert.Len(t, operations, 1)
+	assert.True(t, strings.HasPrefix(operations[0], "Call contract "+cliutil.KoinContractID+" entry point 0x27f576ca"))
+}
+
+func TestConfirm(t *testing.T) {
+	parser, ee := newTestEnvironment()
+
+	prompts := 0
+	answer := false
+	ee.confirmPrompt = func(prompt string) (bool, error) {
+		prompts++
+		return answer, nil
+	}
+
+	ops := []*protocol.Operation{{Op: &protocol.Operation_CallContract{CallContract: &protocol.CallContractOperation{ContractId: base58.Decode(cliutil.KoinContractID), EntryPoint: 0x27f576ca}}}}
+
+	// Confirmation is opt in
+	assert.NoError(t, ee.confirmOperations(ops))
+	assert.Equal(t, 0, prompts)
+
+	ir := ParseAndInterpret(parser, ee, "confirm true")
+	assert.False(t, ir.HasError())
+	assert.True(t, ee.confirm)
+
+	// The operations are shown on the output of the commands
+	var buf bytes.Buffer
+	ee.output = &buf
+
+	// Anything but yes cancels the transaction
+	assert.ErrorIs(t, ee.confirmOperations(ops), cliutil.ErrNotConfirmed)
+	assert.True(t, strings.HasPrefix(buf.String(), "Transaction with 1 operations:\n0: Call contract "+cliutil.KoinContractID))
+	answer = true
+	assert.NoError(t, ee.confirmOperations(ops))
+	assert.Equal(t, 2, prompts)
+
+	// --yes skips the prompt
+	ee.SetAssumeYes(true)
+	answer = false
+	assert.NoError(t, ee.confirmOperations(ops))
+	assert.Equal(t, 2, prompts)
+
+	// A slow answer does not use up the rpc timeout of the calls made after it
+	server := testRPCServer(t, map[string]string{cliutil.GetHeadInfoCall: `{}`})
+	defer server.Close()
+
+	cs := NewCommandSet()
+	cs.AddCommand(NewCommandDeclaration("confirm_then_call", "Confirm, then make an rpc call", false, func(inv *CommandParseResult) Command { return &confirmTestCommand{ops: ops} }))
+	parser = NewCommandParser(cs)
+	ee = NewExecutionEnvironment(cliutil.NewKoinosRPCClient(server.URL), parser)
+	ee.SetConfirm(true)
+	ee.SetRPCTimeout(50 * time.Millisecond)
+	ee.confirmPrompt = func(prompt string) (bool, error) {
+		time.Sleep(100 * time.Millisecond)
+		return true, nil
+	}
+
+	ir = ParseAndInterpret(parser, ee, "confirm_then_call")
+	assert.NoError(t, ir.Err())
+}
+
+// confirmTestCommand confirms its operations, then makes an rpc call as submitting them would
+type confirmTestCommand struct {
+	ops []*protocol.Operation
+}
+
+func (c *confirmTestCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	if err := ee.confirmOperations(c.ops); err != nil {
+		return nil, err
+	}
+
+	_, err := ee.RPCClient.GetHeadInfo(ctx)
+	return NewExecutionResult(), err
+}
+
+func TestHistory(t *testing.T) {
+	parser, ee := newTestEnvironment()
+
+	ir := ParseAndInterpret(parser, ee, "history")
+	assert.False(t, ir.HasError())
+	assert.Equal(t, []string{"No transactions have been submitted"}, ir.Results)
+
+	ops := []*protocol.Operation{{Op: &protocol.Operation_CallContract{CallContract: &protocol.CallContractOperation{ContractId: base58.Decode(cliutil.KoinContractID), EntryPoint: 0x27f576ca}}}}
+	first := ee.recordTransaction(&protocol.TransactionReceipt{Id: []byte{0x12, 0x20, 0x01}}, ops)
+	first.Status = TransactionIncluded
+	first.BlockID = "0x1220ab"
+	ee.recordTransaction(&protocol.TransactionReceipt{Id: []byte{0x12, 0x20, 0x02}, Reverted: true}, ops)
+
+	ir = ParseAndInterpret(parser, ee, "history")
+	assert.False(t, ir.HasError())
+	assert.Len(t, ir.Results, 4)
+	assert.True(t, strings.HasSuffix(ir.Results[0], " 0x122001 (included in block 0x1220ab)"), ir.Results[0])
+	assert.True(t, strings.HasPrefix(ir.Results[1], "   Call contract "+cliutil.KoinContractID), ir.Results[1])
+	assert.True(t, strings.HasSuffix(ir.Results[2], " 0x122002 (submitted, reverted)"), ir.Results[2])
+
+	transactions := ir.Outputs[0].Fields["transactions"].([]*SubmittedTransaction)
+	assert.Len(t, transactions, 2)
+	assert.Equal(t, TransactionSubmitted, transactions[1].Status)
+}
+
+func TestReceiptCommand(t *testing.T) {
+	parser, ee := newTestEnvironment()
+
+	// The transaction id must be hex
+	ir := ParseAndInterpret(parser, ee, "receipt txid")
+	assert.Equal(t, ExitParseError, ExitCode(ir.Err()))
+
+	// Looking up a receipt requires a connection, with or without the 0x prefix
+	for _, id := range []string{"0x1220ab", "1220ab"} {
+		ir = ParseAndInterpret(parser, ee, "receipt "+id)
+		assert.ErrorIs(t, ir.Err(), cliutil.ErrOffline)
+	}
+}
+
+func TestReadCommand(t *testing.T) {
+	server := testRPCServer(t, map[string]string{cliutil.ReadContractCall: `{"result":"CAE="}`})
+	defer server.Close()
+
+	parser, ee := newTestEnvironment()
+	ee.RPCClient = cliutil.NewKoinosRPCClient(server.URL)
+
+	// The raw result is shown on one line as multibase base64, and as hex in the JSON output
+	for _, args := range []string{"0x", "0x0801", "MCAE="} {
+		ir := ParseAndInterpret(parser, ee, "read "+cliutil.KoinContractID+" 0x5c721497 "+args)
+		assert.NoError(t, ir.Err(), args)
+		assert.Equal(t, []string{"MCAE="}, ir.Outputs[0].Messages, args)
+		assert.Equal(t, "0x0801", ir.Outputs[0].Fields["result"], args)
+	}
+}
+
+func TestAccountHistory(t *testing.T) {
+	parser, ee := newTestEnvironment()
+
+	// Without an address, the history is the open wallet's
+	ir := ParseAndInterpret(parser, ee, "account_history")
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrWalletClosed)
+
+	for _, limit := range []string{"0", "101"} {
+		ir = ParseAndInterpret(parser, ee, "account_history "+cliutil.KoinContractID+" --limit "+limit)
+		assert.ErrorIs(t, ir.Err(), cliutil.ErrInvalidParam, limit)
+	}
+
+	ir = ParseAndInterpret(parser, ee, "account_history "+cliutil.KoinContractID)
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrOffline)
+
+	key1, err := util.GenerateKoinosKey()
+	assert.NoError(t, err)
+	key2, err := util.GenerateKoinosKey()
+	assert.NoError(t, err)
+	tokenAddress := base58.Encode(key2.AddressBytes())
+
+	transferOp := func(contractID []byte, from []byte, to []byte, value uint64) *protocol.Operation {
+		args, err := proto.Marshal(&token.TransferArguments{From: from, To: to, Value: value})
+		assert.NoError(t, err)
+		return &protocol.Operation{Op: &protocol.Operation_CallContract{CallContract: &protocol.CallContractOperation{ContractId: contractID, EntryPoint: TokenTransferEntry, Args: args}}}
+	}
+
+	// KOIN transfers are shown in KOIN
+	transfer := ee.decodeTransfer(transferOp(base58.Decode(cliutil.KoinContractID), key1.AddressBytes(), key2.AddressBytes(), 150000000), key1.AddressBytes())
+	assert.Equal(t, "Sent 1.5 KOIN to "+base58.Encode(key2.AddressBytes()), transfer.String())
+
+	transfer = ee.decodeTransfer(transferOp(base58.Decode(cliutil.KoinContractID), key2.AddressBytes(), key1.AddressBytes(), 150000000), key1.AddressBytes())
+	assert.Equal(t, TransferReceived, transfer.Direction)
+	assert.Equal(t, base58.Encode(key2.AddressBytes()), transfer.Counterparty)
+
+	// Transfers between other addresses are not the account's
+	assert.Nil(t, ee.decodeTransfer(transferOp(base58.Decode(cliutil.KoinContractID), key2.AddressBytes(), key2.AddressBytes(), 1), key1.AddressBytes()))
+
+	// Unregistered tokens show the raw amount, registered ones their units
+	op := transferOp(key2.AddressBytes(), key1.AddressBytes(), key2.AddressBytes(), 1234)
+	assert.Equal(t, "Sent 1234 of token "+tokenAddress+" to "+tokenAddress, ee.decodeTransfer(op, key1.AddressBytes()).String())
+
+	assert.NoError(t, ee.Contracts.Add("tok", tokenAddress, nil, nil))
+	assert.NoError(t, ee.Contracts.SetUnits("tok", &ABIUnit{Precision: 2, Symbol: "TOK"}))
+	assert.Equal(t, "Sent 12.34 TOK to "+tokenAddress, ee.decodeTransfer(op, key1.AddressBytes()).String())
+
+	// However many entries there are, their blocks are looked up with one call for the transactions and one for the blocks
+	var lock sync.Mutex
+	calls := make(map[string]int)
+	var historyParams map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     int                    `json:"id"`
+			Method string                 `json:"method"`
+			Params map[string]interface{} `json:"params"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		lock.Lock()
+		calls[req.Method]++
+		if req.Method == cliutil.GetAccountHistoryCall {
+			historyParams = req.Params
+		}
+		lock.Unlock()
+
+		results := map[string]string{
+			cliutil.GetAccountHistoryCall: `{"values":[{"seq_num":"12","trx":{"transaction":{"id":"0x1220aa"}}},{"seq_num":"11","trx":{"transaction":{"id":"0x1220bb"}}},{"seq_num":"10","trx":{"transaction":{"id":"0x1220cc"}}}]}`,
+			cliutil.GetTransactionsByID:   `{"transactions":[{"transaction":{"id":"0x1220aa"},"containing_blocks":["0x1220b1"]},{"transaction":{"id":"0x1220bb"},"containing_blocks":["0x1220b1"]}]}`,
+			cliutil.GetBlocksByIDCall:     `{"block_items":[{"block_id":"0x1220b1","block_height":"42","block":{"header":{"height":"42","timestamp":"1000"}}}]}`,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":%s}`, req.ID, results[req.Method])
+	}))
+	defer server.Close()
+
+	ee.RPCClient = cliutil.NewKoinosRPCClient(server.URL)
+	ir = ParseAndInterpret(parser, ee, "account_history "+tokenAddress)
+	assert.NoError(t, ir.Err())
+
+	items := ir.Outputs[0].Fields["history"].([]*AccountHistoryItem)
+	assert.Len(t, items, 3)
+	assert.Equal(t, uint64(42), items[0].BlockHeight)
+	assert.Equal(t, uint64(42), items[1].BlockHeight)
+	assert.Equal(t, uint64(0), items[2].BlockHeight)
+	assert.Equal(t, uint64(10), items[2].SeqNum)
+
+	lock.Lock()
+	assert.Equal(t, map[string]int{cliutil.GetAccountHistoryCall: 1, cliutil.GetTransactionsByID: 1, cliutil.GetBlocksByIDCall: 1}, calls)
+	assert.Equal(t, "10", historyParams["limit"])
+	assert.NotContains(t, historyParams, "seq_num")
+	lock.Unlock()
+
+	// The start is passed on, and a full page tells where the older entries start
+	ir = ParseAndInterpret(parser, ee, "account_history "+tokenAddress+" 12 --limit 3")
+	assert.NoError(t, ir.Err())
+	assert.Equal(t, "Older entries may follow, list them with start 9", ir.Outputs[0].Messages[len(ir.Outputs[0].Messages)-1])
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, "12", historyParams["seq_num"])
+	assert.Equal(t, "3", historyParams["limit"])
+}
+
+func TestMultipleWallets(t *testing.T) {
+	dir := t.TempDir()
+
+	key1, err := util.GenerateKoinosKey()
+	assert.NoError(t, err)
+	key2, err := util.GenerateKoinosKey()
+	assert.NoError(t, err)
+
+	assert.NoError(t, cliutil.WriteWalletKeysFile(dir+"/alice.wallet", "password", []cliutil.WalletKey{{Label: cliutil.DefaultKeyLabel, PrivateKey: key1.PrivateBytes()}}))
+	assert.NoError(t, cliutil.WriteWalletKeysFile(dir+"/bob.wallet", "password", []cliutil.WalletKey{{Label: cliutil.DefaultKeyLabel, PrivateKey: key2.PrivateBytes()}}))
+
+	parser, ee := newTestEnvironment()
+
+	// Opening a second wallet keeps the first open, and makes the second active
+	ir := ParseAndInterpret(parser, ee, "open "+dir+"/alice.wallet password; open "+dir+"/bob.wallet password")
+	assert.False(t, ir.HasError())
+	assert.Len(t, ee.wallets, 2)
+	assert.Equal(t, key2.AddressBytes(), ee.Key.AddressBytes())
+
+	ir = ParseAndInterpret(parser, ee, "use_wallet alice")
+	assert.False(t, ir.HasError())
+	assert.Equal(t, key1.AddressBytes(), ee.Key.AddressBytes())
+	assert.Equal(t, dir+"/alice.wallet", ee.walletFile)
+
+	ir = ParseAndInterpret(parser, ee, "list_wallets")
+	assert.False(t, ir.HasError())
+	address1, address2 := base58.Encode(key1.AddressBytes()), base58.Encode(key2.AddressBytes())
+	width := len("ADDRESS")
+	for _, a := range []string{address1, address2} {
+		if len(a) > width {
+			width = len(a)
+		}
+	}
+	assert.Equal(t, []string{
+		fmt.Sprintf("   LABEL  %-*s  FILE", width, "ADDRESS"),
+		fmt.Sprintf("   -----  %-*s  ----", width, "-------"),
+		fmt.Sprintf("*  alice  %-*s  %s", width, address1, dir+"/alice.wallet"),
+		fmt.Sprintf("   bob    %-*s  %s", width, address2, dir+"/bob.wallet"),
+	}, ir.Outputs[0].Messages)
+
+	ir = ParseAndInterpret(parser, ee, "use_wallet carol")
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrInvalidParam)
+
+	// Closing closes every wallet
+	ir = ParseAndInterpret(parser, ee, "close")
+	assert.False(t, ir.HasError())
+	assert.False(t, ee.IsWalletOpen())
+	assert.Len(t, ee.wallets, 0)
+}
+
+func TestSignAndVerifyMessage(t *testing.T) {
+	parser, ee := newTestEnvironment()
+
+	// Signing requires an open wallet
+	ir := ParseAndInterpret(parser, ee, "sign_message 'hello world'")
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrWalletClosed)
+
+	key, err := util.GenerateKoinosKey()
+	assert.NoError(t, err)
+	ee.OpenWallet(key)
+
+	ir = ParseAndInterpret(parser, ee, "sign_message 'hello world'")
+	assert.False(t, ir.HasError())
+	signature := ir.Outputs[0].Fields["signature"].(string)
+	address := base58.Encode(key.AddressBytes())
+
+	ir = ParseAndInterpret(parser, ee, "verify_message "+address+" 'hello world' "+signature)
+	assert.False(t, ir.HasError())
+
+	// A different message or address does not verify
+	ir = ParseAndInterpret(parser, ee, "verify_message "+address+" 'hello there' "+signature)
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrInvalidParam)
+
+	ir = ParseAndInterpret(parser, ee, "verify_message 1AdzuXSpC6K9qtXdCBgD5NUpDNwHjMgrc9 'hello world' "+signature)
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrInvalidParam)
+
+	// The signed digest includes the message prefix and length, so it differs from a plain sha256 hash
+	digest := sha256.Sum256([]byte("\x19Koinos Signed Message:\n11hello world"))
+	privateKey, _ := btcec.PrivKeyFromBytes(btcec.S256(), key.PrivateBytes())
+	expected, err := btcec.SignCompact(btcec.S256(), privateKey, digest[:], true)
+	assert.NoError(t, err)
+	assert.Equal(t, base64.URLEncoding.EncodeToString(expected), signature)
+
+	plain := sha256.Sum256([]byte("hello world"))
+	unprefixed, err := btcec.SignCompact(btcec.S256(), privateKey, plain[:], true)
+	assert.NoError(t, err)
+	ir = ParseAndInterpret(parser, ee, "verify_message "+address+" 'hello world' "+base64.URLEncoding.EncodeToString(unprefixed))
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrInvalidParam)
+}
+
+func TestGenerateAddresses(t *testing.T) {
+	dir := t.TempDir()
+
+	parser, ee := newTestEnvironment()
+
+	for _, bad := range []string{"genaddr 0", "genaddr 3 xml"} {
+		ir := ParseAndInterpret(parser, ee, bad)
+		assert.ErrorIs(t, ir.Err(), cliutil.ErrInvalidParam, bad)
+	}
+
+	// The keys are shown after a warning, and each private key belongs to its address
+	ir := ParseAndInterpret(parser, ee, "genaddr 3")
+	assert.False(t, ir.HasError())
+	messages := ir.Outputs[0].Messages
+	assert.Len(t, messages, 4)
+	assert.True(t, strings.HasPrefix(messages[0], "WARNING:"))
+
+	keys := ir.Outputs[0].Fields["keys"].([]*GeneratedKey)
+	assert.Len(t, keys, 3)
+	for i, k := range keys {
+		keyBytes, err := cliutil.DecodePrivateKey(k.PrivateKey)
+		assert.NoError(t, err)
+		key, err := util.NewKoinosKeyFromBytes(keyBytes)
+		assert.NoError(t, err)
+		assert.Equal(t, k.Address, base58.Encode(key.AddressBytes()))
+		assert.Equal(t, fmt.Sprintf("%d: Address: %s Private: %s", i, k.Address, k.PrivateKey), messages[i+1])
+	}
+
+	// Keys written to a file are not shown
+	filename := filepath.Join(dir, "keys.json")
+	ir = ParseAndInterpret(parser, ee, "genaddr 2 json "+filename)
+	assert.False(t, ir.HasError())
+	assert.Nil(t, ir.Outputs[0].Fields["keys"])
+
+	var saved []*GeneratedKey
+	data, err := ioutil.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(data, &saved))
+	assert.Len(t, saved, 2)
+	assert.Equal(t, ir.Outputs[0].Fields["addresses"], []string{saved[0].Address, saved[1].Address})
+
+	info, err := os.Stat(filename)
+	assert.NoError(t, err)
+	if runtime.GOOS != "windows" {
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	}
+
+	// An existing file is not overwritten
+	ir = ParseAndInterpret(parser, ee, "genaddr 2 csv "+filename)
+	assert.True(t, ir.HasError())
+
+	ir = ParseAndInterpret(parser, ee, "genaddr 2 csv")
+	assert.False(t, ir.HasError())
+	assert.Equal(t, "address,public_key,private_key", ir.Outputs[0].Messages[1])
+	assert.Len(t, ir.Outputs[0].Messages, 4)
+}
+
+func TestEncryptFile(t *testing.T) {
+	dir := t.TempDir()
+
+	plain := filepath.Join(dir, "notes.txt")
+	encrypted := filepath.Join(dir, "notes.enc")
+	decrypted := filepath.Join(dir, "notes.out")
+	assert.NoError(t, ioutil.WriteFile(plain, []byte("secret notes"), 0600))
+
+	parser, ee := newTestEnvironment()
+
+	ir := ParseAndInterpret(parser, ee, "encrypt_file "+plain+" "+encrypted+" password")
+	assert.False(t, ir.HasError())
+
+	data, err := ioutil.ReadFile(encrypted)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "secret notes")
+
+	// The output is never overwritten
+	ir = ParseAndInterpret(parser, ee, "encrypt_file "+plain+" "+encrypted+" password")
+	assert.True(t, ir.HasError())
+
+	// The wrong password fails without leaving an output file
+	ir = ParseAndInterpret(parser, ee, "decrypt_file "+encrypted+" "+decrypted+" wrong")
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrWalletDecrypt)
+	_, err = os.Stat(decrypted)
+	assert.True(t, os.IsNotExist(err))
+
+	ir = ParseAndInterpret(parser, ee, "decrypt_file "+encrypted+" "+decrypted+" password")
+	assert.False(t, ir.HasError())
+	data, err = ioutil.ReadFile(decrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret notes", string(data))
+
+	// The passphrase cannot be empty
+	assert.ErrorIs(t, cliutil.EncryptFile(plain, filepath.Join(dir, "empty.enc"), ""), cliutil.ErrEmptyPassphrase)
+	assert.ErrorIs(t, cliutil.DecryptFile(encrypted, filepath.Join(dir, "empty.out"), ""), cliutil.ErrEmptyPassphrase)
+}
+
+func TestSigners(t *testing.T) {
+	key, err := util.GenerateKoinosKey()
+	assert.NoError(t, err)
+	other, err := util.GenerateKoinosKey()
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	ops := []*protocol.Operation{{Op: &protocol.Operation_CallContract{CallContract: &protocol.CallContractOperation{ContractId: base58.Decode(cliutil.KoinContractID), EntryPoint: TokenTransferEntry}}}}
+	chainID := []byte{1, 2, 3}
+
+	expected, err := cliutil.CreateTransaction(ctx, ops, key.AddressBytes(), 1, 100, chainID, key.AddressBytes())
+	assert.NoError(t, err)
+	assert.NoError(t, cliutil.SignTransaction(key.PrivateBytes(), expected))
+
+	// The key signer signs with the key in memory
+	tx, err := cliutil.CreateSignedTransaction(ctx, ops, cliutil.NewKeySigner(key), 1, 100, chainID, key.AddressBytes())
+	assert.NoError(t, err)
+	assert.Equal(t, expected.Signatures, tx.Signatures)
+
+	// An external signer is handed the digest of the transaction id
+	deviceSign := func(signingKey *util.KoinosKey) cliutil.SignDigestFunc {
+		return func(ctx context.Context, digest []byte) ([]byte, error) {
+			privateKey, _ := btcec.PrivKeyFromBytes(btcec.S256(), signingKey.PrivateBytes())
+			return btcec.SignCompact(btcec.S256(), privateKey, digest, true)
+		}
+	}
+
+	device := cliutil.NewExternalSigner("test device", key.AddressBytes(), deviceSign(key))
+	tx, err = cliutil.CreateSignedTransaction(ctx, ops, device, 1, 100, chainID, key.AddressBytes())
+	assert.NoError(t, err)
+	assert.Equal(t, expected.Signatures, tx.Signatures)
+
+	// A signature from the wrong key, or a failure on the device, is an error
+	_, err = cliutil.CreateSignedTransaction(ctx, ops, cliutil.NewExternalSigner("test device", key.AddressBytes(), deviceSign(other)), 1, 100, chainID, key.AddressBytes())
+	assert.ErrorIs(t, err, cliutil.ErrInvalidResponse)
+
+	rejected := cliutil.NewExternalSigner("test device", key.AddressBytes(), func(ctx context.Context, digest []byte) ([]byte, error) {
+		return nil, cliutil.ErrNotConfirmed
+	})
+	_, err = cliutil.CreateSignedTransaction(ctx, ops, rejected, 1, 100, chainID, key.AddressBytes())
+	assert.ErrorIs(t, err, cliutil.ErrNotConfirmed)
+
+	// The environment signs with the wallet's key unless given a signer, which is dropped when the wallet is closed
+	_, ee := newTestEnvironment()
+	ee.OpenWallet(key)
+	_, ok := ee.transactionSigner().(*cliutil.KeySigner)
+	assert.True(t, ok)
+
+	ee.SetSigner(device)
+	assert.Equal(t, device, ee.transactionSigner())
+
+	ee.CloseWallet()
+	assert.Nil(t, ee.signer)
+}
+
+// testContractABI returns the JSON ABI of a contract with a single read method taking a key, including the given address if not empty
+func testContractABI(t *testing.T, address string) []byte {
+	fdProto := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("register_test.proto"),
+		Package: proto.String("test"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("get_value_arguments"), Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("key"), Number: proto.Int32(1), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()},
+			}},
+			{Name: proto.String("get_value_result"), Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("value"), Number: proto.Int32(1), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(), Type: descriptorpb.FieldDescriptorProto_TYPE_UINT32.Enum()},
+			}},
+		},
+	}
+	types, err := proto.Marshal(fdProto)
+	assert.NoError(t, err)
+
+	abi := map[string]interface{}{
+		"types":   types,
+		"methods": map[string]*ABIMethod{"get_value": {Argument: "test.get_value_arguments", Return: "test.get_value_result", EntryPoint: "0x1234abcd", ReadOnly: true}},
+	}
+	if address != "" {
+		abi["address"] = address
+	}
+
+	data, err := json.Marshal(abi)
+	assert.NoError(t, err)
+	return data
+}
+
+func TestRegisterDir(t *testing.T) {
+	parser, ee := newTestEnvironment()
+
+	dir := t.TempDir()
+
+	address := "1AdzuXSpC6K9qtXdCBgD5NUpDNwHjMgrc9"
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "first.abi"), testContractABI(t, address), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "second.json"), testContractABI(t, cliutil.KoinContractID), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "broken.abi"), []byte("not an abi"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "missing.abi"), testContractABI(t, ""), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "notes.txt"), []byte("ignored"), 0644))
+
+	// Valid files are registered under their names, the rest are skipped with a warning
+	ir := ParseAndInterpret(parser, ee, "register_dir "+dir)
+	assert.False(t, ir.HasError())
+	assert.Equal(t, 2, ir.Outputs[0].Fields["registered"])
+	assert.True(t, strings.HasPrefix(ir.Results[0], "Warning: skipped broken.abi, "+cliutil.ErrInvalidABI.Error()), ir.Results[0])
+	assert.True(t, strings.HasPrefix(ir.Results[1], "Warning: skipped missing.abi, "), ir.Results[1])
+	assert.Contains(t, ir.Results[len(ir.Results)-1], "Registered 2 contract(s)")
+
+	first, ok := ee.Contracts.Get("first")
+	assert.True(t, ok)
+	assert.Equal(t, address, first.Address)
+	assert.True(t, ee.Contracts.Contains("second"))
+	assert.False(t, ee.Contracts.Contains("broken"))
+	assert.False(t, ee.Contracts.Contains("missing"))
+	assert.Contains(t, parser.Commands.Name2Command, "first.get_value")
+
+	// Registering again skips the contracts that already exist
+	ir = ParseAndInterpret(parser, ee, "register_dir "+dir)
+	assert.False(t, ir.HasError())
+	assert.Equal(t, 0, ir.Outputs[0].Fields["registered"])
+
+	ir = ParseAndInterpret(parser, ee, "register_dir "+filepath.Join(dir, "nonexistent"))
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrInvalidParam)
+}
+
+func TestSavedContracts(t *testing.T) {
+	dir := t.TempDir()
+
+	filename := filepath.Join(dir, "contracts.json")
+	address := "1AdzuXSpC6K9qtXdCBgD5NUpDNwHjMgrc9"
+	abiFilename := filepath.Join(dir, "test.abi")
+	assert.NoError(t, ioutil.WriteFile(abiFilename, testContractABI(t, ""), 0644))
+
+	// A missing file has no contracts
+	parser, ee := newTestEnvironment()
+	assert.NoError(t, ee.SetContractsFile(filename))
+	assert.Empty(t, ee.Contracts.Names())
+
+	// Registered contracts and tokens are saved
+	ir := ParseAndInterpret(parser, ee, "register first "+address+" "+abiFilename)
+	assert.False(t, ir.HasError())
+	ir = ParseAndInterpret(parser, ee, "register second "+address+" "+abiFilename)
+	assert.False(t, ir.HasError())
+	ir = ParseAndInterpret(parser, ee, "register_token tkn "+address+" TKN 8")
+	assert.False(t, ir.HasError())
+	ir = ParseAndInterpret(parser, ee, "rename second renamed")
+	assert.False(t, ir.HasError())
+	ir = ParseAndInterpret(parser, ee, "set_contract_address renamed "+cliutil.KoinContractID)
+	assert.False(t, ir.HasError())
+
+	saved, err := cliutil.LoadContracts(filename)
+	assert.NoError(t, err)
+	assert.Len(t, saved, 3)
+	assert.Equal(t, address, saved["first"].Address)
+	assert.Equal(t, cliutil.KoinContractID, saved["renamed"].Address)
+	assert.Nil(t, saved["tkn"].ABI)
+	assert.Equal(t, &cliutil.SavedToken{Precision: 8, Symbol: "TKN"}, saved["tkn"].Token)
+
+	// The saved contracts, tokens, and their commands are available in a new session
+	parser, ee = newTestEnvironment()
+	assert.NoError(t, ee.SetContractsFile(filename))
+	assert.Equal(t, []string{"first", "renamed", "tkn"}, ee.Contracts.Names())
+	assert.Contains(t, parser.Commands.Name2Command, "renamed.get_value")
+	assert.Contains(t, parser.Commands.Name2Command, "tkn.transfer")
+	assert.Equal(t, &ABIUnit{Precision: 8, Symbol: "TKN"}, ee.Contracts.GetTokenUnits(address))
+
+	ir = ParseAndInterpret(parser, ee, "unregister first; unregister tkn")
+	assert.False(t, ir.HasError())
+	saved, err = cliutil.LoadContracts(filename)
+	assert.NoError(t, err)
+	assert.Len(t, saved, 1)
+
+	// A saved contract that cannot be registered is skipped with a warning, and kept in the file
+	saved["broken"] = &cliutil.SavedContract{Address: address, ABI: []byte(`{"methods": {"get_value": {"argument": "test.missing"}}}`)}
+	assert.NoError(t, cliutil.SaveContracts(filename, saved))
+
+	parser, ee = newTestEnvironment()
+	err = ee.SetContractsFile(filename)
+	assert.ErrorIs(t, err, cliutil.ErrContract)
+	assert.Contains(t, err.Error(), "broken")
+	assert.Equal(t, []string{"renamed"}, ee.Contracts.Names())
+
+	ir = ParseAndInterpret(parser, ee, "rename renamed other")
+	assert.False(t, ir.HasError())
+	saved, err = cliutil.LoadContracts(filename)
+	assert.NoError(t, err)
+	assert.Len(t, saved, 2)
+	assert.Contains(t, saved, "broken")
+	assert.Contains(t, saved, "other")
+
+	// Unregistering it removes it from the file
+	ir = ParseAndInterpret(parser, ee, "unregister broken")
+	assert.NoError(t, ir.Err())
+	saved, err = cliutil.LoadContracts(filename)
+	assert.NoError(t, err)
+	assert.Len(t, saved, 1)
+	assert.NotContains(t, saved, "broken")
+
+	// A change that cannot be saved is still made, with a warning
+	ee.contractsFile = filepath.Join(dir, "missing", "contracts.json")
+	ir = ParseAndInterpret(parser, ee, "register first "+address+" "+abiFilename)
+	assert.NoError(t, ir.Err())
+	assert.Contains(t, ee.Contracts.Names(), "first")
+	assert.True(t, strings.HasPrefix(ir.Outputs[0].Messages[len(ir.Outputs[0].Messages)-1], "Warning: cannot save contracts to "))
+
+	// An unreadable file is an error
+	assert.NoError(t, ioutil.WriteFile(filename, []byte("not json"), 0644))
+	assert.Error(t, ee.SetContractsFile(filename))
+}
+
+func TestConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	// A missing config file is empty, and values may be strings, numbers, or booleans
+	config, err := cliutil.LoadConfigFile(filepath.Join(dir, "missing.json"))
+	assert.NoError(t, err)
+	assert.Empty(t, config.Options)
+	assert.Empty(t, config.Networks)
+
+	filename := filepath.Join(dir, "config.json")
+	assert.NoError(t, ioutil.WriteFile(filename, []byte(`{"rpc": "http://file", "rpc-timeout": "5s", "retries": 1000000, "confirm": true, "read-format": "json"}`), 0644))
+	config, err = cliutil.LoadConfigFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"rpc": "http://file", "rpc-timeout": "5s", "retries": "1000000", "confirm": "true", "read-format": "json"}, config.Options)
+
+	assert.NoError(t, ioutil.WriteFile(filename, []byte(`{"rpc": ["http://file"]}`), 0644))
+	_, err = cliutil.LoadConfigFile(filename)
+	assert.ErrorIs(t, err, cliutil.ErrInvalidParam)
+
+	newFlags := func() *flag.FlagSet {
+		flags := flag.NewFlagSet("test", flag.ContinueOnError)
+		flags.String("rpc", "", "")
+		flags.Duration("rpc-timeout", time.Second, "")
+		flags.Int("retries", 3, "")
+		flags.Bool("confirm", false, "")
+		flags.String("read-format", "text", "")
+		flags.String("password", "", "")
+		return flags
+	}
+
+	// The command line overrides the environment, which overrides the config file
+	flags := newFlags()
+	assert.NoError(t, flags.Parse([]string{"--read-format", "text"}))
+	os.Setenv("TEST_KOINOS_RPC_URL", "http://env")
+	defer os.Unsetenv("TEST_KOINOS_RPC_URL")
+	os.Setenv(cliutil.ConfigEnvVar("rpc-timeout"), "10s")
+	defer os.Unsetenv(cliutil.ConfigEnvVar("rpc-timeout"))
+
+	options, err := cliutil.ResolveConfig(flags, config.Options, map[string]string{"rpc": "TEST_KOINOS_RPC_URL"}, "password")
+	assert.NoError(t, err)
+
+	resolved := make(map[string]*cliutil.ConfigOption)
+	for _, option := range options {
+		resolved[option.Name] = option
+	}
+	assert.Len(t, resolved, 5)
+	assert.Equal(t, &cliutil.ConfigOption{Name: "rpc", Value: "http://env", Source: cliutil.ConfigSourceEnvironment}, resolved["rpc"])
+	assert.Equal(t, &cliutil.ConfigOption{Name: "rpc-timeout", Value: "10s", Source: cliutil.ConfigSourceEnvironment}, resolved["rpc-timeout"])
+	assert.Equal(t, &cliutil.ConfigOption{Name: "retries", Value: "1000000", Source: cliutil.ConfigSourceFile}, resolved["retries"])
+	assert.Equal(t, &cliutil.ConfigOption{Name: "confirm", Value: "true", Source: cliutil.ConfigSourceFile}, resolved["confirm"])
+	assert.Equal(t, &cliutil.ConfigOption{Name: "read-format", Value: "text", Source: cliutil.ConfigSourceCommandLine}, resolved["read-format"])
+
+	confirm, err := flags.GetBool("confirm")
+	assert.NoError(t, err)
+	assert.True(t, confirm)
+
+	// Unknown or excluded options, and invalid values, are errors
+	_, err = cliutil.ResolveConfig(newFlags(), map[string]string{"unknown": "1"}, nil)
+	assert.ErrorIs(t, err, cliutil.ErrInvalidParam)
+	_, err = cliutil.ResolveConfig(newFlags(), map[string]string{"password": "hunter2"}, nil, "password")
+	assert.ErrorIs(t, err, cliutil.ErrInvalidParam)
+	_, err = cliutil.ResolveConfig(newFlags(), map[string]string{"retries": "many"}, nil)
+	assert.ErrorIs(t, err, cliutil.ErrInvalidParam)
+
+	// The config command shows the resolved options
+	parser, ee := newTestEnvironment()
+	ee.SetConfig(options)
+
+	ir := ParseAndInterpret(parser, ee, "config show")
+	assert.False(t, ir.HasError())
+	assert.Contains(t, ir.Results, "rpc = http://env (environment)")
+	assert.Contains(t, ir.Results, "retries = 1000000 (config file)")
+
+	ir = ParseAndInterpret(parser, ee, "config edit")
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrInvalidParam)
+}
+
+func TestMissingArgumentUsage(t *testing.T) {
+	parser, ee := newTestEnvironment()
+
+	// The error names the missing argument and shows the command's usage, which is not repeated
+	ir := ParseAndInterpret(parser, ee, "transfer 10")
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrMissingParam)
+	assert.Equal(t, []string{"missing parameter: address, usage: transfer <amount:amount> <address:address>"}, ir.Results)
+
+	// Contract commands show the names of their ABI fields
+	assert.NoError(t, registerContractABI(ee, "test", "1AdzuXSpC6K9qtXdCBgD5NUpDNwHjMgrc9", testContractABI(t, "")))
+	_, err := parser.Parse("test.get_value")
+	assert.ErrorIs(t, err, cliutil.ErrMissingParam)
+	assert.Contains(t, err.Error(), "usage: test.get_value <key:string>")
+
+	// Other errors are followed by the usage
+	ir = ParseAndInterpret(parser, ee, "transfer 10 0OIl")
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrInvalidParam)
+	assert.Equal(t, "Usage: transfer <amount:amount> <address:address>", ir.Results[len(ir.Results)-1])
+
+	// The JSON output of the error includes the usage too
+	assert.Equal(t, []string{"Usage: transfer <amount:amount> <address:address>"}, ir.Outputs[0].Messages)
+	assert.NotEmpty(t, ir.Outputs[0].Error)
+}
+
+func TestBalanceDefaultsToWallet(t *testing.T) {
+	parser, ee := newTestEnvironment()
+
+	// Without an address, a wallet must be open, which is checked before the node is needed
+	ir := ParseAndInterpret(parser, ee, "balance")
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrWalletClosed)
+	assert.Contains(t, ir.Err().Error(), "or give an address")
+
+	ir = ParseAndInterpret(parser, ee, "balance 1AdzuXSpC6K9qtXdCBgD5NUpDNwHjMgrc9")
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrOffline)
+
+	key, err := util.GenerateKoinosKey()
+	assert.NoError(t, err)
+	ee.OpenWallet(key)
+
+	ir = ParseAndInterpret(parser, ee, "balance")
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrOffline)
+}
+
+func TestBalanceOfToken(t *testing.T) {
+	dir := t.TempDir()
+
+	result, err := proto.Marshal(&token.BalanceOfResult{Value: 12345})
+	assert.NoError(t, err)
+	server := testRPCServer(t, map[string]string{cliutil.ReadContractCall: fmt.Sprintf(`{"result":"%s"}`, base64.URLEncoding.EncodeToString(result))})
+	defer server.Close()
+
+	parser, ee := newTestEnvironment()
+	ee.RPCClient = cliutil.NewKoinosRPCClient(server.URL)
+
+	// The symbol and precision come from the units of balance_of in the ABI, without asking the contract
+	abiFilename := writeTestABI(t, dir, "token.abi", func(abi map[string]interface{}) {
+		abi["methods"].(map[string]interface{})["balance_of"] = &ABIMethod{Argument: "test.get_value_arguments", Return: "test.get_value_result", EntryPoint: "0x5c721497", ReadOnly: true, Units: map[string]*ABIUnit{"value": {Precision: 2, Symbol: "TST"}}}
+	})
+	ir := ParseAndInterpret(parser, ee, "register tst 1AdzuXSpC6K9qtXdCBgD5NUpDNwHjMgrc9 "+abiFilename)
+	assert.NoError(t, ir.Err())
+
+	ir = ParseAndInterpret(parser, ee, "balance 1AdzuXSpC6K9qtXdCBgD5NUpDNwHjMgrc9 tst")
+	assert.NoError(t, ir.Err())
+	assert.Equal(t, "123.45", ir.Outputs[0].Fields["balance"])
+	assert.Equal(t, "TST", ir.Outputs[0].Fields["symbol"])
+
+	// A token registered with register_token uses the units it was registered with
+	ir = ParseAndInterpret(parser, ee, "register_token tkn 1GbiqgoMhvkztWytizNPn8g5SvXrrYHQQg TKN 4")
+	assert.NoError(t, ir.Err())
+
+	ir = ParseAndInterpret(parser, ee, "balance 1AdzuXSpC6K9qtXdCBgD5NUpDNwHjMgrc9 tkn")
+	assert.NoError(t, ir.Err())
+	assert.Equal(t, "1.2345", ir.Outputs[0].Fields["balance"])
+	assert.Equal(t, "TKN", ir.Outputs[0].Fields["symbol"])
+
+	// A contract whose ABI has no units for its balance cannot be shown
+	abiFilename = writeTestABI(t, dir, "test.abi", nil)
+	ir = ParseAndInterpret(parser, ee, "register test 1AdzuXSpC6K9qtXdCBgD5NUpDNwHjMgrc9 "+abiFilename)
+	assert.NoError(t, ir.Err())
+
+	ir = ParseAndInterpret(parser, ee, "balance 1AdzuXSpC6K9qtXdCBgD5NUpDNwHjMgrc9 test")
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrContract)
+}
+
+func TestBalances(t *testing.T) {
+	result, err := proto.Marshal(&token.BalanceOfResult{Value: 150000000})
+	assert.NoError(t, err)
+
+	// A slow node that tracks the most balances queried at once
+	var lock sync.Mutex
+	var inFlight, mostInFlight int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID int `json:"id"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		lock.Lock()
+		inFlight++
+		if inFlight > mostInFlight {
+			mostInFlight = inFlight
+		}
+		lock.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		lock.Lock()
+		inFlight--
+		lock.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":{"result":"%s"}}`, req.ID, base64.URLEncoding.EncodeToString(result))
+	}))
+	defer server.Close()
+
+	parser, ee := newTestEnvironment()
+	ee.RPCClient = cliutil.NewKoinosRPCClient(server.URL)
+
+	addresses := make([]string, 3*maxBalanceQueries)
+	for i := range addresses {
+		addresses[i] = "1AdzuXSpC6K9qtXdCBgD5NUpDNwHjMgrc9"
+	}
+
+	ir := ParseAndInterpret(parser, ee, "balances "+strings.Join(addresses, " "))
+	assert.NoError(t, ir.Err())
+
+	fields := ir.Outputs[0].Fields["balances"].([]map[string]string)
+	assert.Len(t, fields, len(addresses))
+	for _, field := range fields {
+		assert.Equal(t, "1.5", field["balance"])
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.LessOrEqual(t, mostInFlight, maxBalanceQueries)
+}
+
+func TestNetworkPresets(t *testing.T) {
+	dir := t.TempDir()
+
+	// Custom networks are read from the config file, with the KOIN units by default
+	filename := filepath.Join(dir, "config.json")
+	assert.NoError(t, ioutil.WriteFile(filename, []byte(`{"network": "local", "networks": {
+		"local": {"rpc": "http://localhost:8080", "chain_id": "EiAAKqFi", "koin_symbol": "lKOIN"},
+		"testnet": {"rpc": "http://testnet.example"}
+	}}`), 0644))
+	config, err := cliutil.LoadConfigFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"network": "local"}, config.Options)
+	assert.Equal(t, &cliutil.NetworkPreset{RPC: "http://localhost:8080", ChainID: "EiAAKqFi", Symbol: "lKOIN", Precision: cliutil.KoinPrecision}, config.Networks["local"])
+
+	for _, networks := range []string{`{"bad": {"chain_id": "EiAAKqFi"}}`, `{"bad": {"rpc": "http://localhost", "chain_id": "not base64!"}}`, `[]`} {
+		assert.NoError(t, ioutil.WriteFile(filename, []byte(`{"networks": `+networks+`}`), 0644))
+		_, err = cliutil.LoadConfigFile(filename)
+		assert.Error(t, err, networks)
+	}
+
+	// Custom networks take priority over the built in ones
+	preset, err := cliutil.GetNetwork("testnet", config.Networks)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://testnet.example", preset.RPC)
+	preset, err = cliutil.GetNetwork("mainnet", config.Networks)
+	assert.NoError(t, err)
+	assert.Equal(t, cliutil.Networks["mainnet"], preset)
+	_, err = cliutil.GetNetwork("unknown", config.Networks)
+	assert.ErrorIs(t, err, cliutil.ErrInvalidParam)
+	assert.Equal(t, []string{"local", "mainnet", "testnet"}, cliutil.NetworkNames(config.Networks))
+
+	// A preset only sets the options that are still at their defaults
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.String("rpc", "", "")
+	flags.String("koin-symbol", cliutil.KoinSymbol, "")
+	assert.NoError(t, flags.Parse([]string{"--koin-symbol", "XYZ"}))
+	options, err := cliutil.ResolveConfig(flags, nil, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, cliutil.ApplyConfigDefaults(flags, options, map[string]string{"rpc": "http://localhost:8080", "koin-symbol": "lKOIN"}, "network local"))
+	assert.Equal(t, []*cliutil.ConfigOption{
+		{Name: "koin-symbol", Value: "XYZ", Source: cliutil.ConfigSourceCommandLine},
+		{Name: "rpc", Value: "http://localhost:8080", Source: "network local"},
+	}, options)
+
+	// The set_network command lists the networks, and switches to one
+	parser, ee := newTestEnvironment()
+	ee.SetNetworks(config.Networks)
+
+	ir := ParseAndInterpret(parser, ee, "set_network")
+	assert.False(t, ir.HasError())
+	assert.Equal(t, []string{"local: http://localhost:8080", "mainnet: https://api.koinos.io/", "testnet: http://testnet.example"}, ir.Results)
+
+	ir = ParseAndInterpret(parser, ee, "set_network local")
+	assert.False(t, ir.HasError())
+	assert.True(t, ee.IsOnline())
+	assert.Equal(t, "EiAAKqFi", ee.chainID)
+	assert.Equal(t, "lKOIN", ee.koinSymbol)
+	assert.Equal(t, cliutil.KoinPrecision, ee.koinPrecision)
+
+	ir = ParseAndInterpret(parser, ee, "set_network")
+	assert.Contains(t, ir.Results, "local: http://localhost:8080 (current)")
+
+	// Built in presets ask the node for the chain id
+	ir = ParseAndInterpret(parser, ee, "set_network mainnet")
+	assert.False(t, ir.HasError())
+	assert.True(t, ee.IsChainIDAuto())
+	assert.Equal(t, cliutil.KoinSymbol, ee.koinSymbol)
+
+	ir = ParseAndInterpret(parser, ee, "set_network unknown")
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrInvalidParam)
+}
+
+// interruptTestCommand interrupts the process, then waits to be cancelled
+type interruptTestCommand struct{}
+
+func (c *interruptTestCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	process, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		return nil, err
+	}
+
+	err = process.Signal(os.Interrupt)
+	if err != nil {
+		return nil, err
+	}
+
+	// Both the command's context and the one for work that is not given it are cancelled
+	<-ctx.Done()
+	<-ee.interruptibleContext().Done()
+	return nil, ctx.Err()
+}
+
+func TestInterruptCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("interrupts cannot be sent to a process on windows")
+	}
+
+	cs := NewCommandSet()
+	cs.AddCommand(NewCommandDeclaration("interrupt", "Interrupt the command", false, func(inv *CommandParseResult) Command { return &interruptTestCommand{} }))
+	cs.AddCommand(NewCommandDeclaration("command_aliases", "List the command aliases", false, NewCommandAliasListCommand))
+	parser := NewCommandParser(cs)
+	ee := NewExecutionEnvironment(nil, parser)
+
+	// Ctrl-C cancels only the running command, and later commands run as usual
+	ir := ParseAndInterpret(parser, ee, "interrupt; command_aliases")
+	assert.Len(t, ir.Outputs, 2)
+	assert.ErrorIs(t, ir.Outputs[0].Err, cliutil.ErrInterrupted)
+	assert.Equal(t, ExitInterrupted, ExitCode(ir.Outputs[0].Err))
+	assert.NoError(t, ir.Outputs[1].Err)
+
+	// Outside of a command there is nothing to cancel
+	assert.NoError(t, ee.interruptibleContext().Err())
+}
+
+type deadlineTestCommand struct{}
+
+func (c *deadlineTestCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestMaxDuration(t *testing.T) {
+	cs := NewCommandSet()
+	cs.AddCommand(NewCommandDeclaration("block", "Wait until cancelled", false, func(inv *CommandParseResult) Command { return &deadlineTestCommand{} }))
+	cs.AddCommand(NewCommandDeclaration("command_aliases", "List the command aliases", false, NewCommandAliasListCommand))
+	parser := NewCommandParser(cs)
+	ee := NewExecutionEnvironment(nil, parser)
+
+	assert.False(t, ee.MaxDurationExceeded())
+
+	// The running command is cancelled when the time is up, and later commands are not run
+	ee.SetMaxDuration(50 * time.Millisecond)
+	ir := ParseAndInterpret(parser, ee, "block; command_aliases")
+	assert.Len(t, ir.Outputs, 2)
+	assert.ErrorIs(t, ir.Outputs[0].Err, cliutil.ErrMaxDuration)
+	assert.ErrorIs(t, ir.Outputs[1].Err, cliutil.ErrMaxDuration)
+	assert.Equal(t, ExitTimeout, ExitCode(ir.Outputs[0].Err))
+	assert.True(t, ee.MaxDurationExceeded())
+
+	// Removing the bound runs commands again
+	ee.SetMaxDuration(0)
+	assert.False(t, ee.MaxDurationExceeded())
+	ir = ParseAndInterpret(parser, ee, "command_aliases")
+	assert.NoError(t, ir.Err())
+}
+
+// stallTestCommand waits, as for a prompt or a signer's approval, before making an rpc call
+type stallTestCommand struct {
+	stall time.Duration
+}
+
+func (c *stallTestCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	time.Sleep(c.stall)
+	_, err := ee.RPCClient.GetHeadInfo(ctx)
+	return NewExecutionResult(), err
+}
+
+func TestRPCTimeoutPerCall(t *testing.T) {
+	cs := NewCommandSet()
+	cs.AddCommand(NewCommandDeclaration("stall", "Wait, then make an rpc call", false, func(inv *CommandParseResult) Command { return &stallTestCommand{stall: 100 * time.Millisecond} }))
+	parser := NewCommandParser(cs)
+
+	server := testRPCServer(t, map[string]string{cliutil.GetHeadInfoCall: `{}`})
+	defer server.Close()
+
+	// The time spent before the call does not count against the rpc timeout
+	ee := NewExecutionEnvironment(cliutil.NewKoinosRPCClient(server.URL), parser)
+	ee.SetRPCTimeout(50 * time.Millisecond)
+	ir := ParseAndInterpret(parser, ee, "stall")
+	assert.NoError(t, ir.Err())
+
+	// A call that waits too long on the node still times out
+	slowServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer slowServer.Close()
+
+	client := cliutil.NewKoinosRPCClient(slowServer.URL)
+	client.Retries = 0
+	ee.RPCClient = client
+	ir = ParseAndInterpret(parser, ee, "stall")
+	assert.ErrorIs(t, ir.Err(), context.DeadlineExceeded)
+}
+
+func TestTable(t *testing.T) {
+	table := cliutil.NewTable("NAME", "BALANCE", "NOTE")
+	table.AddRow("koin", "1.5 KOIN", "native")
+	table.AddRow("vhp", "10 VHP")
+	table.AddRow("ünïcode", "0", "", "ignored")
+
+	// Missing cells are blank, and trailing spaces are trimmed
+	assert.Equal(t, []string{
+		"NAME     BALANCE   NOTE",
+		"----     -------   ----",
+		"koin     1.5 KOIN  native",
+		"vhp      10 VHP",
+		"ünïcode  0",
+	}, table.Lines(false))
+
+	// With color, the header is bold and not underlined, but the columns stay aligned
+	lines := table.Lines(true)
+	assert.Len(t, lines, 4)
+	assert.Equal(t, "\x1b[1mNAME\x1b[0m     \x1b[1mBALANCE\x1b[0m   \x1b[1mNOTE\x1b[0m", lines[0])
+	assert.Equal(t, "koin     1.5 KOIN  native", lines[1])
+
+	er := NewExecutionResult()
+	er.AddTable(cliutil.NewTable("EMPTY"), false)
+	assert.Equal(t, []string{"EMPTY", "-----"}, er.Message)
+}
+
+func TestOutputFile(t *testing.T) {
+	parser, ee := newTestEnvironment()
+	assert.Equal(t, os.Stdout, ee.Output())
+
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "out.txt")
+
+	ir := NewInterpretResults()
+	ir.AddResult("first")
+
+	assert.NoError(t, ee.SetOutputFile(filename, false))
+	ir.Fprint(ee.Output())
+	assert.NoError(t, ee.CloseOutputFile())
+	assert.Equal(t, os.Stdout, ee.Output())
+
+	data, err := ioutil.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, "first\n\n", string(data))
+
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(filename)
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	}
+
+	// Appending keeps the previous results, and replacing does not
+	assert.NoError(t, ee.SetOutputFile(filename, true))
+	ir.FprintJSON(ee.Output())
+	assert.NoError(t, ee.CloseOutputFile())
+
+	data, err = ioutil.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, "first\n\n[]\n", string(data))
+
+	assert.NoError(t, ee.SetOutputFile(filename, false))
+	assert.NoError(t, ee.CloseOutputFile())
+
+	data, err = ioutil.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, "", string(data))
+
+	assert.Error(t, ee.SetOutputFile(filepath.Join(dir, "missing", "out.txt"), false))
+}
+
+// newTestEnvironment creates a parser with the base commands, and an offline execution environment that uses it
+func newTestEnvironment() (*CommandParser, *ExecutionEnvironment) {
+	parser := NewCommandParser(NewKoinosCommandSet())
+	return parser, NewExecutionEnvironment(nil, parser)
+}
+
+// writeTestABI writes the ABI of testWriteContractABI to a file in dir, returning its path. If edit is not nil, it is
+// applied to the ABI's JSON first
+func writeTestABI(t *testing.T, dir string, filename string, edit func(abi map[string]interface{})) string {
+	data := testWriteContractABI(t)
+	if edit != nil {
+		var abi map[string]interface{}
+		assert.NoError(t, json.Unmarshal(data, &abi))
+		edit(abi)
+
+		var err error
+		data, err = json.Marshal(abi)
+		assert.NoError(t, err)
+	}
+
+	path := filepath.Join(dir, filename)
+	assert.NoError(t, ioutil.WriteFile(path, data, 0644))
+	return path
+}
+
+// testWriteContractABI returns the JSON ABI of the test contract with a set_value write method added
+func testWriteContractABI(t *testing.T) []byte {
+	var abi map[string]interface{}
+	assert.NoError(t, json.Unmarshal(testContractABI(t, ""), &abi))
+	abi["methods"].(map[string]interface{})["set_value"] = &ABIMethod{Argument: "test.get_value_arguments", Return: "test.get_value_result", EntryPoint: "0x1234abce", Description: "Set the value"}
+
+	data, err := json.Marshal(abi)
+	assert.NoError(t, err)
+	return data
+}
+
+func TestMethodRcLimit(t *testing.T) {
+	dir := t.TempDir()
+
+	abiFilename := writeTestABI(t, dir, "test.abi", nil)
+
+	parser, ee := newTestEnvironment()
+	ir := ParseAndInterpret(parser, ee, "register test 1AdzuXSpC6K9qtXdCBgD5NUpDNwHjMgrc9 "+abiFilename)
+	assert.False(t, ir.HasError())
+
+	ir = ParseAndInterpret(parser, ee, "method_rclimit test.set_value")
+	assert.NoError(t, ir.Err())
+	assert.Equal(t, []string{"test.set_value uses the current rc limit"}, ir.Outputs[0].Messages)
+
+	ir = ParseAndInterpret(parser, ee, "method_rclimit test.set_value 2.5")
+	assert.NoError(t, ir.Err())
+	assert.Equal(t, rcInfo{value: 250000000, absolute: true}, ee.methodRcLimits["test.set_value"])
+
+	ir = ParseAndInterpret(parser, ee, "method_rclimit test.set_value")
+	assert.Equal(t, []string{"Rc limit of test.set_value: 2.5"}, ir.Outputs[0].Messages)
+
+	// The override is only used while submitting the method's transactions
+	restore := ee.overrideRcLimit("test.set_value", nil)
+	limit, err := ee.GetRcLimit(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(250000000), limit)
+	restore()
+	assert.Equal(t, ee.rcLimit, ee.currentRcLimit())
+
+	ir = ParseAndInterpret(parser, ee, "method_rclimit test.set_value default")
+	assert.NoError(t, ir.Err())
+	assert.NotContains(t, ee.methodRcLimits, "test.set_value")
+
+	// Zero, negative, and invalid limits are rejected, as are read only and unknown methods
+	for _, cmd := range []string{
+		"method_rclimit test.set_value 0",
+		"method_rclimit test.set_value 0%",
+		"method_rclimit test.set_value -1",
+		"method_rclimit test.set_value 150%",
+		"method_rclimit test.set_value lots",
+		"method_rclimit test.get_value 1",
+		"method_rclimit test.missing 1",
+	} {
+		ir = ParseAndInterpret(parser, ee, cmd)
+		assert.ErrorIs(t, ir.Err(), cliutil.ErrInvalidParam, cmd)
+	}
+
+	// A token registered with register_token has no ABI to look the method up in
+	ir = ParseAndInterpret(parser, ee, "register_token tkn 1AdzuXSpC6K9qtXdCBgD5NUpDNwHjMgrc9 TKN 8")
+	assert.NoError(t, ir.Err())
+	ir = ParseAndInterpret(parser, ee, "method_rclimit tkn.transfer 1")
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrInvalidParam)
+
+	// The limit follows the contract when it is renamed, and is dropped when it is unregistered
+	ir = ParseAndInterpret(parser, ee, "method_rclimit test.set_value 2.5")
+	assert.NoError(t, ir.Err())
+	ir = ParseAndInterpret(parser, ee, "rename test other")
+	assert.NoError(t, ir.Err())
+	assert.NotContains(t, ee.methodRcLimits, "test.set_value")
+	assert.Equal(t, rcInfo{value: 250000000, absolute: true}, ee.methodRcLimits["other.set_value"])
+
+	ir = ParseAndInterpret(parser, ee, "unregister other")
+	assert.NoError(t, ir.Err())
+	assert.Empty(t, ee.methodRcLimits)
+
+	// Mana follows the network's KOIN precision, while percentages do not
+	assert.NoError(t, ee.SetKoinUnits(4, "tKOIN"))
+	ir = ParseAndInterpret(parser, ee, "register test 1AdzuXSpC6K9qtXdCBgD5NUpDNwHjMgrc9 "+abiFilename)
+	assert.NoError(t, ir.Err())
+
+	ir = ParseAndInterpret(parser, ee, "method_rclimit test.set_value 2.5")
+	assert.NoError(t, ir.Err())
+	assert.Equal(t, rcInfo{value: 25000, absolute: true}, ee.methodRcLimits["test.set_value"])
+	ir = ParseAndInterpret(parser, ee, "method_rclimit test.set_value")
+	assert.Equal(t, []string{"Rc limit of test.set_value: 2.5"}, ir.Outputs[0].Messages)
+
+	ir = ParseAndInterpret(parser, ee, "method_rclimit test.set_value 25%")
+	assert.NoError(t, ir.Err())
+	assert.Equal(t, rcInfo{value: 25000000, absolute: false}, ee.methodRcLimits["test.set_value"])
+	ir = ParseAndInterpret(parser, ee, "method_rclimit test.set_value")
+	assert.Equal(t, []string{"Rc limit of test.set_value: 25%"}, ir.Outputs[0].Messages)
+
+	ir = ParseAndInterpret(parser, ee, "rclimit 2.5")
+	assert.NoError(t, ir.Err())
+	assert.Equal(t, rcInfo{value: 25000, absolute: true}, ee.rcLimit)
+	ir = ParseAndInterpret(parser, ee, "rclimit")
+	assert.Equal(t, []string{"Current rc limit: 2.5"}, ir.Outputs[0].Messages)
+}
+
+func TestCallRcLimit(t *testing.T) {
+	dir := t.TempDir()
+
+	abiFilename := writeTestABI(t, dir, "test.abi", nil)
+
+	parser, ee := newTestEnvironment()
+	ir := ParseAndInterpret(parser, ee, "register test 1AdzuXSpC6K9qtXdCBgD5NUpDNwHjMgrc9 "+abiFilename)
+	assert.NoError(t, ir.Err())
+
+	// The option may come before, between, or after the arguments
+	for _, line := range []string{
+		"test.set_value abc --rc-limit 5",
+		"test.set_value --rc-limit 5 abc",
+	} {
+		results, err := parser.Parse(line)
+		assert.NoError(t, err, line)
+		assert.Equal(t, "abc", *results.CommandResults[0].Args["key"], line)
+		assert.Equal(t, "5", *results.CommandResults[0].Options[RcLimitOption], line)
+	}
+
+	// Without the option, none is set
+	results, err := parser.Parse("test.set_value abc")
+	assert.NoError(t, err)
+	assert.Nil(t, results.CommandResults[0].Options[RcLimitOption])
+
+	// Read commands do not take it
+	results, err = parser.Parse("test.get_value abc --rc-limit 5")
+	assert.NoError(t, err)
+	assert.Nil(t, results.CommandResults[0].Options[RcLimitOption])
+
+	// The limit is checked before anything is submitted
+	for _, value := range []string{"0", "0%", "-1", "150%", "lots"} {
+		results, err := parser.Parse("test.set_value abc --rc-limit " + value)
+		assert.NoError(t, err, value)
+		_, err = parseCallRcLimit(results.CommandResults[0], ee.koinPrecision)
+		assert.ErrorIs(t, err, cliutil.ErrInvalidParam, value)
+	}
+
+	results, err = parser.Parse("test.set_value abc --rc-limit 5")
+	assert.NoError(t, err)
+	limit, err := parseCallRcLimit(results.CommandResults[0], ee.koinPrecision)
+	assert.NoError(t, err)
+
+	// The call's limit is used in place of the one set with method_rclimit
+	ir = ParseAndInterpret(parser, ee, "method_rclimit test.set_value 2.5")
+	assert.NoError(t, ir.Err())
+
+	restore := ee.overrideRcLimit("test.set_value", limit)
+	rcLimit, err := ee.GetRcLimit(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(500000000), rcLimit)
+	assert.Equal(t, "test.set_value ... --rc-limit", ee.rcLimitOverride.command)
+	restore()
+	assert.Nil(t, ee.rcLimitOverride)
+}
+
+func TestMaxRc(t *testing.T) {
+	dir := t.TempDir()
+	abiFilename := writeTestABI(t, dir, "test.abi", nil)
+
+	key, err := util.GenerateKoinosKey()
+	assert.NoError(t, err)
+
+	// A node that estimates every transaction at 2 mana, counting the checks and the broadcasts
+	var lock sync.Mutex
+	var checked, broadcast int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     int `json:"id"`
+			Params struct {
+				Broadcast bool `json:"broadcast"`
+			} `json:"params"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		lock.Lock()
+		if req.Params.Broadcast {
+			broadcast++
+		} else {
+			checked++
+		}
+		lock.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":{"receipt":{"id":"0x1220","rc_used":"200000000"}}}`, req.ID)
+	}))
+	defer server.Close()
+
+	counts := func() (int, int) {
+		lock.Lock()
+		defer lock.Unlock()
+		return checked, broadcast
+	}
+
+	parser, ee := newTestEnvironment()
+	ee.RPCClient = cliutil.NewKoinosRPCClient(server.URL)
+	ee.openWalletFile("main.wallet", nil, key)
+	ee.SetWait(0, 0)
+
+	ir := ParseAndInterpret(parser, ee, "register test 1AdzuXSpC6K9qtXdCBgD5NUpDNwHjMgrc9 "+abiFilename)
+	assert.NoError(t, ir.Err())
+	ir = ParseAndInterpret(parser, ee, "nonce 5; chain_id AQID; rclimit 10")
+	assert.NoError(t, ir.Err())
+
+	// Invalid maximums are rejected before the node is asked anything
+	for _, value := range []string{"0", "10%", "-1", "lots"} {
+		ir = ParseAndInterpret(parser, ee, "test.set_value abc --max-rc "+value)
+		assert.ErrorIs(t, ir.Err(), cliutil.ErrInvalidParam, value)
+	}
+	checks, broadcasts := counts()
+	assert.Equal(t, 0, checks)
+	assert.Equal(t, 0, broadcasts)
+
+	// A transaction that would use more mana than the maximum is never broadcast
+	ir = ParseAndInterpret(parser, ee, "test.set_value abc --max-rc 1.5")
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrMaxRcExceeded)
+	assert.Contains(t, ir.Err().Error(), "estimated mana cost 2 is more than --max-rc 1.5")
+	assert.Nil(t, ee.lastTransaction)
+	assert.Nil(t, ee.maxMana)
+	checks, broadcasts = counts()
+	assert.Equal(t, 1, checks)
+	assert.Equal(t, 0, broadcasts)
+
+	// Within the maximum, it is broadcast
+	ir = ParseAndInterpret(parser, ee, "test.set_value abc --max-rc 3")
+	assert.NoError(t, ir.Err())
+	checks, broadcasts = counts()
+	assert.Equal(t, 2, checks)
+	assert.Equal(t, 1, broadcasts)
+
+	// An rc limit no higher than the maximum already caps the transaction, so it is not checked
+	ir = ParseAndInterpret(parser, ee, "test.set_value abc --rc-limit 1 --max-rc 1.5")
+	assert.NoError(t, ir.Err())
+	checks, broadcasts = counts()
+	assert.Equal(t, 2, checks)
+	assert.Equal(t, 2, broadcasts)
+
+	// A session submits its operations together, so a call in one cannot have its own maximum
+	ir = ParseAndInterpret(parser, ee, "session begin")
+	assert.NoError(t, ir.Err())
+	ir = ParseAndInterpret(parser, ee, "test.set_value abc --max-rc 3")
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrInvalidParam)
+}
+
+func TestPayerSignature(t *testing.T) {
+	key, err := util.GenerateKoinosKey()
+	assert.NoError(t, err)
+	sponsor, err := util.GenerateKoinosKey()
+	assert.NoError(t, err)
+	stranger, err := util.GenerateKoinosKey()
+	assert.NoError(t, err)
+
+	parser, ee := newTestEnvironment()
+	ee.openWalletFile("sponsor.wallet", []cliutil.WalletKey{{Label: "default", PrivateKey: sponsor.PrivateBytes()}}, sponsor)
+	ee.openWalletFile("main.wallet", nil, key)
+
+	// Paying for its own transactions, the sender signs alone
+	signer, err := ee.submissionSigner()
+	assert.NoError(t, err)
+	_, ok := signer.(*cliutil.KeySigner)
+	assert.True(t, ok)
+
+	// A payer with its key in an open wallet signs after the sender
+	ir := ParseAndInterpret(parser, ee, "payer "+base58.Encode(sponsor.AddressBytes()))
+	assert.NoError(t, ir.Err())
+	assert.Contains(t, ir.Outputs[0].Messages, "The payer signs transactions with its key from an open wallet")
+
+	signer, err = ee.submissionSigner()
+	assert.NoError(t, err)
+	assert.Equal(t, key.AddressBytes(), signer.AddressBytes())
+
+	ctx := context.Background()
+	ops := []*protocol.Operation{{Op: &protocol.Operation_CallContract{CallContract: &protocol.CallContractOperation{ContractId: base58.Decode(cliutil.KoinContractID), EntryPoint: TokenTransferEntry}}}}
+	tx, err := cliutil.CreateSignedTransaction(ctx, ops, signer, 1, 100, []byte{1, 2, 3}, sponsor.AddressBytes())
+	assert.NoError(t, err)
+	assert.Equal(t, sponsor.AddressBytes(), tx.Header.Payer)
+	assert.Len(t, tx.Signatures, 2)
+
+	expected, err := cliutil.CreateTransaction(ctx, ops, key.AddressBytes(), 1, 100, []byte{1, 2, 3}, sponsor.AddressBytes())
+	assert.NoError(t, err)
+	assert.NoError(t, cliutil.SignTransaction(key.PrivateBytes(), expected))
+	assert.NoError(t, cliutil.SignTransaction(sponsor.PrivateBytes(), expected))
+	assert.Equal(t, expected.Signatures, tx.Signatures)
+
+	// A payer whose key is not open cannot sign, unless it authorizes transactions on its own
+	ir = ParseAndInterpret(parser, ee, "payer "+base58.Encode(stranger.AddressBytes()))
+	assert.NoError(t, ir.Err())
+	assert.Contains(t, ir.Outputs[0].Messages, "Warning: the payer must sign transactions, but no open wallet holds its key")
+
+	_, err = ee.submissionSigner()
+	assert.ErrorIs(t, err, cliutil.ErrWalletClosed)
+
+	ir = ParseAndInterpret(parser, ee, "payer "+base58.Encode(stranger.AddressBytes())+" false")
+	assert.NoError(t, ir.Err())
+
+	signer, err = ee.submissionSigner()
+	assert.NoError(t, err)
+	_, ok = signer.(*cliutil.KeySigner)
+	assert.True(t, ok)
+
+	// The open wallet pays again after payer me, or one of the keywords for its address
+	for _, payer := range []string{"me", "self", "@me"} {
+		ir = ParseAndInterpret(parser, ee, "payer "+base58.Encode(stranger.AddressBytes()))
+		assert.NoError(t, ir.Err())
+
+		ir = ParseAndInterpret(parser, ee, "payer "+payer)
+		assert.NoError(t, ir.Err(), payer)
+		assert.True(t, ee.IsSelfPaying(), payer)
+	}
+
+	ir = ParseAndInterpret(parser, ee, "payer meh")
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrInvalidParam)
+}
+
+func TestInvoke(t *testing.T) {
+	dir := t.TempDir()
+
+	abiFilename := writeTestABI(t, dir, "test.abi", nil)
+
+	parser, ee := newTestEnvironment()
+	key, err := util.GenerateKoinosKey()
+	assert.NoError(t, err)
+	ee.OpenWallet(key)
+
+	ir := ParseAndInterpret(parser, ee, "register test 1AdzuXSpC6K9qtXdCBgD5NUpDNwHjMgrc9 "+abiFilename+"; parse_only true")
+	assert.NoError(t, ir.Err())
+
+	answers := []string{}
+	prompts := []string{}
+	ee.inputPrompt = func(prompt string) (string, error) {
+		prompts = append(prompts, prompt)
+		if len(answers) == 0 {
+			return "", io.EOF
+		}
+
+		answer := answers[0]
+		answers = answers[1:]
+		return answer, nil
+	}
+
+	// Methods are listed in order of name, an invalid choice is asked again, and strings are taken as they are
+	answers = []string{"3", "set_value", "hello world"}
+	ir = ParseAndInterpret(parser, ee, "invoke test")
+	assert.NoError(t, ir.Err())
+	assert.Equal(t, []string{"Method: ", "Method: ", "key (string): "}, prompts)
+	assert.Equal(t, true, ir.Outputs[0].Fields["parse_only"])
+	assert.Contains(t, ir.Outputs[0].Messages[0], "hello world")
+
+	// The method can be given, by name or number
+	prompts = nil
+	answers = []string{"abc"}
+	ir = ParseAndInterpret(parser, ee, "invoke test 2")
+	assert.NoError(t, ir.Err())
+	assert.Equal(t, []string{"key (string): "}, prompts)
+
+	ir = ParseAndInterpret(parser, ee, "invoke test missing")
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrInvalidParam)
+
+	ir = ParseAndInterpret(parser, ee, "invoke unknown")
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrContract)
+
+	// Running out of input stops the command
+	answers = nil
+	ir = ParseAndInterpret(parser, ee, "invoke test set_value")
+	assert.ErrorIs(t, ir.Err(), io.EOF)
+
+	// The list of methods and the reasons for asking again go to the output, such as an output file
+	filename := filepath.Join(dir, "invoke.txt")
+	assert.NoError(t, ee.SetOutputFile(filename, false))
+	answers = []string{"9", "1", "abc"}
+	ir = ParseAndInterpret(parser, ee, "invoke test")
+	assert.NoError(t, ee.CloseOutputFile())
+	assert.NoError(t, ir.Err())
+
+	data, err := ioutil.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, "Methods of test:\n1: get_value\n2: set_value - Set the value\ninvalid value given for parameter: choose a method from 1 to 2\n", string(data))
+}
+
+func TestParseArgInput(t *testing.T) {
+	parser := NewCommandParser(NewKoinosCommandSet())
+
+	values, err := parser.ParseArgInput(NewCommandArg("value", UIntArg), " 42 ")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"42"}, values)
+
+	_, err = parser.ParseArgInput(NewCommandArg("value", UIntArg), "42 43")
+	assert.ErrorIs(t, err, cliutil.ErrInvalidParam)
+
+	_, err = parser.ParseArgInput(NewCommandArg("value", UIntArg), "-1")
+	assert.Error(t, err)
+
+	_, err = parser.ParseArgInput(NewCommandArg("value", UIntArg), "")
+	assert.ErrorIs(t, err, cliutil.ErrMissingParam)
+
+	values, err = parser.ParseArgInput(NewOptionalVariadicCommandArg("values", UIntArg), "1 2  3")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1", "2", "3"}, values)
+
+	values, err = parser.ParseArgInput(NewOptionalVariadicCommandArg("values", UIntArg), "")
+	assert.NoError(t, err)
+	assert.Nil(t, values)
+
+	values, err = parser.ParseArgInput(NewDefaultCommandArg("format", StringArg, "text"), "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"text"}, values)
+
+	values, err = parser.ParseArgInput(NewCommandArg("memo", StringArg), "two words")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"two words"}, values)
+}
+
+func TestPromptBalance(t *testing.T) {
+	key, err := util.GenerateKoinosKey()
+	assert.NoError(t, err)
+
+	parser, ee := newTestEnvironment()
+
+	// self_balance always checks the open wallet
+	ir := ParseAndInterpret(parser, ee, "self_balance")
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrWalletClosed)
+
+	results, err := parser.Parse("self_balance")
+	assert.NoError(t, err)
+	cmd, ok := results.CommandResults[0].Instantiate().(*BalanceCommand)
+	assert.True(t, ok)
+	assert.Nil(t, cmd.Address)
+	assert.Nil(t, cmd.Contract)
+
+	// The balance is hidden by default
+	ir = ParseAndInterpret(parser, ee, "prompt_balance")
+	assert.NoError(t, ir.Err())
+	assert.Contains(t, ir.Outputs[0].Messages, "The balance is not shown in the prompt")
+	assert.Equal(t, time.Duration(0), ee.GetPromptBalanceInterval())
+
+	ir = ParseAndInterpret(parser, ee, "prompt_balance 30")
+	assert.NoError(t, ir.Err())
+	assert.Contains(t, ir.Outputs[0].Messages, "The balance is shown in the prompt, refreshed every 30s")
+	assert.Equal(t, 30*time.Second, ee.GetPromptBalanceInterval())
+
+	// Nothing is shown, nor fetched, without a wallet or a node
+	assert.Equal(t, "", ee.PromptBalance())
+	ee.openWalletFile("main.wallet", nil, key)
+	assert.Equal(t, "", ee.PromptBalance())
+	assert.False(t, ee.promptBalance.refreshing)
+
+	// Closing the wallet, as the inactivity lock does, hides the balance of the wallet it was fetched for
+	client := cliutil.NewKoinosRPCClient("http://127.0.0.1:1")
+	client.Retries = 0
+	ee.RPCClient = client
+	assert.Equal(t, "", ee.PromptBalance())
+	assert.Equal(t, base58.Encode(key.AddressBytes()), ee.promptBalance.address)
+	ee.CloseWallet()
+	assert.Equal(t, "", ee.PromptBalance())
+	ee.RPCClient = nil
+
+	ir = ParseAndInterpret(parser, ee, "prompt_balance 0")
+	assert.NoError(t, ir.Err())
+	assert.Contains(t, ir.Outputs[0].Messages, "The balance is not shown in the prompt")
+
+	ir = ParseAndInterpret(parser, ee, "prompt_balance -1")
+	assert.Error(t, ir.Err())
+}
+
+func TestSelfAddress(t *testing.T) {
+	key, err := util.GenerateKoinosKey()
+	assert.NoError(t, err)
+	other, err := util.GenerateKoinosKey()
+	assert.NoError(t, err)
+
+	parser, ee := newTestEnvironment()
+
+	ir := ParseAndInterpret(parser, ee, "balance self")
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrWalletClosed)
+
+	ir = ParseAndInterpret(parser, ee, "alias_add self "+base58.Encode(other.AddressBytes()))
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrInvalidParam)
+
+	ee.openWalletFile("main.wallet", nil, key)
+	address := base58.Encode(key.AddressBytes())
+
+	results, err := parser.Parse("balances self " + base58.Encode(other.AddressBytes()) + " @me")
+	assert.NoError(t, err)
+	inv := results.CommandResults[0]
+	assert.NoError(t, ee.resolveSelfAddresses(inv))
+	assert.Equal(t, address, *inv.Args["addresses"])
+	assert.Equal(t, []string{address, base58.Encode(other.AddressBytes()), address}, inv.VariadicArgs["addresses"])
+
+	results, err = parser.Parse("balance @me")
+	assert.NoError(t, err)
+	inv = results.CommandResults[0]
+	assert.NoError(t, ee.resolveSelfAddresses(inv))
+	assert.Equal(t, address, *inv.Args["address"])
+}
+
+// writeV1WalletFixture writes data the way version 1 wallet files were written, with no header and the sha256 hash of
+// the password as the key
+func writeV1WalletFixture(t *testing.T, filename string, password string, data []byte) {
+	out, err := os.Create(filename)
+	assert.NoError(t, err)
+	hash := sha256.Sum256([]byte(password))
+	_, err = sio.Encrypt(out, bytes.NewReader(data), sio.Config{MinVersion: sio.Version20, MaxVersion: sio.Version20, CipherSuites: []byte{sio.AES_256_GCM, sio.CHACHA20_POLY1305}, Key: hash[:]})
+	assert.NoError(t, err)
+	assert.NoError(t, out.Close())
+}
+
+func TestWalletFileVersion(t *testing.T) {
+	dir := t.TempDir()
+
+	key1, err := util.GenerateKoinosKey()
+	assert.NoError(t, err)
+	key2, err := util.GenerateKoinosKey()
+	assert.NoError(t, err)
+
+	// A version 1 file holding labeled keys reads as legacy, so that it is migrated
+	filename := filepath.Join(dir, "v1.wallet")
+	data, err := json.Marshal(map[string]interface{}{"keys": []cliutil.WalletKey{{Label: "first", PrivateKey: key1.PrivateBytes()}, {Label: "second", PrivateKey: key2.PrivateBytes()}}})
+	assert.NoError(t, err)
+	writeV1WalletFixture(t, filename, "password", data)
+
+	version, err := cliutil.ReadWalletFileVersion(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, cliutil.WalletFileV1, version)
+
+	file, err := os.Open(filename)
+	assert.NoError(t, err)
+	keys, legacy, err := cliutil.ReadWalletKeysFile(file, "password")
+	file.Close()
+	assert.NoError(t, err)
+	assert.True(t, legacy)
+	assert.Len(t, keys, 2)
+	assert.Equal(t, "second", keys[1].Label)
+
+	// Opening it migrates it to the current version, keeping its keys
+	parser, ee := newTestEnvironment()
+	ir := ParseAndInterpret(parser, ee, "open "+filename+" password")
+	assert.NoError(t, ir.Err())
+	assert.Contains(t, ir.Outputs[0].Messages, "Upgraded wallet file to version 2, which supports multiple keys")
+	assert.Equal(t, key1.AddressBytes(), ee.Key.AddressBytes())
+
+	version, err = cliutil.ReadWalletFileVersion(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, cliutil.CurrentWalletFileVersion, version)
+
+	file, err = os.Open(filename)
+	assert.NoError(t, err)
+	keys, legacy, err = cliutil.ReadWalletKeysFile(file, "password")
+	file.Close()
+	assert.NoError(t, err)
+	assert.False(t, legacy)
+	assert.Len(t, keys, 2)
+	assert.True(t, bytes.Equal(key2.PrivateBytes(), keys[1].PrivateKey))
+
+	// Headers written before the version was recorded are version 2
+	unversioned := filepath.Join(dir, "unversioned.wallet")
+	assert.NoError(t, ioutil.WriteFile(unversioned, []byte("KOINOS-KDF {\"kdf\":\"scrypt\",\"n\":16384,\"r\":8,\"p\":1,\"salt\":\"AAAA\"}\n"), 0600))
+	version, err = cliutil.ReadWalletFileVersion(unversioned)
+	assert.NoError(t, err)
+	assert.Equal(t, cliutil.WalletFileV2, version)
+
+	// Newer versions are refused, rather than reported as a wrong password
+	newer := filepath.Join(dir, "newer.wallet")
+	assert.NoError(t, ioutil.WriteFile(newer, []byte("KOINOS-KDF {\"version\":3,\"kdf\":\"scrypt\",\"n\":16384,\"r\":8,\"p\":1,\"salt\":\"AAAA\"}\n"), 0600))
+	_, err = cliutil.ReadWalletFileVersion(newer)
+	assert.ErrorIs(t, err, cliutil.ErrWalletVersion)
+
+	ir = ParseAndInterpret(parser, ee, "open "+newer+" password")
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrWalletVersion)
+}
+
+func TestBalanceEntryPoint(t *testing.T) {
+	parser, ee := newTestEnvironment()
+	koin := base58.Decode(cliutil.KoinContractID)
+	other := base58.Decode("1GbiqgoMhvkztWytizNPn8g5SvXrrYHQQg")
+
+	ir := ParseAndInterpret(parser, ee, "balance_entry_point")
+	assert.NoError(t, ir.Err())
+	assert.Contains(t, ir.Outputs[0].Messages, "KOIN balances are read from entry point 0x5c721497")
+
+	ir = ParseAndInterpret(parser, ee, "balance_entry_point 0x1234ABCD")
+	assert.NoError(t, ir.Err())
+	assert.Equal(t, uint32(0x1234abcd), ee.balanceOfEntry(koin))
+
+	// Only KOIN is overridden, other tokens keep the standard entry point
+	assert.Equal(t, TokenBalanceOfEntry, ee.balanceOfEntry(other))
+
+	ir = ParseAndInterpret(parser, ee, "balance_entry_point 42")
+	assert.NoError(t, ir.Err())
+	assert.Contains(t, ir.Outputs[0].Messages, "KOIN balances are read from entry point 0x0000002a")
+
+	for _, entryPoint := range []string{"0x", "0x123456789", "-1", "koin"} {
+		ir = ParseAndInterpret(parser, ee, "balance_entry_point "+entryPoint)
+		assert.ErrorIs(t, ir.Err(), cliutil.ErrInvalidParam, entryPoint)
+	}
+	assert.Equal(t, uint32(42), ee.balanceOfEntry(koin))
+
+	ir = ParseAndInterpret(parser, ee, "balance_entry_point default")
+	assert.NoError(t, ir.Err())
+	assert.Equal(t, TokenBalanceOfEntry, ee.balanceOfEntry(koin))
+}
+
+func TestResubmit(t *testing.T) {
+	key, err := util.GenerateKoinosKey()
+	assert.NoError(t, err)
+
+	// A node that never answers, so that submitting fails without a response
+	client := cliutil.NewKoinosRPCClient("http://127.0.0.1:1")
+	client.Retries = 0
+
+	parser := NewCommandParser(NewKoinosCommandSet())
+	ee := NewExecutionEnvironment(client, parser)
+
+	ir := ParseAndInterpret(parser, ee, "resubmit")
+	assert.Error(t, ir.Err())
+
+	// Set everything the transaction needs, so the node is only contacted to submit it
+	ee.openWalletFile("main.wallet", nil, key)
+	ir = ParseAndInterpret(parser, ee, "nonce 5; chain_id AQID; rclimit 10")
+	assert.NoError(t, ir.Err())
+
+	ctx := context.Background()
+	ops := []*protocol.Operation{{Op: &protocol.Operation_CallContract{CallContract: &protocol.CallContractOperation{ContractId: base58.Decode(cliutil.KoinContractID), EntryPoint: TokenTransferEntry}}}}
+	result := NewExecutionResult()
+	assert.Error(t, ee.SubmitTransaction(ctx, result, ops...))
+
+	transaction := ee.lastTransaction
+	assert.NotNil(t, transaction)
+	assert.Len(t, result.ErrorMessage, 1)
+	assert.Contains(t, result.ErrorMessage[0], "0x"+hex.EncodeToString(transaction.Id))
+
+	// Resubmitting sends the same signed transaction
+	result, err = NewResubmitCommand(nil).Execute(ctx, ee)
+	assert.Error(t, err)
+	assert.Equal(t, []string{"Resubmitting transaction 0x" + hex.EncodeToString(transaction.Id)}, result.Message)
+	assert.Same(t, transaction, ee.lastTransaction)
+
+	// Dry runs are never broadcast, so they are not kept
+	ee.SetDryRun(true)
+	result = NewExecutionResult()
+	assert.Error(t, ee.SubmitTransaction(ctx, result, ops...))
+	assert.Same(t, transaction, ee.lastTransaction)
+	assert.Empty(t, result.ErrorMessage)
+
+	// Once the node answers, whether with a receipt or by rejecting it, the transaction is no longer kept
+	ee.SetDryRun(false)
+	ee.SetWait(0, 0)
+	server := testRPCServer(t, map[string]string{cliutil.SubmitTransactionCall: `{"receipt":{"id":"0x1220"}}`})
+	defer server.Close()
+
+	ee.RPCClient = cliutil.NewKoinosRPCClient(server.URL)
+	_, err = NewResubmitCommand(nil).Execute(ctx, ee)
+	assert.NoError(t, err)
+	assert.Nil(t, ee.lastTransaction)
+
+	ir = ParseAndInterpret(parser, ee, "nonce 6")
+	assert.NoError(t, ir.Err())
+	result = NewExecutionResult()
+	assert.NoError(t, ee.SubmitTransaction(ctx, result, ops...))
+	assert.Nil(t, ee.lastTransaction)
+
+	rejecting := testRPCServer(t, nil)
+	defer rejecting.Close()
+
+	ee.RPCClient = cliutil.NewKoinosRPCClient(rejecting.URL)
+	ir = ParseAndInterpret(parser, ee, "nonce 7")
+	assert.NoError(t, ir.Err())
+	result = NewExecutionResult()
+	err = ee.SubmitTransaction(ctx, result, ops...)
+	assert.True(t, errors.As(err, &cliutil.KoinosRPCError{}))
+	assert.Nil(t, ee.lastTransaction)
+	assert.Empty(t, result.ErrorMessage)
+}
+
+func TestWhoami(t *testing.T) {
+	key, err := util.GenerateKoinosKey()
+	assert.NoError(t, err)
+
+	parser, ee := newTestEnvironment()
+
+	// Nothing set up yet is reported without failing
+	ir := ParseAndInterpret(parser, ee, "whoami")
+	assert.NoError(t, ir.Err())
+	assert.Equal(t, []string{"No wallet open", "Not connected to an RPC endpoint", "Chain ID: fetched from the node when connected", "Network: none", "Registered contracts: 0"}, ir.Outputs[0].Messages)
+	assert.Equal(t, false, ir.Outputs[0].Fields["wallet_open"])
+
+	ee.RPCClient = cliutil.NewKoinosRPCClient("http://127.0.0.1:1")
+	ee.openWalletFile("main.wallet", nil, key)
+	ir = ParseAndInterpret(parser, ee, "chain_id AQID; whoami")
+	assert.NoError(t, ir.Err())
+	assert.Equal(t, []string{"Wallet: main.wallet", "Address: " + base58.Encode(key.AddressBytes()), "RPC endpoint: http://127.0.0.1:1", "Chain ID: AQID", "Network: none", "Registered contracts: 0"}, ir.Outputs[1].Messages)
+	assert.Equal(t, base58.Encode(key.AddressBytes()), ir.Outputs[1].Fields["address"])
+}
+
+// testRPCServer starts a node that answers each rpc method with the given JSON result, or an error for other methods
+func testRPCServer(t *testing.T, results map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     int    `json:"id"`
+			Method string `json:"method"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		w.Header().Set("Content-Type", "application/json")
+		if result, ok := results[req.Method]; ok {
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":%s}`, req.ID, result)
+		} else {
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"error":{"code":-32601,"message":"method not found"}}`, req.ID)
+		}
+	}))
+}
+
+func TestPing(t *testing.T) {
+	parser, ee := newTestEnvironment()
+
+	ir := ParseAndInterpret(parser, ee, "ping")
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrOffline)
+
+	// A node that cannot be reached is reported as such, rather than with the dial error
+	ee.RPCClient = cliutil.NewKoinosRPCClient("http://127.0.0.1:1")
+	ir = ParseAndInterpret(parser, ee, "ping")
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrUnreachable)
+	assert.Equal(t, "rpc endpoint unreachable: http://127.0.0.1:1", ir.Err().Error())
+	assert.Equal(t, ExitNetworkError, ExitCode(ir.Err()))
+
+	server := testRPCServer(t, map[string]string{cliutil.GetHeadInfoCall: `{}`})
+	defer server.Close()
+
+	ee.RPCClient = cliutil.NewKoinosRPCClient(server.URL)
+	ir = ParseAndInterpret(parser, ee, "ping")
+	assert.NoError(t, ir.Err())
+	assert.True(t, strings.HasPrefix(ir.Outputs[0].Messages[0], server.URL+" is reachable, and responded in "), ir.Outputs[0].Messages[0])
+	assert.Equal(t, true, ir.Outputs[0].Fields["reachable"])
+
+	// An error response still reaches the node
+	errorServer := testRPCServer(t, nil)
+	defer errorServer.Close()
+
+	ee.RPCClient = cliutil.NewKoinosRPCClient(errorServer.URL)
+	ir = ParseAndInterpret(parser, ee, "ping")
+	assert.NoError(t, ir.Err())
+	assert.True(t, strings.HasSuffix(ir.Outputs[0].Messages[0], "with an error: method not found"), ir.Outputs[0].Messages[0])
+}
+
+func TestHeadAndChainInfo(t *testing.T) {
+	parser, ee := newTestEnvironment()
+
+	ir := ParseAndInterpret(parser, ee, "head")
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrOffline)
+	ir = ParseAndInterpret(parser, ee, "chain_info")
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrOffline)
+
+	server := testRPCServer(t, map[string]string{
+		cliutil.GetHeadInfoCall: `{"head_topology":{"id":"0x1234","height":"42"},"last_irreversible_block":"30"}`,
+		cliutil.GetChainIDCall:  `{"chain_id":"AQID"}`,
+	})
+	defer server.Close()
+
+	ee.RPCClient = cliutil.NewKoinosRPCClient(server.URL)
+	ir = ParseAndInterpret(parser, ee, "head")
+	assert.NoError(t, ir.Err())
+	assert.Equal(t, []string{"Head block height: 42", "Head block id: 0x1234", "Last irreversible block: 30"}, ir.Outputs[0].Messages)
+	assert.Equal(t, uint64(42), ir.Outputs[0].Fields["head_height"])
+
+	ir = ParseAndInterpret(parser, ee, "chain_info")
+	assert.NoError(t, ir.Err())
+	assert.Equal(t, []string{"RPC endpoint: " + server.URL, "Chain ID: AQID", "Head block height: 42", "Head block id: 0x1234", "Last irreversible block: 30"}, ir.Outputs[0].Messages)
+
+	// A chain id set by hand that differs from the node's is pointed out
+	ir = ParseAndInterpret(parser, ee, "chain_id BAUG; chain_info")
+	assert.NoError(t, ir.Err())
+	assert.Equal(t, "Warning: the chain id set with chain_id is BAUG, which does not match the node", ir.Outputs[1].Messages[2])
+}
+
+func TestNumberFormatCommand(t *testing.T) {
+	parser, ee := newTestEnvironment()
+
+	ir := ParseAndInterpret(parser, ee, "number_format")
+	assert.NoError(t, ir.Err())
+	assert.Equal(t, []string{"Number format: plain (e.g. 12345.6789 KOIN)"}, ir.Outputs[0].Messages)
+
+	ir = ParseAndInterpret(parser, ee, "number_format separators,decimals=2")
+	assert.NoError(t, ir.Err())
+	assert.Equal(t, []string{"Number format: separators,decimals=2 (e.g. 12,345.67 KOIN)"}, ir.Outputs[0].Messages)
+
+	ir = ParseAndInterpret(parser, ee, "number_format thousands")
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrInvalidParam)
+	assert.Equal(t, "separators,decimals=2", ee.numberFormat.String())
+}
+
+func TestUnregisteredContractCommand(t *testing.T) {
+	abiFilename := writeTestABI(t, t.TempDir(), "test.abi", nil)
+
+	parser, ee := newTestEnvironment()
+	ir := ParseAndInterpret(parser, ee, "register test 1AdzuXSpC6K9qtXdCBgD5NUpDNwHjMgrc9 "+abiFilename)
+	assert.NoError(t, ir.Err())
+
+	// A line is parsed before it runs, so its commands can outlive the contract
+	ir = ParseAndInterpret(parser, ee, "rename test other; test.get_value abc")
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrContract)
+	assert.Contains(t, ir.Err().Error(), "test.get_value is no longer registered")
+
+	ir = ParseAndInterpret(parser, ee, "unregister other; other.get_value abc")
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrContract)
+
+	ir = ParseAndInterpret(parser, ee, "register test 1AdzuXSpC6K9qtXdCBgD5NUpDNwHjMgrc9 "+abiFilename)
+	assert.NoError(t, ir.Err())
+	ir = ParseAndInterpret(parser, ee, "unregister test; test.set_value abc")
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrContract)
+}
+
+func TestPreviewABI(t *testing.T) {
+	dir := t.TempDir()
+	abiFilename := writeTestABI(t, dir, "mytoken.abi", func(abi map[string]interface{}) {
+		abi["methods"].(map[string]interface{})["broken"] = &ABIMethod{Argument: "test.missing", Return: "test.get_value_result", EntryPoint: "0x1234abcf"}
+	})
+
+	parser, ee := newTestEnvironment()
+	ir := ParseAndInterpret(parser, ee, "preview_abi "+abiFilename)
+	assert.NoError(t, ir.Err())
+
+	messages := ir.Outputs[0].Messages
+	assert.Len(t, messages, 5)
+	assert.Equal(t, abiFilename+" would add 2 commands, nothing was registered", messages[0])
+	assert.True(t, strings.HasPrefix(messages[1], "mytoken.get_value "), messages[1])
+	assert.True(t, strings.HasSuffix(messages[1], "(read)"), messages[1])
+	assert.True(t, strings.HasSuffix(messages[2], "(write) - Set the value"), messages[2])
+	assert.Contains(t, messages[3], "method broken takes argument type test.missing, which is not defined in the ABI types")
+	assert.Equal(t, "1 of 3 methods cannot be called, so register would fail", messages[4])
+
+	// Nothing is registered
+	assert.Empty(t, ee.Contracts)
+	_, ok := parser.Commands.Name2Command["mytoken.get_value"]
+	assert.False(t, ok)
+
+	ir = ParseAndInterpret(parser, ee, "preview_abi "+abiFilename+" other")
+	assert.NoError(t, ir.Err())
+	assert.True(t, strings.HasPrefix(ir.Outputs[0].Messages[1], "other.get_value "))
+
+	ir = ParseAndInterpret(parser, ee, "preview_abi "+filepath.Join(dir, "missing.abi"))
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrInvalidABI)
+}
+
+// writeGzipFile writes data gzip compressed, as a compressed ABI file
+func writeGzipFile(t *testing.T, filename string, data []byte) {
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	_, err := w.Write(data)
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	assert.NoError(t, ioutil.WriteFile(filename, b.Bytes(), 0644))
+}
+
+func TestRegisterGzipABI(t *testing.T) {
+	dir := t.TempDir()
+
+	abiFilename := filepath.Join(dir, "test.abi.gz")
+	writeGzipFile(t, abiFilename, testContractABI(t, ""))
+
+	parser, ee := newTestEnvironment()
+	ir := ParseAndInterpret(parser, ee, "register test 1AdzuXSpC6K9qtXdCBgD5NUpDNwHjMgrc9 "+abiFilename)
+	assert.NoError(t, ir.Err())
+	_, ok := parser.Commands.Name2Command["test.get_value"]
+	assert.True(t, ok)
+
+	// Compressed files in a directory are named without either extension, next to plain ones
+	contracts := filepath.Join(dir, "contracts")
+	assert.NoError(t, os.Mkdir(contracts, 0755))
+	writeGzipFile(t, filepath.Join(contracts, "first.json.gz"), testContractABI(t, "1AdzuXSpC6K9qtXdCBgD5NUpDNwHjMgrc9"))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(contracts, "second.abi"), testContractABI(t, cliutil.KoinContractID), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(contracts, "notes.txt.gz"), []byte("not an ABI"), 0644))
+
+	_, ee = newTestEnvironment()
+	ir = ParseAndInterpret(ee.Parser, ee, "register_dir "+contracts)
+	assert.NoError(t, ir.Err())
+	assert.True(t, ee.Contracts.Contains("first"))
+	assert.True(t, ee.Contracts.Contains("second"))
+	assert.Len(t, ee.Contracts, 2)
+
+	// A corrupt compressed file is an invalid ABI
+	corrupt := filepath.Join(dir, "corrupt.abi.gz")
+	assert.NoError(t, ioutil.WriteFile(corrupt, []byte{0x1f, 0x8b, 0x00}, 0644))
+	ir = ParseAndInterpret(parser, ee, "register corrupt 1AdzuXSpC6K9qtXdCBgD5NUpDNwHjMgrc9 "+corrupt)
+	assert.ErrorIs(t, ir.Err(), cliutil.ErrInvalidABI)
+}
+
+func TestRegisterEmptyABI(t *testing.T) {
+	dir := t.TempDir()
+	abiFilename := writeTestABI(t, dir, "empty.abi", func(abi map[string]interface{}) {
+		abi["methods"] = map[string]interface{}{}
+	})
+	fullFilename := writeTestABI(t, dir, "full.abi", nil)
+
+	// An ABI without methods registers, with a warning that no commands were added
+	parser, ee := newTestEnvironment()
+	ir := ParseAndInterpret(parser, ee, "register empty 1AdzuXSpC6K9qtXdCBgD5NUpDNwHjMgrc9 "+abiFilename)
+	assert.NoError(t, ir.Err())
+	assert.True(t, ee.Contracts.Contains("empty"))
+	messages := ir.Outputs[0].Messages
+	assert.Len(t, messages, 2)
+	assert.Contains(t, messages[1], "Warning: the ABI of empty has no methods")
+
+	// A full ABI registers without the warning
+	ir = ParseAndInterpret(parser, ee, "register test 1AdzuXSpC6K9qtXdCBgD5NUpDNwHjMgrc9 "+fullFilename)
+	assert.NoError(t, ir.Err())
+	assert.Len(t, ir.Outputs[0].Messages, 1)
+
+	ir = ParseAndInterpret(parser, ee, "preview_abi "+abiFilename)
+	assert.NoError(t, ir.Err())
+	assert.Contains(t, ir.Outputs[0].Messages, "Warning: the ABI has no methods")
+}
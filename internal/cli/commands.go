@@ -1,8 +1,11 @@
 package cli
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,14 +13,18 @@ import (
 	"os"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/btcsuite/btcutil/base58"
 	"github.com/koinos/koinos-cli/internal/cliutil"
+	kjson "github.com/koinos/koinos-proto-golang/encoding/json"
 	"github.com/koinos/koinos-proto-golang/koinos/chain"
 	"github.com/koinos/koinos-proto-golang/koinos/protocol"
+	chain_rpc "github.com/koinos/koinos-proto-golang/koinos/rpc/chain"
 	"github.com/shopspring/decimal"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/dynamicpb"
 
 	util "github.com/koinos/koinos-util-golang"
 )
@@ -47,6 +54,93 @@ func (cs *CommandSet) AddCommand(decl *CommandDeclaration) {
 	cs.Revision++
 }
 
+// Limits on the commands suggested for an unknown command name
+const (
+	MaxCommandSuggestions = 3
+	MinPartialMatchLength = 3 // The shortest name that is matched as part of a command name
+)
+
+// Suggest returns the names of the commands closest to an unknown command name, closest first. Names within a few
+// typos of a command, or contained in its name, are suggested. When the name starts with a registered contract's
+// prefix, only that contract's commands are considered, so that a mistyped method is matched
+func (cs *CommandSet) Suggest(name string) []string {
+	candidates := cs.Commands
+	method := name
+
+	if i := strings.LastIndex(name, "."); i > 0 {
+		prefix := name[:i+1]
+		contractCommands := make([]*CommandDeclaration, 0)
+		for _, decl := range cs.Commands {
+			if strings.HasPrefix(decl.Name, prefix) {
+				contractCommands = append(contractCommands, decl)
+			}
+		}
+
+		if len(contractCommands) > 0 {
+			candidates = contractCommands
+			method = name[i+1:]
+		}
+	}
+
+	// Allow about one typo for every three characters
+	maxDistance := len([]rune(method)) / 3
+	if maxDistance < 1 {
+		maxDistance = 1
+	}
+
+	type suggestion struct {
+		name     string
+		distance int
+	}
+
+	suggestions := make([]suggestion, 0)
+	for _, decl := range candidates {
+		if decl.Hidden {
+			continue
+		}
+
+		distance := cliutil.EditDistance(name, decl.Name)
+		if distance > maxDistance && (len(method) < MinPartialMatchLength || !strings.Contains(decl.Name[len(name)-len(method):], method)) {
+			continue
+		}
+
+		suggestions = append(suggestions, suggestion{name: decl.Name, distance: distance})
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].distance != suggestions[j].distance {
+			return suggestions[i].distance < suggestions[j].distance
+		}
+		return suggestions[i].name < suggestions[j].name
+	})
+
+	names := make([]string, 0, MaxCommandSuggestions)
+	for i := 0; i < len(suggestions) && i < MaxCommandSuggestions; i++ {
+		names = append(names, suggestions[i].name)
+	}
+
+	return names
+}
+
+// RemoveCommand removes the command with the given name from the command set, returning false if it does not exist
+func (cs *CommandSet) RemoveCommand(name string) bool {
+	if _, ok := cs.Name2Command[name]; !ok {
+		return false
+	}
+
+	for i, c := range cs.Commands {
+		if c.Name == name {
+			cs.Commands = append(cs.Commands[:i], cs.Commands[i+1:]...)
+			break
+		}
+	}
+
+	delete(cs.Name2Command, name)
+	cs.Revision++
+
+	return true
+}
+
 // List returns an alphabetized list of commands. The pretty argument makes it return the commands in neat columns with the descriptions
 func (cs *CommandSet) List(pretty bool) []string {
 	names := make([]string, 0)
@@ -81,6 +175,55 @@ func (cs *CommandSet) List(pretty bool) []string {
 	return o
 }
 
+// ListMatching returns the commands whose names start with the given prefix in neat columns with their descriptions.
+// Built-in commands come first, then the commands of each registered contract, grouped under the contract's name
+func (cs *CommandSet) ListMatching(prefix string) []string {
+	builtin := make([]string, 0)
+	contracts := make(map[string][]string)
+	longest := 0
+
+	for _, c := range cs.Commands {
+		if c.Hidden || !strings.HasPrefix(c.Name, prefix) {
+			continue
+		}
+
+		if i := strings.Index(c.Name, "."); i > 0 {
+			contracts[c.Name[:i]] = append(contracts[c.Name[:i]], c.Name)
+		} else {
+			builtin = append(builtin, c.Name)
+		}
+
+		if len(c.Name) > longest {
+			longest = len(c.Name)
+		}
+	}
+
+	o := make([]string, 0)
+	sort.Strings(builtin)
+	for _, name := range builtin {
+		o = append(o, fmt.Sprintf("%*s - %s", -longest, name, cs.Name2Command[name].Description))
+	}
+
+	contractNames := make([]string, 0, len(contracts))
+	for name := range contracts {
+		contractNames = append(contractNames, name)
+	}
+	sort.Strings(contractNames)
+
+	// Contract commands are indented under their contract, so they line up with each other
+	for _, contract := range contractNames {
+		o = append(o, fmt.Sprintf("%s:", contract))
+
+		names := contracts[contract]
+		sort.Strings(names)
+		for _, name := range names {
+			o = append(o, fmt.Sprintf("  %*s - %s", -longest, name, cs.Name2Command[name].Description))
+		}
+	}
+
+	return o
+}
+
 // ----------------------------------------------------------------------------
 // Command Declarations
 // ----------------------------------------------------------------------------
@@ -91,36 +234,103 @@ func (cs *CommandSet) List(pretty bool) []string {
 func NewKoinosCommandSet() *CommandSet {
 	cs := NewCommandSet()
 
+	cs.AddCommand(NewCommandDeclaration("change_password", "Change the password of the open wallet file", false, NewChangePasswordCommand, *NewOptionalCommandArg("old-password", StringArg), *NewOptionalCommandArg("new-password", StringArg)))
+	cs.AddCommand(NewCommandDeclaration("add_key", "Generate a new key and add it to the open wallet file under the given label", false, NewAddKeyCommand, *NewCommandArg("label", StringArg), *NewOptionalCommandArg("password", StringArg)))
+	cs.AddCommand(NewCommandDeclaration("use_key", "Switch the active key to the one in the open wallet file with the given label", false, NewUseKeyCommand, *NewCommandArg("label", StringArg)))
+	cs.AddCommand(NewCommandDeclaration("list_keys", "List the keys in the open wallet file", false, NewListKeysCommand))
+	cs.AddCommand(NewCommandDeclaration("use_wallet", "Switch the active wallet to the open wallet file with the given label", false, NewUseWalletCommand, *NewCommandArg("label", StringArg)))
+	cs.AddCommand(NewCommandDeclaration("list_wallets", "List the open wallet files, and which is active", false, NewListWalletsCommand))
 	cs.AddCommand(NewCommandDeclaration("address", "Show the currently opened wallet's address", false, NewAddressCommand))
+	cs.AddCommand(NewCommandDeclaration("whoami", "Show the open wallet and its address, the RPC endpoint, the chain id, the network preset, and the number of registered contracts", false, NewWhoamiCommand))
+	cs.AddCommand(NewCommandDeclaration("ping", "Check that the RPC endpoint is reachable, and show the round trip time of a request", false, NewPingCommand))
+	cs.AddCommand(NewCommandDeclaration("head", "Show the height and id of the connected node's head block, and its last irreversible block", false, NewHeadCommand))
+	cs.AddCommand(NewCommandDeclaration("chain_info", "Show the chain id and head block of the connected node", false, NewChainInfoCommand))
 	cs.AddCommand(NewCommandDeclaration("connect", "Connect to an RPC endpoint", false, NewConnectCommand, *NewCommandArg("url", StringArg)))
-	cs.AddCommand(NewCommandDeclaration("close", "Close the currently open wallet (lock also works)", false, NewCloseCommand))
+	cs.AddCommand(NewCommandDeclaration("close", "Close all open wallets (lock also works)", false, NewCloseCommand))
 	cs.AddCommand(NewCommandDeclaration("lock", "Synonym for close", true, NewCloseCommand))
-	cs.AddCommand(NewCommandDeclaration("create", "Create and open a new wallet file", false, NewCreateCommand, *NewCommandArg("filename", FileArg), *NewOptionalCommandArg("password", StringArg)))
+	create := NewCommandDeclaration("create", "Create and open a new wallet file", false, NewCreateCommand, *NewCommandArg("filename", FileArg), *NewOptionalCommandArg("password", StringArg))
+	create.Options = []CommandArg{*NewCommandArg(PasswordFileOption, FileArg)}
+	cs.AddCommand(create)
 	cs.AddCommand(NewCommandDeclaration("disconnect", "Disconnect from RPC endpoint", false, NewDisconnectCommand))
+	cs.AddCommand(NewCommandDeclaration("genaddr", "Generate several new keys at once, such as for test accounts, showing the address and private key of each in text, csv, or json format, or writing them to a new file. The private keys are not encrypted", false, NewGenerateAddressesCommand, *NewCommandArg("count", UIntArg), *NewDefaultCommandArg("format", StringArg, TextKeyFormat), *NewOptionalCommandArg("filename", FileArg)))
+	cs.AddCommand(NewCommandDeclaration("mnemonic", "Generate and display a new BIP-39 mnemonic phrase (12 or 24 words) and its address, at an optional derivation path or account index", false, NewMnemonicCommand, *NewOptionalCommandArg("words", StringArg), *NewOptionalCommandArg("path", StringArg)))
 	cs.AddCommand(NewCommandDeclaration("generate", "Generate and display a new private key", false, NewGenerateKeyCommand))
-	cs.AddCommand(NewCommandDeclaration("help", "Show help on a given command", false, NewHelpCommand, *NewCommandArg("command", CmdNameArg)))
-	cs.AddCommand(NewCommandDeclaration("import", "Import a WIF private key to a new wallet file", false, NewImportCommand, *NewCommandArg("private-key", StringArg), *NewCommandArg("filename", FileArg), *NewOptionalCommandArg("password", StringArg)))
+	cs.AddCommand(NewCommandDeclaration("help", "Show help on a given command, or list the commands starting with a prefix", false, NewHelpCommand, *NewOptionalCommandArg("command", CmdNameArg)))
+	cs.AddCommand(NewCommandDeclaration("import_mnemonic", "Import a BIP-39 mnemonic phrase to a new wallet file, deriving the key at an optional derivation path or account index", false, NewImportMnemonicCommand, *NewCommandArg("mnemonic", StringArg), *NewCommandArg("filename", FileArg), *NewOptionalCommandArg("password", StringArg), *NewOptionalCommandArg("path", StringArg)))
+	cs.AddCommand(NewCommandDeclaration("import", "Import a private key, in WIF or hex, to a new wallet file (import_key also works)", false, NewImportCommand, *NewCommandArg("private-key", StringArg), *NewCommandArg("filename", FileArg), *NewOptionalCommandArg("password", StringArg)))
+	cs.AddCommand(NewCommandDeclaration("import_key", "Synonym for import", true, NewImportCommand, *NewCommandArg("private-key", StringArg), *NewCommandArg("filename", FileArg), *NewOptionalCommandArg("password", StringArg)))
 	cs.AddCommand(NewCommandDeclaration("list", "List available commands", false, NewListCommand))
 	cs.AddCommand(NewCommandDeclaration("upload", "Upload a smart contract", false, NewUploadContractCommand, *NewCommandArg("filename", FileArg), *NewOptionalCommandArg("abi-filename", FileArg), *NewOptionalCommandArg("override-authorize-call-contract", BoolArg), *NewOptionalCommandArg("override-authorize-transaction-application", BoolArg), *NewOptionalCommandArg("override-authorize-upload-contract", BoolArg)))
-	cs.AddCommand(NewCommandDeclaration("call", "Call a smart contract", false, NewCallCommand, *NewCommandArg("contract-id", StringArg), *NewCommandArg("entry-point", HexArg), *NewCommandArg("arguments", StringArg)))
-	cs.AddCommand(NewCommandDeclaration("open", "Open a wallet file (unlock also works)", false, NewOpenCommand, *NewCommandArg("filename", FileArg), *NewOptionalCommandArg("password", StringArg)))
-	cs.AddCommand(NewCommandDeclaration("unlock", "Synonym for open", true, NewOpenCommand, *NewCommandArg("filename", FileArg), *NewOptionalCommandArg("password", StringArg)))
+	cs.AddCommand(NewCommandDeclaration("call", "Call a smart contract entry point with raw arguments, given as 0x prefixed hex or base64", false, NewCallCommand, *NewCommandArg("contract-id", StringArg), *NewCommandArg("entry-point", HexArg), *NewCommandArg("arguments", StringArg)))
+	open := NewCommandDeclaration("open", "Open a wallet file, keeping any other open wallets available to use_wallet (unlock also works)", false, NewOpenCommand, *NewCommandArg("filename", FileArg), *NewOptionalCommandArg("password", StringArg))
+	open.Options = []CommandArg{*NewCommandArg(PasswordFileOption, FileArg)}
+	cs.AddCommand(open)
+	unlock := NewCommandDeclaration("unlock", "Synonym for open", true, NewOpenCommand, *NewCommandArg("filename", FileArg), *NewOptionalCommandArg("password", StringArg))
+	unlock.Options = open.Options
+	cs.AddCommand(unlock)
 	cs.AddCommand(NewCommandDeclaration("nonce", "Set nonce for transactions. 'auto' will default to querying for nonce. Blank nonce to view", false, NewNonceCommand, *NewOptionalCommandArg("nonce", StringArg)))
+	cs.AddCommand(NewCommandDeclaration("set_network", "Connect to a network preset (e.g. mainnet or testnet), setting its RPC endpoint, chain id, and KOIN units. Give no name to list the networks", false, NewSetNetworkCommand, *NewOptionalCommandArg("name", StringArg)))
 	cs.AddCommand(NewCommandDeclaration("chain_id", "Set chain id in base64 for transactions. 'auto' will default to querying for chain id. Blank id to view", false, NewChainIDCommand, *NewOptionalCommandArg("id", StringArg)))
-	cs.AddCommand(NewCommandDeclaration("payer", "Set the payer address for transactions. 'me' will default to current wallet. Blank address to view", false, NewPayerCommand, *NewOptionalCommandArg("payer", AddressArg)))
+	cs.AddCommand(NewCommandDeclaration("payer", "Set the payer address for transactions. 'me' will default to current wallet. Blank address to view. The payer signs with its key from an open wallet, unless signed is false because it authorizes transactions on its own", false, NewPayerCommand, *NewOptionalCommandArg("payer", PayerArg), *NewDefaultCommandArg("signed", BoolArg, "true")))
+	cs.AddCommand(NewCommandDeclaration("export_key", "Show the open wallet's private key after re-entering the password, or write it to a new file", false, NewExportKeyCommand, *NewOptionalCommandArg("filename", FileArg), *NewOptionalCommandArg("password", StringArg)))
+	cs.AddCommand(NewCommandDeclaration("wallet_kdf", "Set or show the scrypt cost (N, r, p) of the key derived from the password of wallets created from now on, or whose password is changed. Give no N to view", false, NewWalletKDFCommand, *NewOptionalCommandArg("n", UIntArg), *NewDefaultCommandArg("r", UIntArg, strconv.Itoa(cliutil.DefaultKDFParams.R)), *NewDefaultCommandArg("p", UIntArg, strconv.Itoa(cliutil.DefaultKDFParams.P))))
+	cs.AddCommand(NewCommandDeclaration("encrypt_file", "Encrypt a file with a password, using the same encryption as wallet files. The output file must not exist", false, NewEncryptFileCommand, *NewCommandArg("input", FileArg), *NewCommandArg("output", FileArg), *NewOptionalCommandArg("password", StringArg)))
+	cs.AddCommand(NewCommandDeclaration("decrypt_file", "Decrypt a file made by encrypt_file. The output file must not exist", false, NewDecryptFileCommand, *NewCommandArg("input", FileArg), *NewCommandArg("output", FileArg), *NewOptionalCommandArg("password", StringArg)))
 	cs.AddCommand(NewCommandDeclaration("private", "Show the currently opened wallet's private key", false, NewPrivateCommand))
 	cs.AddCommand(NewCommandDeclaration("public", "Show the currently opened wallet's public key", false, NewPublicCommand))
+	cs.AddCommand(NewCommandDeclaration("method_rclimit", "Set or show the rc limit used when calling a contract method, in place of rclimit. Give the limit as either mana or a percent, or default to use rclimit again", false, NewMethodRcLimitCommand, *NewCommandArg("method", StringArg), *NewOptionalCommandArg("limit", StringArg)))
 	cs.AddCommand(NewCommandDeclaration("rclimit", "Set or show the current rc limit. Give no limit to see current value. Give limit as either mana or a percent (i.e. 80%).", false, NewRcLimitCommand, *NewOptionalCommandArg("limit", StringArg)))
-	cs.AddCommand(NewCommandDeclaration("read", "Read from a smart contract", false, NewReadCommand, *NewCommandArg("contract-id", StringArg), *NewCommandArg("entry-point", StringArg), *NewCommandArg("arguments", StringArg)))
+	cs.AddCommand(NewCommandDeclaration("read", "Read from a smart contract entry point with raw arguments, given as 0x prefixed hex or multibase base64, showing the raw result", false, NewReadCommand, *NewCommandArg("contract-id", StringArg), *NewCommandArg("entry-point", StringArg), *NewCommandArg("arguments", StringArg)))
+	cs.AddCommand(NewCommandDeclaration("read_format", "Set or show the format of contract read results, either 'text' (the default) or 'json'", false, NewReadFormatCommand, *NewOptionalCommandArg("format", StringArg)))
 	cs.AddCommand(NewCommandDeclaration("register", "Register a smart contract's commands", false, NewRegisterCommand, *NewCommandArg("name", ContractNameArg), *NewCommandArg("address", AddressArg), *NewOptionalCommandArg("abi-filename", FileArg)))
+	cs.AddCommand(NewCommandDeclaration("register_dir", "Register every smart contract ABI file (.abi or .json) in a directory. Each contract is named after its file, and its ABI must include an \"address\" field", false, NewRegisterDirCommand, *NewCommandArg("path", FileArg)))
+	cs.AddCommand(NewCommandDeclaration("preview_abi", "Show the commands a smart contract ABI file would add, and any methods with unsupported types, without registering it. The commands are named after the file unless a name is given", false, NewPreviewABICommand, *NewCommandArg("abi-filename", FileArg), *NewOptionalCommandArg("name", ContractNameArg)))
+	cs.AddCommand(NewCommandDeclaration("invoke", "Call a method of a registered contract, choosing the method from a list if not given and asking for each of its arguments", false, NewInvokeCommand, *NewCommandArg("contract", ContractNameArg), *NewOptionalCommandArg("method", StringArg)))
+	cs.AddCommand(NewCommandDeclaration("list_contracts", "List the registered smart contracts and tokens", false, NewListContractsCommand))
+	cs.AddCommand(NewCommandDeclaration("rename", "Rename a registered smart contract or token, along with its commands", false, NewRenameCommand, *NewCommandArg("old-name", ContractNameArg), *NewCommandArg("new-name", ContractNameArg)))
+	cs.AddCommand(NewCommandDeclaration("set_contract_address", "Change the address of a registered smart contract or token, keeping its commands, such as after redeploying it", false, NewSetContractAddressCommand, *NewCommandArg("name", ContractNameArg), *NewCommandArg("address", AddressArg)))
+	cs.AddCommand(NewCommandDeclaration("unregister", "Unregister a smart contract or token and remove its commands", false, NewUnregisterCommand, *NewCommandArg("name", ContractNameArg)))
 	cs.AddCommand(NewCommandDeclaration("register_token", "Register a token's commands", false, NewRegisterTokenCommand, *NewCommandArg("name", ContractNameArg), *NewCommandArg("address", AddressArg), *NewOptionalCommandArg("symbol", StringArg), *NewOptionalCommandArg("precision", StringArg)))
 	cs.AddCommand(NewCommandDeclaration("account_rc", "Get the current resource credits for a given address (open wallet if blank)", false, NewAccountRcCommand, *NewOptionalCommandArg("address", AddressArg)))
 	cs.AddCommand(NewCommandDeclaration("set_system_call", "Set a system call to a new contract and entry point", false, NewSetSystemCallCommand, *NewCommandArg("system-call", StringArg), *NewCommandArg("contract-id", AddressArg), *NewCommandArg("entry-point", HexArg)))
 	cs.AddCommand(NewCommandDeclaration("set_system_contract", "Change a contract's permission level between user and system", false, NewSetSystemContractCommand, *NewCommandArg("contract-id", AddressArg), *NewCommandArg("system-contract", BoolArg)))
-	cs.AddCommand(NewCommandDeclaration("session", "Create or manage a transaction session (begin, submit, cancel, or view)", false, NewSessionCommand, *NewCommandArg("command", StringArg)))
+	cs.AddCommand(NewCommandDeclaration("session", "Create or manage a transaction session (begin, submit, cancel, or view). When offline, submit can write the signed transaction to a file", false, NewSessionCommand, *NewCommandArg("command", StringArg), *NewOptionalCommandArg("filename", FileArg)))
 	cs.AddCommand(NewCommandDeclaration("sign_transaction", "Signs a transaction with the open wallet, adding it to the transaction", true, NewSignTransactionCommand, *NewCommandArg("transaction", StringArg)))
+	cs.AddCommand(NewCommandDeclaration("sign_message", "Sign a message with the open wallet, to prove ownership of its address", false, NewSignMessageCommand, *NewCommandArg("message", StringArg)))
+	cs.AddCommand(NewCommandDeclaration("verify_message", "Check that a signature from sign_message was made by the given address", false, NewVerifyMessageCommand, *NewCommandArg("address", AddressArg), *NewCommandArg("message", StringArg), *NewCommandArg("signature", StringArg)))
+	cs.AddCommand(NewCommandDeclaration("broadcast", "Submit a signed transaction from a file containing its base64 data", false, NewBroadcastCommand, *NewCommandArg("filename", FileArg)))
+	cs.AddCommand(NewCommandDeclaration("decode_tx", "Show the header and operations of a transaction from hex or base64 data, without submitting it", false, NewDecodeTransactionCommand, *NewCommandArg("transaction", StringArg)))
+	cs.AddCommand(NewCommandDeclaration("history", "List the transactions submitted during this session", false, NewHistoryCommand))
+	cs.AddCommand(NewCommandDeclaration("receipt", "Look up the receipt of a transaction included in a block, showing its status, mana cost, and events", false, NewReceiptCommand, *NewCommandArg("transaction-id", HexArg)))
 	cs.AddCommand(NewCommandDeclaration("submit_transaction", "Submit a transaction from base64 data", false, NewSubmitTransactionCommand, *NewCommandArg("transaction", StringArg)))
+	cs.AddCommand(NewCommandDeclaration("resubmit", "Send the last transaction submitted again, with the same nonce and signatures, after the node did not answer. The node accepts it at most once", false, NewResubmitCommand))
+	cs.AddCommand(NewCommandDeclaration("balance", "Check the KOIN balance, or the balance of a registered token, at an address (defaults to the open wallet)", false, NewBalanceCommand, *NewOptionalCommandArg("address", AddressArg), *NewOptionalCommandArg("token", ContractNameArg)))
+	cs.AddCommand(NewCommandDeclaration("self_balance", "Check the KOIN balance, or the balance of a registered token, of the open wallet", false, NewSelfBalanceCommand, *NewOptionalCommandArg("token", ContractNameArg)))
+	cs.AddCommand(NewCommandDeclaration("watch_balance", "Check a balance, as with balance, every given number of seconds until interrupted with Ctrl-C, showing it whenever it changes", false, NewWatchBalanceCommand, *NewCommandArg("seconds", AmountArg), *NewOptionalCommandArg("address", AddressArg), *NewOptionalCommandArg("token", ContractNameArg)))
+	accountHistory := NewCommandDeclaration("account_history", "List the recent transactions involving an address (defaults to the open wallet), showing KOIN and token transfers, newest first from the start sequence number if given. Requires a node with account history", false, NewAccountHistoryCommand, *NewOptionalCommandArg("address", AddressArg), *NewOptionalCommandArg("start", UIntArg))
+	accountHistory.Options = []CommandArg{*NewCommandArg(LimitOption, UIntArg)}
+	cs.AddCommand(accountHistory)
+	cs.AddCommand(NewCommandDeclaration("balances", "Check the KOIN balances of one or more addresses", false, NewBalancesCommand, *NewVariadicCommandArg("addresses", AddressArg)))
+	cs.AddCommand(NewCommandDeclaration("transfer", "Transfer KOIN from the open wallet to a given address", false, NewTransferCommand, *NewCommandArg("amount", AmountArg), *NewCommandArg("address", AddressArg)))
+	cs.AddCommand(NewCommandDeclaration("alias_add", "Add an address book alias, which can be used in place of the address in any command", false, NewAliasAddCommand, *NewCommandArg("name", ContractNameArg), *NewCommandArg("address", AddressArg)))
+	cs.AddCommand(NewCommandDeclaration("alias_remove", "Remove an address book alias", false, NewAliasRemoveCommand, *NewCommandArg("name", ContractNameArg)))
+	cs.AddCommand(NewCommandDeclaration("alias_list", "List the address book aliases", false, NewAliasListCommand))
+	cs.AddCommand(NewCommandDeclaration("alias", "Define a shortcut name for a command line. Arguments given after the name are appended to it", false, NewCommandAliasCommand, *NewCommandArg("name", ContractNameArg), *NewVariadicCommandArg("command", StringArg)))
+	cs.AddCommand(NewCommandDeclaration("unalias", "Remove a command shortcut defined with alias", false, NewCommandUnaliasCommand, *NewCommandArg("name", ContractNameArg)))
+	cs.AddCommand(NewCommandDeclaration("command_aliases", "List the command shortcuts defined with alias", false, NewCommandAliasListCommand))
+	cs.AddCommand(NewCommandDeclaration("config", "Show the configuration the CLI was started with (show), resolved from the config file, environment, and command line", false, NewConfigCommand, *NewCommandArg("command", StringArg)))
+	cs.AddCommand(NewCommandDeclaration("set_precision", "Set or show the precision and symbol used to display KOIN amounts, for chains that differ from mainnet", false, NewSetPrecisionCommand, *NewOptionalCommandArg("precision", UIntArg), *NewOptionalCommandArg("symbol", StringArg)))
+	cs.AddCommand(NewCommandDeclaration("balance_entry_point", "Set or show the entry point called to read KOIN balances, given as 0x prefixed hex or decimal, for chains that differ from mainnet. Use default to restore the mainnet entry point", false, NewBalanceEntryPointCommand, *NewOptionalCommandArg("entry-point", StringArg)))
+	cs.AddCommand(NewCommandDeclaration("number_format", "Set or show how balances are shown, as words separated by commas: plain, separators to group thousands, decimals=N to fix the decimal places, and scientific for amounts below 0.001", false, NewNumberFormatCommand, *NewOptionalCommandArg("format", StringArg)))
+	cs.AddCommand(NewCommandDeclaration("dry_run", "Set or show dry run mode. When enabled, transactions are checked by the node to estimate their mana cost, but are not broadcast", false, NewDryRunCommand, *NewOptionalCommandArg("enabled", BoolArg)))
+	cs.AddCommand(NewCommandDeclaration("confirm", "Set or show confirmation mode. When enabled, the operations of each transaction are shown and must be confirmed by typing yes before it is submitted", false, NewConfirmCommand, *NewOptionalCommandArg("enabled", BoolArg)))
+	cs.AddCommand(NewCommandDeclaration("parse_only", "Set or show parse only mode. When enabled, the operations of transactions are shown, but nothing is sent to the node", false, NewParseOnlyCommand, *NewOptionalCommandArg("enabled", BoolArg)))
+	cs.AddCommand(NewCommandDeclaration("wait", "Set or show the number of seconds to wait for submitted transactions to be included in a block (0 disables), and optionally the seconds between checks", false, NewWaitCommand, *NewOptionalCommandArg("seconds", StringArg), *NewOptionalCommandArg("interval", StringArg)))
+	cs.AddCommand(NewCommandDeclaration("rpc_timeout", "Set or show the number of seconds each RPC call may wait on the endpoint (0 disables)", false, NewRPCTimeoutCommand, *NewOptionalCommandArg("seconds", StringArg)))
+	cs.AddCommand(NewCommandDeclaration("prompt_balance", "Set or show how often, in seconds, the KOIN balance of the open wallet shown in the interactive prompt is refreshed. 0 hides it, avoiding the RPC calls", false, NewPromptBalanceCommand, *NewOptionalCommandArg("seconds", UIntArg)))
+	cs.AddCommand(NewCommandDeclaration("set_timeout", "Set or show the minutes of inactivity after which the wallet is closed in interactive mode (0 disables)", false, NewSetTimeoutCommand, *NewOptionalCommandArg("minutes", StringArg)))
 	cs.AddCommand(NewCommandDeclaration("sleep", "Sleep for the given number seconds", true, NewSleepCommand, *NewCommandArg("seconds", AmountArg)))
+	cs.AddCommand(NewCommandDeclaration("version", "Show the version of the CLI, and the head block of the connected node", false, NewVersionCommand))
 	cs.AddCommand(NewCommandDeclaration("exit", "Exit the wallet (quit also works)", false, NewExitCommand))
 	cs.AddCommand(NewCommandDeclaration("quit", "Synonym for exit", true, NewExitCommand))
 
@@ -177,6 +387,11 @@ func NewConnectCommand(inv *CommandParseResult) Command {
 
 // Execute connects to an RPC endpoint
 func (c *ConnectCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	// Release any connection to the previous endpoint
+	if ee.IsOnline() {
+		ee.RPCClient.Close()
+	}
+
 	rpc := cliutil.NewKoinosRPCClient(c.URL)
 	ee.RPCClient = rpc
 
@@ -186,6 +401,73 @@ func (c *ConnectCommand) Execute(ctx context.Context, ee *ExecutionEnvironment)
 	result := NewExecutionResult()
 	result.AddMessage(fmt.Sprintf("Connected to endpoint %s", c.URL))
 
+	// Fetch the chain id now, so that it is known before the first transaction
+	if ee.IsChainIDAuto() {
+		chainID, err := ee.GetChainID(ctx)
+		if err != nil {
+			result.AddMessage(fmt.Sprintf("Could not fetch chain id: %s", err))
+		} else {
+			result.AddMessage(fmt.Sprintf("Chain ID: %s", base64.URLEncoding.EncodeToString(chainID)))
+		}
+	}
+
+	return result, nil
+}
+
+// ----------------------------------------------------------------------------
+// Set Network Command
+// ----------------------------------------------------------------------------
+
+// SetNetworkCommand is a command that switches to a network preset
+type SetNetworkCommand struct {
+	Name *string
+}
+
+// NewSetNetworkCommand creates a new set network object
+func NewSetNetworkCommand(inv *CommandParseResult) Command {
+	return &SetNetworkCommand{Name: inv.Args["name"]}
+}
+
+// Execute connects to the network's RPC endpoint and applies its settings, or lists the networks if none is given
+func (c *SetNetworkCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	result := NewExecutionResult()
+
+	if c.Name == nil {
+		names := cliutil.NetworkNames(ee.networks)
+		for _, name := range names {
+			preset, _ := cliutil.GetNetwork(name, ee.networks)
+			current := ""
+			if name == ee.network {
+				current = " (current)"
+			}
+			result.AddMessage(fmt.Sprintf("%s: %s%s", name, preset.RPC, current))
+		}
+
+		result.SetField("networks", names)
+		result.SetField("network", ee.network)
+		return result, nil
+	}
+
+	preset, err := ee.UseNetwork(*c.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Release any connection to the previous endpoint
+	if ee.IsOnline() {
+		ee.RPCClient.Close()
+	}
+	ee.RPCClient = cliutil.NewKoinosRPCClient(preset.RPC)
+
+	result.AddMessage(fmt.Sprintf("Using network %s at endpoint %s", *c.Name, preset.RPC))
+	result.AddMessage(fmt.Sprintf("KOIN amounts are shown with precision %d and symbol %s", preset.Precision, preset.Symbol))
+
+	if preset.ChainID != "" {
+		result.AddMessage(fmt.Sprintf("Chain ID: %s", preset.ChainID))
+	} else {
+		result.AddMessage("Chain ID: auto")
+	}
+
 	return result, nil
 }
 
@@ -209,6 +491,7 @@ func (c *DisconnectCommand) Execute(ctx context.Context, ee *ExecutionEnvironmen
 	}
 
 	// Disconnect from the RPC endpoint
+	ee.RPCClient.Close()
 	ee.RPCClient = nil
 
 	result := NewExecutionResult()
@@ -217,6 +500,46 @@ func (c *DisconnectCommand) Execute(ctx context.Context, ee *ExecutionEnvironmen
 	return result, nil
 }
 
+// ----------------------------------------------------------------------------
+// Version Command
+// ----------------------------------------------------------------------------
+
+// VersionCommand is a command that shows version information
+type VersionCommand struct {
+}
+
+// NewVersionCommand creates a new version command object
+func NewVersionCommand(inv *CommandParseResult) Command {
+	return &VersionCommand{}
+}
+
+// Execute shows the version information
+func (c *VersionCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	result := NewExecutionResult()
+	result.AddMessage(fmt.Sprintf("Koinos CLI %s", cliutil.VersionString()))
+	result.SetField("version", cliutil.Version)
+	result.SetField("commit", cliutil.Commit)
+
+	if protoVersion := cliutil.DependencyVersion(cliutil.ProtoModule); protoVersion != "" {
+		result.AddMessage(fmt.Sprintf("Koinos protocol types %s", protoVersion))
+		result.SetField("proto_version", protoVersion)
+	}
+
+	// The node has no version call, so identify it by its head block instead
+	if ee.IsOnline() {
+		headInfo, err := ee.RPCClient.GetHeadInfo(ctx)
+		if err != nil {
+			result.AddMessage(fmt.Sprintf("Could not get node head info: %s", err))
+		} else {
+			height := headInfo.GetHeadTopology().GetHeight()
+			result.AddMessage(fmt.Sprintf("Node head block height %d, last irreversible block %d", height, headInfo.GetLastIrreversibleBlock()))
+			result.SetField("head_height", height)
+		}
+	}
+
+	return result, nil
+}
+
 // ----------------------------------------------------------------------------
 // Exit Command
 // ----------------------------------------------------------------------------
@@ -265,6 +588,193 @@ func (c *GenerateKeyCommand) Execute(ctx context.Context, ee *ExecutionEnvironme
 	return result, nil
 }
 
+// ----------------------------------------------------------------------------
+// Generate Addresses Command
+// ----------------------------------------------------------------------------
+
+// MaxGeneratedKeys is the most keys genaddr generates at once
+const MaxGeneratedKeys = 10000
+
+// Formats of the keys generated by genaddr
+const (
+	TextKeyFormat = "text"
+	CSVKeyFormat  = "csv"
+	JSONKeyFormat = "json"
+)
+
+// GeneratedKey is a key generated by genaddr
+type GeneratedKey struct {
+	Address    string `json:"address"`
+	PublicKey  string `json:"public_key"`
+	PrivateKey string `json:"private_key"`
+}
+
+// GenerateAddressesCommand is a command that generates several keys at once, such as for test accounts
+type GenerateAddressesCommand struct {
+	Count    string
+	Format   string
+	Filename *string
+}
+
+// NewGenerateAddressesCommand creates a new generate addresses command object
+func NewGenerateAddressesCommand(inv *CommandParseResult) Command {
+	return &GenerateAddressesCommand{Count: *inv.Args["count"], Format: *inv.Args["format"], Filename: inv.Args["filename"]}
+}
+
+// Execute generates the keys, showing them or writing them to a file
+func (c *GenerateAddressesCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	count, err := strconv.Atoi(c.Count)
+	if err != nil || count < 1 || count > MaxGeneratedKeys {
+		return nil, fmt.Errorf("%w: count must be from 1 to %d, got %s", cliutil.ErrInvalidParam, MaxGeneratedKeys, c.Count)
+	}
+
+	if c.Format != TextKeyFormat && c.Format != CSVKeyFormat && c.Format != JSONKeyFormat {
+		return nil, fmt.Errorf("%w: format must be %s, %s, or %s, got %s", cliutil.ErrInvalidParam, TextKeyFormat, CSVKeyFormat, JSONKeyFormat, c.Format)
+	}
+
+	keys := make([]*GeneratedKey, count)
+	addresses := make([]string, count)
+	for i := range keys {
+		k, err := util.GenerateKoinosKey()
+		if err != nil {
+			return nil, err
+		}
+
+		addresses[i] = base58.Encode(k.AddressBytes())
+		keys[i] = &GeneratedKey{Address: addresses[i], PublicKey: base64.URLEncoding.EncodeToString(k.PublicBytes()), PrivateKey: k.Private()}
+	}
+
+	data, err := formatGeneratedKeys(keys, c.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	result := NewExecutionResult()
+	result.SetField("addresses", addresses)
+
+	if c.Filename != nil {
+		// Never overwrite an existing file with secret material
+		out, err := os.OpenFile(*c.Filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = out.Write(data)
+		if err != nil {
+			out.Close()
+			return nil, err
+		}
+
+		err = out.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		result.AddMessage(fmt.Sprintf("WARNING: %s holds %d private keys in plaintext. Anyone who can read it can spend from these addresses, so use them for testing only", *c.Filename, count))
+		result.AddMessage(fmt.Sprintf("Wrote %d keys to %s", count, *c.Filename))
+		result.SetField("filename", *c.Filename)
+		return result, nil
+	}
+
+	result.AddMessage(fmt.Sprintf("WARNING: the %d private keys below are shown in plaintext. Anyone who sees them can spend from these addresses, so use them for testing only\n---", count))
+	result.AddMessage(strings.Split(strings.TrimRight(string(data), "\n"), "\n")...)
+	result.SetField("keys", keys)
+
+	return result, nil
+}
+
+// formatGeneratedKeys formats the keys as lines of text, CSV with a header row, or a JSON array
+func formatGeneratedKeys(keys []*GeneratedKey, format string) ([]byte, error) {
+	var buf bytes.Buffer
+
+	switch format {
+	case CSVKeyFormat:
+		w := csv.NewWriter(&buf)
+		rows := [][]string{{"address", "public_key", "private_key"}}
+		for _, k := range keys {
+			rows = append(rows, []string{k.Address, k.PublicKey, k.PrivateKey})
+		}
+
+		err := w.WriteAll(rows)
+		if err != nil {
+			return nil, err
+		}
+	case JSONKeyFormat:
+		data, err := json.MarshalIndent(keys, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(data)
+		buf.WriteString("\n")
+	default:
+		for i, k := range keys {
+			fmt.Fprintf(&buf, "%d: Address: %s Private: %s\n", i, k.Address, k.PrivateKey)
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// ----------------------------------------------------------------------------
+// Mnemonic Command
+// ----------------------------------------------------------------------------
+
+// MnemonicCommand is a command that generates a mnemonic phrase and its key
+type MnemonicCommand struct {
+	Words *string
+	Path  *string
+}
+
+// NewMnemonicCommand creates a new mnemonic object
+func NewMnemonicCommand(inv *CommandParseResult) Command {
+	return &MnemonicCommand{Words: inv.Args["words"], Path: inv.Args["path"]}
+}
+
+// Execute generates a mnemonic phrase
+func (c *MnemonicCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	words := 12
+	if c.Words != nil {
+		var err error
+		words, err = strconv.Atoi(*c.Words)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", cliutil.ErrInvalidParam, err)
+		}
+	}
+
+	path, err := derivationPath(c.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	mnemonic, err := cliutil.GenerateMnemonic(words)
+	if err != nil {
+		return nil, err
+	}
+
+	k, err := cliutil.KeyFromMnemonicPath(mnemonic, path)
+	if err != nil {
+		return nil, err
+	}
+
+	result := NewExecutionResult()
+	result.AddMessage("New mnemonic generated\nThis is only shown once, make sure to record this information\n---")
+	result.AddMessage(fmt.Sprintf("Mnemonic: %s", mnemonic))
+	result.AddMessage(fmt.Sprintf("Path    : %s", cliutil.FormatDerivationPath(path)))
+	result.AddMessage(fmt.Sprintf("Address : %s", base58.Encode(k.AddressBytes())))
+
+	return result, nil
+}
+
+// derivationPath parses an optional derivation path argument, defaulting to the standard Koinos path
+func derivationPath(path *string) ([]uint32, error) {
+	if path == nil {
+		return cliutil.KoinosDerivationPath, nil
+	}
+
+	return cliutil.ParseDerivationPath(*path)
+}
+
 // ----------------------------------------------------------------------------
 // Upload Contract Command
 // ----------------------------------------------------------------------------
@@ -295,7 +805,7 @@ func (c *UploadContractCommand) Execute(ctx context.Context, ee *ExecutionEnviro
 		return nil, fmt.Errorf("%w: cannot upload contract", cliutil.ErrWalletClosed)
 	}
 
-	if !ee.IsOnline() && !ee.Session.IsValid() {
+	if !ee.CanSubmit() {
 		return nil, fmt.Errorf("%w: cannot upload contract", cliutil.ErrOffline)
 	}
 
@@ -393,19 +903,46 @@ func (c *UploadContractCommand) Execute(ctx context.Context, ee *ExecutionEnviro
 	return result, nil
 }
 
+// PasswordFileOption is the option of open and create giving a file to read the wallet password from
+const PasswordFileOption = "password-file"
+
+// passwordArg returns the password given to a command, read from the file given with --password-file if there is
+// one, or nil if neither was given
+func passwordArg(password *string, passwordFile *string) (*string, error) {
+	if passwordFile == nil {
+		return password, nil
+	}
+
+	if password != nil {
+		return nil, fmt.Errorf("%w: give either a password or %s%s, not both", cliutil.ErrInvalidParam, OptionPrefix, PasswordFileOption)
+	}
+
+	pass, err := cliutil.ReadPasswordFile(*passwordFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if pass == "" {
+		return nil, fmt.Errorf("%w: password file %s is empty", cliutil.ErrBlankPassword, *passwordFile)
+	}
+
+	return &pass, nil
+}
+
 // ----------------------------------------------------------------------------
 // Create
 // ----------------------------------------------------------------------------
 
 // CreateCommand is a command that creates a new wallet
 type CreateCommand struct {
-	Filename string
-	Password *string
+	Filename     string
+	Password     *string
+	PasswordFile *string
 }
 
 // NewCreateCommand creates a new create object
 func NewCreateCommand(inv *CommandParseResult) Command {
-	return &CreateCommand{Filename: *inv.Args["filename"], Password: inv.Args["password"]}
+	return &CreateCommand{Filename: *inv.Args["filename"], Password: inv.Args["password"], PasswordFile: inv.Options[PasswordFileOption]}
 }
 
 // Execute creates a new wallet
@@ -422,26 +959,26 @@ func (c *CreateCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (
 		return nil, err
 	}
 
-	// Create the wallet file
-	file, err := os.Create(c.Filename)
+	password, err := passwordArg(c.Password, c.PasswordFile)
 	if err != nil {
 		return nil, err
 	}
 
-	// Get the password
-	pass, err := cliutil.GetPassword(c.Password)
+	// Get the password, confirming it if prompted
+	pass, err := cliutil.GetNewPassword(password)
 	if err != nil {
 		return nil, err
 	}
 
 	// Write the key to the wallet file
-	err = cliutil.CreateWalletFile(file, pass, key.PrivateBytes())
+	keys := []cliutil.WalletKey{{Label: cliutil.DefaultKeyLabel, PrivateKey: key.PrivateBytes()}}
+	err = ee.writeWalletFile(c.Filename, pass, keys)
 	if err != nil {
 		return nil, err
 	}
 
 	// Set the wallet keys
-	ee.Key = key
+	ee.openWalletFile(c.Filename, keys, key)
 
 	result := NewExecutionResult()
 	result.AddMessage(fmt.Sprintf("Created and opened new wallet: %s", c.Filename))
@@ -474,7 +1011,7 @@ func (c *ImportCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (
 	}
 
 	// Convert the private key to bytes
-	keyBytes, err := util.DecodeWIF(c.PrivateKey)
+	keyBytes, err := cliutil.DecodePrivateKey(c.PrivateKey)
 	if err != nil {
 		return nil, err
 	}
@@ -485,26 +1022,21 @@ func (c *ImportCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (
 		return nil, err
 	}
 
-	// Create the wallet file
-	file, err := os.Create(c.Filename)
-	if err != nil {
-		return nil, err
-	}
-
-	// Get the password
-	pass, err := cliutil.GetPassword(c.Password)
+	// Get the password, confirming it if prompted
+	pass, err := cliutil.GetNewPassword(c.Password)
 	if err != nil {
 		return nil, err
 	}
 
 	// Write the key to the wallet file
-	err = cliutil.CreateWalletFile(file, pass, key.PrivateBytes())
+	keys := []cliutil.WalletKey{{Label: cliutil.DefaultKeyLabel, PrivateKey: key.PrivateBytes()}}
+	err = ee.writeWalletFile(c.Filename, pass, keys)
 	if err != nil {
 		return nil, err
 	}
 
 	// Set the wallet keys
-	ee.Key = key
+	ee.openWalletFile(c.Filename, keys, key)
 
 	result := NewExecutionResult()
 	result.AddMessage(fmt.Sprintf("Created and opened new wallet: %s", c.Filename))
@@ -514,29 +1046,293 @@ func (c *ImportCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (
 }
 
 // ----------------------------------------------------------------------------
-// Address Command
+// Import Mnemonic
 // ----------------------------------------------------------------------------
 
-// AddressCommand is a command that shows the currently opened wallet's address and private key
-type AddressCommand struct {
+// ImportMnemonicCommand is a command that imports a key from a mnemonic phrase to a wallet
+type ImportMnemonicCommand struct {
+	Filename string
+	Password *string
+	Mnemonic string
+	Path     *string
 }
 
-// NewAddressCommand creates a new address command object
-func NewAddressCommand(inv *CommandParseResult) Command {
-	return &AddressCommand{}
+// NewImportMnemonicCommand creates a new import mnemonic object
+func NewImportMnemonicCommand(inv *CommandParseResult) Command {
+	return &ImportMnemonicCommand{Filename: *inv.Args["filename"], Password: inv.Args["password"], Mnemonic: *inv.Args["mnemonic"], Path: inv.Args["path"]}
 }
 
-// Execute shows wallet address
-func (c *AddressCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
-	if !ee.IsWalletOpen() {
-		return nil, fmt.Errorf("%w: cannot show address", cliutil.ErrWalletClosed)
+// Execute creates a new wallet from the mnemonic
+func (c *ImportMnemonicCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	// Check if the wallet already exists
+	if _, err := os.Stat(c.Filename); !os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s", cliutil.ErrWalletExists, c.Filename)
 	}
 
-	result := NewExecutionResult()
-	result.AddMessage(fmt.Sprintf("Wallet address: %s", base58.Encode(ee.Key.AddressBytes())))
+	// Derive the key from the mnemonic
+	path, err := derivationPath(c.Path)
+	if err != nil {
+		return nil, err
+	}
 
-	return result, nil
-}
+	key, err := cliutil.KeyFromMnemonicPath(c.Mnemonic, path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the password, confirming it if prompted
+	pass, err := cliutil.GetNewPassword(c.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	// Write the key to the wallet file
+	keys := []cliutil.WalletKey{{Label: cliutil.DefaultKeyLabel, PrivateKey: key.PrivateBytes(), DerivationPath: cliutil.FormatDerivationPath(path)}}
+	err = ee.writeWalletFile(c.Filename, pass, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set the wallet keys
+	ee.openWalletFile(c.Filename, keys, key)
+
+	result := NewExecutionResult()
+	result.AddMessage(fmt.Sprintf("Created and opened new wallet: %s", c.Filename))
+	result.AddMessage(fmt.Sprintf("Derivation path: %s", keys[0].DerivationPath))
+	result.AddMessage(fmt.Sprintf("Address: %s", base58.Encode(key.AddressBytes())))
+
+	return result, nil
+}
+
+// ----------------------------------------------------------------------------
+// Address Command
+// ----------------------------------------------------------------------------
+
+// AddressCommand is a command that shows the currently opened wallet's address and private key
+type AddressCommand struct {
+}
+
+// NewAddressCommand creates a new address command object
+func NewAddressCommand(inv *CommandParseResult) Command {
+	return &AddressCommand{}
+}
+
+// Execute shows wallet address
+func (c *AddressCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	if !ee.IsWalletOpen() {
+		return nil, fmt.Errorf("%w: cannot show address", cliutil.ErrWalletClosed)
+	}
+
+	result := NewExecutionResult()
+	result.AddMessage(fmt.Sprintf("Wallet address: %s", base58.Encode(ee.Key.AddressBytes())))
+	if k := ee.activeWalletKey(); k != nil && k.DerivationPath != "" {
+		result.AddMessage(fmt.Sprintf("Derivation path: %s", k.DerivationPath))
+		result.SetField("derivation_path", k.DerivationPath)
+	}
+
+	return result, nil
+}
+
+// ----------------------------------------------------------------------------
+// Whoami Command
+// ----------------------------------------------------------------------------
+
+// WhoamiCommand is a command that summarizes the state of the session
+type WhoamiCommand struct {
+}
+
+// NewWhoamiCommand creates a new whoami command object
+func NewWhoamiCommand(inv *CommandParseResult) Command {
+	return &WhoamiCommand{}
+}
+
+// Execute shows the wallet, endpoint, chain, network, and contracts in use. It never fails, so that it can be used
+// to find out what is missing
+func (c *WhoamiCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	result := NewExecutionResult()
+
+	result.SetField("wallet_open", ee.IsWalletOpen())
+	if ee.IsWalletOpen() {
+		address := base58.Encode(ee.Key.AddressBytes())
+		if ee.walletFile != "" {
+			result.AddMessage(fmt.Sprintf("Wallet: %s", ee.walletFile))
+			result.SetField("wallet_file", ee.walletFile)
+		}
+		if k := ee.activeWalletKey(); k != nil && len(ee.walletKeys) > 1 {
+			result.AddMessage(fmt.Sprintf("Key: %s", k.Label))
+			result.SetField("key", k.Label)
+		}
+		result.AddMessage(fmt.Sprintf("Address: %s", address))
+		result.SetField("address", address)
+	} else {
+		result.AddMessage("No wallet open")
+	}
+
+	if ee.IsOnline() {
+		result.AddMessage(fmt.Sprintf("RPC endpoint: %s", ee.RPCClient.URL()))
+		result.SetField("rpc", ee.RPCClient.URL())
+	} else {
+		result.AddMessage("Not connected to an RPC endpoint")
+	}
+
+	// An automatic chain id is only shown once it can be fetched
+	if !ee.IsChainIDAuto() || ee.IsOnline() {
+		chainID, err := ee.GetChainID(ctx)
+		if err != nil {
+			result.AddMessage(fmt.Sprintf("Chain ID: could not fetch, %s", err))
+		} else {
+			result.AddMessage(fmt.Sprintf("Chain ID: %s", base64.URLEncoding.EncodeToString(chainID)))
+			result.SetField("chain_id", base64.URLEncoding.EncodeToString(chainID))
+		}
+	} else {
+		result.AddMessage("Chain ID: fetched from the node when connected")
+	}
+
+	if ee.network != "" {
+		result.AddMessage(fmt.Sprintf("Network: %s", ee.network))
+		result.SetField("network", ee.network)
+	} else {
+		result.AddMessage("Network: none")
+	}
+
+	result.AddMessage(fmt.Sprintf("Registered contracts: %d", len(ee.Contracts)))
+	result.SetField("contracts", len(ee.Contracts))
+
+	return result, nil
+}
+
+// ----------------------------------------------------------------------------
+// Ping Command
+// ----------------------------------------------------------------------------
+
+// PingCommand is a command that checks that the RPC endpoint is reachable
+type PingCommand struct {
+}
+
+// NewPingCommand creates a new ping command object
+func NewPingCommand(inv *CommandParseResult) Command {
+	return &PingCommand{}
+}
+
+// Execute makes a lightweight request to the node and shows how long it took
+func (c *PingCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	if !ee.IsOnline() {
+		return nil, fmt.Errorf("%w: cannot ping", cliutil.ErrOffline)
+	}
+
+	elapsed, err := ee.RPCClient.Ping(ctx)
+	if errors.Is(err, cliutil.ErrUnreachable) {
+		return nil, err
+	}
+
+	latency := float64(elapsed.Microseconds()) / 1000
+
+	result := NewExecutionResult()
+	result.SetField("rpc", ee.RPCClient.URL())
+	result.SetField("reachable", true)
+	result.SetField("latency_ms", latency)
+
+	// An error response still shows that the node is reachable
+	if err != nil {
+		result.AddMessage(fmt.Sprintf("%s is reachable, and responded in %.1f ms with an error: %s", ee.RPCClient.URL(), latency, err))
+		result.SetField("error", err.Error())
+	} else {
+		result.AddMessage(fmt.Sprintf("%s is reachable, and responded in %.1f ms", ee.RPCClient.URL(), latency))
+	}
+
+	return result, nil
+}
+
+// ----------------------------------------------------------------------------
+// Head Command
+// ----------------------------------------------------------------------------
+
+// HeadCommand is a command that shows the head block of the connected node
+type HeadCommand struct {
+}
+
+// NewHeadCommand creates a new head command object
+func NewHeadCommand(inv *CommandParseResult) Command {
+	return &HeadCommand{}
+}
+
+// Execute shows the head block
+func (c *HeadCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	if !ee.IsOnline() {
+		return nil, fmt.Errorf("%w: cannot show head block", cliutil.ErrOffline)
+	}
+
+	headInfo, err := ee.RPCClient.GetHeadInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get head block, %w", err)
+	}
+
+	result := NewExecutionResult()
+	addHeadInfo(result, headInfo)
+
+	return result, nil
+}
+
+// addHeadInfo adds the head block and last irreversible block of a node to a result
+func addHeadInfo(result *ExecutionResult, headInfo *chain_rpc.GetHeadInfoResponse) {
+	height := headInfo.GetHeadTopology().GetHeight()
+	id := "0x" + hex.EncodeToString(headInfo.GetHeadTopology().GetId())
+
+	result.AddMessage(fmt.Sprintf("Head block height: %d", height))
+	result.AddMessage(fmt.Sprintf("Head block id: %s", id))
+	result.AddMessage(fmt.Sprintf("Last irreversible block: %d", headInfo.GetLastIrreversibleBlock()))
+	result.SetField("head_height", height)
+	result.SetField("head_id", id)
+	result.SetField("last_irreversible_block", headInfo.GetLastIrreversibleBlock())
+}
+
+// ----------------------------------------------------------------------------
+// Chain Info Command
+// ----------------------------------------------------------------------------
+
+// ChainInfoCommand is a command that shows which chain the connected node is on
+type ChainInfoCommand struct {
+}
+
+// NewChainInfoCommand creates a new chain info command object
+func NewChainInfoCommand(inv *CommandParseResult) Command {
+	return &ChainInfoCommand{}
+}
+
+// Execute shows the chain id and head block of the node. The node has no version call, so its head block identifies
+// how far it has synced instead
+func (c *ChainInfoCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	if !ee.IsOnline() {
+		return nil, fmt.Errorf("%w: cannot show chain info", cliutil.ErrOffline)
+	}
+
+	chainID, err := ee.RPCClient.GetChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get chain id, %w", err)
+	}
+
+	headInfo, err := ee.RPCClient.GetHeadInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get head block, %w", err)
+	}
+
+	result := NewExecutionResult()
+	result.AddMessage(fmt.Sprintf("RPC endpoint: %s", ee.RPCClient.URL()))
+	result.AddMessage(fmt.Sprintf("Chain ID: %s", base64.URLEncoding.EncodeToString(chainID)))
+	result.SetField("rpc", ee.RPCClient.URL())
+	result.SetField("chain_id", base64.URLEncoding.EncodeToString(chainID))
+
+	// Transactions are signed for the chain id set with chain_id, so they would be refused by this node
+	if !ee.IsChainIDAuto() {
+		if manualID, err := ee.GetChainID(ctx); err == nil && !bytes.Equal(manualID, chainID) {
+			result.AddMessage(fmt.Sprintf("Warning: the chain id set with chain_id is %s, which does not match the node", base64.URLEncoding.EncodeToString(manualID)))
+		}
+	}
+
+	addHeadInfo(result, headInfo)
+
+	return result, nil
+}
 
 // ----------------------------------------------------------------------------
 // Private Command
@@ -564,527 +1360,2021 @@ func (c *PrivateCommand) Execute(ctx context.Context, ee *ExecutionEnvironment)
 }
 
 // ----------------------------------------------------------------------------
-// Public Command
+// Export Key Command
 // ----------------------------------------------------------------------------
 
-// PublicCommand is a command that shows the currently opened wallet's public
-type PublicCommand struct {
+// ExportKeyCommand is a command that reveals the open wallet's private key once the wallet password is given again
+type ExportKeyCommand struct {
+	Filename *string
+	Password *string
 }
 
-// NewPublicCommand creates a new public command object
-func NewPublicCommand(inv *CommandParseResult) Command {
-	return &PublicCommand{}
+// NewExportKeyCommand creates a new export key command object
+func NewExportKeyCommand(inv *CommandParseResult) Command {
+	return &ExportKeyCommand{Filename: inv.Args["filename"], Password: inv.Args["password"]}
+}
+
+// Execute exports the wallet private key
+func (c *ExportKeyCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	if !ee.IsWalletOpen() || ee.walletFile == "" {
+		return nil, fmt.Errorf("%w: cannot export private key", cliutil.ErrWalletClosed)
+	}
+
+	// Require the password again, in case the wallet was opened earlier in the session
+	pass, err := cliutil.GetPassword(c.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(ee.walletFile)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, _, err := cliutil.ReadWalletKeysFile(file, pass)
+	file.Close()
+	if err != nil {
+		return nil, fmt.Errorf("%w: check your password", cliutil.ErrWalletDecrypt)
+	}
+
+	// Make sure the active key is one protected by this password
+	found := false
+	for _, k := range keys {
+		if bytes.Equal(k.PrivateKey, ee.Key.PrivateBytes()) {
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("%w: open key is not in %s", cliutil.ErrWalletDecrypt, ee.walletFile)
+	}
+
+	result := NewExecutionResult()
+
+	if c.Filename != nil {
+		// Never overwrite an existing file with secret material
+		out, err := os.OpenFile(*c.Filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = fmt.Fprintf(out, "%s\n", ee.Key.Private())
+		if err != nil {
+			out.Close()
+			return nil, err
+		}
+
+		err = out.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		result.AddMessage(fmt.Sprintf("Exported private key to: %s", *c.Filename))
+		return result, nil
+	}
+
+	result.AddMessage(fmt.Sprintf("Private key (WIF): %s", ee.Key.Private()))
+	result.AddMessage(fmt.Sprintf("Private key (hex): %s", hex.EncodeToString(ee.Key.PrivateBytes())))
+
+	return result, nil
+}
+
+// ----------------------------------------------------------------------------
+// Wallet KDF Command
+// ----------------------------------------------------------------------------
+
+// WalletKDFCommand is a command that sets the key derivation cost of new wallet files
+type WalletKDFCommand struct {
+	N *string
+	R string
+	P string
+}
+
+// NewWalletKDFCommand creates a new wallet kdf command object
+func NewWalletKDFCommand(inv *CommandParseResult) Command {
+	return &WalletKDFCommand{N: inv.Args["n"], R: *inv.Args["r"], P: *inv.Args["p"]}
+}
+
+// Execute sets or shows the key derivation cost
+func (c *WalletKDFCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	result := NewExecutionResult()
+
+	if c.N != nil {
+		params := cliutil.KDFParams{}
+		var err error
+		for _, v := range []struct {
+			value string
+			dest  *int
+		}{{*c.N, &params.N}, {c.R, &params.R}, {c.P, &params.P}} {
+			*v.dest, err = strconv.Atoi(v.value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", cliutil.ErrInvalidParam, err)
+			}
+		}
+
+		err = params.Validate()
+		if err != nil {
+			return nil, err
+		}
+
+		ee.walletKDF = &params
+	}
+
+	params := cliutil.DefaultKDFParams
+	if ee.walletKDF != nil {
+		params = *ee.walletKDF
+	}
+
+	result.AddMessage(fmt.Sprintf("New wallet files use %s", params))
+	result.SetField("n", params.N)
+	result.SetField("r", params.R)
+	result.SetField("p", params.P)
+
+	// Show what protects the open wallet, since only new files and password changes use the setting
+	if ee.IsWalletOpen() && ee.walletFile != "" {
+		current, err := cliutil.ReadWalletFileKDF(ee.walletFile)
+		if err != nil {
+			return nil, err
+		}
+
+		if current == nil {
+			result.AddMessage(fmt.Sprintf("Wallet %s uses the legacy sha256 key, change its password to upgrade it", ee.walletFile))
+		} else {
+			result.AddMessage(fmt.Sprintf("Wallet %s uses %s", ee.walletFile, current))
+		}
+	}
+
+	return result, nil
+}
+
+// ----------------------------------------------------------------------------
+// Encrypt File Command
+// ----------------------------------------------------------------------------
+
+// EncryptFileCommand is a command that encrypts a file with a password, as wallet files are
+type EncryptFileCommand struct {
+	Input    string
+	Output   string
+	Password *string
+}
+
+// NewEncryptFileCommand creates a new encrypt file command object
+func NewEncryptFileCommand(inv *CommandParseResult) Command {
+	return &EncryptFileCommand{Input: *inv.Args["input"], Output: *inv.Args["output"], Password: inv.Args["password"]}
+}
+
+// Execute encrypts the file
+func (c *EncryptFileCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	pass, err := cliutil.GetNewPassword(c.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cliutil.EncryptFile(c.Input, c.Output, pass)
+	if err != nil {
+		return nil, err
+	}
+
+	result := NewExecutionResult()
+	result.AddMessage(fmt.Sprintf("Encrypted %s to %s", c.Input, c.Output))
+	return result, nil
+}
+
+// ----------------------------------------------------------------------------
+// Decrypt File Command
+// ----------------------------------------------------------------------------
+
+// DecryptFileCommand is a command that decrypts a file made by encrypt_file
+type DecryptFileCommand struct {
+	Input    string
+	Output   string
+	Password *string
+}
+
+// NewDecryptFileCommand creates a new decrypt file command object
+func NewDecryptFileCommand(inv *CommandParseResult) Command {
+	return &DecryptFileCommand{Input: *inv.Args["input"], Output: *inv.Args["output"], Password: inv.Args["password"]}
+}
+
+// Execute decrypts the file
+func (c *DecryptFileCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	pass, err := cliutil.GetPassword(c.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cliutil.DecryptFile(c.Input, c.Output, pass)
+	if err != nil {
+		return nil, err
+	}
+
+	result := NewExecutionResult()
+	result.AddMessage(fmt.Sprintf("Decrypted %s to %s", c.Input, c.Output))
+	return result, nil
+}
+
+// ----------------------------------------------------------------------------
+// Public Command
+// ----------------------------------------------------------------------------
+
+// PublicCommand is a command that shows the currently opened wallet's public
+type PublicCommand struct {
+}
+
+// NewPublicCommand creates a new public command object
+func NewPublicCommand(inv *CommandParseResult) Command {
+	return &PublicCommand{}
+}
+
+// Execute shows wallet public key
+func (c *PublicCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	if !ee.IsWalletOpen() {
+		return nil, fmt.Errorf("%w: cannot show public key", cliutil.ErrWalletClosed)
+	}
+
+	result := NewExecutionResult()
+	result.AddMessage(fmt.Sprintf("Public key: %s", base64.URLEncoding.EncodeToString(ee.Key.PublicBytes())))
+
+	return result, nil
+}
+
+// ----------------------------------------------------------------------------
+// Help
+// ----------------------------------------------------------------------------
+
+// HelpCommand is a command that displays help for a given command
+type HelpCommand struct {
+	Command *string
+}
+
+// NewHelpCommand creates a new help command object
+func NewHelpCommand(inv *CommandParseResult) Command {
+	return &HelpCommand{Command: inv.Args["command"]}
+}
+
+// Execute displays help for a given command. A name that is not a command lists the commands starting with it
+func (c *HelpCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	prefix := ""
+	if c.Command != nil {
+		prefix = *c.Command
+	}
+
+	decl, ok := ee.Parser.Commands.Name2Command[prefix]
+	if !ok {
+		matches := ee.Parser.Commands.ListMatching(prefix)
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("%w: cannot show help for %s%s", cliutil.ErrUnknownCommand, prefix, didYouMean(ee.Parser.Commands.Suggest(prefix)))
+		}
+
+		result := NewExecutionResult()
+		if c.Command == nil {
+			result.AddMessage("Use help <command> for its arguments, or help <prefix> to list the commands starting with it")
+		}
+		result.AddMessage(matches...)
+
+		return result, nil
+	}
+
+	result := NewExecutionResult()
+	result.AddMessage(decl.Description)
+	result.AddMessage(fmt.Sprintf("Usage: %s", decl))
+
+	if len(decl.Options) > 0 {
+		result.AddMessage("Options:")
+		for _, option := range decl.Options {
+			result.AddMessage(fmt.Sprintf("  %s%s - %s: %s", OptionPrefix, option.Name, option.ArgType.String(), optionDescriptions[option.Name]))
+		}
+	}
+
+	if len(decl.Args) == 0 {
+		return result, nil
+	}
+
+	// Describe each of the arguments
+	longest := 0
+	for _, arg := range decl.Args {
+		if len(arg.Name) > longest {
+			longest = len(arg.Name)
+		}
+	}
+
+	result.AddMessage("Arguments:")
+	for _, arg := range decl.Args {
+		info := arg.ArgType.String()
+		if arg.Default != nil {
+			info += fmt.Sprintf(", optional, default %s", *arg.Default)
+		} else if arg.Optional {
+			info += ", optional"
+		}
+
+		line := fmt.Sprintf("  %*s - %s", -longest, arg.Name, info)
+		if desc := ee.Contracts.GetFieldDescription(decl.Name, arg.Name); desc != "" {
+			line += fmt.Sprintf(": %s", desc)
+		}
+
+		result.AddMessage(line)
+	}
+
+	return result, nil
+}
+
+// ----------------------------------------------------------------------------
+// Submit Transaction Command
+// ----------------------------------------------------------------------------
+
+// SubmitTransactionCommand is a command that submits a given transaction to the blockchain
+type SubmitTransactionCommand struct {
+	Transaction string
+}
+
+// NewSubmitTransactionCommand creates a new submit transaction command object
+func NewSubmitTransactionCommand(inv *CommandParseResult) Command {
+	return &SubmitTransactionCommand{Transaction: *inv.Args["transaction"]}
+}
+
+// Execute submits a transaction to the blockchain
+func (c *SubmitTransactionCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	result := NewExecutionResult()
+
+	if !ee.IsOnline() {
+		return nil, fmt.Errorf("%w: cannot submit transaction", cliutil.ErrOffline)
+	}
+
+	// Decode the transaction
+	data, err := base64.URLEncoding.DecodeString(c.Transaction)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction := &protocol.Transaction{}
+	err = proto.Unmarshal(data, transaction)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ee.confirmOperations(transaction.GetOperations())
+	if err != nil {
+		return nil, err
+	}
+
+	receipt, err := ee.RPCClient.SubmitTransaction(ctx, transaction, true)
+	if err != nil {
+		return result, err
+	}
+
+	result.AddMessage(cliutil.TransactionReceiptToString(receipt, len(transaction.GetOperations()), ee.koinPrecision))
+
+	entry := ee.recordTransaction(receipt, transaction.GetOperations())
+	err = ee.waitForTransaction(receipt, entry, result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// ----------------------------------------------------------------------------
+// Resubmit Command
+// ----------------------------------------------------------------------------
+
+// ResubmitCommand is a command that sends the last submitted transaction again
+type ResubmitCommand struct {
+}
+
+// NewResubmitCommand creates a new resubmit command object
+func NewResubmitCommand(inv *CommandParseResult) Command {
+	return &ResubmitCommand{}
+}
+
+// Execute sends the last submitted transaction again, unchanged, so it cannot be applied twice
+func (c *ResubmitCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	result := NewExecutionResult()
+
+	if !ee.IsOnline() {
+		return nil, fmt.Errorf("%w: cannot resubmit transaction", cliutil.ErrOffline)
+	}
+
+	transaction := ee.lastTransaction
+	if transaction == nil {
+		return nil, errors.New("no transaction has been submitted in this session")
+	}
+
+	result.AddMessage(fmt.Sprintf("Resubmitting transaction 0x%s", hex.EncodeToString(transaction.Id)))
+
+	receipt, err := ee.RPCClient.SubmitTransaction(ctx, transaction, true)
+	var rpcErr cliutil.KoinosRPCError
+	if err == nil || errors.As(err, &rpcErr) {
+		ee.forgetTransaction(transaction)
+	}
+	if err != nil {
+		return result, err
+	}
+
+	result.AddMessage(cliutil.TransactionReceiptToString(receipt, len(transaction.GetOperations()), ee.koinPrecision))
+	result.SetField("transaction_id", "0x"+hex.EncodeToString(receipt.Id))
+	result.SetField("reverted", receipt.Reverted)
+
+	entry := ee.recordTransaction(receipt, transaction.GetOperations())
+	err = ee.waitForTransaction(receipt, entry, result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// ----------------------------------------------------------------------------
+// Broadcast Command
+// ----------------------------------------------------------------------------
+
+// BroadcastCommand is a command that submits a signed transaction stored in a file
+type BroadcastCommand struct {
+	Filename string
+}
+
+// NewBroadcastCommand creates a new broadcast command object
+func NewBroadcastCommand(inv *CommandParseResult) Command {
+	return &BroadcastCommand{Filename: *inv.Args["filename"]}
+}
+
+// Execute submits the transaction in the file to the blockchain
+func (c *BroadcastCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	data, err := ioutil.ReadFile(c.Filename)
+	if err != nil {
+		return nil, err
+	}
+
+	submit := &SubmitTransactionCommand{Transaction: strings.TrimSpace(string(data))}
+	return submit.Execute(ctx, ee)
+}
+
+// ----------------------------------------------------------------------------
+// History Command
+// ----------------------------------------------------------------------------
+
+// HistoryCommand is a command that lists the transactions submitted during this session
+type HistoryCommand struct {
+}
+
+// NewHistoryCommand creates a new history command object
+func NewHistoryCommand(inv *CommandParseResult) Command {
+	return &HistoryCommand{}
+}
+
+// Execute lists the submitted transactions, oldest first
+func (c *HistoryCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	result := NewExecutionResult()
+	result.SetField("transactions", ee.history)
+
+	if len(ee.history) == 0 {
+		result.AddMessage("No transactions have been submitted")
+		return result, nil
+	}
+
+	for i, entry := range ee.history {
+		status := entry.Status
+		if entry.BlockID != "" {
+			status += " in block " + entry.BlockID
+		}
+		if entry.Reverted {
+			status += ", reverted"
+		}
+
+		result.AddMessage(fmt.Sprintf("%d: %s %s (%s)", i, entry.Time.Format(timestampFormat), entry.ID, status))
+		for _, op := range entry.Operations {
+			result.AddMessage(fmt.Sprintf("   %s", op))
+		}
+	}
+
+	return result, nil
+}
+
+// ----------------------------------------------------------------------------
+// Receipt Command
+// ----------------------------------------------------------------------------
+
+// ReceiptCommand is a command that looks up the receipt of a transaction
+type ReceiptCommand struct {
+	TransactionID string
+}
+
+// NewReceiptCommand creates a new receipt command object
+func NewReceiptCommand(inv *CommandParseResult) Command {
+	return &ReceiptCommand{TransactionID: *inv.Args["transaction-id"]}
+}
+
+// Execute fetches the receipt from the node and shows it, with events decoded using the registered contracts
+func (c *ReceiptCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	if !ee.IsOnline() {
+		return nil, fmt.Errorf("%w: cannot look up receipt", cliutil.ErrOffline)
+	}
+
+	id, err := hex.DecodeString(strings.TrimPrefix(c.TransactionID, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", cliutil.ErrInvalidParam, err)
+	}
+
+	receipt, blockID, err := ee.RPCClient.GetTransactionReceipt(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	result := NewExecutionResult()
+	result.SetField("transaction_id", c.TransactionID)
+
+	// The transaction store only knows about included transactions
+	if receipt == nil {
+		result.AddMessage(fmt.Sprintf("Transaction %s was not found in a block. It may still be pending, or may never have been submitted", c.TransactionID))
+		result.SetField("status", TransactionNotFound)
+		return result, nil
+	}
+
+	status := TransactionIncluded
+	if receipt.GetReverted() {
+		status = TransactionReverted
+	}
+
+	manaDec, err := util.SatoshiToDecimal(receipt.GetRcUsed(), ee.koinPrecision)
+	if err != nil {
+		return nil, err
+	}
+
+	result.AddMessage(fmt.Sprintf("Transaction %s %s in block 0x%s", c.TransactionID, status, hex.EncodeToString(blockID)))
+	result.AddMessage(fmt.Sprintf("Mana cost: %v (Disk: %d, Network: %d, Compute: %d)", manaDec, receipt.GetDiskStorageUsed(), receipt.GetNetworkBandwidthUsed(), receipt.GetComputeBandwidthUsed()))
+	if len(receipt.GetLogs()) > 0 {
+		result.AddMessage("Logs:")
+		result.AddMessage(receipt.GetLogs()...)
+	}
+
+	result.SetField("status", status)
+	result.SetField("block_id", "0x"+hex.EncodeToString(blockID))
+	result.SetField("reverted", receipt.GetReverted())
+	result.SetField("mana_used", manaDec.String())
+	ee.addEvents(receipt.GetEvents(), result)
+
+	return result, nil
+}
+
+// ----------------------------------------------------------------------------
+// Decode Transaction Command
+// ----------------------------------------------------------------------------
+
+// DecodeTransactionCommand is a command that shows the contents of a serialized transaction without submitting it
+type DecodeTransactionCommand struct {
+	Transaction string
+}
+
+// NewDecodeTransactionCommand creates a new decode transaction command object
+func NewDecodeTransactionCommand(inv *CommandParseResult) Command {
+	return &DecodeTransactionCommand{Transaction: *inv.Args["transaction"]}
+}
+
+// Execute decodes the transaction and describes its header and operations
+func (c *DecodeTransactionCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	transaction, err := decodeTransaction(c.Transaction)
+	if err != nil {
+		return nil, err
+	}
+
+	result := NewExecutionResult()
+	header := transaction.GetHeader()
+
+	result.AddMessage(fmt.Sprintf("Transaction ID: 0x%s", hex.EncodeToString(transaction.GetId())))
+	result.AddMessage(fmt.Sprintf("Chain ID: %s", base64.URLEncoding.EncodeToString(header.GetChainId())))
+	result.AddMessage(fmt.Sprintf("Payer: %s", base58.Encode(header.GetPayer())))
+	if len(header.GetPayee()) > 0 {
+		result.AddMessage(fmt.Sprintf("Payee: %s", base58.Encode(header.GetPayee())))
+	}
+
+	nonce := &chain.ValueType{}
+	if err := proto.Unmarshal(header.GetNonce(), nonce); err == nil {
+		result.AddMessage(fmt.Sprintf("Nonce: %d", nonce.GetUint64Value()))
+	} else {
+		result.AddMessage(fmt.Sprintf("Nonce: 0x%s", hex.EncodeToString(header.GetNonce())))
+	}
+
+	manaDec, err := util.SatoshiToDecimal(header.GetRcLimit(), ee.koinPrecision)
+	if err != nil {
+		return nil, err
+	}
+	result.AddMessage(fmt.Sprintf("Mana limit: %v", manaDec))
+	result.AddMessage(fmt.Sprintf("Signatures: %d", len(transaction.GetSignatures())))
+
+	result.AddMessage(fmt.Sprintf("Operations (%d):", len(transaction.GetOperations()), ee.koinPrecision))
+	for i, op := range transaction.GetOperations() {
+		result.AddMessage(fmt.Sprintf("%d: %s", i, describeOperation(op, ee.Contracts)))
+	}
+
+	jsonBytes, err := kjson.Marshal(transaction)
+	if err != nil {
+		return nil, err
+	}
+
+	result.SetField("transaction_id", "0x"+hex.EncodeToString(transaction.GetId()))
+	result.SetField("transaction", json.RawMessage(jsonBytes))
+
+	return result, nil
+}
+
+// decodeTransaction deserializes a transaction given as hex (with or without 0x) or base64
+func decodeTransaction(data string) (*protocol.Transaction, error) {
+	data = strings.TrimSpace(data)
+
+	candidates := make([][]byte, 0, 3)
+	if b, err := hex.DecodeString(strings.TrimPrefix(data, "0x")); err == nil {
+		candidates = append(candidates, b)
+	}
+	if b, err := base64.URLEncoding.DecodeString(data); err == nil {
+		candidates = append(candidates, b)
+	}
+	if b, err := base64.StdEncoding.DecodeString(data); err == nil {
+		candidates = append(candidates, b)
+	}
+
+	for _, b := range candidates {
+		transaction := &protocol.Transaction{}
+		if err := proto.Unmarshal(b, transaction); err == nil && transaction.GetHeader() != nil {
+			return transaction, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: transaction must be the hex or base64 encoding of a serialized transaction", cliutil.ErrInvalidParam)
+}
+
+// describeOperation returns a readable description of an operation. Calls to registered contracts are decoded using their ABI,
+// calls to any other contract show the raw entry point and arguments
+func describeOperation(op *protocol.Operation, contracts Contracts) string {
+	switch {
+	case op.GetCallContract() != nil:
+		call := op.GetCallContract()
+		address := base58.Encode(call.GetContractId())
+
+		if methodName := contracts.GetMethodByEntryPoint(address, call.GetEntryPoint()); methodName != "" {
+			if args, err := decodeCallArguments(methodName, call.GetArgs(), contracts); err == nil {
+				return fmt.Sprintf("Call %s %s", methodName, args)
+			}
+		}
+
+		return fmt.Sprintf("Call contract %s entry point 0x%08x with arguments 0x%s", address, call.GetEntryPoint(), hex.EncodeToString(call.GetArgs()))
+	case op.GetUploadContract() != nil:
+		upload := op.GetUploadContract()
+		return fmt.Sprintf("Upload contract %s (%d bytes of bytecode)", base58.Encode(upload.GetContractId()), len(upload.GetBytecode()))
+	case op.GetSetSystemCall() != nil:
+		call := op.GetSetSystemCall()
+		return fmt.Sprintf("Set system call %d to %v", call.GetCallId(), call.GetTarget())
+	case op.GetSetSystemContract() != nil:
+		sc := op.GetSetSystemContract()
+		return fmt.Sprintf("Set system contract %s to %t", base58.Encode(sc.GetContractId()), sc.GetSystemContract())
+	}
+
+	return "Unknown operation"
+}
+
+// decodeCallArguments decodes the arguments of a call to a registered contract method as JSON
+func decodeCallArguments(methodName string, args []byte, contracts Contracts) (string, error) {
+	md, err := contracts.GetMethodArguments(methodName)
+	if err != nil {
+		return "", err
+	}
+
+	msg := dynamicpb.NewMessage(md)
+	err = proto.Unmarshal(args, msg)
+	if err != nil {
+		return "", err
+	}
+
+	b, err := kjson.Marshal(msg)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// ----------------------------------------------------------------------------
+// Call Command
+// ----------------------------------------------------------------------------
+
+// CallCommand is a command that calls a contract method
+type CallCommand struct {
+	ContractID string
+	EntryPoint string
+	Arguments  string
+}
+
+// NewCallCommand calls a contract method
+func NewCallCommand(inv *CommandParseResult) Command {
+	return &CallCommand{
+		ContractID: *inv.Args["contract-id"],
+		EntryPoint: *inv.Args["entry-point"],
+		Arguments:  *inv.Args["arguments"],
+	}
+}
+
+// Execute a contract call
+func (c *CallCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	if !ee.IsWalletOpen() {
+		return nil, fmt.Errorf("%w: cannot call contract", cliutil.ErrWalletClosed)
+	}
+
+	if !ee.CanSubmit() {
+		return nil, fmt.Errorf("%w: cannot call", cliutil.ErrOffline)
+	}
+
+	entryPoint, err := cliutil.ParseEntryPoint(c.EntryPoint)
+	if err != nil {
+		return nil, err
+	}
+
+	contractID := base58.Decode(c.ContractID)
+	if len(contractID) == 0 {
+		return nil, errors.New("could not parse contract id")
+	}
+
+	// Get the argument bytes, given as 0x prefixed hex or base64
+	var argumentBytes []byte
+	if strings.HasPrefix(c.Arguments, "0x") {
+		argumentBytes, err = hex.DecodeString(c.Arguments[2:])
+	} else {
+		argumentBytes, err = base64.StdEncoding.DecodeString(c.Arguments)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	op := &protocol.Operation{
+		Op: &protocol.Operation_CallContract{
+			CallContract: &protocol.CallContractOperation{
+				ContractId: contractID,
+				EntryPoint: entryPoint,
+				Args:       argumentBytes,
+			},
+		},
+	}
+
+	result := NewExecutionResult()
+	result.AddMessage(fmt.Sprintf("Calling contract %s at entry point: %s with arguments %s", c.ContractID, c.EntryPoint, c.Arguments))
+
+	err = ee.Session.AddOperation(op, fmt.Sprintf("Call contract %s at entry point: %s with arguments %s", c.ContractID, c.EntryPoint, c.Arguments))
+	if err == nil {
+		result.AddMessage("Adding operation to transaction session")
+	}
+	if err != nil {
+		err := ee.SubmitTransaction(ctx, result, op)
+		if err != nil {
+			return result, fmt.Errorf("cannot call contract, %w", err)
+		}
+	}
+
+	return result, nil
+}
+
+// ----------------------------------------------------------------------------
+// Open
+// ----------------------------------------------------------------------------
+
+// OpenCommand is a command that opens a wallet file
+type OpenCommand struct {
+	Filename     string
+	Password     *string
+	PasswordFile *string
+}
+
+// NewOpenCommand creates a new open command object
+func NewOpenCommand(inv *CommandParseResult) Command {
+	return &OpenCommand{Filename: *inv.Args["filename"], Password: inv.Args["password"], PasswordFile: inv.Options[PasswordFileOption]}
+}
+
+// Execute opens a wallet
+func (c *OpenCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	// Open the wallet file
+	file, err := os.Open(c.Filename)
+	if err != nil {
+		return nil, err
+	}
+
+	password, err := passwordArg(c.Password, c.PasswordFile)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	// Get the password
+	pass, err := cliutil.GetPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the wallet file
+	keys, legacy, err := cliutil.ReadWalletKeysFile(file, pass)
+	file.Close()
+	if errors.Is(err, cliutil.ErrWalletVersion) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: check your password", cliutil.ErrWalletDecrypt)
+	}
+
+	// Create the key object, the first key is active when opening
+	key, err := util.NewKoinosKeyFromBytes(keys[0].PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	result := NewExecutionResult()
+
+	// Migrate single key and older version wallet files to the labeled key format of the current version
+	if legacy {
+		err = cliutil.WriteWalletKeysFile(c.Filename, pass, keys)
+		if err != nil {
+			return nil, fmt.Errorf("could not upgrade wallet file, %w", err)
+		}
+		result.AddMessage(fmt.Sprintf("Upgraded wallet file to version %d, which supports multiple keys", cliutil.CurrentWalletFileVersion))
+	}
+
+	// Open the wallet
+	label := ee.openWalletFile(c.Filename, keys, key)
+
+	result.AddMessage(fmt.Sprintf("Opened wallet: %s", c.Filename))
+	if len(ee.wallets) > 1 {
+		result.AddMessage(fmt.Sprintf("Using wallet '%s', %d wallets open", label, len(ee.wallets)))
+	}
+	if len(keys) > 1 {
+		result.AddMessage(fmt.Sprintf("Using key '%s', %d keys available", keys[0].Label, len(keys)))
+	}
+
+	return result, nil
+}
+
+// ----------------------------------------------------------------------------
+// Add Key Command
+// ----------------------------------------------------------------------------
+
+// AddKeyCommand is a command that adds a new key to the open wallet file
+type AddKeyCommand struct {
+	Label    string
+	Password *string
+}
+
+// NewAddKeyCommand creates a new add key command object
+func NewAddKeyCommand(inv *CommandParseResult) Command {
+	return &AddKeyCommand{Label: *inv.Args["label"], Password: inv.Args["password"]}
+}
+
+// Execute adds a key to the wallet
+func (c *AddKeyCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	if !ee.IsWalletOpen() || ee.walletFile == "" {
+		return nil, fmt.Errorf("%w: cannot add key", cliutil.ErrWalletClosed)
+	}
+
+	for _, k := range ee.walletKeys {
+		if k.Label == c.Label {
+			return nil, fmt.Errorf("%w: key with label %s already exists", cliutil.ErrInvalidParam, c.Label)
+		}
+	}
+
+	// Get the password
+	pass, err := cliutil.GetPassword(c.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the keys back from the file to verify the password
+	file, err := os.Open(ee.walletFile)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, _, err := cliutil.ReadWalletKeysFile(file, pass)
+	file.Close()
+	if err != nil {
+		return nil, fmt.Errorf("%w: check your password", cliutil.ErrWalletDecrypt)
+	}
+
+	key, err := util.GenerateKoinosKey()
+	if err != nil {
+		return nil, err
+	}
+
+	keys = append(keys, cliutil.WalletKey{Label: c.Label, PrivateKey: key.PrivateBytes()})
+	err = cliutil.WriteWalletKeysFile(ee.walletFile, pass, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	ee.setWalletKeys(ee.walletFile, keys)
+
+	result := NewExecutionResult()
+	result.AddMessage(fmt.Sprintf("Added key '%s' to wallet: %s", c.Label, ee.walletFile))
+	result.AddMessage(fmt.Sprintf("Address: %s", base58.Encode(key.AddressBytes())))
+
+	return result, nil
+}
+
+// ----------------------------------------------------------------------------
+// Change Password Command
+// ----------------------------------------------------------------------------
+
+// ChangePasswordCommand is a command that re-encrypts the open wallet file with a new password
+type ChangePasswordCommand struct {
+	OldPassword *string
+	NewPassword *string
+}
+
+// NewChangePasswordCommand creates a new change password command object
+func NewChangePasswordCommand(inv *CommandParseResult) Command {
+	return &ChangePasswordCommand{OldPassword: inv.Args["old-password"], NewPassword: inv.Args["new-password"]}
+}
+
+// Execute changes the wallet password
+func (c *ChangePasswordCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	if !ee.IsWalletOpen() || ee.walletFile == "" {
+		return nil, fmt.Errorf("%w: cannot change password", cliutil.ErrWalletClosed)
+	}
+
+	// Get the current password
+	oldPass, err := cliutil.GetPassword(c.OldPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify the current password by decrypting the wallet file
+	file, err := os.Open(ee.walletFile)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, _, err := cliutil.ReadWalletKeysFile(file, oldPass)
+	file.Close()
+	if err != nil {
+		return nil, fmt.Errorf("%w: check your password", cliutil.ErrWalletDecrypt)
+	}
+
+	// Get the new password, always prompting rather than using WALLET_PASS
+	var newPass string
+	if c.NewPassword != nil {
+		newPass = *c.NewPassword
+	} else {
+		newPass, err = cliutil.ReadPassword("New password: ")
+		if err != nil {
+			return nil, err
+		}
+
+		again, err := cliutil.ReadPassword("Confirm new password: ")
+		if err != nil {
+			return nil, err
+		}
+
+		if again != newPass {
+			return nil, cliutil.ErrPasswordMismatch
+		}
+	}
+
+	if newPass == "" {
+		return nil, fmt.Errorf("%w: password cannot be empty", cliutil.ErrBlankPassword)
+	}
+
+	err = ee.writeWalletFile(ee.walletFile, newPass, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	result := NewExecutionResult()
+	result.AddMessage(fmt.Sprintf("Changed password of wallet: %s", ee.walletFile))
+
+	return result, nil
+}
+
+// ----------------------------------------------------------------------------
+// Use Key Command
+// ----------------------------------------------------------------------------
+
+// UseKeyCommand is a command that switches the active key of the open wallet
+type UseKeyCommand struct {
+	Label string
+}
+
+// NewUseKeyCommand creates a new use key command object
+func NewUseKeyCommand(inv *CommandParseResult) Command {
+	return &UseKeyCommand{Label: *inv.Args["label"]}
+}
+
+// Execute switches the active key
+func (c *UseKeyCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	if !ee.IsWalletOpen() {
+		return nil, fmt.Errorf("%w: cannot switch key", cliutil.ErrWalletClosed)
+	}
+
+	for _, k := range ee.walletKeys {
+		if k.Label != c.Label {
+			continue
+		}
+
+		key, err := util.NewKoinosKeyFromBytes(k.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+
+		ee.OpenWallet(key)
+
+		result := NewExecutionResult()
+		result.AddMessage(fmt.Sprintf("Using key '%s'", c.Label))
+		result.AddMessage(fmt.Sprintf("Address: %s", base58.Encode(key.AddressBytes())))
+
+		return result, nil
+	}
+
+	return nil, fmt.Errorf("%w: no key with label %s", cliutil.ErrInvalidParam, c.Label)
+}
+
+// ----------------------------------------------------------------------------
+// List Keys Command
+// ----------------------------------------------------------------------------
+
+// ListKeysCommand is a command that lists the keys of the open wallet
+type ListKeysCommand struct {
+}
+
+// NewListKeysCommand creates a new list keys command object
+func NewListKeysCommand(inv *CommandParseResult) Command {
+	return &ListKeysCommand{}
+}
+
+// Execute lists the keys in the wallet
+func (c *ListKeysCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	if !ee.IsWalletOpen() {
+		return nil, fmt.Errorf("%w: cannot list keys", cliutil.ErrWalletClosed)
+	}
+
+	table := cliutil.NewTable("", "LABEL", "ADDRESS", "PATH")
+	for _, k := range ee.walletKeys {
+		key, err := util.NewKoinosKeyFromBytes(k.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+
+		// Mark the active key
+		marker := " "
+		if bytes.Equal(key.PrivateBytes(), ee.Key.PrivateBytes()) {
+			marker = "*"
+		}
+
+		table.AddRow(marker, k.Label, base58.Encode(key.AddressBytes()), k.DerivationPath)
+	}
+
+	result := NewExecutionResult()
+	result.AddTable(table, ee.color)
+
+	return result, nil
+}
+
+// ----------------------------------------------------------------------------
+// Use Wallet Command
+// ----------------------------------------------------------------------------
+
+// UseWalletCommand is a command that switches the active wallet between the open wallet files
+type UseWalletCommand struct {
+	Label string
+}
+
+// NewUseWalletCommand creates a new use wallet command object
+func NewUseWalletCommand(inv *CommandParseResult) Command {
+	return &UseWalletCommand{Label: *inv.Args["label"]}
+}
+
+// Execute switches the active wallet
+func (c *UseWalletCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	if !ee.IsWalletOpen() {
+		return nil, fmt.Errorf("%w: cannot switch wallet", cliutil.ErrWalletClosed)
+	}
+
+	if !ee.useWallet(c.Label) {
+		return nil, fmt.Errorf("%w: no open wallet with label %s", cliutil.ErrInvalidParam, c.Label)
+	}
+
+	result := NewExecutionResult()
+	result.AddMessage(fmt.Sprintf("Using wallet '%s': %s", c.Label, ee.walletFile))
+	result.AddMessage(fmt.Sprintf("Address: %s", base58.Encode(ee.Key.AddressBytes())))
+
+	return result, nil
+}
+
+// ----------------------------------------------------------------------------
+// List Wallets Command
+// ----------------------------------------------------------------------------
+
+// ListWalletsCommand is a command that lists the open wallet files
+type ListWalletsCommand struct {
+}
+
+// NewListWalletsCommand creates a new list wallets command object
+func NewListWalletsCommand(inv *CommandParseResult) Command {
+	return &ListWalletsCommand{}
+}
+
+// Execute lists the open wallets in order of label
+func (c *ListWalletsCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	if !ee.IsWalletOpen() {
+		return nil, fmt.Errorf("%w: cannot list wallets", cliutil.ErrWalletClosed)
+	}
+
+	labels := make([]string, 0, len(ee.wallets))
+	for label := range ee.wallets {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	table := cliutil.NewTable("", "LABEL", "ADDRESS", "FILE")
+	for _, label := range labels {
+		w := ee.wallets[label]
+
+		// Mark the active wallet
+		marker := " "
+		if label == ee.walletLabel {
+			marker = "*"
+		}
+
+		table.AddRow(marker, label, base58.Encode(w.key.AddressBytes()), w.file)
+	}
+
+	result := NewExecutionResult()
+	result.AddTable(table, ee.color)
+
+	return result, nil
+}
+
+// ----------------------------------------------------------------------------
+// Payer Command
+// ----------------------------------------------------------------------------
+
+// PayerCommand is a command shows or sets the current payer
+type PayerCommand struct {
+	Payer  *string
+	Signed string // Whether the payer signs transactions, or authorizes them on its own
+}
+
+// NewPayerCommand creates a new payer command object
+func NewPayerCommand(inv *CommandParseResult) Command {
+	payerString := inv.Args["payer"]
+	return &PayerCommand{Payer: payerString, Signed: *inv.Args["signed"]}
+}
+
+// Execute shows wallet address
+func (c *PayerCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	result := NewExecutionResult()
+
+	// If the payer string is null, then we are showing the current payer
+	if c.Payer == nil {
+		if ee.IsSelfPaying() {
+			if ee.IsWalletOpen() {
+				result.AddMessage(fmt.Sprintf("Payer: me (%s)", base58.Encode(ee.GetPayerAddress())))
+			} else {
+				result.AddMessage("Payer: me")
+			}
+		} else {
+			result.AddMessage(fmt.Sprintf("Payer: %s", base58.Encode(ee.GetPayerAddress())))
+			result.AddMessage(payerSignatureMessage(ee))
+		}
+
+		return result, nil
+	}
+
+	// Otherwise, we are setting the payer
+	signed, err := strconv.ParseBool(c.Signed)
+	if err != nil {
+		return nil, fmt.Errorf("%w: signed must be true or false, %s", cliutil.ErrInvalidParam, err)
+	}
+
+	ee.SetPayer(*c.Payer)
+	ee.SetPayerSigns(signed)
+
+	if !ee.IsSelfPaying() {
+		result.AddMessage(fmt.Sprintf("Payer set to %s", ee.payer))
+		result.AddMessage(payerSignatureMessage(ee))
+	}
+
+	return result, nil
+}
+
+// payerSignatureMessage describes how the payer authorizes transactions, warning if it must sign but its key is not open
+func payerSignatureMessage(ee *ExecutionEnvironment) string {
+	if !ee.payerSigns {
+		return "The payer authorizes transactions without a signature"
+	}
+
+	if ee.IsWalletOpen() && ee.payerKey() != nil {
+		return "The payer signs transactions with its key from an open wallet"
+	}
+
+	return "Warning: the payer must sign transactions, but no open wallet holds its key"
+}
+
+// ----------------------------------------------------------------------------
+// Nonce Command
+// ----------------------------------------------------------------------------
+
+// NonceCommand is a command that shows or sets the current nonce
+type NonceCommand struct {
+	Nonce *string
+}
+
+// NewNonceCommand creates a new nonce command object
+func NewNonceCommand(inv *CommandParseResult) Command {
+	nonceString := inv.Args["nonce"]
+	return &NonceCommand{Nonce: nonceString}
+}
+
+// Execute shows or sets the current nonce
+func (c *NonceCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	result := NewExecutionResult()
+
+	// If the nonce string is null, then we are showing the current nonce
+	if c.Nonce == nil {
+		if ee.IsNonceAuto() {
+			if ee.IsOnline() && ee.IsWalletOpen() {
+				nonce, err := ee.GetNextNonce(ctx, false)
+				if err != nil {
+					return nil, err
+				}
+				result.AddMessage(fmt.Sprintf("Nonce: auto (next nonce: %d)", nonce))
+			} else {
+				result.AddMessage("Nonce: auto")
+			}
+		} else {
+			n, err := ee.GetNextNonce(ctx, false)
+			if err != nil {
+				return nil, err
+			}
+			result.AddMessage(fmt.Sprintf("Nonce: %d", n))
+		}
+
+		return result, nil
+	}
+
+	// Otherwise, we are setting the nonce
+
+	// If it's auto just set that
+	if *c.Nonce == AutoNonce {
+		ee.nonceMode = AutoNonce
+		return result, nil
+	}
+
+	// Otherwise, parse the nonce to make sure it is correct
+	_, err := strconv.ParseUint(*c.Nonce, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: nonce must either be an integer number or \"auto\"", cliutil.ErrInvalidParam)
+	}
+
+	ee.nonceMode = *c.Nonce
+
+	return result, nil
+}
+
+// ----------------------------------------------------------------------------
+// ChainID Command
+// ----------------------------------------------------------------------------
+
+// ChainIDCommand is a command that shows or sets the current chain ID
+type ChainIDCommand struct {
+	ID *string
+}
+
+// NewChainIDCommand creates a new chain ID command object
+func NewChainIDCommand(inv *CommandParseResult) Command {
+	nonceString := inv.Args["id"]
+	return &ChainIDCommand{ID: nonceString}
+}
+
+// Execute shows or sets the current chain ID
+func (c *ChainIDCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	result := NewExecutionResult()
+
+	// If the id string is null, then we are showing the current chain id
+	if c.ID == nil {
+		if ee.IsChainIDAuto() && ee.IsOnline() {
+			chainID, err := ee.GetChainID(ctx)
+			if err != nil {
+				return nil, err
+			}
+			result.AddMessage(fmt.Sprintf("Chain ID: auto (%s)", base64.URLEncoding.EncodeToString(chainID)))
+		} else {
+			result.AddMessage(fmt.Sprintf("Chain ID: %s", ee.chainID))
+		}
+		return result, nil
+	}
+
+	// Otherwise, we are setting the chain id
+
+	err := ee.SetChainID(*c.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// ----------------------------------------------------------------------------
+// RcLimit Command
+// ----------------------------------------------------------------------------
+
+// RcLimitCommand is a command that sets or checks your cuttent rc limit
+type RcLimitCommand struct {
+	limit *string
+}
+
+// NewRcLimitCommand creates a new rc limit command object
+func NewRcLimitCommand(inv *CommandParseResult) Command {
+	return &RcLimitCommand{limit: inv.Args["limit"]}
+}
+
+// Execute handles the rc limit command
+func (c *RcLimitCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	result := NewExecutionResult()
+	// If no limit given, display current
+	if c.limit == nil {
+		if ee.rcLimit.absolute || !ee.IsOnline() || !ee.IsWalletOpen() {
+			display, err := ee.rcLimitString(ee.rcLimit)
+			if err != nil {
+				return nil, err
+			}
+			result.AddMessage(fmt.Sprintf("Current rc limit: %s", display))
+			return result, nil
+		}
+
+		amount, err := ee.GetRcLimit(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		decAmount, err := util.SatoshiToDecimal(amount, ee.koinPrecision)
+		if err != nil {
+			return nil, err
+		}
+
+		display, err := ee.rcLimitString(ee.rcLimit)
+		if err != nil {
+			return nil, err
+		}
+
+		result.AddMessage(fmt.Sprintf("Current rc limit: %s (%v)", display, decAmount))
+		return result, nil
+	}
+
+	// Otherwise we are setting the limit
+	limit, display, err := parseRcLimit(*c.limit, ee.koinPrecision)
+	if err != nil {
+		return nil, err
+	}
+
+	ee.rcLimit = limit
+	result.AddMessage(fmt.Sprintf("Set rc limit to %s", display))
+
+	return result, nil
+}
+
+// parseRcLimit parses an rc limit given as either mana, with the given precision, or a percent of the available mana,
+// returning it along with how it should be shown
+func parseRcLimit(s string, precision int) (rcInfo, string, error) {
+	if s == "" {
+		return rcInfo{}, "", fmt.Errorf("%w: rc limit cannot be empty", cliutil.ErrInvalidParam)
+	}
+
+	if s[len(s)-1] == '%' {
+		res, err := decimal.NewFromString(s[:len(s)-1])
+		if err != nil {
+			return rcInfo{}, "", err
+		}
+
+		// Check bounds
+		if res.LessThan(decimal.NewFromInt(0)) || res.GreaterThan(decimal.NewFromInt(100)) {
+			return rcInfo{}, "", fmt.Errorf("%w: percentage rc limit must be between 0%% and 100%%", cliutil.ErrInvalidParam)
+		}
+
+		// Convert to decimal
+		resFrac := res.Div(decimal.NewFromInt(100))
+		val, err := util.DecimalToSatoshi(&resFrac, rcFractionPrecision)
+		if err != nil {
+			return rcInfo{}, "", err
+		}
+
+		return rcInfo{value: val, absolute: false}, res.String() + "%", nil
+	}
+
+	// Otherwise it is an absolute limit
+	res, err := decimal.NewFromString(s)
+	if err != nil {
+		return rcInfo{}, "", err
+	}
+
+	if res.IsNegative() {
+		return rcInfo{}, "", fmt.Errorf("%w: rc limit cannot be negative", cliutil.ErrInvalidParam)
+	}
+
+	// Convert to satoshi
+	val, err := util.DecimalToSatoshi(&res, precision)
+	if err != nil {
+		return rcInfo{}, "", err
+	}
+
+	return rcInfo{value: val, absolute: true}, res.String(), nil
+}
+
+// ----------------------------------------------------------------------------
+// Method RcLimit Command
+// ----------------------------------------------------------------------------
+
+// DefaultMethodRcLimit removes the rc limit override of a contract method, so that it uses the rc limit again
+const DefaultMethodRcLimit = "default"
+
+// MethodRcLimitCommand is a command that sets or shows the rc limit used when calling a contract method
+type MethodRcLimitCommand struct {
+	Method string
+	Limit  *string
+}
+
+// NewMethodRcLimitCommand creates a new method rc limit command object
+func NewMethodRcLimitCommand(inv *CommandParseResult) Command {
+	return &MethodRcLimitCommand{Method: *inv.Args["method"], Limit: inv.Args["limit"]}
+}
+
+// Execute sets or shows the rc limit of the method
+func (c *MethodRcLimitCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	method := ee.Contracts.GetMethod(c.Method)
+	if method == nil {
+		return nil, fmt.Errorf("%w: %s is not a method of a registered contract", cliutil.ErrInvalidParam, c.Method)
+	}
+
+	if method.ReadOnly {
+		return nil, fmt.Errorf("%w: %s is read only, and does not submit a transaction", cliutil.ErrInvalidParam, c.Method)
+	}
+
+	result := NewExecutionResult()
+	if c.Limit == nil {
+		limit, ok := ee.methodRcLimits[c.Method]
+		if !ok {
+			result.AddMessage(fmt.Sprintf("%s uses the current rc limit", c.Method))
+			return result, nil
+		}
+
+		display, err := ee.rcLimitString(limit)
+		if err != nil {
+			return nil, err
+		}
+
+		result.AddMessage(fmt.Sprintf("Rc limit of %s: %s", c.Method, display))
+		return result, nil
+	}
+
+	if *c.Limit == DefaultMethodRcLimit {
+		delete(ee.methodRcLimits, c.Method)
+		result.AddMessage(fmt.Sprintf("%s now uses the current rc limit", c.Method))
+		return result, nil
+	}
+
+	limit, display, err := parseRcLimit(*c.Limit, ee.koinPrecision)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", cliutil.ErrInvalidParam, err)
+	}
+
+	// A transaction cannot be submitted without any mana
+	if limit.value == 0 {
+		return nil, fmt.Errorf("%w: rc limit of %s must be greater than 0", cliutil.ErrInvalidParam, c.Method)
+	}
+
+	ee.methodRcLimits[c.Method] = limit
+	result.AddMessage(fmt.Sprintf("Set rc limit of %s to %s", c.Method, display))
+
+	return result, nil
+}
+
+// ----------------------------------------------------------------------------
+// Read
+// ----------------------------------------------------------------------------
+
+// ReadCommand is a command that reads from a contract
+type ReadCommand struct {
+	ContractID string
+	EntryPoint string
+	Arguments  string
+}
+
+// NewReadCommand creates a new read command object
+func NewReadCommand(inv *CommandParseResult) Command {
+	return &ReadCommand{ContractID: *inv.Args["contract-id"], EntryPoint: *inv.Args["entry-point"], Arguments: *inv.Args["arguments"]}
+}
+
+// Execute reads from a contract
+func (c *ReadCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	if !ee.IsOnline() {
+		return nil, fmt.Errorf("%w: cannot read contract", cliutil.ErrOffline)
+	}
+
+	cid := base58.Decode(c.ContractID)
+	if len(cid) == 0 {
+		return nil, errors.New("could not parse contract id")
+	}
+
+	// Parse the entry point
+	entryPoint, err := cliutil.ParseEntryPoint(c.EntryPoint)
+	if err != nil {
+		return nil, err
+	}
+
+	// Serialize and assign the args, given as 0x prefixed hex or multibase base64
+	var argumentBytes []byte
+	if strings.HasPrefix(c.Arguments, "0x") {
+		argumentBytes, err = hex.DecodeString(c.Arguments[2:])
+	} else if len(c.Arguments) > 0 {
+		argumentBytes, err = base64.StdEncoding.DecodeString(c.Arguments[1:])
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cResp, err := ee.RPCClient.ReadContract(ctx, argumentBytes, cid, entryPoint)
+	if err != nil {
+		return nil, err
+	}
+
+	result := NewExecutionResult()
+	result.AddMessage("M" + base64.StdEncoding.EncodeToString(cResp.Result))
+	result.SetField("result", "0x"+hex.EncodeToString(cResp.Result))
+
+	return result, nil
+}
+
+// ----------------------------------------------------------------------------
+// ReadFormat Command
+// ----------------------------------------------------------------------------
+
+// ReadFormatCommand is a command that sets or shows the format of contract read results
+type ReadFormatCommand struct {
+	Format *string
+}
+
+// NewReadFormatCommand creates a new read format command object
+func NewReadFormatCommand(inv *CommandParseResult) Command {
+	return &ReadFormatCommand{Format: inv.Args["format"]}
 }
 
-// Execute shows wallet public key
-func (c *PublicCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
-	if !ee.IsWalletOpen() {
-		return nil, fmt.Errorf("%w: cannot show public key", cliutil.ErrWalletClosed)
+// Execute sets or shows the read format
+func (c *ReadFormatCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	result := NewExecutionResult()
+
+	if c.Format != nil {
+		err := ee.SetReadFormat(strings.ToLower(*c.Format))
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	result := NewExecutionResult()
-	result.AddMessage(fmt.Sprintf("Public key: %s", base64.URLEncoding.EncodeToString(ee.Key.PublicBytes())))
+	result.AddMessage(fmt.Sprintf("Contract read results are shown as %s", ee.readFormat))
 
 	return result, nil
 }
 
 // ----------------------------------------------------------------------------
-// Help
+// Config Command
 // ----------------------------------------------------------------------------
 
-// HelpCommand is a command that displays help for a given command
-type HelpCommand struct {
+// ConfigCommand is a command that shows the configuration the CLI was started with
+type ConfigCommand struct {
 	Command string
 }
 
-// NewHelpCommand creates a new help command object
-func NewHelpCommand(inv *CommandParseResult) Command {
-	return &HelpCommand{Command: *inv.Args["command"]}
+// NewConfigCommand creates a new config command object
+func NewConfigCommand(inv *CommandParseResult) Command {
+	return &ConfigCommand{Command: *inv.Args["command"]}
 }
 
-// Execute displays help for a given command
-func (c *HelpCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
-	decl, ok := ee.Parser.Commands.Name2Command[string(c.Command)]
-
-	if !ok {
-		return nil, fmt.Errorf("%w: cannot show help for %s", cliutil.ErrUnknownCommand, c.Command)
+// Execute shows the configuration
+func (c *ConfigCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	if c.Command != "show" {
+		return nil, fmt.Errorf("%w: unknown config command %s, expected show", cliutil.ErrInvalidParam, c.Command)
 	}
 
 	result := NewExecutionResult()
-	result.AddMessage(decl.Description)
-	result.AddMessage(fmt.Sprintf("Usage: %s", decl))
+	if len(ee.config) == 0 {
+		result.AddMessage("No configuration was loaded")
+	}
+
+	for _, option := range ee.config {
+		result.AddMessage(fmt.Sprintf("%s = %s (%s)", option.Name, option.Value, option.Source))
+	}
+
+	result.SetField("config", ee.config)
 
 	return result, nil
 }
 
 // ----------------------------------------------------------------------------
-// Submit Transaction Command
+// SetPrecision Command
 // ----------------------------------------------------------------------------
 
-// SubmitTransactionCommand is a command that submits a given transaction to the blockchain
-type SubmitTransactionCommand struct {
-	Transaction string
+// SetPrecisionCommand is a command that sets or shows the precision and symbol of KOIN amounts
+type SetPrecisionCommand struct {
+	Precision *string
+	Symbol    *string
 }
 
-// NewSubmitTransactionCommand creates a new submit transaction command object
-func NewSubmitTransactionCommand(inv *CommandParseResult) Command {
-	return &SubmitTransactionCommand{Transaction: *inv.Args["transaction"]}
+// NewSetPrecisionCommand creates a new set precision command object
+func NewSetPrecisionCommand(inv *CommandParseResult) Command {
+	return &SetPrecisionCommand{Precision: inv.Args["precision"], Symbol: inv.Args["symbol"]}
 }
 
-// Execute submits a transaction to the blockchain
-func (c *SubmitTransactionCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+// Execute sets or shows the KOIN precision and symbol
+func (c *SetPrecisionCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
 	result := NewExecutionResult()
 
-	if !ee.IsOnline() {
-		return nil, fmt.Errorf("%w: cannot submit transaction", cliutil.ErrOffline)
-	}
+	precision := ee.koinPrecision
+	if c.Precision != nil {
+		p, err := strconv.ParseUint(strings.TrimPrefix(*c.Precision, "+"), 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("%w: precision must be between 0 and 18", cliutil.ErrInvalidParam)
+		}
 
-	// Decode the transaction
-	data, err := base64.URLEncoding.DecodeString(c.Transaction)
-	if err != nil {
-		return nil, err
+		precision = int(p)
 	}
 
-	transaction := &protocol.Transaction{}
-	err = proto.Unmarshal(data, transaction)
-	if err != nil {
-		return nil, err
+	symbol := ee.koinSymbol
+	if c.Symbol != nil {
+		symbol = *c.Symbol
 	}
 
-	receipt, err := ee.RPCClient.SubmitTransaction(ctx, transaction, true)
+	err := ee.SetKoinUnits(precision, symbol)
 	if err != nil {
-		return result, err
+		return nil, err
 	}
 
-	result.AddMessage(cliutil.TransactionReceiptToString(receipt, len(transaction.GetOperations())))
+	result.AddMessage(fmt.Sprintf("KOIN amounts are shown with precision %d and symbol %s", ee.koinPrecision, ee.koinSymbol))
 
 	return result, nil
 }
 
 // ----------------------------------------------------------------------------
-// Call Command
+// BalanceEntryPoint Command
 // ----------------------------------------------------------------------------
 
-// CallCommand is a command that calls a contract method
-type CallCommand struct {
-	ContractID string
-	EntryPoint string
-	Arguments  string
+// DefaultBalanceEntryPoint restores the mainnet entry point for reading KOIN balances
+const DefaultBalanceEntryPoint = "default"
+
+// BalanceEntryPointCommand is a command that sets or shows the entry point called to read KOIN balances
+type BalanceEntryPointCommand struct {
+	EntryPoint *string
 }
 
-// NewCallCommand calls a contract method
-func NewCallCommand(inv *CommandParseResult) Command {
-	return &CallCommand{
-		ContractID: *inv.Args["contract-id"],
-		EntryPoint: *inv.Args["entry-point"],
-		Arguments:  *inv.Args["arguments"],
-	}
+// NewBalanceEntryPointCommand creates a new balance entry point command object
+func NewBalanceEntryPointCommand(inv *CommandParseResult) Command {
+	return &BalanceEntryPointCommand{EntryPoint: inv.Args["entry-point"]}
 }
 
-// Execute a contract call
-func (c *CallCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
-	if !ee.IsWalletOpen() {
-		return nil, fmt.Errorf("%w: cannot call contract", cliutil.ErrWalletClosed)
-	}
+// Execute sets or shows the KOIN balanceOf entry point
+func (c *BalanceEntryPointCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	result := NewExecutionResult()
 
-	if !ee.IsOnline() && !ee.Session.IsValid() {
-		return nil, fmt.Errorf("%w: cannot call", cliutil.ErrOffline)
-	}
+	if c.EntryPoint != nil {
+		entryPoint := TokenBalanceOfEntry
+		if *c.EntryPoint != DefaultBalanceEntryPoint {
+			var err error
+			entryPoint, err = cliutil.ParseEntryPoint(*c.EntryPoint)
+			if err != nil {
+				return nil, err
+			}
+		}
 
-	entryPoint, err := strconv.ParseUint(c.EntryPoint[2:], 16, 32)
-	if err != nil {
-		return nil, err
+		ee.SetKoinBalanceOfEntry(entryPoint)
 	}
 
-	contractID := base58.Decode(c.ContractID)
-	if len(contractID) == 0 {
-		return nil, errors.New("could not parse contract id")
-	}
+	result.AddMessage(fmt.Sprintf("KOIN balances are read from entry point 0x%08x", ee.koinBalanceOfEntry))
+	result.SetField("entry_point", fmt.Sprintf("0x%08x", ee.koinBalanceOfEntry))
 
-	// Get the argument bytes
-	argumentBytes, err := base64.StdEncoding.DecodeString(c.Arguments)
-	if err != nil {
-		return nil, err
-	}
+	return result, nil
+}
 
-	op := &protocol.Operation{
-		Op: &protocol.Operation_CallContract{
-			CallContract: &protocol.CallContractOperation{
-				ContractId: contractID,
-				EntryPoint: uint32(entryPoint),
-				Args:       argumentBytes,
-			},
-		},
-	}
+// ----------------------------------------------------------------------------
+// NumberFormat Command
+// ----------------------------------------------------------------------------
+
+// NumberFormatCommand is a command that sets or shows how balances are shown
+type NumberFormatCommand struct {
+	Format *string
+}
+
+// NewNumberFormatCommand creates a new number format command object
+func NewNumberFormatCommand(inv *CommandParseResult) Command {
+	return &NumberFormatCommand{Format: inv.Args["format"]}
+}
 
+// Execute sets or shows the number format
+func (c *NumberFormatCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
 	result := NewExecutionResult()
-	result.AddMessage(fmt.Sprintf("Calling contract %s at entry point: %s with arguments %s", c.ContractID, c.EntryPoint, c.Arguments))
 
-	err = ee.Session.AddOperation(op, fmt.Sprintf("Call contract %s at entry point: %s with arguments %s", c.ContractID, c.EntryPoint, c.Arguments))
-	if err == nil {
-		result.AddMessage("Adding operation to transaction session")
-	}
-	if err != nil {
-		err := ee.SubmitTransaction(ctx, result, op)
+	if c.Format != nil {
+		format, err := cliutil.ParseNumberFormat(*c.Format)
 		if err != nil {
-			return result, fmt.Errorf("cannot call contract, %w", err)
+			return nil, err
 		}
+
+		ee.SetNumberFormat(format)
 	}
 
+	example := decimal.New(123456789, -4)
+	result.AddMessage(fmt.Sprintf("Number format: %s (e.g. %s)", ee.numberFormat, ee.formatAmount(&example, ee.koinSymbol)))
+	result.SetField("format", ee.numberFormat.String())
+
 	return result, nil
 }
 
 // ----------------------------------------------------------------------------
-// Open
+// DryRun Command
 // ----------------------------------------------------------------------------
 
-// OpenCommand is a command that opens a wallet file
-type OpenCommand struct {
-	Filename string
-	Password *string
+// DryRunCommand is a command that sets or shows dry run mode
+type DryRunCommand struct {
+	Enabled *string
 }
 
-// NewOpenCommand creates a new open command object
-func NewOpenCommand(inv *CommandParseResult) Command {
-	return &OpenCommand{Filename: *inv.Args["filename"], Password: inv.Args["password"]}
+// NewDryRunCommand creates a new dry run command object
+func NewDryRunCommand(inv *CommandParseResult) Command {
+	return &DryRunCommand{Enabled: inv.Args["enabled"]}
 }
 
-// Execute opens a wallet
-func (c *OpenCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
-	// Open the wallet file
-	file, err := os.Open(c.Filename)
-	if err != nil {
-		return nil, err
-	}
+// Execute sets or shows dry run mode
+func (c *DryRunCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	result := NewExecutionResult()
 
-	// Get the password
-	pass, err := cliutil.GetPassword(c.Password)
-	if err != nil {
-		return nil, err
-	}
+	if c.Enabled != nil {
+		enabled, err := strconv.ParseBool(*c.Enabled)
+		if err != nil {
+			return nil, err
+		}
 
-	// Read the wallet file
-	keyBytes, err := cliutil.ReadWalletFile(file, pass)
-	if err != nil {
-		return nil, fmt.Errorf("%w: check your password", cliutil.ErrWalletDecrypt)
+		ee.dryRun = enabled
 	}
 
-	// Create the key object
-	key, err := util.NewKoinosKeyFromBytes(keyBytes)
-	if err != nil {
-		return nil, err
+	if ee.dryRun {
+		result.AddMessage("Dry run is enabled, transactions will not be broadcast")
+	} else {
+		result.AddMessage("Dry run is disabled")
 	}
 
-	// Open the wallet
-	ee.OpenWallet(key)
+	return result, nil
+}
+
+// ----------------------------------------------------------------------------
+// ParseOnly Command
+// ----------------------------------------------------------------------------
+
+// ParseOnlyCommand is a command that sets or shows parse only mode
+type ParseOnlyCommand struct {
+	Enabled *string
+}
+
+// NewParseOnlyCommand creates a new parse only command object
+func NewParseOnlyCommand(inv *CommandParseResult) Command {
+	return &ParseOnlyCommand{Enabled: inv.Args["enabled"]}
+}
 
+// Execute sets or shows parse only mode
+func (c *ParseOnlyCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
 	result := NewExecutionResult()
-	result.AddMessage(fmt.Sprintf("Opened wallet: %s", c.Filename))
+
+	if c.Enabled != nil {
+		enabled, err := strconv.ParseBool(*c.Enabled)
+		if err != nil {
+			return nil, err
+		}
+
+		ee.parseOnly = enabled
+	}
+
+	if ee.parseOnly {
+		result.AddMessage("Parse only is enabled, the operations of transactions will be shown without contacting the node")
+	} else {
+		result.AddMessage("Parse only is disabled")
+	}
 
 	return result, nil
 }
 
 // ----------------------------------------------------------------------------
-// Payer Command
+// Confirm Command
 // ----------------------------------------------------------------------------
 
-// PayerCommand is a command shows or sets the current payer
-type PayerCommand struct {
-	Payer *string
+// ConfirmCommand is a command that sets or shows whether transactions must be confirmed before they are submitted
+type ConfirmCommand struct {
+	Enabled *string
 }
 
-// NewPayerCommand creates a new payer command object
-func NewPayerCommand(inv *CommandParseResult) Command {
-	payerString := inv.Args["payer"]
-	return &PayerCommand{Payer: payerString}
+// NewConfirmCommand creates a new confirm command object
+func NewConfirmCommand(inv *CommandParseResult) Command {
+	return &ConfirmCommand{Enabled: inv.Args["enabled"]}
 }
 
-// Execute shows wallet address
-func (c *PayerCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+// Execute sets or shows confirmation mode
+func (c *ConfirmCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
 	result := NewExecutionResult()
 
-	// If the payer string is null, then we are showing the current payer
-	if c.Payer == nil {
-		if ee.IsSelfPaying() {
-			if ee.IsWalletOpen() {
-				result.AddMessage(fmt.Sprintf("Payer: me (%s)", base58.Encode(ee.GetPayerAddress())))
-			} else {
-				result.AddMessage("Payer: me")
-			}
-		} else {
-			result.AddMessage(fmt.Sprintf("Payer: %s", base58.Encode(ee.GetPayerAddress())))
+	if c.Enabled != nil {
+		enabled, err := strconv.ParseBool(*c.Enabled)
+		if err != nil {
+			return nil, err
 		}
 
-		return result, nil
+		ee.confirm = enabled
+	}
+
+	if !ee.confirm {
+		result.AddMessage("Confirmation is disabled")
+	} else if ee.assumeYes {
+		result.AddMessage("Confirmation is enabled, but skipped by the --yes switch")
+	} else {
+		result.AddMessage("Confirmation is enabled, transactions must be confirmed before they are submitted")
 	}
 
-	// Otherwise, we are setting the payer
-	ee.SetPayer(*c.Payer)
 	return result, nil
 }
 
 // ----------------------------------------------------------------------------
-// Nonce Command
+// Wait Command
 // ----------------------------------------------------------------------------
 
-// NonceCommand is a command that shows or sets the current nonce
-type NonceCommand struct {
-	Nonce *string
+// WaitCommand is a command that sets or shows how long to wait for submitted transactions to be included in a block
+type WaitCommand struct {
+	Seconds  *string
+	Interval *string
 }
 
-// NewNonceCommand creates a new nonce command object
-func NewNonceCommand(inv *CommandParseResult) Command {
-	nonceString := inv.Args["nonce"]
-	return &NonceCommand{Nonce: nonceString}
+// NewWaitCommand creates a new wait command object
+func NewWaitCommand(inv *CommandParseResult) Command {
+	return &WaitCommand{Seconds: inv.Args["seconds"], Interval: inv.Args["interval"]}
 }
 
-// Execute shows or sets the current nonce
-func (c *NonceCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+// Execute sets or shows the wait settings
+func (c *WaitCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
 	result := NewExecutionResult()
 
-	// If the nonce string is null, then we are showing the current nonce
-	if c.Nonce == nil {
-		if ee.IsNonceAuto() {
-			if ee.IsOnline() && ee.IsWalletOpen() {
-				nonce, err := ee.GetNextNonce(ctx, false)
-				if err != nil {
-					return nil, err
-				}
-				result.AddMessage(fmt.Sprintf("Nonce: auto (next nonce: %d)", nonce))
-			} else {
-				result.AddMessage("Nonce: auto")
-			}
+	// If no value given, display current
+	if c.Seconds == nil {
+		if ee.waitTimeout == 0 {
+			result.AddMessage("Waiting for transactions is disabled")
 		} else {
-			n, err := ee.GetNextNonce(ctx, false)
-			if err != nil {
-				return nil, err
-			}
-			result.AddMessage(fmt.Sprintf("Nonce: %d", n))
+			result.AddMessage(fmt.Sprintf("Waiting up to %v seconds for transactions, checking every %v seconds", ee.waitTimeout.Seconds(), ee.waitInterval.Seconds()))
 		}
 
 		return result, nil
 	}
 
-	// Otherwise, we are setting the nonce
-
-	// If it's auto just set that
-	if *c.Nonce == AutoNonce {
-		ee.nonceMode = AutoNonce
-		return result, nil
+	seconds, err := strconv.ParseUint(*c.Seconds, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", cliutil.ErrInvalidParam, err)
 	}
 
-	// Otherwise, parse the nonce to make sure it is correct
-	_, err := strconv.ParseUint(*c.Nonce, 10, 64)
-	if err != nil {
-		return nil, fmt.Errorf("%w: nonce must either be an integer number or \"auto\"", cliutil.ErrInvalidParam)
+	interval := ee.waitInterval
+	if c.Interval != nil {
+		intervalSeconds, err := strconv.ParseUint(*c.Interval, 10, 32)
+		if err != nil || intervalSeconds == 0 {
+			return nil, fmt.Errorf("%w: interval must be a positive number of seconds", cliutil.ErrInvalidParam)
+		}
+
+		interval = time.Duration(intervalSeconds) * time.Second
 	}
 
-	ee.nonceMode = *c.Nonce
+	ee.SetWait(time.Duration(seconds)*time.Second, interval)
+
+	if seconds == 0 {
+		result.AddMessage("Disabled waiting for transactions")
+	} else {
+		result.AddMessage(fmt.Sprintf("Waiting up to %d seconds for transactions, checking every %v seconds", seconds, interval.Seconds()))
+	}
 
 	return result, nil
 }
 
 // ----------------------------------------------------------------------------
-// ChainID Command
+// RPCTimeout Command
 // ----------------------------------------------------------------------------
 
-// ChainIDCommand is a command that shows or sets the current chain ID
-type ChainIDCommand struct {
-	ID *string
+// RPCTimeoutCommand is a command that sets or shows the RPC timeout
+type RPCTimeoutCommand struct {
+	Seconds *string
 }
 
-// NewChainIDCommand creates a new chain ID command object
-func NewChainIDCommand(inv *CommandParseResult) Command {
-	nonceString := inv.Args["id"]
-	return &ChainIDCommand{ID: nonceString}
+// NewRPCTimeoutCommand creates a new rpc timeout command object
+func NewRPCTimeoutCommand(inv *CommandParseResult) Command {
+	return &RPCTimeoutCommand{Seconds: inv.Args["seconds"]}
 }
 
-// Execute shows or sets the current chain ID
-func (c *ChainIDCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+// Execute sets or shows the rpc timeout
+func (c *RPCTimeoutCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
 	result := NewExecutionResult()
 
-	// If the id string is null, then we are showing the current chain id
-	if c.ID == nil {
-		if ee.IsChainIDAuto() && ee.IsOnline() {
-			chainID, err := ee.GetChainID(ctx)
-			if err != nil {
-				return nil, err
-			}
-			result.AddMessage(fmt.Sprintf("Chain ID: auto (%s)", base64.URLEncoding.EncodeToString(chainID)))
+	// If no value given, display current
+	if c.Seconds == nil {
+		if ee.rpcTimeout == 0 {
+			result.AddMessage("RPC timeout is disabled")
 		} else {
-			result.AddMessage(fmt.Sprintf("Chain ID: %s", ee.chainID))
+			result.AddMessage(fmt.Sprintf("Current RPC timeout: %v seconds", ee.rpcTimeout.Seconds()))
 		}
-		return result, nil
-	}
-
-	// Otherwise, we are setting the chain id
 
-	// If it's auto just set that
-	if *c.ID == AutoChainID {
-		ee.chainID = AutoChainID
 		return result, nil
 	}
 
-	// Make sure the chain id is valid base64
-	_, err := base64.URLEncoding.DecodeString(*c.ID)
+	seconds, err := strconv.ParseUint(*c.Seconds, 10, 32)
 	if err != nil {
-		return nil, fmt.Errorf("%w: chain id must either be a base64 string or \"auto\"", cliutil.ErrInvalidParam)
+		return nil, fmt.Errorf("%w: %s", cliutil.ErrInvalidParam, err)
 	}
 
-	ee.chainID = *c.ID
+	ee.SetRPCTimeout(time.Duration(seconds) * time.Second)
+
+	if seconds == 0 {
+		result.AddMessage("Disabled RPC timeout")
+	} else {
+		result.AddMessage(fmt.Sprintf("Set RPC timeout to %d seconds", seconds))
+	}
 
 	return result, nil
 }
 
 // ----------------------------------------------------------------------------
-// RcLimit Command
+// Prompt Balance Command
 // ----------------------------------------------------------------------------
 
-// RcLimitCommand is a command that sets or checks your cuttent rc limit
-type RcLimitCommand struct {
-	limit *string
+// PromptBalanceCommand is a command that sets or shows how often the balance in the interactive prompt is refreshed
+type PromptBalanceCommand struct {
+	Seconds *string
 }
 
-// NewRcLimitCommand creates a new rc limit command object
-func NewRcLimitCommand(inv *CommandParseResult) Command {
-	return &RcLimitCommand{limit: inv.Args["limit"]}
+// NewPromptBalanceCommand creates a new prompt balance command object
+func NewPromptBalanceCommand(inv *CommandParseResult) Command {
+	return &PromptBalanceCommand{Seconds: inv.Args["seconds"]}
 }
 
-// Execute handles the rc limit command
-func (c *RcLimitCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+// Execute sets or shows the prompt balance interval
+func (c *PromptBalanceCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
 	result := NewExecutionResult()
-	// If no limit given, display current
-	if c.limit == nil {
-		if ee.rcLimit.absolute {
-			decAmount, err := util.SatoshiToDecimal(ee.rcLimit.value, cliutil.KoinPrecision)
-			if err != nil {
-				return nil, err
-			}
-			result.AddMessage(fmt.Sprintf("Current rc limit: %v", decAmount))
-			return result, nil
-		}
-
-		// Otherwise its relative
-		if !ee.IsOnline() || !ee.IsWalletOpen() {
-			decAmount, err := util.SatoshiToDecimal(ee.rcLimit.value, cliutil.KoinPrecision)
-			resultVal := decimal.NewFromFloat(100).Mul(*decAmount)
-			if err != nil {
-				return nil, err
-			}
-			result.AddMessage(fmt.Sprintf("Current rc limit: %v%%", resultVal))
-			return result, nil
-		}
-
-		amount, err := ee.GetRcLimit(ctx)
-		if err != nil {
-			return nil, err
-		}
-
-		decAmount, err := util.SatoshiToDecimal(amount, cliutil.KoinPrecision)
-		if err != nil {
-			return nil, err
-		}
-
-		decLimit, err := util.SatoshiToDecimal(ee.rcLimit.value, cliutil.KoinPrecision)
-		if err != nil {
-			return nil, err
-		}
-
-		result.AddMessage(fmt.Sprintf("Current rc limit: %v%% (%v)", decLimit.Mul(decimal.NewFromInt(100)), decAmount))
-		return result, nil
-	}
-
-	// Otherwise we are setting the limit
-	s := *c.limit
-	if s[len(s)-1] == '%' {
-		res, err := decimal.NewFromString(s[:len(s)-1])
-		if err != nil {
-			return nil, err
-		}
 
-		// Check bounds
-		if res.LessThan(decimal.NewFromInt(0)) || res.GreaterThan(decimal.NewFromInt(100)) {
-			return nil, fmt.Errorf("%w: percentage rc limit must be between 0%% and 100%%", cliutil.ErrInvalidParam)
-		}
-
-		// Convert to decimal
-		resFrac := res.Div(decimal.NewFromInt(100))
-		val, err := util.DecimalToSatoshi(&resFrac, cliutil.KoinPrecision)
+	if c.Seconds != nil {
+		seconds, err := strconv.ParseUint(*c.Seconds, 10, 32)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("%w: %s", cliutil.ErrInvalidParam, err)
 		}
 
-		ee.rcLimit.value = val
-		ee.rcLimit.absolute = false
-		result.AddMessage(fmt.Sprintf("Set rc limit to %v%%", res))
-		return result, nil
-	}
-
-	// Otherwise we are setting the absolute limit
-	res, err := decimal.NewFromString(s)
-	if err != nil {
-		return nil, err
+		ee.SetPromptBalanceInterval(time.Duration(seconds) * time.Second)
 	}
 
-	// Convert to satoshi
-	val, err := util.DecimalToSatoshi(&res, cliutil.KoinPrecision)
-	if err != nil {
-		return nil, err
+	interval := ee.GetPromptBalanceInterval()
+	if interval == 0 {
+		result.AddMessage("The balance is not shown in the prompt")
+	} else {
+		result.AddMessage(fmt.Sprintf("The balance is shown in the prompt, refreshed every %v", interval))
 	}
 
-	ee.rcLimit.value = val
-	ee.rcLimit.absolute = true
-	result.AddMessage(fmt.Sprintf("Set rc limit to %v", res))
-
 	return result, nil
 }
 
 // ----------------------------------------------------------------------------
-// Read
+// SetTimeout Command
 // ----------------------------------------------------------------------------
 
-// ReadCommand is a command that reads from a contract
-type ReadCommand struct {
-	ContractID string
-	EntryPoint string
-	Arguments  string
+// SetTimeoutCommand is a command that sets or shows the wallet inactivity timeout
+type SetTimeoutCommand struct {
+	Minutes *string
 }
 
-// NewReadCommand creates a new read command object
-func NewReadCommand(inv *CommandParseResult) Command {
-	return &ReadCommand{ContractID: *inv.Args["contract-id"], EntryPoint: *inv.Args["entry-point"], Arguments: *inv.Args["arguments"]}
+// NewSetTimeoutCommand creates a new set timeout command object
+func NewSetTimeoutCommand(inv *CommandParseResult) Command {
+	return &SetTimeoutCommand{Minutes: inv.Args["minutes"]}
 }
 
-// Execute reads from a contract
-func (c *ReadCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
-	if !ee.IsOnline() {
-		return nil, fmt.Errorf("%w: cannot read contract", cliutil.ErrOffline)
-	}
+// Execute sets or shows the inactivity timeout
+func (c *SetTimeoutCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	result := NewExecutionResult()
 
-	cid := base58.Decode(c.ContractID)
-	if len(cid) == 0 {
-		return nil, errors.New("could not parse contract id")
-	}
+	// If no value given, display current
+	if c.Minutes == nil {
+		if ee.lockTimeout == 0 {
+			result.AddMessage("Inactivity timeout is disabled")
+		} else {
+			result.AddMessage(fmt.Sprintf("Current inactivity timeout: %v minutes", ee.lockTimeout.Minutes()))
+		}
 
-	// Parse the entry point (drop the 0x)
-	entryPoint, err := strconv.ParseUint(c.EntryPoint[2:], 16, 32)
-	if err != nil {
-		return nil, err
+		return result, nil
 	}
 
-	// Serialize and assign the args
-	argumentBytes, err := base64.StdEncoding.DecodeString(c.Arguments[1:])
+	minutes, err := strconv.ParseUint(*c.Minutes, 10, 32)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %s", cliutil.ErrInvalidParam, err)
 	}
 
-	cResp, err := ee.RPCClient.ReadContract(ctx, argumentBytes, cid, uint32(entryPoint))
-	if err != nil {
-		return nil, err
-	}
+	ee.lockTimeout = time.Duration(minutes) * time.Minute
 
-	result := NewExecutionResult()
-	result.AddMessage("M" + base64.StdEncoding.EncodeToString(cResp.Result))
+	if minutes == 0 {
+		result.AddMessage("Disabled inactivity timeout")
+	} else {
+		result.AddMessage(fmt.Sprintf("Set inactivity timeout to %d minutes", minutes))
+	}
 
 	return result, nil
 }
@@ -1143,7 +3433,7 @@ func (c *SetSystemCallCommand) Execute(ctx context.Context, ee *ExecutionEnviron
 		return nil, fmt.Errorf("%w: cannot call contract", cliutil.ErrWalletClosed)
 	}
 
-	if !ee.IsOnline() && !ee.Session.IsValid() {
+	if !ee.CanSubmit() {
 		return nil, fmt.Errorf("%w: cannot call contract", cliutil.ErrOffline)
 	}
 
@@ -1156,7 +3446,7 @@ func (c *SetSystemCallCommand) Execute(ctx context.Context, ee *ExecutionEnviron
 		}
 	}
 
-	entryPoint, err := strconv.ParseUint(c.EntryPoint[2:], 16, 32)
+	entryPoint, err := cliutil.ParseEntryPoint(c.EntryPoint)
 	if err != nil {
 		return nil, err
 	}
@@ -1174,7 +3464,7 @@ func (c *SetSystemCallCommand) Execute(ctx context.Context, ee *ExecutionEnviron
 					Target: &protocol.SystemCallTarget_SystemCallBundle{
 						SystemCallBundle: &protocol.ContractCallBundle{
 							ContractId: contractID,
-							EntryPoint: uint32(entryPoint),
+							EntryPoint: entryPoint,
 						},
 					},
 				},
@@ -1223,7 +3513,7 @@ func (c *SetSystemContractCommand) Execute(ctx context.Context, ee *ExecutionEnv
 		return nil, fmt.Errorf("%w: cannot set system contract", cliutil.ErrWalletClosed)
 	}
 
-	if !ee.IsOnline() && !ee.Session.IsValid() {
+	if !ee.CanSubmit() {
 		return nil, fmt.Errorf("%w: cannot set system contract", cliutil.ErrOffline)
 	}
 
@@ -1274,13 +3564,15 @@ func (c *SetSystemContractCommand) Execute(ctx context.Context, ee *ExecutionEnv
 
 // SessionCommand is a command that sets a system call to a new contract and entry point
 type SessionCommand struct {
-	Command string
+	Command  string
+	Filename *string
 }
 
 // NewSessionCommand calls a contract method
 func NewSessionCommand(inv *CommandParseResult) Command {
 	return &SessionCommand{
-		Command: *inv.Args["command"],
+		Command:  *inv.Args["command"],
+		Filename: inv.Args["filename"],
 	}
 }
 
@@ -1356,6 +3648,16 @@ func (c *SessionCommand) Execute(ctx context.Context, ee *ExecutionEnvironment)
 
 				result.AddMessage("\nBase64:")
 				result.AddMessage(base64.URLEncoding.EncodeToString(data))
+
+				// Write the transaction to a file for broadcasting elsewhere
+				if c.Filename != nil {
+					err = ioutil.WriteFile(*c.Filename, []byte(base64.URLEncoding.EncodeToString(data)), 0600)
+					if err != nil {
+						return nil, fmt.Errorf("cannot write transaction file, %w", err)
+					}
+
+					result.AddMessage(fmt.Sprintf("\nSigned transaction written to %s", *c.Filename))
+				}
 			} else {
 				err := ee.SubmitTransaction(ctx, result, ops...)
 				if err != nil {
@@ -1383,9 +3685,12 @@ func (c *SessionCommand) Execute(ctx context.Context, ee *ExecutionEnvironment)
 		}
 
 		result.AddMessage(fmt.Sprintf("Transaction Session (%v operations):", len(reqs)))
+		operations := make([]string, len(reqs))
 		for i, op := range reqs {
 			result.AddMessage(fmt.Sprintf("%v: %s", i, op.LogMessage))
+			operations[i] = op.LogMessage
 		}
+		result.SetField("operations", operations)
 	default:
 		return nil, fmt.Errorf("unknown command %s, options are (begin, submit, cancel, view)", c.Command)
 	}
@@ -1426,7 +3731,7 @@ func (c *SignTransactionCommand) Execute(ctx context.Context, ee *ExecutionEnvir
 		return nil, err
 	}
 
-	err = util.SignTransaction(ee.Key.PrivateBytes(), trx)
+	err = ee.transactionSigner().SignTransaction(ctx, trx)
 	if err != nil {
 		return nil, err
 	}
@@ -1449,6 +3754,81 @@ func (c *SignTransactionCommand) Execute(ctx context.Context, ee *ExecutionEnvir
 	return result, nil
 }
 
+// ----------------------------------------------------------------------------
+// Sign Message Command
+// ----------------------------------------------------------------------------
+
+// SignMessageCommand is a command that signs an arbitrary message with the open wallet, to prove ownership of its key
+type SignMessageCommand struct {
+	Message string
+}
+
+// NewSignMessageCommand creates a new sign message command object
+func NewSignMessageCommand(inv *CommandParseResult) Command {
+	return &SignMessageCommand{Message: *inv.Args["message"]}
+}
+
+// Execute signs the message
+func (c *SignMessageCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	if !ee.IsWalletOpen() {
+		return nil, fmt.Errorf("%w: cannot sign message", cliutil.ErrWalletClosed)
+	}
+
+	signature, err := cliutil.SignMessage(ee.Key.PrivateBytes(), c.Message)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded := base64.URLEncoding.EncodeToString(signature)
+
+	result := NewExecutionResult()
+	result.AddMessage(fmt.Sprintf("Address: %s", base58.Encode(ee.Key.AddressBytes())))
+	result.AddMessage(fmt.Sprintf("Signature: %s", encoded))
+	result.SetField("address", base58.Encode(ee.Key.AddressBytes()))
+	result.SetField("signature", encoded)
+
+	return result, nil
+}
+
+// ----------------------------------------------------------------------------
+// Verify Message Command
+// ----------------------------------------------------------------------------
+
+// VerifyMessageCommand is a command that checks the signature of a message made with sign_message
+type VerifyMessageCommand struct {
+	Address   string
+	Message   string
+	Signature string
+}
+
+// NewVerifyMessageCommand creates a new verify message command object
+func NewVerifyMessageCommand(inv *CommandParseResult) Command {
+	return &VerifyMessageCommand{Address: *inv.Args["address"], Message: *inv.Args["message"], Signature: *inv.Args["signature"]}
+}
+
+// Execute verifies the signature, failing if it was not made by the address
+func (c *VerifyMessageCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	signature, err := base64.URLEncoding.DecodeString(c.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature must be base64, %s", cliutil.ErrInvalidParam, err)
+	}
+
+	valid, err := cliutil.VerifyMessage(base58.Decode(c.Address), c.Message, signature)
+	if err != nil {
+		return nil, err
+	}
+
+	if !valid {
+		return nil, fmt.Errorf("%w: signature was not made by %s", cliutil.ErrInvalidParam, c.Address)
+	}
+
+	result := NewExecutionResult()
+	result.AddMessage(fmt.Sprintf("Signature is valid for %s", c.Address))
+	result.SetField("valid", true)
+
+	return result, nil
+}
+
 // ----------------------------------------------------------------------------
 // AccountRc Command
 // ----------------------------------------------------------------------------
@@ -1512,10 +3892,226 @@ func NewListCommand(inv *CommandParseResult) Command {
 
 // Execute lists available commands
 func (c *ListCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
-	cmds := ee.Parser.Commands.List(true)
+	cmds := ee.Parser.Commands.ListMatching("")
 
 	result := NewExecutionResult()
 	result.AddMessage(cmds...)
 
 	return result, nil
 }
+
+// ----------------------------------------------------------------------------
+// Alias Commands
+// ----------------------------------------------------------------------------
+
+// AliasAddCommand is a command that adds an address book alias
+type AliasAddCommand struct {
+	Name    string
+	Address string
+}
+
+// NewAliasAddCommand creates a new alias add command object
+func NewAliasAddCommand(inv *CommandParseResult) Command {
+	return &AliasAddCommand{Name: *inv.Args["name"], Address: *inv.Args["address"]}
+}
+
+// Execute adds or replaces the alias
+func (c *AliasAddCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	// An alias that is also an address would hide that address
+	if cliutil.ValidateAddress(c.Name) == nil {
+		return nil, fmt.Errorf("%w: alias %s is an address", cliutil.ErrInvalidParam, c.Name)
+	}
+
+	// The keywords for the open wallet would hide the alias
+	if c.Name == SelfKeyword || c.Name == MeKeyword {
+		return nil, fmt.Errorf("%w: %s is reserved for the open wallet", cliutil.ErrInvalidParam, c.Name)
+	}
+
+	err := cliutil.ValidateAddress(c.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	ee.Parser.Aliases[c.Name] = c.Address
+	err = ee.saveAddressBook()
+	if err != nil {
+		return nil, fmt.Errorf("cannot save address book, %w", err)
+	}
+
+	result := NewExecutionResult()
+	result.AddMessage(fmt.Sprintf("Alias %s added for %s", c.Name, c.Address))
+
+	return result, nil
+}
+
+// AliasRemoveCommand is a command that removes an address book alias
+type AliasRemoveCommand struct {
+	Name string
+}
+
+// NewAliasRemoveCommand creates a new alias remove command object
+func NewAliasRemoveCommand(inv *CommandParseResult) Command {
+	return &AliasRemoveCommand{Name: *inv.Args["name"]}
+}
+
+// Execute removes the alias
+func (c *AliasRemoveCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	if _, ok := ee.Parser.Aliases[c.Name]; !ok {
+		return nil, fmt.Errorf("%w: no alias named %s", cliutil.ErrInvalidParam, c.Name)
+	}
+
+	delete(ee.Parser.Aliases, c.Name)
+	err := ee.saveAddressBook()
+	if err != nil {
+		return nil, fmt.Errorf("cannot save address book, %w", err)
+	}
+
+	result := NewExecutionResult()
+	result.AddMessage(fmt.Sprintf("Alias %s removed", c.Name))
+
+	return result, nil
+}
+
+// AliasListCommand is a command that lists the address book aliases
+type AliasListCommand struct {
+}
+
+// NewAliasListCommand creates a new alias list command object
+func NewAliasListCommand(inv *CommandParseResult) Command {
+	return &AliasListCommand{}
+}
+
+// Execute lists the aliases in order of name
+func (c *AliasListCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	result := NewExecutionResult()
+
+	if len(ee.Parser.Aliases) == 0 {
+		result.AddMessage("No aliases")
+		return result, nil
+	}
+
+	names := make([]string, 0, len(ee.Parser.Aliases))
+	for name := range ee.Parser.Aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		result.AddMessage(fmt.Sprintf("%s: %s", name, ee.Parser.Aliases[name]))
+	}
+	result.SetField("aliases", ee.Parser.Aliases)
+
+	return result, nil
+}
+
+// ----------------------------------------------------------------------------
+// Command Alias Commands
+// ----------------------------------------------------------------------------
+
+// CommandAliasCommand is a command that defines a shortcut for a command line
+type CommandAliasCommand struct {
+	Name    string
+	Command []string
+}
+
+// NewCommandAliasCommand creates a new command alias command object
+func NewCommandAliasCommand(inv *CommandParseResult) Command {
+	return &CommandAliasCommand{Name: *inv.Args["name"], Command: inv.VariadicArgs["command"]}
+}
+
+// Execute adds or replaces the command alias. A single quoted value is taken as the whole command line, so that it can
+// hold several commands
+func (c *CommandAliasCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	command := c.Command[0]
+	if len(c.Command) > 1 {
+		words := make([]string, len(c.Command))
+		for i, word := range c.Command {
+			words[i] = quoteArgument(word)
+		}
+		command = strings.Join(words, " ")
+	}
+
+	err := ee.Parser.AddCommandAlias(c.Name, command)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ee.saveCommandAliases()
+	if err != nil {
+		return nil, fmt.Errorf("cannot save command aliases, %w", err)
+	}
+
+	result := NewExecutionResult()
+	result.AddMessage(fmt.Sprintf("Alias %s added for %s", c.Name, command))
+
+	return result, nil
+}
+
+// quoteArgument quotes a parsed argument value that would otherwise be split or cut short when parsed again
+func quoteArgument(value string) string {
+	if value != "" && !strings.ContainsAny(value, " \t\n\"';\\") {
+		return value
+	}
+
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// CommandUnaliasCommand is a command that removes a command alias
+type CommandUnaliasCommand struct {
+	Name string
+}
+
+// NewCommandUnaliasCommand creates a new command unalias command object
+func NewCommandUnaliasCommand(inv *CommandParseResult) Command {
+	return &CommandUnaliasCommand{Name: *inv.Args["name"]}
+}
+
+// Execute removes the command alias
+func (c *CommandUnaliasCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	if _, ok := ee.Parser.CommandAliases[c.Name]; !ok {
+		return nil, fmt.Errorf("%w: no command alias named %s", cliutil.ErrInvalidParam, c.Name)
+	}
+
+	delete(ee.Parser.CommandAliases, c.Name)
+	err := ee.saveCommandAliases()
+	if err != nil {
+		return nil, fmt.Errorf("cannot save command aliases, %w", err)
+	}
+
+	result := NewExecutionResult()
+	result.AddMessage(fmt.Sprintf("Alias %s removed", c.Name))
+
+	return result, nil
+}
+
+// CommandAliasListCommand is a command that lists the command aliases
+type CommandAliasListCommand struct {
+}
+
+// NewCommandAliasListCommand creates a new command alias list command object
+func NewCommandAliasListCommand(inv *CommandParseResult) Command {
+	return &CommandAliasListCommand{}
+}
+
+// Execute lists the command aliases in order of name
+func (c *CommandAliasListCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	result := NewExecutionResult()
+
+	if len(ee.Parser.CommandAliases) == 0 {
+		result.AddMessage("No command aliases")
+		return result, nil
+	}
+
+	names := make([]string, 0, len(ee.Parser.CommandAliases))
+	for name := range ee.Parser.CommandAliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		result.AddMessage(fmt.Sprintf("%s: %s", name, ee.Parser.CommandAliases[name]))
+	}
+	result.SetField("command_aliases", ee.Parser.CommandAliases)
+
+	return result, nil
+}
@@ -1,26 +1,112 @@
 package cli
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
-	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/btcsuite/btcutil/base58"
 	"github.com/koinos/koinos-cli/internal/cliutil"
+	kjson "github.com/koinos/koinos-proto-golang/encoding/json"
 	"github.com/koinos/koinos-proto-golang/encoding/text"
 	"github.com/koinos/koinos-proto-golang/koinos"
 	"github.com/koinos/koinos-proto-golang/koinos/protocol"
+	util "github.com/koinos/koinos-util-golang"
+	"github.com/shopspring/decimal"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
 	"google.golang.org/protobuf/types/descriptorpb"
 	"google.golang.org/protobuf/types/dynamicpb"
 )
 
+// Options of the commands generated for contract methods
+const (
+	RcLimitOption = "rc-limit"
+	MaxRcOption   = "max-rc"
+	FieldOption   = "field"
+)
+
+// parseCallRcLimit parses the rc limit given with a call, if any, with mana in the given precision
+func parseCallRcLimit(inv *CommandParseResult, precision int) (*rcInfo, error) {
+	value := inv.Options[RcLimitOption]
+	if value == nil {
+		return nil, nil
+	}
+
+	limit, _, err := parseRcLimit(*value, precision)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s%s, %s", cliutil.ErrInvalidParam, OptionPrefix, RcLimitOption, err)
+	}
+
+	// A transaction cannot be submitted without any mana
+	if limit.value == 0 {
+		return nil, fmt.Errorf("%w: %s%s must be greater than 0", cliutil.ErrInvalidParam, OptionPrefix, RcLimitOption)
+	}
+
+	return &limit, nil
+}
+
+// parseCallMaxRc parses the most mana a call may use, if given, with the given precision
+func parseCallMaxRc(inv *CommandParseResult, precision int) (*uint64, error) {
+	value := inv.Options[MaxRcOption]
+	if value == nil {
+		return nil, nil
+	}
+
+	if strings.HasSuffix(*value, "%") {
+		return nil, fmt.Errorf("%w: %s%s must be an amount of mana, not a percent", cliutil.ErrInvalidParam, OptionPrefix, MaxRcOption)
+	}
+
+	limit, _, err := parseRcLimit(*value, precision)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s%s, %s", cliutil.ErrInvalidParam, OptionPrefix, MaxRcOption, err)
+	}
+
+	// No transaction can be submitted without any mana
+	if limit.value == 0 {
+		return nil, fmt.Errorf("%w: %s%s must be greater than 0", cliutil.ErrInvalidParam, OptionPrefix, MaxRcOption)
+	}
+
+	return &limit.value, nil
+}
+
+// optionDescriptions describes the options of commands for help
+var optionDescriptions = map[string]string{
+	RcLimitOption:      "rc limit of this call, as mana or a percent of the available mana, in place of rclimit and method_rclimit",
+	MaxRcOption:        "most mana this call may use, checked with the node before the transaction is broadcast",
+	FieldOption:        "dotted path of the only field of the result to show, such as value or info.name",
+	PasswordFileOption: "file to read the wallet password from, readable only by you, in place of the password",
+	LimitOption:        fmt.Sprintf("most entries to list, from 1 to %d, %d by default", MaxAccountHistoryLimit, DefaultAccountHistoryLimit),
+}
+
+// parseFieldPath parses the dotted path of the field given with a read, if any, returning an empty path to show the
+// whole result
+func parseFieldPath(inv *CommandParseResult) (string, error) {
+	path := inv.Options[FieldOption]
+	if path == nil {
+		return "", nil
+	}
+
+	for _, part := range strings.Split(*path, ".") {
+		if part == "" {
+			return "", fmt.Errorf("%w: invalid field path %s", cliutil.ErrInvalidParam, *path)
+		}
+	}
+
+	return *path, nil
+}
+
 // ----------------------------------------------------------------------------
 // Register Command
 // ----------------------------------------------------------------------------
@@ -39,29 +125,17 @@ func NewRegisterCommand(inv *CommandParseResult) Command {
 
 // Execute closes the wallet
 func (c *RegisterCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
-	if ee.Contracts.Contains(c.Name) {
-		return nil, fmt.Errorf("%w: contract %s already exists", cliutil.ErrContract, c.Name)
-	}
-
-	// Ensure that the name is a valid command name
-	_, err := ee.Parser.parseCommandName([]byte(c.Name))
+	err := checkNewContract(ee, c.Name, c.Address)
 	if err != nil {
-		return nil, fmt.Errorf("%w: invalid characters in contract name %s", cliutil.ErrContract, err)
+		return nil, err
 	}
 
 	// Get the ABI
 	var abiBytes []byte
 	if c.ABIFilename != nil { // If an ABI file was given, use it
-		jsonFile, err := os.Open(*c.ABIFilename)
+		abiBytes, err = readABIFile(*c.ABIFilename)
 		if err != nil {
-			return nil, fmt.Errorf("%w: %s", cliutil.ErrInvalidABI, err)
-		}
-
-		defer jsonFile.Close()
-
-		abiBytes, err = ioutil.ReadAll(jsonFile)
-		if err != nil {
-			return nil, fmt.Errorf("%w: %s", cliutil.ErrInvalidABI, err)
+			return nil, err
 		}
 	} else { // Otherwise ask the RPC server for the ABI
 		if !ee.IsOnline() {
@@ -69,80 +143,689 @@ func (c *RegisterCommand) Execute(ctx context.Context, ee *ExecutionEnvironment)
 		}
 		meta, err := ee.RPCClient.GetContractMeta(ctx, base58.Decode(c.Address))
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("%w: could not fetch contract ABI, %s. Try providing an ABI file", cliutil.ErrInvalidABI, err)
+		}
+
+		if len(meta.GetAbi()) == 0 {
+			return nil, fmt.Errorf("%w: contract %s has no ABI on chain. Try providing an ABI file", cliutil.ErrInvalidABI, c.Address)
 		}
 
 		abiBytes = []byte(meta.GetAbi())
 	}
 
+	err = registerContractABI(ee, c.Name, c.Address, abiBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	er := NewExecutionResult()
+	er.AddMessage(fmt.Sprintf("Contract '%s' at address %s registered", c.Name, c.Address))
+	if warning := emptyContractWarning(ee, c.Name); warning != "" {
+		er.AddMessage(warning)
+	}
+	ee.saveContracts(er)
+
+	return er, nil
+}
+
+// emptyContractWarning returns a warning if the ABI of a registered contract has no methods, so that registering it
+// added no commands, or an empty string otherwise
+func emptyContractWarning(ee *ExecutionEnvironment, name string) string {
+	contract, ok := ee.Contracts.Get(name)
+	if !ok || len(contract.ABI.Methods) > 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("Warning: the ABI of %s has no methods, so no commands were added. Check that it is the contract's complete ABI", name)
+}
+
+// checkNewContract returns an error if a contract cannot be registered under the given name and address
+func checkNewContract(ee *ExecutionEnvironment, name string, address string) error {
+	if ee.Contracts.Contains(name) {
+		return fmt.Errorf("%w: contract %s already exists", cliutil.ErrContract, name)
+	}
+
+	// Ensure that the name is a valid command name
+	_, err := ee.Parser.parseCommandName([]byte(name))
+	if err != nil {
+		return fmt.Errorf("%w: invalid characters in contract name %s", cliutil.ErrContract, err)
+	}
+
+	if err := cliutil.ValidateAddress(address); err != nil {
+		return fmt.Errorf("%w: contract %s, %s", cliutil.ErrContract, name, err)
+	}
+
+	return nil
+}
+
+// registerContractABI parses a contract's ABI, and registers the contract along with a command for each of its methods
+func registerContractABI(ee *ExecutionEnvironment, name string, address string, abiBytes []byte) error {
+	abi, files, err := parseContractABI(abiBytes)
+	if err != nil {
+		return err
+	}
+
+	commands := []*CommandDeclaration{}
+
+	// Iterate through the methods and construct the commands
+	for methodName, method := range abi.Methods {
+		cmd, err := contractMethodCommand(name, methodName, method, files)
+		if err != nil {
+			return err
+		}
+
+		commands = append(commands, cmd)
+	}
+
+	// Register the contract
+	err = ee.Contracts.Add(name, address, abi, files)
+	if err != nil {
+		return err
+	}
+
+	for _, cmd := range commands {
+		ee.Parser.Commands.AddCommand(cmd)
+	}
+
+	return nil
+}
+
+// parseContractABI parses a contract's ABI and the protobuf types it describes
+func parseContractABI(abiBytes []byte) (*ABI, *protoregistry.Files, error) {
 	var abi ABI
-	err = json.Unmarshal(abiBytes, &abi)
+	err := json.Unmarshal(abiBytes, &abi)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %s", cliutil.ErrInvalidABI, err)
+		return nil, nil, fmt.Errorf("%w: %s", cliutil.ErrInvalidABI, err)
 	}
 
 	files, err := abi.GetFiles()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %s", cliutil.ErrInvalidABI, err)
+	}
+
+	return &abi, files, nil
+}
+
+// contractMethodCommand creates the command that calls a method of a contract with the given name
+func contractMethodCommand(name string, methodName string, method *ABIMethod, files *protoregistry.Files) (*CommandDeclaration, error) {
+	d, err := files.FindDescriptorByName(protoreflect.FullName(method.Argument))
+	if err != nil {
+		return nil, fmt.Errorf("%w: method %s takes argument type %s, which is not defined in the ABI types", cliutil.ErrInvalidABI, methodName, method.Argument)
+	}
+
+	md, ok := d.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%w: argument type %s of method %s is not a message", cliutil.ErrInvalidABI, method.Argument, methodName)
+	}
+
+	// Check that every argument can be parsed now, rather than when the method is first called
+	params, err := ParseABIFields(md)
+	if err != nil {
+		return nil, fmt.Errorf("%w: arguments of method %s, %s", cliutil.ErrInvalidABI, methodName, err)
+	}
+
+	d, err = files.FindDescriptorByName(protoreflect.FullName(method.Return))
+	if err != nil {
+		return nil, fmt.Errorf("%w: method %s returns type %s, which is not defined in the ABI types", cliutil.ErrInvalidABI, methodName, method.Return)
+	}
+
+	_, ok = d.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%w: return type %s of method %s is not a message", cliutil.ErrInvalidABI, method.Return, methodName)
+	}
+
+	commandName := fmt.Sprintf("%s.%s", name, methodName)
+
+	// Create the command
+	if method.ReadOnly {
+		decl := NewCommandDeclaration(commandName, method.Description, false, NewReadContractCommand, params...)
+		decl.Options = []CommandArg{*NewCommandArg(FieldOption, StringArg)}
+
+		return decl, nil
+	}
+
+	decl := NewCommandDeclaration(commandName, method.Description, false, NewWriteContractCommand, params...)
+	decl.Options = []CommandArg{*NewCommandArg(RcLimitOption, StringArg), *NewCommandArg(MaxRcOption, StringArg)}
+
+	return decl, nil
+}
+
+// gzipMagic starts gzip compressed files, which ABI files may be
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// gzipExtension is the extension of gzip compressed ABI files, after that of the ABI itself, such as .abi.gz
+const gzipExtension = ".gz"
+
+// maxABISize limits the size of a decompressed ABI file, so that a small compressed file cannot exhaust memory
+const maxABISize = 64 << 20
+
+// readABIFile reads an ABI file, decompressing it if it is gzip compressed
+func readABIFile(filename string) ([]byte, error) {
+	data, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %s", cliutil.ErrInvalidABI, err)
 	}
 
-	commands := []*CommandDeclaration{}
+	if !bytes.HasPrefix(data, gzipMagic) {
+		return data, nil
+	}
 
-	// Iterate through the methods and construct the commands
-	for name, method := range abi.Methods {
-		d, err := files.FindDescriptorByName(protoreflect.FullName(method.Argument))
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", cliutil.ErrInvalidABI, err)
+	}
+	defer reader.Close()
+
+	data, err = ioutil.ReadAll(io.LimitReader(reader, maxABISize+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", cliutil.ErrInvalidABI, err)
+	}
+
+	if len(data) > maxABISize {
+		return nil, fmt.Errorf("%w: decompressed ABI is larger than %d MiB", cliutil.ErrInvalidABI, maxABISize>>20)
+	}
+
+	return data, nil
+}
+
+// abiFileContractName returns the name of a contract registered from an ABI file, the file name without its
+// extension, or extensions for a compressed file
+func abiFileContractName(filename string) string {
+	base := filepath.Base(filename)
+	if strings.EqualFold(filepath.Ext(base), gzipExtension) {
+		base = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// ----------------------------------------------------------------------------
+// Preview ABI Command
+// ----------------------------------------------------------------------------
+
+// PreviewABICommand is a command that shows the commands an ABI file would add, without registering it
+type PreviewABICommand struct {
+	ABIFilename string
+	Name        *string
+}
+
+// NewPreviewABICommand creates a new preview ABI object
+func NewPreviewABICommand(inv *CommandParseResult) Command {
+	return &PreviewABICommand{ABIFilename: *inv.Args["abi-filename"], Name: inv.Args["name"]}
+}
+
+// Execute lists the command each method of the ABI would add, and the methods that could not be called because of
+// unsupported types
+func (c *PreviewABICommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	// Name the commands as register_dir would, unless a name is given
+	name := abiFileContractName(c.ABIFilename)
+	if c.Name != nil {
+		name = *c.Name
+	}
+
+	abiBytes, err := readABIFile(c.ABIFilename)
+	if err != nil {
+		return nil, err
+	}
+
+	abi, files, err := parseContractABI(abiBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	methodNames := make([]string, 0, len(abi.Methods))
+	for methodName := range abi.Methods {
+		methodNames = append(methodNames, methodName)
+	}
+	sort.Strings(methodNames)
+
+	commands := make([]map[string]interface{}, 0, len(methodNames))
+	unsupported := make([]string, 0)
+	lines := make([]string, 0, len(methodNames))
+	for _, methodName := range methodNames {
+		method := abi.Methods[methodName]
+		cmd, err := contractMethodCommand(name, methodName, method, files)
 		if err != nil {
-			return nil, fmt.Errorf("%w: could not find type %s", cliutil.ErrInvalidABI, method.Argument)
+			unsupported = append(unsupported, err.Error())
+			continue
 		}
 
-		md, ok := d.(protoreflect.MessageDescriptor)
-		if !ok {
-			return nil, fmt.Errorf("%w: %s is not a message", cliutil.ErrInvalidABI, method.Argument)
+		kind := "write"
+		if method.ReadOnly {
+			kind = "read"
 		}
 
-		params, err := ParseABIFields(md)
-		if err != nil {
-			return nil, fmt.Errorf("%w: %s", cliutil.ErrInvalidABI, err)
+		line := fmt.Sprintf("%s (%s)", cmd.String(), kind)
+		if cmd.Description != "" {
+			line += " - " + cmd.Description
 		}
+		lines = append(lines, line)
 
-		d, err = files.FindDescriptorByName(protoreflect.FullName(method.Return))
-		if err != nil {
-			return nil, fmt.Errorf("%w: could not find type %s", cliutil.ErrInvalidABI, method.Argument)
+		args := make([]string, len(cmd.Args))
+		for i := range cmd.Args {
+			args[i] = cmd.Args[i].String()
 		}
+		commands = append(commands, map[string]interface{}{"name": cmd.Name, "arguments": args, "read_only": method.ReadOnly, "description": cmd.Description})
+	}
 
-		_, ok = d.(protoreflect.MessageDescriptor)
-		if !ok {
-			return nil, fmt.Errorf("%w: %s is not a message", cliutil.ErrInvalidABI, method.Argument)
+	result := NewExecutionResult()
+	result.AddMessage(fmt.Sprintf("%s would add %d commands, nothing was registered", c.ABIFilename, len(commands)))
+	if len(methodNames) == 0 {
+		result.AddMessage("Warning: the ABI has no methods")
+	}
+	result.AddMessage(lines...)
+	for _, e := range unsupported {
+		result.AddMessage(fmt.Sprintf("Warning: %s", e))
+	}
+	if len(unsupported) > 0 {
+		result.AddMessage(fmt.Sprintf("%d of %d methods cannot be called, so register would fail", len(unsupported), len(methodNames)))
+	}
+
+	result.SetField("commands", commands)
+	result.SetField("unsupported", unsupported)
+
+	return result, nil
+}
+
+// ----------------------------------------------------------------------------
+// Register Dir Command
+// ----------------------------------------------------------------------------
+
+// RegisterDirCommand is a command that registers every contract ABI file in a directory
+type RegisterDirCommand struct {
+	Path string
+}
+
+// NewRegisterDirCommand creates a new register dir object
+func NewRegisterDirCommand(inv *CommandParseResult) Command {
+	return &RegisterDirCommand{Path: *inv.Args["path"]}
+}
+
+// Execute registers the contracts. Each ABI file must give the contract's address in an "address" field, and
+// the contract is named after the file. Files that cannot be registered are skipped with a warning
+func (c *RegisterDirCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	entries, err := ioutil.ReadDir(c.Path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", cliutil.ErrInvalidParam, err)
+	}
+
+	er := NewExecutionResult()
+	registered := 0
+
+	// ReadDir returns the entries sorted by filename, so contracts are registered in a predictable order
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(strings.TrimSuffix(strings.ToLower(entry.Name()), gzipExtension)))
+		if entry.IsDir() || (ext != ".abi" && ext != ".json") {
+			continue
 		}
 
-		commandName := fmt.Sprintf("%s.%s", c.Name, name)
+		name := abiFileContractName(entry.Name())
+		err := registerContractFile(ee, name, filepath.Join(c.Path, entry.Name()))
+		if err != nil {
+			er.AddMessage(fmt.Sprintf("Warning: skipped %s, %s", entry.Name(), err))
+			continue
+		}
 
-		// Create the command
-		var cmd *CommandDeclaration
-		if method.ReadOnly {
-			cmd = NewCommandDeclaration(commandName, method.Description, false, NewReadContractCommand, params...)
-		} else {
-			cmd = NewCommandDeclaration(commandName, method.Description, false, NewWriteContractCommand, params...)
+		if warning := emptyContractWarning(ee, name); warning != "" {
+			er.AddMessage(warning)
 		}
 
-		commands = append(commands, cmd)
+		registered++
 	}
 
-	// Register the contract
-	err = ee.Contracts.Add(c.Name, c.Address, &abi, files)
+	er.AddMessage(fmt.Sprintf("Registered %d contract(s) from %s", registered, c.Path))
+	er.SetField("registered", registered)
+	ee.saveContracts(er)
+	return er, nil
+}
+
+// registerContractFile registers a contract from an ABI file that includes the contract's address
+func registerContractFile(ee *ExecutionEnvironment, name string, filename string) error {
+	abiBytes, err := readABIFile(filename)
+	if err != nil {
+		return err
+	}
+
+	var header struct {
+		Address string `json:"address"`
+	}
+	err = json.Unmarshal(abiBytes, &header)
+	if err != nil {
+		return fmt.Errorf("%w: %s", cliutil.ErrInvalidABI, err)
+	}
+
+	if header.Address == "" {
+		return fmt.Errorf("%w: no contract address given", cliutil.ErrInvalidABI)
+	}
+
+	err = checkNewContract(ee, name, header.Address)
+	if err != nil {
+		return err
+	}
+
+	return registerContractABI(ee, name, header.Address, abiBytes)
+}
+
+// ----------------------------------------------------------------------------
+// Unregister Command
+// ----------------------------------------------------------------------------
+
+// UnregisterCommand is a command that removes a registered contract and its commands
+type UnregisterCommand struct {
+	Name string
+}
+
+// NewUnregisterCommand creates a new unregister object
+func NewUnregisterCommand(inv *CommandParseResult) Command {
+	return &UnregisterCommand{Name: *inv.Args["name"]}
+}
+
+// Execute unregisters the contract
+func (c *UnregisterCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	// A saved contract that could not be registered can still be removed from the contracts file
+	_, unloaded := ee.unloadedContracts[c.Name]
+	delete(ee.unloadedContracts, c.Name)
+	if !ee.Contracts.Remove(c.Name) && !unloaded {
+		return nil, fmt.Errorf("%w: contract %s is not registered", cliutil.ErrContract, c.Name)
+	}
+
+	// Remove all of the generated commands belonging to the contract, and their rc limits
+	for _, name := range contractCommandNames(ee, c.Name) {
+		ee.Parser.Commands.RemoveCommand(name)
+		delete(ee.methodRcLimits, name)
+	}
+
+	er := NewExecutionResult()
+	er.AddMessage(fmt.Sprintf("Contract '%s' unregistered", c.Name))
+	ee.saveContracts(er)
+	return er, nil
+}
+
+// ----------------------------------------------------------------------------
+// Rename Command
+// ----------------------------------------------------------------------------
+
+// RenameCommand is a command that renames a registered contract and its commands
+type RenameCommand struct {
+	OldName string
+	NewName string
+}
+
+// NewRenameCommand creates a new rename object
+func NewRenameCommand(inv *CommandParseResult) Command {
+	return &RenameCommand{OldName: *inv.Args["old-name"], NewName: *inv.Args["new-name"]}
+}
+
+// Execute renames the contract
+func (c *RenameCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	err := ee.Contracts.Rename(c.OldName, c.NewName)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, cmd := range commands {
-		ee.Parser.Commands.AddCommand(cmd)
+	// Replace each of the contract's commands with one under the new name, which keeps its rc limit
+	for _, name := range contractCommandNames(ee, c.OldName) {
+		decl := *ee.Parser.Commands.Name2Command[name]
+		decl.Name = c.NewName + strings.TrimPrefix(name, c.OldName)
+
+		ee.Parser.Commands.RemoveCommand(name)
+		ee.Parser.Commands.AddCommand(&decl)
+
+		if limit, ok := ee.methodRcLimits[name]; ok {
+			delete(ee.methodRcLimits, name)
+			ee.methodRcLimits[decl.Name] = limit
+		}
 	}
 
 	er := NewExecutionResult()
-	er.AddMessage(fmt.Sprintf("Contract '%s' at address %s registered", c.Name, c.Address))
+	er.AddMessage(fmt.Sprintf("Contract '%s' renamed to '%s'", c.OldName, c.NewName))
+	ee.saveContracts(er)
 	return er, nil
 }
 
+// ----------------------------------------------------------------------------
+// Set Contract Address Command
+// ----------------------------------------------------------------------------
+
+// SetContractAddressCommand is a command that changes the address of a registered contract
+type SetContractAddressCommand struct {
+	Name    string
+	Address string
+}
+
+// NewSetContractAddressCommand creates a new set contract address object
+func NewSetContractAddressCommand(inv *CommandParseResult) Command {
+	return &SetContractAddressCommand{Name: *inv.Args["name"], Address: *inv.Args["address"]}
+}
+
+// Execute changes the contract's address. Its commands look up the address when run, so they are left as they are
+func (c *SetContractAddressCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	if err := cliutil.ValidateAddress(c.Address); err != nil {
+		return nil, fmt.Errorf("%w: contract %s, %s", cliutil.ErrContract, c.Name, err)
+	}
+
+	previous, err := ee.Contracts.SetAddress(c.Name, c.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	er := NewExecutionResult()
+	er.AddMessage(fmt.Sprintf("Contract '%s' moved from address %s to %s", c.Name, previous, c.Address))
+	ee.saveContracts(er)
+	return er, nil
+}
+
+// ----------------------------------------------------------------------------
+// List Contracts Command
+// ----------------------------------------------------------------------------
+
+// ListContractsCommand is a command that lists the registered contracts
+type ListContractsCommand struct {
+}
+
+// NewListContractsCommand creates a new list contracts object
+func NewListContractsCommand(inv *CommandParseResult) Command {
+	return &ListContractsCommand{}
+}
+
+// Execute lists the registered contracts
+func (c *ListContractsCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	er := NewExecutionResult()
+
+	// Alphabetize the contracts
+	names := ee.Contracts.Names()
+	if len(names) == 0 {
+		er.AddMessage("No contracts registered")
+		return er, nil
+	}
+
+	table := cliutil.NewTable("NAME", "ADDRESS", "METHODS")
+	for _, name := range names {
+		contract, ok := ee.Contracts.Get(name)
+		if !ok {
+			continue
+		}
+
+		table.AddRow(name, contract.GetAddress(), strconv.Itoa(len(contractCommandNames(ee, name))))
+	}
+
+	er.AddTable(table, ee.color)
+	return er, nil
+}
+
+// contractCommandNames returns the names of the commands generated for the given contract
+func contractCommandNames(ee *ExecutionEnvironment, name string) []string {
+	prefix := name + "."
+	names := make([]string, 0)
+	for _, cmd := range ee.Parser.Commands.Commands {
+		if strings.HasPrefix(cmd.Name, prefix) {
+			names = append(names, cmd.Name)
+		}
+	}
+
+	return names
+}
+
+// contractAddressBytes returns the decoded address of a registered contract, or an error if it is not a valid address
+func contractAddressBytes(contract *ContractInfo) ([]byte, error) {
+	address := contract.GetAddress()
+	if err := cliutil.ValidateAddress(address); err != nil {
+		return nil, fmt.Errorf("%w: registered contract, %s", cliutil.ErrContract, err)
+	}
+
+	return base58.Decode(address), nil
+}
+
+// ----------------------------------------------------------------------------
+// Invoke Command
+// ----------------------------------------------------------------------------
+
+// InvokeCommand is a command that guides the user through calling a method of a registered contract, asking for the
+// method and each of its arguments with their names and types
+type InvokeCommand struct {
+	Contract string
+	Method   *string
+}
+
+// NewInvokeCommand creates a new invoke command object
+func NewInvokeCommand(inv *CommandParseResult) Command {
+	return &InvokeCommand{Contract: *inv.Args["contract"], Method: inv.Args["method"]}
+}
+
+// Execute asks for the method and its arguments, then calls it as the method's own command would. The rpc timeout
+// applies to each rpc call, so the time spent answering does not count against the call
+func (c *InvokeCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	if _, ok := ee.Contracts.Get(c.Contract); !ok {
+		return nil, fmt.Errorf("%w: contract %s is not registered", cliutil.ErrContract, c.Contract)
+	}
+
+	names := contractCommandNames(ee, c.Contract)
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%w: contract %s has no methods", cliutil.ErrContract, c.Contract)
+	}
+	sort.Strings(names)
+
+	var name string
+	var err error
+	if c.Method != nil {
+		name, err = selectMethod(c.Contract, names, *c.Method)
+	} else {
+		name, err = c.pickMethod(ee, names)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	decl := ee.Parser.Commands.Name2Command[name]
+	inv := NewCommandParseResult(name)
+	inv.Decl = decl
+
+	for i := range decl.Args {
+		arg := &decl.Args[i]
+		values, err := askArg(ee, arg)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(values) == 0 {
+			continue
+		}
+
+		inv.Args[arg.Name] = &values[0]
+		if arg.Variadic {
+			inv.VariadicArgs[arg.Name] = values
+		}
+	}
+
+	err = ee.resolveSelfAddresses(inv)
+	if err != nil {
+		return nil, err
+	}
+
+	return decl.Instantiation(inv).Execute(ctx, ee)
+}
+
+// pickMethod lists the contract's methods and asks for one, by number or name
+func (c *InvokeCommand) pickMethod(ee *ExecutionEnvironment, names []string) (string, error) {
+	fmt.Fprintf(ee.Output(), "Methods of %s:\n", c.Contract)
+	for i, name := range names {
+		line := fmt.Sprintf("%d: %s", i+1, strings.TrimPrefix(name, c.Contract+"."))
+		if description := ee.Parser.Commands.Name2Command[name].Description; description != "" {
+			line += " - " + description
+		}
+		fmt.Fprintln(ee.Output(), line)
+	}
+
+	for {
+		answer, err := ee.inputPrompt("Method: ")
+		if err != nil {
+			return "", err
+		}
+
+		name, err := selectMethod(c.Contract, names, answer)
+		if err == nil {
+			return name, nil
+		}
+		fmt.Fprintln(ee.Output(), err)
+	}
+}
+
+// selectMethod returns the command name of a contract's method, given its number in the list of names or its name
+func selectMethod(contract string, names []string, choice string) (string, error) {
+	choice = strings.TrimSpace(choice)
+	if n, err := strconv.Atoi(choice); err == nil {
+		if n < 1 || n > len(names) {
+			return "", fmt.Errorf("%w: choose a method from 1 to %d", cliutil.ErrInvalidParam, len(names))
+		}
+		return names[n-1], nil
+	}
+
+	name := contract + "." + strings.TrimPrefix(choice, contract+".")
+	for _, n := range names {
+		if n == name {
+			return n, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: %s has no method %s", cliutil.ErrInvalidParam, contract, choice)
+}
+
+// askArg asks for the value of an argument, showing its name and type, until a valid value is given
+func askArg(ee *ExecutionEnvironment, arg *CommandArg) ([]string, error) {
+	prompt := fmt.Sprintf("%s (%s", arg.Name, arg.ArgType.String())
+	if arg.Variadic {
+		prompt += ", separated by spaces"
+	}
+	if arg.Optional {
+		prompt += ", optional"
+	}
+	prompt += "): "
+
+	for {
+		answer, err := ee.inputPrompt(prompt)
+		if err != nil {
+			return nil, err
+		}
+
+		values, err := ee.Parser.ParseArgInput(arg, answer)
+		if err == nil {
+			return values, nil
+		}
+		fmt.Fprintln(ee.Output(), err)
+	}
+}
+
+// registeredMethod returns the ABI method of a contract command. The command may outlive its contract, such as when
+// the contract is unregistered earlier on the same line, so a missing method is an error
+func registeredMethod(ee *ExecutionEnvironment, commandName string) (*ABIMethod, error) {
+	method := ee.Contracts.GetMethod(commandName)
+	if method == nil {
+		return nil, fmt.Errorf("%w: %s is no longer registered", cliutil.ErrContract, commandName)
+	}
+
+	return method, nil
+}
+
 // ----------------------------------------------------------------------------
 // Read Contract Command
 // ----------------------------------------------------------------------------
@@ -159,15 +842,25 @@ func NewReadContractCommand(inv *CommandParseResult) Command {
 
 // Execute executes the read contract command
 func (c *ReadContractCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	method, err := registeredMethod(ee, c.ParseResult.CommandName)
+	if err != nil {
+		return nil, err
+	}
+
 	if !ee.IsOnline() {
 		return nil, fmt.Errorf("%w: cannot execute method", cliutil.ErrOffline)
 	}
 
+	field, err := parseFieldPath(c.ParseResult)
+	if err != nil {
+		return nil, err
+	}
+
 	contract := ee.Contracts.GetFromMethodName(c.ParseResult.CommandName)
 
-	entryPoint, err := strconv.ParseUint(ee.Contracts.GetMethod(c.ParseResult.CommandName).EntryPoint[2:], 16, 32)
+	entryPoint, err := cliutil.ParseEntryPoint(method.EntryPoint)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %s", cliutil.ErrInvalidABI, err)
 	}
 
 	// Form a protobuf message from the command input
@@ -183,9 +876,12 @@ func (c *ReadContractCommand) Execute(ctx context.Context, ee *ExecutionEnvironm
 	}
 
 	// Get the contractID
-	contractID := base58.Decode(contract.Address)
+	contractID, err := contractAddressBytes(contract)
+	if err != nil {
+		return nil, err
+	}
 
-	cResp, err := ee.RPCClient.ReadContract(ctx, argBytes, contractID, uint32(entryPoint))
+	cResp, err := ee.RPCClient.ReadContract(ctx, argBytes, contractID, entryPoint)
 	if err != nil {
 		return nil, err
 	}
@@ -209,16 +905,236 @@ func (c *ReadContractCommand) Execute(ctx context.Context, ee *ExecutionEnvironm
 		return nil, err
 	}
 
+	jsonBytes, err := kjson.Marshal(dMsg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Show the fields annotated with units as decimal amounts
+	amounts, err := unitAmounts(dMsg, method.Units)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(amounts) > 0 {
+		er.SetField("amounts", amounts)
+	}
+
+	er.SetField("result", json.RawMessage(jsonBytes))
+
+	if field != "" {
+		value, err := selectResultField(md, jsonBytes, field)
+		if err != nil {
+			return nil, err
+		}
+
+		er.SetField("field", field)
+		er.SetField("value", value)
+		er.AddMessage(formatFieldValue(value, ee.readFormat == JSONReadFormat))
+		return er, nil
+	}
+
+	if ee.readFormat == JSONReadFormat {
+		var b bytes.Buffer
+		err = json.Indent(&b, jsonBytes, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+
+		er.AddMessage(b.String())
+		addAmountMessages(er, amounts)
+		return er, nil
+	}
+
 	b, err := text.MarshalPretty(dMsg)
 	if err != nil {
 		return nil, err
 	}
 
 	er.AddMessage(string(b))
+	addAmountMessages(er, amounts)
 
 	return er, nil
 }
 
+// selectResultField returns the JSON value of a field of a read result, given by dotted path. Fields left at their
+// default value are not in the JSON, so their zero value is returned
+func selectResultField(md protoreflect.MessageDescriptor, jsonBytes []byte, path string) (json.RawMessage, error) {
+	raw := json.RawMessage(jsonBytes)
+	parts := strings.Split(path, ".")
+
+	for i, part := range parts {
+		fd := md.Fields().ByName(protoreflect.Name(part))
+		if fd == nil {
+			return nil, fmt.Errorf("%w: %s has no field %s", cliutil.ErrInvalidParam, md.FullName(), part)
+		}
+
+		last := i == len(parts)-1
+		if !last && (fd.Kind() != protoreflect.MessageKind || fd.IsList() || fd.IsMap()) {
+			return nil, fmt.Errorf("%w: field %s of %s is not a message", cliutil.ErrInvalidParam, part, md.FullName())
+		}
+
+		fields := make(map[string]json.RawMessage)
+		if len(raw) > 0 && string(raw) != "null" {
+			err := json.Unmarshal(raw, &fields)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		value, ok := fields[string(fd.Name())]
+		if !ok {
+			value, ok = fields[fd.JSONName()]
+		}
+
+		// A missing message is read as empty on the way to the field
+		if !ok && last {
+			value = zeroFieldValue(fd)
+		}
+
+		raw = value
+		if !last {
+			md = fd.Message()
+		}
+	}
+
+	return raw, nil
+}
+
+// zeroFieldValue returns the JSON of a field's default value, as it would be encoded if it were set
+func zeroFieldValue(fd protoreflect.FieldDescriptor) json.RawMessage {
+	switch {
+	case fd.IsList():
+		return json.RawMessage("[]")
+	case fd.IsMap():
+		return json.RawMessage("{}")
+	}
+
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return json.RawMessage("{}")
+	case protoreflect.BoolKind:
+		return json.RawMessage("false")
+	case protoreflect.StringKind, protoreflect.BytesKind:
+		return json.RawMessage(`""`)
+	case protoreflect.EnumKind:
+		if values := fd.Enum().Values(); values.Len() > 0 {
+			return json.RawMessage(strconv.Quote(string(values.Get(0).Name())))
+		}
+		return json.RawMessage("0")
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		// 64 bit integers are encoded as strings
+		return json.RawMessage(`"0"`)
+	}
+
+	return json.RawMessage("0")
+}
+
+// formatFieldValue formats a selected field for display. Strings are shown without quotes, so that the value can be
+// used directly in scripts, unless the read format is JSON
+func formatFieldValue(value json.RawMessage, asJSON bool) string {
+	var s string
+	if !asJSON && json.Unmarshal(value, &s) == nil {
+		return s
+	}
+
+	var b bytes.Buffer
+	if json.Indent(&b, value, "", "  ") != nil {
+		return string(value)
+	}
+
+	return b.String()
+}
+
+// unitAmounts returns the decimal amounts, with symbols, of the integer fields of a message annotated with units in the ABI.
+// Fields that are missing or not integers are left as raw values
+func unitAmounts(msg protoreflect.Message, units map[string]*ABIUnit) (map[string]string, error) {
+	amounts := make(map[string]string)
+
+	for name, unit := range units {
+		if unit == nil {
+			continue
+		}
+
+		m, fd, err := findField(msg, name)
+		if err != nil {
+			return nil, err
+		}
+		if fd == nil {
+			continue
+		}
+
+		var dec *decimal.Decimal
+		switch fd.Kind() {
+		case protoreflect.Uint32Kind, protoreflect.Uint64Kind:
+			dec, err = util.SatoshiToDecimal(m.Get(fd).Uint(), unit.Precision)
+		case protoreflect.Int32Kind, protoreflect.Int64Kind:
+			v := m.Get(fd).Int()
+			if v < 0 {
+				dec, err = util.SatoshiToDecimal(uint64(-v), unit.Precision)
+				if err == nil {
+					neg := dec.Neg()
+					dec = &neg
+				}
+			} else {
+				dec, err = util.SatoshiToDecimal(uint64(v), unit.Precision)
+			}
+		default:
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		amounts[name] = strings.TrimSpace(fmt.Sprintf("%v %s", dec, unit.Symbol))
+	}
+
+	return amounts, nil
+}
+
+// findField finds a field of a message by dotted name, returning it along with the message containing it.
+// The field is nil if it does not exist, or is a repeated field. Map fields cannot be looked into, so naming one is an
+// error
+func findField(msg protoreflect.Message, name string) (protoreflect.Message, protoreflect.FieldDescriptor, error) {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		fd := msg.Descriptor().Fields().ByName(protoreflect.Name(part))
+		if fd != nil && fd.IsMap() {
+			return nil, nil, fmt.Errorf("%w: units field %s, %s is a map", cliutil.ErrInvalidABI, name, part)
+		}
+
+		if fd == nil || fd.IsList() {
+			return nil, nil, nil
+		}
+
+		if i == len(parts)-1 {
+			return msg, fd, nil
+		}
+
+		if fd.Kind() != protoreflect.MessageKind {
+			return nil, nil, nil
+		}
+
+		msg = msg.Get(fd).Message()
+	}
+
+	return nil, nil, nil
+}
+
+// addAmountMessages adds a message for each decimal amount, sorted by field name
+func addAmountMessages(er *ExecutionResult, amounts map[string]string) {
+	names := make([]string, 0, len(amounts))
+	for name := range amounts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		er.AddMessage(fmt.Sprintf("%s: %s", name, amounts[name]))
+	}
+}
+
 func DecodeMessageBytes(dMsg *dynamicpb.Message, md protoreflect.MessageDescriptor) error {
 	l := md.Fields().Len()
 	for i := 0; i < l; i++ {
@@ -294,21 +1210,46 @@ func NewWriteContractCommand(inv *CommandParseResult) Command {
 
 // Execute executes the write contract command
 func (c *WriteContractCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	method, err := registeredMethod(ee, c.ParseResult.CommandName)
+	if err != nil {
+		return nil, err
+	}
+
 	if !ee.IsWalletOpen() {
 		return nil, fmt.Errorf("%w: cannot execute method", cliutil.ErrWalletClosed)
 	}
 
-	if !ee.IsOnline() && !ee.Session.IsValid() {
+	if !ee.CanSubmit() {
 		return nil, fmt.Errorf("%w: cannot execute method", cliutil.ErrOffline)
 	}
 
-	contract := ee.Contracts.GetFromMethodName(c.ParseResult.CommandName)
+	callLimit, err := parseCallRcLimit(c.ParseResult, ee.koinPrecision)
+	if err != nil {
+		return nil, err
+	}
 
-	entryPoint, err := strconv.ParseUint(ee.Contracts.GetMethod(c.ParseResult.CommandName).EntryPoint[2:], 16, 32)
+	maxMana, err := parseCallMaxRc(c.ParseResult, ee.koinPrecision)
 	if err != nil {
 		return nil, err
 	}
 
+	// A session submits its operations together, under the rclimit
+	if ee.Session.IsValid() {
+		if callLimit != nil {
+			return nil, fmt.Errorf("%w: %s%s cannot be used in a session", cliutil.ErrInvalidParam, OptionPrefix, RcLimitOption)
+		}
+		if maxMana != nil {
+			return nil, fmt.Errorf("%w: %s%s cannot be used in a session", cliutil.ErrInvalidParam, OptionPrefix, MaxRcOption)
+		}
+	}
+
+	contract := ee.Contracts.GetFromMethodName(c.ParseResult.CommandName)
+
+	entryPoint, err := cliutil.ParseEntryPoint(method.EntryPoint)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", cliutil.ErrInvalidABI, err)
+	}
+
 	// Form a protobuf message from the command input
 	msg, err := ParseResultToMessage(c.ParseResult, ee.Contracts)
 	if err != nil {
@@ -316,7 +1257,10 @@ func (c *WriteContractCommand) Execute(ctx context.Context, ee *ExecutionEnviron
 	}
 
 	// Get the contractID
-	contractID := base58.Decode(contract.Address)
+	contractID, err := contractAddressBytes(contract)
+	if err != nil {
+		return nil, err
+	}
 
 	args, err := proto.Marshal(msg)
 	if err != nil {
@@ -327,7 +1271,7 @@ func (c *WriteContractCommand) Execute(ctx context.Context, ee *ExecutionEnviron
 		Op: &protocol.Operation_CallContract{
 			CallContract: &protocol.CallContractOperation{
 				ContractId: contractID,
-				EntryPoint: uint32(entryPoint),
+				EntryPoint: entryPoint,
 				Args:       args,
 			},
 		},
@@ -345,7 +1289,11 @@ func (c *WriteContractCommand) Execute(ctx context.Context, ee *ExecutionEnviron
 		result.AddMessage("Adding operation to transaction session")
 	}
 	if err != nil {
+		restore := ee.overrideRcLimit(c.ParseResult.CommandName, callLimit)
+		ee.maxMana = maxMana
 		err := ee.SubmitTransaction(ctx, result, op)
+		ee.maxMana = nil
+		restore()
 		if err != nil {
 			return result, fmt.Errorf("cannot make call, %w", err)
 		}
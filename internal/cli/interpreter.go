@@ -1,10 +1,22 @@
 package cli
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -26,6 +38,18 @@ const (
 	AutoChainID    = "auto"
 )
 
+// DefaultRPCTimeout is the default time each rpc call may spend waiting on the endpoint
+const DefaultRPCTimeout = time.Second * 30
+
+// Formats for the results of contract reads
+const (
+	TextReadFormat = "text"
+	JSONReadFormat = "json"
+)
+
+// DefaultWaitInterval is the default time between checks for a submitted transaction's inclusion in a block
+const DefaultWaitInterval = time.Second
+
 // Command is the interface that all commands must implement
 type Command interface {
 	Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error)
@@ -35,6 +59,9 @@ type Command interface {
 type ExecutionResult struct {
 	Message      []string
 	ErrorMessage []string
+
+	// Fields holds typed values for structured (JSON) output
+	Fields map[string]interface{}
 }
 
 // NewExecutionResult creates a new execution result object
@@ -48,71 +75,947 @@ func (er *ExecutionResult) AddMessage(m ...string) {
 	er.Message = append(er.Message, m...)
 }
 
-func (er *ExecutionResult) AddErrorMessage(m ...string) {
-	er.ErrorMessage = append(er.ErrorMessage, m...)
+// AddTable adds the lines of a table to the execution result, with its header emphasized if color is enabled
+func (er *ExecutionResult) AddTable(table *cliutil.Table, color bool) {
+	er.AddMessage(table.Lines(color)...)
+}
+
+func (er *ExecutionResult) AddErrorMessage(m ...string) {
+	er.ErrorMessage = append(er.ErrorMessage, m...)
+}
+
+// SetField sets a typed value in the execution result for structured output
+func (er *ExecutionResult) SetField(key string, value interface{}) {
+	if er.Fields == nil {
+		er.Fields = make(map[string]interface{})
+	}
+
+	er.Fields[key] = value
+}
+
+// Print prints each message in the execution result
+func (er *ExecutionResult) Print() {
+	for _, m := range er.Message {
+		fmt.Println(m)
+	}
+}
+
+// PrintError prints each error message in the execution result
+func (er *ExecutionResult) PrintError() {
+	for _, m := range er.ErrorMessage {
+		fmt.Println(m)
+	}
+}
+
+// rcFractionPrecision is the precision of a relative rc limit, which is a fraction of the available mana
+const rcFractionPrecision = 8
+
+type rcInfo struct {
+	value    uint64
+	absolute bool
+}
+
+// methodRcLimit is the rc limit used for a transaction calling a contract method, set by method_rclimit or given with
+// the call
+type methodRcLimit struct {
+	command string // How to raise the limit, suggested when the transaction runs out of mana
+	limit   rcInfo
+}
+
+// promptBalanceInfo caches the balance shown in the interactive prompt, which is refreshed in the background
+type promptBalanceInfo struct {
+	mu         sync.Mutex
+	interval   time.Duration // Zero hides the balance
+	address    string
+	display    string
+	updated    time.Time
+	refreshing bool
+}
+
+type nonceInfo struct {
+	currentNonce uint64
+	nonceTime    time.Time
+}
+
+// openWallet is a wallet file held open in memory, with its decrypted keys and the one in use
+type openWallet struct {
+	file string
+	keys []cliutil.WalletKey
+	key  *util.KoinosKey
+}
+
+// ExecutionEnvironment is a struct that holds the environment for command execution.
+type ExecutionEnvironment struct {
+	RPCClient *cliutil.KoinosRPCClient
+	Key       *util.KoinosKey
+	Parser    *CommandParser
+	Contracts Contracts
+	Session   *TransactionSession
+	nonceMap  map[string]*nonceInfo
+	nonceMode string
+	rcLimit   rcInfo
+	payer     string
+	chainID   string
+
+	walletFile  string
+	walletKeys  []cliutil.WalletKey
+	walletLabel string
+	wallets     map[string]*openWallet
+
+	// Signs transactions in place of the open wallet's key, see SetSigner
+	signer cliutil.Signer
+
+	// The key derivation cost of new wallet files, set with wallet_kdf. Nil uses the default
+	walletKDF *cliutil.KDFParams
+
+	// Rc limits of contract methods that replace rcLimit when they are called, by command name, and the override
+	// of the transaction being submitted, if any
+	methodRcLimits  map[string]rcInfo
+	rcLimitOverride *methodRcLimit
+
+	// The most mana the transaction being submitted may use, given with --max-rc, if any
+	maxMana *uint64
+
+	// Whether a payer other than the sender must sign transactions, rather than authorizing them on its own
+	payerSigns bool
+
+	// The last signed transaction submitted for broadcast, which resubmit sends again unchanged
+	lastTransaction *protocol.Transaction
+
+	// The balance of the open wallet shown in the interactive prompt, see PromptBalance
+	promptBalance promptBalanceInfo
+
+	// Whether output such as table headers may be colored, see SetColor
+	color bool
+
+	// Where results are written, standard output and any file set with SetOutputFile
+	output     io.Writer
+	outputFile *os.File
+
+	lockTimeout time.Duration
+	rpcTimeout  time.Duration
+	dryRun      bool
+	parseOnly   bool
+
+	// Whether transactions must be confirmed before they are submitted, and how to ask
+	confirm       bool
+	assumeYes     bool
+	confirmPrompt func(prompt string) (bool, error)
+
+	// How commands that guide the user, such as invoke, ask for input
+	inputPrompt func(prompt string) (string, error)
+
+	waitTimeout  time.Duration
+	waitInterval time.Duration
+
+	readFormat string
+
+	// The resolved command line options the CLI was started with, for the config command
+	config []*cliutil.ConfigOption
+
+	// The selected network preset, and the custom presets from the config file
+	network  string
+	networks map[string]*cliutil.NetworkPreset
+
+	koinSymbol    string
+	koinPrecision int
+
+	// The entry point called to read KOIN balances, for chains where it differs from mainnet
+	koinBalanceOfEntry uint32
+
+	// How amounts such as balances are shown, set with number_format
+	numberFormat cliutil.NumberFormat
+
+	addressBookFile  string
+	commandAliasFile string
+	contractsFile    string
+
+	// Saved contracts that could not be registered, which are kept in the contracts file
+	unloadedContracts map[string]*cliutil.SavedContract
+
+	// The running command's context, see interruptibleContext
+	commandCtx context.Context
+
+	// When running commands must stop, see SetMaxDuration. Zero is unbounded
+	deadline time.Time
+
+	// The transactions submitted during this session, oldest first
+	history []*SubmittedTransaction
+
+	// The chain ID fetched from the node, cached for the client it was fetched with
+	autoChainID       []byte
+	autoChainIDClient *cliutil.KoinosRPCClient
+}
+
+// NewExecutionEnvironment creates a new ExecutionEnvironment object
+func NewExecutionEnvironment(rpcClient *cliutil.KoinosRPCClient, parser *CommandParser) *ExecutionEnvironment {
+	return &ExecutionEnvironment{
+		RPCClient: rpcClient,
+		Parser:    parser,
+		Contracts: make(map[string]*ContractInfo),
+		Session:   &TransactionSession{},
+		nonceMap:  make(map[string]*nonceInfo),
+		wallets:   make(map[string]*openWallet),
+		rcLimit:   rcInfo{value: 10000000, absolute: false},
+		payer:     SelfPayer,
+		chainID:   AutoChainID,
+		nonceMode: AutoNonce,
+
+		methodRcLimits: make(map[string]rcInfo),
+		payerSigns:     true,
+
+		rpcTimeout:   DefaultRPCTimeout,
+		waitInterval: DefaultWaitInterval,
+		readFormat:   TextReadFormat,
+
+		confirmPrompt: cliutil.ReadConfirmation,
+		inputPrompt:   cliutil.ReadInput,
+
+		koinSymbol:    cliutil.KoinSymbol,
+		koinPrecision: cliutil.KoinPrecision,
+
+		koinBalanceOfEntry: TokenBalanceOfEntry,
+		numberFormat:       cliutil.DefaultNumberFormat,
+	}
+}
+
+// SetAddressBookFile loads the address book aliases from the given file, and saves changes to them there
+func (ee *ExecutionEnvironment) SetAddressBookFile(filename string) error {
+	aliases, err := cliutil.LoadAddressBook(filename)
+	if err != nil {
+		return err
+	}
+
+	ee.addressBookFile = filename
+	ee.Parser.Aliases = aliases
+	return nil
+}
+
+// saveAddressBook saves the address book aliases, if they were loaded from a file
+func (ee *ExecutionEnvironment) saveAddressBook() error {
+	if ee.addressBookFile == "" {
+		return nil
+	}
+
+	return cliutil.SaveAddressBook(ee.addressBookFile, ee.Parser.Aliases)
+}
+
+// SetCommandAliasFile loads the command aliases from the given file, and saves changes to them there
+func (ee *ExecutionEnvironment) SetCommandAliasFile(filename string) error {
+	aliases, err := cliutil.LoadCommandAliases(filename)
+	if err != nil {
+		return err
+	}
+
+	// Check every alias as if it were added by the alias command, keeping the current aliases if any are invalid
+	previous := ee.Parser.CommandAliases
+	ee.Parser.CommandAliases = make(map[string]string)
+	for name, command := range aliases {
+		err = ee.Parser.AddCommandAlias(name, command)
+		if err != nil {
+			ee.Parser.CommandAliases = previous
+			return fmt.Errorf("invalid command alias file %s, %w", filename, err)
+		}
+	}
+
+	ee.commandAliasFile = filename
+	return nil
+}
+
+// saveCommandAliases saves the command aliases, if they were loaded from a file
+func (ee *ExecutionEnvironment) saveCommandAliases() error {
+	if ee.commandAliasFile == "" {
+		return nil
+	}
+
+	return cliutil.SaveCommandAliases(ee.commandAliasFile, ee.Parser.CommandAliases)
+}
+
+// SetContractsFile registers the contracts saved in the given file, and saves changes to the registered contracts there.
+// Saved contracts that can no longer be registered are skipped, and reported in the returned error
+func (ee *ExecutionEnvironment) SetContractsFile(filename string) error {
+	saved, err := cliutil.LoadContracts(filename)
+	if err != nil {
+		return err
+	}
+
+	ee.contractsFile = filename
+	ee.unloadedContracts = make(map[string]*cliutil.SavedContract)
+
+	names := make([]string, 0, len(saved))
+	for name := range saved {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	skipped := make([]string, 0)
+	for _, name := range names {
+		contract := saved[name]
+		err := checkNewContract(ee, name, contract.Address)
+		if err == nil && contract.Token != nil {
+			err = registerToken(ee, name, contract.Address, contract.Token.Precision, contract.Token.Symbol)
+		} else if err == nil {
+			err = registerContractABI(ee, name, contract.Address, contract.ABI)
+		}
+		if err != nil {
+			ee.unloadedContracts[name] = contract
+			skipped = append(skipped, fmt.Sprintf("%s (%s)", name, err))
+		}
+	}
+
+	if len(skipped) > 0 {
+		return fmt.Errorf("%w: skipped saved contracts in %s: %s", cliutil.ErrContract, filename, strings.Join(skipped, ", "))
+	}
+
+	return nil
+}
+
+// saveContracts saves the registered contracts, if they were loaded from a file, along with the saved contracts that
+// could not be registered. The change to the contracts was already made, so a failure to save it is only a warning
+func (ee *ExecutionEnvironment) saveContracts(result *ExecutionResult) {
+	if ee.contractsFile == "" {
+		return
+	}
+
+	err := ee.writeContractsFile()
+	if err != nil {
+		result.AddMessage(fmt.Sprintf("Warning: cannot save contracts to %s, %s", ee.contractsFile, err))
+	}
+}
+
+// writeContractsFile writes the registered contracts and the unloaded ones to the contracts file
+func (ee *ExecutionEnvironment) writeContractsFile() error {
+	saved := make(map[string]*cliutil.SavedContract)
+	for name, contract := range ee.unloadedContracts {
+		saved[name] = contract
+	}
+
+	for _, name := range ee.Contracts.Names() {
+		contract, ok := ee.Contracts.Get(name)
+		if !ok {
+			continue
+		}
+
+		// Tokens are registered without an ABI, so their units are saved instead
+		if contract.ABI == nil {
+			if contract.Units == nil {
+				continue
+			}
+
+			token := &cliutil.SavedToken{Precision: contract.Units.Precision, Symbol: contract.Units.Symbol}
+			saved[name] = &cliutil.SavedContract{Address: contract.GetAddress(), Token: token}
+			continue
+		}
+
+		abiBytes, err := json.Marshal(contract.ABI)
+		if err != nil {
+			return err
+		}
+
+		saved[name] = &cliutil.SavedContract{Address: contract.GetAddress(), ABI: abiBytes}
+	}
+
+	return cliutil.SaveContracts(ee.contractsFile, saved)
+}
+
+// OpenWallet opens a wallet
+func (ee *ExecutionEnvironment) OpenWallet(key *util.KoinosKey) {
+	ee.Key = key
+	if w, ok := ee.wallets[ee.walletLabel]; ok {
+		w.key = key
+	}
+}
+
+// writeWalletFile writes a wallet file with the key derivation cost set by wallet_kdf. If none was set, an existing
+// file keeps its cost
+func (ee *ExecutionEnvironment) writeWalletFile(filename string, passphrase string, keys []cliutil.WalletKey) error {
+	if ee.walletKDF == nil {
+		return cliutil.WriteWalletKeysFile(filename, passphrase, keys)
+	}
+
+	return cliutil.WriteWalletKeysFileWithKDF(filename, passphrase, keys, *ee.walletKDF)
+}
+
+// SetSigner makes an external signer, such as a hardware wallet, sign transactions in place of the open wallet's key.
+// Transactions are still built for the open wallet, so the signer must hold the key of its address. A nil signer
+// restores signing with the key
+func (ee *ExecutionEnvironment) SetSigner(signer cliutil.Signer) {
+	ee.signer = signer
+}
+
+// transactionSigner returns the signer for transactions, which is the open wallet's key unless SetSigner was used
+func (ee *ExecutionEnvironment) transactionSigner() cliutil.Signer {
+	if ee.signer != nil {
+		return ee.signer
+	}
+
+	return cliutil.NewKeySigner(ee.Key)
+}
+
+// submissionSigner returns the signer of new transactions. When a separate payer must sign, its key is taken from
+// the open wallets, and it signs after the sender
+func (ee *ExecutionEnvironment) submissionSigner() (cliutil.Signer, error) {
+	signer := ee.transactionSigner()
+	if ee.IsSelfPaying() || !ee.payerSigns {
+		return signer, nil
+	}
+
+	payer := ee.GetPayerAddress()
+	if bytes.Equal(payer, signer.AddressBytes()) {
+		return signer, nil
+	}
+
+	key := ee.payerKey()
+	if key == nil {
+		return nil, fmt.Errorf("%w: no open wallet holds the key of payer %s, open one that does, or use 'payer %s false' if the payer authorizes transactions without a signature", cliutil.ErrWalletClosed, ee.payer, ee.payer)
+	}
+
+	return cliutil.NewCoSigner(signer, cliutil.NewKeySigner(key)), nil
+}
+
+// payerKey returns the key of the payer from the open wallets, or nil if none holds it
+func (ee *ExecutionEnvironment) payerKey() *util.KoinosKey {
+	payer := ee.GetPayerAddress()
+
+	keys := make([]cliutil.WalletKey, 0)
+	keys = append(keys, ee.walletKeys...)
+	for _, w := range ee.wallets {
+		if bytes.Equal(w.key.AddressBytes(), payer) {
+			return w.key
+		}
+		keys = append(keys, w.keys...)
+	}
+
+	for _, k := range keys {
+		key, err := util.NewKoinosKeyFromBytes(k.PrivateKey)
+		if err == nil && bytes.Equal(key.AddressBytes(), payer) {
+			return key
+		}
+	}
+
+	return nil
+}
+
+// CloseWallet closes the wallet, along with every other open wallet file
+func (ee *ExecutionEnvironment) CloseWallet() {
+	ee.Key = nil
+	ee.signer = nil
+	ee.walletFile = ""
+	ee.walletKeys = nil
+	ee.walletLabel = ""
+	ee.wallets = make(map[string]*openWallet)
+}
+
+// setWalletKeys records the wallet file and labeled keys backing the open wallet
+func (ee *ExecutionEnvironment) setWalletKeys(filename string, keys []cliutil.WalletKey) {
+	ee.walletFile = filename
+	ee.walletKeys = keys
+	if w, ok := ee.wallets[ee.walletLabel]; ok {
+		w.file = filename
+		w.keys = keys
+	}
+}
+
+// activeWalletKey returns the wallet file entry of the active key, or nil if there is none
+func (ee *ExecutionEnvironment) activeWalletKey() *cliutil.WalletKey {
+	if ee.Key == nil {
+		return nil
+	}
+
+	for i := range ee.walletKeys {
+		if bytes.Equal(ee.walletKeys[i].PrivateKey, ee.Key.PrivateBytes()) {
+			return &ee.walletKeys[i]
+		}
+	}
+
+	return nil
+}
+
+// openWalletFile keeps a wallet file open alongside any others, and makes it the active wallet with the given key.
+// The wallet is labeled with the file's name, or its full path if another open wallet file has the same name
+func (ee *ExecutionEnvironment) openWalletFile(filename string, keys []cliutil.WalletKey, key *util.KoinosKey) string {
+	label := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	if w, ok := ee.wallets[label]; ok && w.file != filename {
+		label = filename
+	}
+
+	ee.wallets[label] = &openWallet{file: filename, keys: keys, key: key}
+	ee.useWallet(label)
+
+	return label
+}
+
+// useWallet makes the open wallet file with the given label the active wallet
+func (ee *ExecutionEnvironment) useWallet(label string) bool {
+	w, ok := ee.wallets[label]
+	if !ok {
+		return false
+	}
+
+	ee.walletLabel = label
+	ee.Key = w.key
+	ee.walletFile = w.file
+	ee.walletKeys = w.keys
+
+	return true
+}
+
+// resolveSelfAddresses replaces the SelfKeyword in the address arguments of a command with the address of the open
+// wallet
+func (ee *ExecutionEnvironment) resolveSelfAddresses(inv *CommandParseResult) error {
+	for _, arg := range inv.Decl.Args {
+		if arg.ArgType != AddressArg {
+			continue
+		}
+
+		values := inv.VariadicArgs[arg.Name]
+		if !arg.Variadic {
+			if inv.Args[arg.Name] == nil {
+				continue
+			}
+			values = []string{*inv.Args[arg.Name]}
+		}
+
+		for i := range values {
+			if values[i] != SelfKeyword {
+				continue
+			}
+
+			if !ee.IsWalletOpen() {
+				return fmt.Errorf("%w: open a wallet to use %s as the %s", cliutil.ErrWalletClosed, SelfKeyword, arg.Name)
+			}
+
+			values[i] = base58.Encode(ee.Key.AddressBytes())
+		}
+
+		if len(values) > 0 {
+			inv.Args[arg.Name] = &values[0]
+		}
+	}
+
+	return nil
+}
+
+// SetPromptBalanceInterval sets how often the balance shown in the interactive prompt is refreshed, zero hides it
+func (ee *ExecutionEnvironment) SetPromptBalanceInterval(interval time.Duration) {
+	pb := &ee.promptBalance
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+
+	pb.interval = interval
+	pb.updated = time.Time{}
+}
+
+// GetPromptBalanceInterval returns how often the balance shown in the interactive prompt is refreshed, zero if hidden
+func (ee *ExecutionEnvironment) GetPromptBalanceInterval() time.Duration {
+	pb := &ee.promptBalance
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+
+	return pb.interval
+}
+
+// PromptBalance returns the KOIN balance of the open wallet to show in the interactive prompt, or an empty string if
+// it is hidden or not known yet. A stale balance is refreshed in the background, so the prompt never waits on the node.
+// The caller must hold the lock the inactivity timer closes the wallet with
+func (ee *ExecutionEnvironment) PromptBalance() string {
+	pb := &ee.promptBalance
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+
+	// Work from a single read of the key, which is nil once the wallet is closed
+	key := ee.Key
+	if pb.interval == 0 || key == nil || !ee.IsOnline() {
+		return ""
+	}
+
+	// Forget the balance of another wallet
+	address := base58.Encode(key.AddressBytes())
+	if address != pb.address {
+		pb.address = address
+		pb.display = ""
+		pb.updated = time.Time{}
+	}
+
+	if !pb.refreshing && time.Since(pb.updated) >= pb.interval {
+		pb.refreshing = true
+		ctx, cancel := ee.rpcContext(context.Background())
+		go ee.refreshPromptBalance(ctx, cancel, ee.RPCClient, address, ee.koinBalanceOfEntry, ee.koinPrecision, ee.koinSymbol, ee.numberFormat)
+	}
+
+	return pb.display
+}
+
+// refreshPromptBalance fetches the balance shown in the interactive prompt. Failures keep the previous balance until
+// the next refresh
+func (ee *ExecutionEnvironment) refreshPromptBalance(ctx context.Context, cancel context.CancelFunc, client *cliutil.KoinosRPCClient, address string, entryPoint uint32, precision int, symbol string, format cliutil.NumberFormat) {
+	defer cancel()
+
+	display := ""
+	balance, err := retrieveBalance(ctx, client, base58.Decode(cliutil.KoinContractID), base58.Decode(address), entryPoint)
+	if err == nil {
+		var dec *decimal.Decimal
+		dec, err = util.SatoshiToDecimal(*balance, precision)
+		if err == nil {
+			display = fmt.Sprintf("%s %s", format.Format(*dec), symbol)
+		}
+	}
+
+	pb := &ee.promptBalance
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+
+	pb.refreshing = false
+	pb.updated = time.Now()
+	if err == nil && pb.address == address {
+		pb.display = display
+	}
+}
+
+// GetLockTimeout returns the inactivity period after which the wallet is closed, zero if disabled
+func (ee *ExecutionEnvironment) GetLockTimeout() time.Duration {
+	return ee.lockTimeout
+}
+
+// SetRPCTimeout sets the time each rpc call may spend waiting on the endpoint, zero disables the timeout
+func (ee *ExecutionEnvironment) SetRPCTimeout(timeout time.Duration) {
+	ee.rpcTimeout = timeout
+}
+
+// SetColor sets whether output such as table headers may use terminal colors. It should be disabled when the output
+// is not a terminal
+func (ee *ExecutionEnvironment) SetColor(color bool) {
+	ee.color = color
+}
+
+// SetOutputFile writes results to the file as well as standard output, appending to it or replacing its contents.
+// The file is created if needed, readable only by the user since results may include keys
+func (ee *ExecutionEnvironment) SetOutputFile(filename string, appendOutput bool) error {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if appendOutput {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+
+	file, err := os.OpenFile(filename, flags, 0600)
+	if err != nil {
+		return fmt.Errorf("could not open output file, %w", err)
+	}
+
+	ee.CloseOutputFile()
+	ee.outputFile = file
+	ee.output = io.MultiWriter(os.Stdout, file)
+	return nil
+}
+
+// CloseOutputFile stops writing results to the output file, if any
+func (ee *ExecutionEnvironment) CloseOutputFile() error {
+	if ee.outputFile == nil {
+		return nil
+	}
+
+	err := ee.outputFile.Close()
+	ee.outputFile = nil
+	ee.output = nil
+	return err
+}
+
+// Output returns where results should be written
+func (ee *ExecutionEnvironment) Output() io.Writer {
+	if ee.output == nil {
+		return os.Stdout
+	}
+
+	return ee.output
+}
+
+// SetMaxDuration bounds how long commands may run from now, cancelling any command still running when the time is up
+// and failing those run after. A zero duration removes the bound
+func (ee *ExecutionEnvironment) SetMaxDuration(d time.Duration) {
+	if d == 0 {
+		ee.deadline = time.Time{}
+		return
+	}
+
+	ee.deadline = time.Now().Add(d)
+}
+
+// MaxDurationExceeded returns whether the maximum duration set with SetMaxDuration has passed
+func (ee *ExecutionEnvironment) MaxDurationExceeded() bool {
+	return !ee.deadline.IsZero() && !time.Now().Before(ee.deadline)
+}
+
+// SetWait sets how long submitted transactions are waited on to be included in a block, and how often
+// their inclusion is checked. A zero timeout disables waiting
+func (ee *ExecutionEnvironment) SetWait(timeout time.Duration, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultWaitInterval
+	}
+
+	ee.waitTimeout = timeout
+	ee.waitInterval = interval
+}
+
+// SetReadFormat sets the format contract read results are shown in, either TextReadFormat or JSONReadFormat
+func (ee *ExecutionEnvironment) SetReadFormat(format string) error {
+	if format != TextReadFormat && format != JSONReadFormat {
+		return fmt.Errorf("%w: format must be %s or %s", cliutil.ErrInvalidParam, TextReadFormat, JSONReadFormat)
+	}
+
+	ee.readFormat = format
+	return nil
+}
+
+// SetKoinUnits sets the precision and symbol KOIN amounts are shown with
+func (ee *ExecutionEnvironment) SetKoinUnits(precision int, symbol string) error {
+	if precision < 0 || precision > 18 {
+		return fmt.Errorf("%w: precision must be between 0 and 18", cliutil.ErrInvalidParam)
+	}
+
+	ee.koinPrecision = precision
+	ee.koinSymbol = symbol
+	return nil
+}
+
+// SetNumberFormat sets how amounts such as balances are shown
+func (ee *ExecutionEnvironment) SetNumberFormat(format cliutil.NumberFormat) {
+	ee.numberFormat = format
+}
+
+// formatAmount shows an amount with its symbol in the number format
+func (ee *ExecutionEnvironment) formatAmount(amount *decimal.Decimal, symbol string) string {
+	return fmt.Sprintf("%s %s", ee.numberFormat.Format(*amount), symbol)
+}
+
+// SetKoinBalanceOfEntry sets the entry point called to read KOIN balances
+func (ee *ExecutionEnvironment) SetKoinBalanceOfEntry(entryPoint uint32) {
+	ee.koinBalanceOfEntry = entryPoint
+}
+
+// balanceOfEntry returns the entry point called to read balances from a token contract, which is only overridden
+// for KOIN
+func (ee *ExecutionEnvironment) balanceOfEntry(contractID []byte) uint32 {
+	if bytes.Equal(contractID, base58.Decode(cliutil.KoinContractID)) {
+		return ee.koinBalanceOfEntry
+	}
+
+	return TokenBalanceOfEntry
+}
+
+// SetChainID sets the chain id used to sign transactions, given in base64, or AutoChainID to ask the node for it
+func (ee *ExecutionEnvironment) SetChainID(id string) error {
+	if id != AutoChainID {
+		chainID, err := base64.URLEncoding.DecodeString(id)
+		if err != nil || len(chainID) == 0 {
+			return fmt.Errorf("%w: chain id must either be a base64 string or \"%s\"", cliutil.ErrInvalidParam, AutoChainID)
+		}
+	}
+
+	ee.chainID = id
+	return nil
+}
+
+// SetNetworks sets the custom network presets, which take priority over the built in ones
+func (ee *ExecutionEnvironment) SetNetworks(networks map[string]*cliutil.NetworkPreset) {
+	ee.networks = networks
+}
+
+// UseNetwork sets the chain id and KOIN units of the named network preset, returning the preset so that the
+// caller can connect to its RPC endpoint
+func (ee *ExecutionEnvironment) UseNetwork(name string) (*cliutil.NetworkPreset, error) {
+	preset, err := cliutil.GetNetwork(name, ee.networks)
+	if err != nil {
+		return nil, err
+	}
+
+	chainID := preset.ChainID
+	if chainID == "" {
+		chainID = AutoChainID
+	}
+
+	err = ee.SetChainID(chainID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ee.SetKoinUnits(preset.Precision, preset.Symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	ee.network = name
+	return preset, nil
+}
+
+// SetConfig records the resolved command line options, to be shown by the config command
+func (ee *ExecutionEnvironment) SetConfig(options []*cliutil.ConfigOption) {
+	ee.config = options
+}
+
+// waitForTransaction waits for a submitted transaction to be included in a block, if waiting is enabled.
+// Waiting is bounded by the wait timeout, as well as each of its rpc calls by the rpc timeout. Once included, the receipt's events are shown
+func (ee *ExecutionEnvironment) waitForTransaction(receipt *protocol.TransactionReceipt, entry *SubmittedTransaction, result *ExecutionResult) error {
+	if ee.waitTimeout == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ee.interruptibleContext(), ee.waitTimeout)
+	defer cancel()
+
+	blockID, err := ee.RPCClient.WaitForTransaction(ctx, receipt.GetId(), ee.waitInterval)
+	if err != nil {
+		entry.Status = TransactionNotIncluded
+		return err
+	}
+
+	entry.Status = TransactionIncluded
+	entry.BlockID = "0x" + hex.EncodeToString(blockID)
+
+	result.AddMessage(fmt.Sprintf("Transaction included in block 0x%s", hex.EncodeToString(blockID)))
+	result.SetField("block_id", "0x"+hex.EncodeToString(blockID))
+	ee.addEvents(receipt.GetEvents(), result)
+
+	return nil
+}
+
+// Statuses of a submitted transaction
+const (
+	TransactionSubmitted   = "submitted"
+	TransactionIncluded    = "included"
+	TransactionNotIncluded = "not included"
+	TransactionReverted    = "reverted"
+	TransactionNotFound    = "not found"
+)
+
+// SubmittedTransaction is a record of a transaction submitted during this session
+type SubmittedTransaction struct {
+	ID         string    `json:"id"`
+	Time       time.Time `json:"time"`
+	Operations []string  `json:"operations"`
+	Reverted   bool      `json:"reverted"`
+	Status     string    `json:"status"`
+	BlockID    string    `json:"block_id,omitempty"`
 }
 
-// Print prints each message in the execution result
-func (er *ExecutionResult) Print() {
-	for _, m := range er.Message {
-		fmt.Println(m)
+// recordTransaction adds a submitted transaction to the session history
+func (ee *ExecutionEnvironment) recordTransaction(receipt *protocol.TransactionReceipt, ops []*protocol.Operation) *SubmittedTransaction {
+	entry := &SubmittedTransaction{
+		ID:         "0x" + hex.EncodeToString(receipt.GetId()),
+		Time:       time.Now(),
+		Operations: make([]string, len(ops)),
+		Reverted:   receipt.GetReverted(),
+		Status:     TransactionSubmitted,
 	}
-}
 
-// PrintError prints each error message in the execution result
-func (er *ExecutionResult) PrintError() {
-	for _, m := range er.ErrorMessage {
-		fmt.Println(m)
+	for i, op := range ops {
+		entry.Operations[i] = describeOperation(op, ee.Contracts)
 	}
+
+	ee.history = append(ee.history, entry)
+	return entry
 }
 
-type rcInfo struct {
-	value    uint64
-	absolute bool
+// EventOutput is the structured output of an event emitted by a transaction
+type EventOutput struct {
+	Source   string          `json:"source"`
+	Name     string          `json:"name"`
+	Contract string          `json:"contract,omitempty"`
+	Data     json.RawMessage `json:"data,omitempty"`
+	Raw      string          `json:"raw,omitempty"`
 }
 
-type nonceInfo struct {
-	currentNonce uint64
-	nonceTime    time.Time
+// addEvents adds the events of a receipt to the result, decoded with the ABI of the registered contract that emitted them.
+// Events from other contracts are shown as raw bytes
+func (ee *ExecutionEnvironment) addEvents(events []*protocol.EventData, result *ExecutionResult) {
+	if len(events) == 0 {
+		return
+	}
+
+	outputs := make([]*EventOutput, len(events))
+	result.AddMessage("Events:")
+	for i, event := range events {
+		output := &EventOutput{Source: base58.Encode(event.GetSource()), Name: event.GetName()}
+
+		contract, data, err := ee.Contracts.DecodeEvent(event)
+		if err == nil {
+			output.Contract = contract
+			output.Data = json.RawMessage(data)
+			result.AddMessage(fmt.Sprintf("%d: %s %s %s", i, contract, event.GetName(), data))
+		} else {
+			output.Raw = "0x" + hex.EncodeToString(event.GetData())
+			result.AddMessage(fmt.Sprintf("%d: %s %s %s", i, output.Source, event.GetName(), output.Raw))
+		}
+
+		outputs[i] = output
+	}
+
+	result.SetField("events", outputs)
 }
 
-// ExecutionEnvironment is a struct that holds the environment for command execution.
-type ExecutionEnvironment struct {
-	RPCClient *cliutil.KoinosRPCClient
-	Key       *util.KoinosKey
-	Parser    *CommandParser
-	Contracts Contracts
-	Session   *TransactionSession
-	nonceMap  map[string]*nonceInfo
-	nonceMode string
-	rcLimit   rcInfo
-	payer     string
-	chainID   string
+// commandContext creates the context for a single command execution. Each rpc call made with it is bounded by the rpc
+// timeout, while prompts and signer approvals in between are not. Interrupting with Ctrl-C cancels the command, and
+// interrupting again before it finishes exits the CLI
+func (ee *ExecutionEnvironment) commandContext() (context.Context, context.CancelFunc) {
+	var parent context.Context
+	var cancel context.CancelFunc
+	if ee.deadline.IsZero() {
+		parent, cancel = context.WithCancel(context.Background())
+	} else {
+		parent, cancel = context.WithDeadline(context.Background(), ee.deadline)
+	}
+	stop := cancelOnInterrupt(cancel)
+
+	ctx, rpcCancel := ee.rpcContext(parent)
+	ee.commandCtx = ctx
+	return ctx, func() {
+		rpcCancel()
+		stop()
+		cancel()
+		ee.commandCtx = nil
+	}
 }
 
-// NewExecutionEnvironment creates a new ExecutionEnvironment object
-func NewExecutionEnvironment(rpcClient *cliutil.KoinosRPCClient, parser *CommandParser) *ExecutionEnvironment {
-	return &ExecutionEnvironment{
-		RPCClient: rpcClient,
-		Parser:    parser,
-		Contracts: make(map[string]*ContractInfo),
-		Session:   &TransactionSession{},
-		nonceMap:  make(map[string]*nonceInfo),
-		rcLimit:   rcInfo{value: 10000000, absolute: false},
-		payer:     SelfPayer,
-		chainID:   AutoChainID,
-		nonceMode: AutoNonce,
+// interruptibleContext returns the running command's context, for work that is not given it, such as waiting for a
+// transaction. It is cancelled when the command is interrupted
+func (ee *ExecutionEnvironment) interruptibleContext() context.Context {
+	if ee.commandCtx == nil {
+		return context.Background()
 	}
+
+	return ee.commandCtx
 }
 
-// OpenWallet opens a wallet
-func (ee *ExecutionEnvironment) OpenWallet(key *util.KoinosKey) {
-	ee.Key = key
+// cancelOnInterrupt calls cancel when the process is interrupted with Ctrl-C, and exits if it is interrupted again.
+// The returned function stops handling interrupts
+func cancelOnInterrupt(cancel context.CancelFunc) func() {
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case <-interrupt:
+			fmt.Fprintln(os.Stderr, "Cancelling the command, press Ctrl-C again to exit")
+			cancel()
+		case <-done:
+			return
+		}
+
+		select {
+		case <-interrupt:
+			os.Exit(ExitInterrupted)
+		case <-done:
+		}
+	}()
+
+	return func() {
+		signal.Stop(interrupt)
+		close(done)
+	}
 }
 
-// CloseWallet closes the wallet
-func (ee *ExecutionEnvironment) CloseWallet() {
-	ee.Key = nil
+// rpcContext creates a context from the parent in which each rpc call is bounded by the rpc timeout
+func (ee *ExecutionEnvironment) rpcContext(parent context.Context) (context.Context, context.CancelFunc) {
+	return context.WithCancel(cliutil.WithRPCTimeout(parent, ee.rpcTimeout))
 }
 
 // IsSelfPaying returns a bool representing whether or not the user is self paying
@@ -134,6 +1037,11 @@ func (ee *ExecutionEnvironment) SetPayer(payer string) {
 	ee.payer = payer
 }
 
+// SetPayerSigns sets whether a payer other than the sender must sign transactions with its key from an open wallet
+func (ee *ExecutionEnvironment) SetPayerSigns(signs bool) {
+	ee.payerSigns = signs
+}
+
 // ResetNonce resets the nonce
 func (ee *ExecutionEnvironment) ResetNonce() {
 	if nInfo, exists := ee.nonceMap[string(ee.Key.AddressBytes())]; exists {
@@ -184,19 +1092,39 @@ func (ee *ExecutionEnvironment) IsChainIDAuto() bool {
 	return ee.chainID == AutoChainID
 }
 
-// GetChainID returns the current chain ID
+// GetChainID returns the current chain ID. When automatic, it is fetched from the node once per connection
 func (ee *ExecutionEnvironment) GetChainID(ctx context.Context) ([]byte, error) {
-	if ee.IsChainIDAuto() {
-		return ee.RPCClient.GetChainID(ctx)
+	if !ee.IsChainIDAuto() {
+		return base64.URLEncoding.DecodeString(ee.chainID)
+	}
+
+	if !ee.IsOnline() {
+		return nil, fmt.Errorf("%w: cannot fetch chain id, set it with chain_id", cliutil.ErrOffline)
+	}
+
+	if ee.autoChainIDClient == ee.RPCClient && len(ee.autoChainID) > 0 {
+		return ee.autoChainID, nil
+	}
+
+	chainID, err := ee.RPCClient.GetChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(chainID) == 0 {
+		return nil, fmt.Errorf("%w: node returned an empty chain id", cliutil.ErrInvalidResponse)
 	}
 
-	return base64.URLEncoding.DecodeString(ee.chainID)
+	ee.autoChainID = chainID
+	ee.autoChainIDClient = ee.RPCClient
+	return chainID, nil
 }
 
 // GetRcLimit returns the current RC limit
 func (ee *ExecutionEnvironment) GetRcLimit(ctx context.Context) (uint64, error) {
-	if ee.rcLimit.absolute {
-		return ee.rcLimit.value, nil
+	rcLimit := ee.currentRcLimit()
+	if rcLimit.absolute {
+		return rcLimit.value, nil
 	}
 
 	// else it's relative
@@ -205,18 +1133,18 @@ func (ee *ExecutionEnvironment) GetRcLimit(ctx context.Context) (uint64, error)
 		return 0, err
 	}
 
-	decLimit, err := util.SatoshiToDecimal(limit, 8)
+	decLimit, err := util.SatoshiToDecimal(limit, ee.koinPrecision)
 	if err != nil {
 		return 0, err
 	}
 
-	decVal, err := util.SatoshiToDecimal(ee.rcLimit.value, 8)
+	decVal, err := util.SatoshiToDecimal(rcLimit.value, rcFractionPrecision)
 	if err != nil {
 		return 0, err
 	}
 
 	decResult := decLimit.Mul(*decVal)
-	res, err := util.DecimalToSatoshi(&decResult, 8)
+	res, err := util.DecimalToSatoshi(&decResult, ee.koinPrecision)
 	if err != nil {
 		return 0, err
 	}
@@ -224,17 +1152,116 @@ func (ee *ExecutionEnvironment) GetRcLimit(ctx context.Context) (uint64, error)
 	return res, nil
 }
 
+// rcLimitString shows an rc limit as mana, with the network's KOIN precision, or as a percent of the available mana
+func (ee *ExecutionEnvironment) rcLimitString(limit rcInfo) (string, error) {
+	if limit.absolute {
+		dec, err := util.SatoshiToDecimal(limit.value, ee.koinPrecision)
+		if err != nil {
+			return "", err
+		}
+		return dec.String(), nil
+	}
+
+	dec, err := util.SatoshiToDecimal(limit.value, rcFractionPrecision)
+	if err != nil {
+		return "", err
+	}
+	return dec.Mul(decimal.NewFromInt(100)).String() + "%", nil
+}
+
+// currentRcLimit returns the rc limit of the transaction being submitted, the method's override if it has one
+func (ee *ExecutionEnvironment) currentRcLimit() rcInfo {
+	if ee.rcLimitOverride != nil {
+		return ee.rcLimitOverride.limit
+	}
+
+	return ee.rcLimit
+}
+
+// overrideRcLimit uses the rc limit given with a call of the method, if not nil, or else the one set for the method
+// with method_rclimit, if any, for transactions submitted until the returned function is called
+func (ee *ExecutionEnvironment) overrideRcLimit(method string, callLimit *rcInfo) func() {
+	if callLimit != nil {
+		ee.rcLimitOverride = &methodRcLimit{command: fmt.Sprintf("%s ... %s%s", method, OptionPrefix, RcLimitOption), limit: *callLimit}
+	} else if limit, ok := ee.methodRcLimits[method]; ok {
+		ee.rcLimitOverride = &methodRcLimit{command: "method_rclimit " + method, limit: limit}
+	} else {
+		return func() {}
+	}
+
+	return func() {
+		ee.rcLimitOverride = nil
+	}
+}
+
 // SubmitTransaction is a utility function to submit a transaction from a command
 func (ee *ExecutionEnvironment) SubmitTransaction(ctx context.Context, result *ExecutionResult, ops ...*protocol.Operation) error {
+	if ee.parseOnly {
+		result.AddMessage(fmt.Sprintf("Parse only, transaction with %d operations was not submitted:", len(ops)))
+		operations := make([]string, len(ops))
+		for i, op := range ops {
+			operations[i] = describeOperation(op, ee.Contracts)
+			result.AddMessage(fmt.Sprintf("%d: %s", i, operations[i]))
+		}
+		result.SetField("operations", operations)
+		result.SetField("parse_only", true)
+		return nil
+	}
+
+	// Nothing is broadcast in a dry run, so there is nothing to confirm
+	if !ee.dryRun {
+		err := ee.confirmOperations(ops)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Check the payer can sign before using a nonce
+	signer, err := ee.submissionSigner()
+	if err != nil {
+		return err
+	}
+
 	// Fetch the nonce
 	subParams, err := ee.GetSubmissionParams(ctx)
 	if err != nil {
 		return err
 	}
 
-	receipt, err := ee.RPCClient.SubmitTransactionOpsWithPayer(ctx, ops, ee.Key, subParams, ee.GetPayerAddress(), true)
+	transaction, err := ee.RPCClient.CreateTransactionOpsWithPayer(ctx, ops, signer, subParams, ee.GetPayerAddress())
+	if err != nil {
+		ee.ResetNonce()
+		return err
+	}
+
+	// A transaction over the call's maximum is never broadcast. The rc limit already caps it when no higher than the maximum
+	if ee.maxMana != nil && !ee.dryRun && subParams.RCLimit > *ee.maxMana {
+		err = ee.checkMaxMana(ctx, transaction)
+		if err != nil {
+			ee.ResetNonce()
+			return err
+		}
+	}
+
+	// Keep the signed transaction, so that if the node does not answer it can be sent again without using a new nonce
+	if !ee.dryRun {
+		ee.lastTransaction = transaction
+	}
+
+	receipt, err := ee.RPCClient.SubmitTransaction(ctx, transaction, !ee.dryRun)
+	if ee.dryRun {
+		// The transaction was never broadcast, so the nonce was not used
+		ee.ResetNonce()
+	}
 	if err != nil {
 		ee.ResetNonce()
+		var rpcErr cliutil.KoinosRPCError
+		if errors.As(err, &rpcErr) {
+			// The node answered and rejected the transaction, so there is nothing to send again
+			ee.forgetTransaction(transaction)
+		} else if !ee.dryRun {
+			result.AddErrorMessage(fmt.Sprintf("Transaction 0x%s may have been received by the node. Use resubmit to send the same transaction again, rather than running the command again", hex.EncodeToString(transaction.Id)))
+		}
 		if err.Error() == "insufficient rc" {
 			err2 := ee.createInsufficientRCMessage(ctx, result)
 			if err2 != nil {
@@ -244,23 +1271,79 @@ func (ee *ExecutionEnvironment) SubmitTransaction(ctx context.Context, result *E
 		return err
 	}
 
-	result.AddMessage(cliutil.TransactionReceiptToString(receipt, len(ops)))
+	if ee.dryRun {
+		manaDec, err := util.SatoshiToDecimal(receipt.RcUsed, ee.koinPrecision)
+		if err != nil {
+			return err
+		}
 
-	return nil
+		result.AddMessage(fmt.Sprintf("Dry run, transaction was not broadcast. Estimated mana cost: %v (Disk: %d, Network: %d, Compute: %d)", manaDec, receipt.DiskStorageUsed, receipt.NetworkBandwidthUsed, receipt.ComputeBandwidthUsed))
+		result.SetField("mana_used", manaDec.String())
+		result.SetField("dry_run", true)
+		return nil
+	}
+
+	// The node has the transaction, as the receipt shows
+	ee.forgetTransaction(transaction)
+
+	result.AddMessage(cliutil.TransactionReceiptToString(receipt, len(ops), ee.koinPrecision))
+	result.SetField("transaction_id", "0x"+hex.EncodeToString(receipt.Id))
+	result.SetField("reverted", receipt.Reverted)
+
+	entry := ee.recordTransaction(receipt, ops)
+	return ee.waitForTransaction(receipt, entry, result)
+}
+
+// checkMaxMana has the node check the transaction without broadcasting it, and rejects it if it would use more than the
+// most mana allowed for it
+func (ee *ExecutionEnvironment) checkMaxMana(ctx context.Context, transaction *protocol.Transaction) error {
+	receipt, err := ee.RPCClient.SubmitTransaction(ctx, transaction, false)
+	if err != nil {
+		return err
+	}
+
+	if receipt.GetRcUsed() <= *ee.maxMana {
+		return nil
+	}
+
+	manaDec, err := util.SatoshiToDecimal(receipt.RcUsed, ee.koinPrecision)
+	if err != nil {
+		return err
+	}
+
+	maxDec, err := util.SatoshiToDecimal(*ee.maxMana, ee.koinPrecision)
+	if err != nil {
+		return err
+	}
+
+	return fmt.Errorf("%w: estimated mana cost %v is more than %s%s %v", cliutil.ErrMaxRcExceeded, manaDec, OptionPrefix, MaxRcOption, maxDec)
+}
+
+// forgetTransaction stops keeping the transaction for resubmit, once the node has answered for it
+func (ee *ExecutionEnvironment) forgetTransaction(transaction *protocol.Transaction) {
+	if ee.lastTransaction == transaction {
+		ee.lastTransaction = nil
+	}
 }
 
 func (ee *ExecutionEnvironment) createInsufficientRCMessage(ctx context.Context, result *ExecutionResult) error {
-	if ee.rcLimit.absolute {
+	rcLimit := ee.currentRcLimit()
+	command := "rclimit"
+	if ee.rcLimitOverride != nil {
+		command = ee.rcLimitOverride.command
+	}
+
+	if rcLimit.absolute {
 		rc, err := ee.RPCClient.GetAccountRc(ctx, ee.Key.AddressBytes())
 		if err != nil {
 			return err
 		}
-		if ee.rcLimit.value < rc {
-			decValue, err := util.SatoshiToDecimal(ee.rcLimit.value, cliutil.KoinPrecision)
+		if rcLimit.value < rc {
+			decValue, err := util.SatoshiToDecimal(rcLimit.value, ee.koinPrecision)
 			if err != nil {
 				return err
 			}
-			decRc, err := util.SatoshiToDecimal(rc, cliutil.KoinPrecision)
+			decRc, err := util.SatoshiToDecimal(rc, ee.koinPrecision)
 			if err != nil {
 				return err
 			}
@@ -272,13 +1355,13 @@ func (ee *ExecutionEnvironment) createInsufficientRCMessage(ctx context.Context,
 			}
 
 			result.AddErrorMessage(fmt.Sprintf("Current RC limit: %v, RC available: %v", decValue, decRc))
-			result.AddErrorMessage(fmt.Sprintf("Try using a higher RC limit. Example: rclimit %v", suggestVal))
+			result.AddErrorMessage(fmt.Sprintf("Try using a higher RC limit. Example: %s %v", command, suggestVal))
 		} else {
 			result.AddErrorMessage("You are already using the maximum RC limit, more RC is required to submit this transaction.")
 		}
 	} else {
-		if ee.rcLimit.value < 100000000 {
-			decAmount, err := util.SatoshiToDecimal(ee.rcLimit.value, cliutil.KoinPrecision)
+		if rcLimit.value < 100000000 {
+			decAmount, err := util.SatoshiToDecimal(rcLimit.value, rcFractionPrecision)
 			resultVal := decimal.NewFromFloat(100).Mul(*decAmount)
 			if err != nil {
 				return err
@@ -291,7 +1374,7 @@ func (ee *ExecutionEnvironment) createInsufficientRCMessage(ctx context.Context,
 			}
 
 			result.AddErrorMessage(fmt.Sprintf("Current rc limit: %v%%", resultVal))
-			result.AddErrorMessage(fmt.Sprintf("Try using a higher RC limit. Example: rclimit %v%%", suggestVal))
+			result.AddErrorMessage(fmt.Sprintf("Try using a higher RC limit. Example: %s %v%%", command, suggestVal))
 		} else {
 			result.AddErrorMessage("You are already using the maximum RC limit, more RC is required to submit this transaction.")
 		}
@@ -312,9 +1395,15 @@ func (ee *ExecutionEnvironment) GetSubmissionParams(ctx context.Context) (*cliut
 		return nil, err
 	}
 
+	chainID, err := ee.GetChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	return &cliutil.SubmissionParams{
 		Nonce:   nonce,
 		RCLimit: rcLimit,
+		ChainID: chainID,
 	}, nil
 }
 
@@ -328,7 +1417,62 @@ func (ee *ExecutionEnvironment) IsOnline() bool {
 	return ee.RPCClient != nil
 }
 
+// CanSubmit returns true if operations can be submitted, or added to a session, or shown in parse only mode
+func (ee *ExecutionEnvironment) CanSubmit() bool {
+	return ee.IsOnline() || ee.Session.IsValid() || ee.parseOnly
+}
+
+// SetDryRun sets whether transactions are checked by the node without being broadcast
+func (ee *ExecutionEnvironment) SetDryRun(enabled bool) {
+	ee.dryRun = enabled
+}
+
+// SetParseOnly sets whether the operations of transactions are only shown, without contacting the node
+func (ee *ExecutionEnvironment) SetParseOnly(enabled bool) {
+	ee.parseOnly = enabled
+}
+
+// SetConfirm sets whether the operations of transactions are shown and must be confirmed before they are submitted
+func (ee *ExecutionEnvironment) SetConfirm(enabled bool) {
+	ee.confirm = enabled
+}
+
+// SetAssumeYes sets whether confirmations are skipped, as if the user always answered yes
+func (ee *ExecutionEnvironment) SetAssumeYes(yes bool) {
+	ee.assumeYes = yes
+}
+
+// confirmOperations shows the operations of a transaction and asks the user to confirm them, when confirmation is enabled.
+// Anything but yes cancels the transaction
+func (ee *ExecutionEnvironment) confirmOperations(ops []*protocol.Operation) error {
+	if !ee.confirm || ee.assumeYes {
+		return nil
+	}
+
+	fmt.Fprintf(ee.Output(), "Transaction with %d operations:\n", len(ops))
+	for i, op := range ops {
+		fmt.Fprintf(ee.Output(), "%d: %s\n", i, describeOperation(op, ee.Contracts))
+	}
+
+	confirmed, err := ee.confirmPrompt("Type yes to submit: ")
+	if err != nil {
+		return err
+	}
+
+	if !confirmed {
+		return cliutil.ErrNotConfirmed
+	}
+
+	return nil
+}
+
 func (ee *ExecutionEnvironment) CreateSignedTransaction(ctx context.Context, ops ...*protocol.Operation) (*protocol.Transaction, error) {
+	// Check the payer can sign before using a nonce
+	signer, err := ee.submissionSigner()
+	if err != nil {
+		return nil, err
+	}
+
 	nonce, err := ee.GetNextNonce(ctx, true)
 	if err != nil {
 		return nil, err
@@ -346,7 +1490,7 @@ func (ee *ExecutionEnvironment) CreateSignedTransaction(ctx context.Context, ops
 
 	payer := ee.GetPayerAddress()
 
-	txn, err := cliutil.CreateSignedTransaction(ctx, ops, ee.Key, nonce, rcLimit, chainID, payer)
+	txn, err := cliutil.CreateSignedTransaction(ctx, ops, signer, nonce, rcLimit, chainID, payer)
 	if err != nil {
 		return nil, fmt.Errorf("cannot submit transaction session, %w", err)
 	}
@@ -360,7 +1504,8 @@ type CommandDeclaration struct {
 	Description   string
 	Instantiation func(*CommandParseResult) Command
 	Args          []CommandArg
-	Hidden        bool // If true, the command is not shown in the help
+	Options       []CommandArg // Named options, given as --name value before, among, or after the arguments
+	Hidden        bool         // If true, the command is not shown in the help
 }
 
 func (d *CommandDeclaration) String() string {
@@ -368,6 +1513,9 @@ func (d *CommandDeclaration) String() string {
 	for _, arg := range d.Args {
 		s += fmt.Sprintf(" %s", arg.String())
 	}
+	for _, option := range d.Options {
+		s += fmt.Sprintf(" [%s%s:%s]", OptionPrefix, option.Name, option.ArgType.String())
+	}
 
 	return s
 }
@@ -377,7 +1525,7 @@ func NewCommandDeclaration(name string, description string, hidden bool,
 	instantiation func(*CommandParseResult) Command, args ...CommandArg) *CommandDeclaration {
 	// Ensure optionals are only at the end
 	req := true
-	for _, arg := range args {
+	for i, arg := range args {
 		if !arg.Optional {
 			if !req {
 				return nil
@@ -385,6 +1533,16 @@ func NewCommandDeclaration(name string, description string, hidden bool,
 		} else {
 			req = false
 		}
+
+		// Ensure only the last argument is variadic
+		if arg.Variadic && i != len(args)-1 {
+			return nil
+		}
+
+		// Only a single optional value can have a default
+		if arg.Default != nil && (!arg.Optional || arg.Variadic) {
+			return nil
+		}
 	}
 
 	return &CommandDeclaration{
@@ -401,6 +1559,8 @@ type CommandArg struct {
 	Name     string
 	ArgType  CommandArgType
 	Optional bool
+	Variadic bool    // If true, the argument consumes every remaining value of the command
+	Default  *string // If not nil, the value of an optional argument that is not given
 }
 
 // NewCommandArg creates a new command argument
@@ -421,8 +1581,54 @@ func NewOptionalCommandArg(name string, argType CommandArgType) *CommandArg {
 	}
 }
 
+// NewDefaultCommandArg creates a new optional command argument, which takes the default value when not given
+func NewDefaultCommandArg(name string, argType CommandArgType, defaultValue string) *CommandArg {
+	return &CommandArg{
+		Name:     name,
+		ArgType:  argType,
+		Optional: true,
+		Default:  &defaultValue,
+	}
+}
+
+// NewVariadicCommandArg creates a new command argument taking one or more values. It must be the last argument
+func NewVariadicCommandArg(name string, argType CommandArgType) *CommandArg {
+	return &CommandArg{
+		Name:     name,
+		ArgType:  argType,
+		Optional: false,
+		Variadic: true,
+	}
+}
+
+// NewOptionalVariadicCommandArg creates a new command argument taking zero or more values. It must be the last argument
+func NewOptionalVariadicCommandArg(name string, argType CommandArgType) *CommandArg {
+	return &CommandArg{
+		Name:     name,
+		ArgType:  argType,
+		Optional: true,
+		Variadic: true,
+	}
+}
+
+// defaultValue returns a copy of the argument's default value, or nil if it has none
+func (arg *CommandArg) defaultValue() *string {
+	if arg.Default == nil {
+		return nil
+	}
+
+	value := *arg.Default
+	return &value
+}
+
 func (arg *CommandArg) String() string {
 	filling := fmt.Sprintf("%s:%s", arg.Name, arg.ArgType.String())
+	if arg.Variadic {
+		filling += "..."
+	}
+	if arg.Default != nil {
+		filling += "=" + *arg.Default
+	}
 	var val string
 	if arg.Optional {
 		val = "[" + filling + "]"
@@ -433,15 +1639,85 @@ func (arg *CommandArg) String() string {
 	return val
 }
 
+// Exit codes returned by the CLI when running commands non-interactively
+const (
+	ExitSuccess      = 0
+	ExitFailure      = 1
+	ExitParseError   = 2
+	ExitWalletError  = 3
+	ExitNetworkError = 4
+	ExitTimeout      = 124 // The code of the timeout utility when the time is up
+	ExitInterrupted  = 130 // The conventional code of a process stopped by Ctrl-C
+)
+
+// ExitCode returns the process exit code for the given command error
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitSuccess
+	}
+
+	switch {
+	case errors.Is(err, cliutil.ErrInvalidCommandName),
+		errors.Is(err, cliutil.ErrUnknownCommand),
+		errors.Is(err, cliutil.ErrNotEnoughArguments),
+		errors.Is(err, cliutil.ErrMissingParam),
+		errors.Is(err, cliutil.ErrInvalidParam),
+		errors.Is(err, cliutil.ErrInvalidString):
+		return ExitParseError
+
+	case errors.Is(err, cliutil.ErrWalletClosed),
+		errors.Is(err, cliutil.ErrWalletDecrypt),
+		errors.Is(err, cliutil.ErrWalletExists),
+		errors.Is(err, cliutil.ErrEmptyPassphrase),
+		errors.Is(err, cliutil.ErrBlankPassword),
+		errors.Is(err, cliutil.ErrPasswordMismatch),
+		errors.Is(err, cliutil.ErrInvalidPrivateKey),
+		errors.Is(err, cliutil.ErrInvalidMnemonic):
+		return ExitWalletError
+
+	case errors.Is(err, cliutil.ErrInterrupted):
+		return ExitInterrupted
+
+	case errors.Is(err, cliutil.ErrMaxDuration):
+		return ExitTimeout
+
+	case errors.Is(err, cliutil.ErrOffline),
+		errors.Is(err, cliutil.ErrUnreachable),
+		errors.Is(err, cliutil.ErrInvalidResponse),
+		errors.Is(err, cliutil.ErrTransactionNotIncluded),
+		errors.Is(err, context.DeadlineExceeded):
+		return ExitNetworkError
+	}
+
+	var rpcErr cliutil.KoinosRPCError
+	var netErr net.Error
+	if errors.As(err, &rpcErr) || errors.As(err, &netErr) {
+		return ExitNetworkError
+	}
+
+	return ExitFailure
+}
+
+// CommandOutput is the structured output of a single command
+type CommandOutput struct {
+	Command  string                 `json:"command,omitempty"`
+	Messages []string               `json:"messages"`
+	Error    string                 `json:"error,omitempty"`
+	Fields   map[string]interface{} `json:"fields,omitempty"`
+	Err      error                  `json:"-"`
+}
+
 // InterpretResults is a struct that holds the results of a multi-command interpretation
 type InterpretResults struct {
 	Results []string
+	Outputs []*CommandOutput
 }
 
 // NewInterpretResults creates a new InterpretResults object
 func NewInterpretResults() *InterpretResults {
 	ir := &InterpretResults{}
 	ir.Results = make([]string, 0)
+	ir.Outputs = make([]*CommandOutput, 0)
 	return ir
 }
 
@@ -450,16 +1726,63 @@ func (ir *InterpretResults) AddResult(result ...string) {
 	ir.Results = append(ir.Results, result...)
 }
 
+// AddOutput adds the structured output of a command to the InterpretResults
+func (ir *InterpretResults) AddOutput(output ...*CommandOutput) {
+	ir.Outputs = append(ir.Outputs, output...)
+}
+
+// HasError returns true if any of the interpreted commands failed
+func (ir *InterpretResults) HasError() bool {
+	for _, o := range ir.Outputs {
+		if o.Error != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Err returns the error of the first interpreted command that failed, or nil if none failed
+func (ir *InterpretResults) Err() error {
+	for _, o := range ir.Outputs {
+		if o.Err != nil {
+			return o.Err
+		}
+	}
+
+	return nil
+}
+
 // Print prints the results of a command interpretation
 func (ir *InterpretResults) Print() {
+	ir.Fprint(os.Stdout)
+}
+
+// Fprint writes the results of a command interpretation to w
+func (ir *InterpretResults) Fprint(w io.Writer) {
 	for _, result := range ir.Results {
-		fmt.Println(result)
+		fmt.Fprintln(w, result)
 	}
 
 	// If there were results, skip a line at the end for readability
 	if len(ir.Results) > 0 {
-		fmt.Println("")
+		fmt.Fprintln(w, "")
+	}
+}
+
+// FprintJSON writes the structured outputs of a command interpretation to w as a JSON array
+func (ir *InterpretResults) FprintJSON(w io.Writer) {
+	fprintJSON(w, ir.Outputs)
+}
+
+func fprintJSON(w io.Writer, v interface{}) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		fmt.Fprintln(w, err)
+		return
 	}
+
+	fmt.Fprintln(w, string(b))
 }
 
 // Interpret interprets and executes the results of a command parse
@@ -467,16 +1790,36 @@ func (pr *ParseResults) Interpret(ee *ExecutionEnvironment) *InterpretResults {
 	output := NewInterpretResults()
 
 	for _, inv := range pr.CommandResults {
-		cmd := inv.Instantiate()
-		result, err := cmd.Execute(context.Background(), ee)
+		var result *ExecutionResult
+		var err error
+		if ee.MaxDurationExceeded() {
+			err = fmt.Errorf("%w: %s was not run", cliutil.ErrMaxDuration, inv.CommandName)
+		} else if err = ee.resolveSelfAddresses(inv); err == nil {
+			cmd := inv.Instantiate()
+			ctx, cancel := ee.commandContext()
+			result, err = cmd.Execute(ctx, ee)
+			if err != nil && errors.Is(ctx.Err(), context.Canceled) {
+				err = fmt.Errorf("%w: %s", cliutil.ErrInterrupted, err)
+			} else if err != nil && ee.MaxDurationExceeded() {
+				err = fmt.Errorf("%w: %s", cliutil.ErrMaxDuration, err)
+			}
+			cancel()
+		}
+		co := &CommandOutput{Command: inv.CommandName, Messages: make([]string, 0)}
 		if err != nil {
 			output.AddResult(err.Error())
+			co.Error = err.Error()
+			co.Err = err
 			if result != nil {
 				output.AddResult(result.ErrorMessage...)
+				co.Messages = append(co.Messages, result.ErrorMessage...)
 			}
 		} else {
 			output.AddResult(result.Message...)
+			co.Messages = append(co.Messages, result.Message...)
+			co.Fields = result.Fields
 		}
+		output.AddOutput(co)
 	}
 
 	return output
@@ -523,12 +1866,22 @@ func ParseAndInterpret(parser *CommandParser, ee *ExecutionEnvironment, input st
 	if err != nil {
 		o := NewInterpretResults()
 		o.AddResult(err.Error())
+		output := &CommandOutput{Messages: make([]string, 0), Error: err.Error(), Err: err}
+		o.AddOutput(output)
 		metrics := result.Metrics()
-		// Display help for the command if it is a valid command
+		// Display help for the command if it is a valid command, unless the error already shows it. The JSON
+		// output includes it too
+		hint := ""
 		if len(result.CommandResults) > 0 && result.CommandResults[metrics.CurrentResultIndex].Decl != nil {
-			o.AddResult("Usage: " + result.CommandResults[metrics.CurrentResultIndex].Decl.String())
+			if !errors.Is(err, cliutil.ErrMissingParam) {
+				hint = "Usage: " + result.CommandResults[metrics.CurrentResultIndex].Decl.String()
+			}
 		} else {
-			o.AddResult("Type \"list\" for a list of commands.")
+			hint = "Type \"list\" for a list of commands."
+		}
+		if hint != "" {
+			o.AddResult(hint)
+			output.Messages = append(output.Messages, hint)
 		}
 		return o
 	}
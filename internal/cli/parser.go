@@ -1,8 +1,12 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
 
 	"github.com/koinos/koinos-cli/internal/cliutil"
 )
@@ -21,6 +25,15 @@ const (
 	CommandNameTokens = `[a-zA-Z0-9_]`
 )
 
+// MaxAliasExpansions is the most command aliases expanded while parsing one command line
+const MaxAliasExpansions = 100
+
+// Keywords that address arguments accept for the address of the open wallet
+const (
+	SelfKeyword = "self"
+	MeKeyword   = "@me"
+)
+
 // CommandArgType is an enum that defines the types of arguments a command can take
 type CommandArgType int
 
@@ -37,6 +50,8 @@ const (
 	HexArg
 	FileArg
 	ContractNameArg
+	FloatArg
+	PayerArg
 
 	// A parameter should never be declared as type nothing, this is only for parsing errors
 	NoArg
@@ -68,6 +83,10 @@ func (c *CommandArgType) String() string {
 		return "none"
 	case ContractNameArg:
 		return "contract-name"
+	case FloatArg:
+		return "float"
+	case PayerArg:
+		return "payer"
 
 	default:
 		return "unknown"
@@ -79,21 +98,28 @@ const (
 	CommandTerminator = ';'
 )
 
+// OptionPrefix starts the name of a command option, such as --rc-limit
+const OptionPrefix = "--"
+
 // CommandParseResult is the result of parsing a single command string
 type CommandParseResult struct {
-	CommandName string
-	Args        map[string]*string // This could be a slice of strings potentially
-	Decl        *CommandDeclaration
-	CurrentArg  int
-	Termination TerminationStatus
+	CommandName  string
+	Args         map[string]*string  // A variadic argument holds its first value here
+	VariadicArgs map[string][]string // All values of a variadic argument
+	Options      map[string]*string  // The values of the options that were given
+	Decl         *CommandDeclaration
+	CurrentArg   int
+	Termination  TerminationStatus
 }
 
 // NewCommandParseResult creates a new parse result object
 func NewCommandParseResult(name string) *CommandParseResult {
 	inv := &CommandParseResult{
-		CommandName: name,
-		Args:        make(map[string]*string),
-		CurrentArg:  -1,
+		CommandName:  name,
+		Args:         make(map[string]*string),
+		VariadicArgs: make(map[string][]string),
+		Options:      make(map[string]*string),
+		CurrentArg:   -1,
 	}
 
 	return inv
@@ -128,6 +154,12 @@ func (pr *ParseResults) Len() int {
 type CommandParser struct {
 	Commands *CommandSet
 
+	// Aliases maps address book names to the addresses they stand for in address arguments
+	Aliases map[string]string
+
+	// CommandAliases maps command aliases to the command lines they stand for
+	CommandAliases map[string]string
+
 	// Parser token recognizer regexps
 	commandNameRE  *regexp.Regexp
 	contractNameRE *regexp.Regexp
@@ -138,6 +170,7 @@ type CommandParser struct {
 	amountRE       *regexp.Regexp
 	uintRE         *regexp.Regexp
 	intRE          *regexp.Regexp
+	floatRE        *regexp.Regexp
 	bytesRE        *regexp.Regexp
 	boolRE         *regexp.Regexp
 	hexRE          *regexp.Regexp
@@ -146,7 +179,9 @@ type CommandParser struct {
 // NewCommandParser creates a new command parser
 func NewCommandParser(commands *CommandSet) *CommandParser {
 	parser := &CommandParser{
-		Commands: commands,
+		Commands:       commands,
+		Aliases:        make(map[string]string),
+		CommandAliases: make(map[string]string),
 	}
 
 	parser.contractNameRE = regexp.MustCompile(fmt.Sprintf(`^%s+`, CommandNameTokens))
@@ -158,9 +193,10 @@ func NewCommandParser(commands *CommandSet) *CommandParser {
 	parser.amountRE = regexp.MustCompile(`^((\d+(\.\d*)?)|(\.\d+))`)
 	parser.uintRE = regexp.MustCompile(`^[+]?[0-9]+`)
 	parser.intRE = regexp.MustCompile(`^[+-]?[0-9]+`)
+	parser.floatRE = regexp.MustCompile(`^[+-]?(\d+(\.\d*)?|\.\d+)([eE][+-]?\d+)?`)
 	parser.bytesRE = regexp.MustCompile(`^[A-Za-z0-9\-_=]+`)
-	parser.boolRE = regexp.MustCompile(`^(?P<false>[Ff][Aa][Ll][Ss][Ee]|0)|(?P<true>[Tt][Rr][Uu][Ee]|1)`)
-	parser.hexRE = regexp.MustCompile(`^0x[0-9a-fA-F]+`)
+	parser.boolRE = regexp.MustCompile(`^(?:(?P<false>[Ff][Aa][Ll][Ss][Ee]|0)|(?P<true>[Tt][Rr][Uu][Ee]|1))`)
+	parser.hexRE = regexp.MustCompile(`^(0[xX])?[0-9a-fA-F]+`)
 
 	return parser
 }
@@ -174,11 +210,12 @@ func (p *CommandParser) Parse(commands string) (*ParseResults, error) {
 	input, _, _ = p.parseSkip(input, nil, false)
 
 	// Loop until we've consumed all input
+	expansions := 0
 	for len(input) > 0 {
 		var err error
 		var inv *CommandParseResult
 
-		inv, input, err = p.parseNextCommand(input)
+		inv, input, err = p.parseNextCommand(input, &expansions)
 		if inv != nil {
 			invs.AddResult(inv)
 		}
@@ -195,7 +232,12 @@ func (p *CommandParser) Parse(commands string) (*ParseResults, error) {
 	return invs, nil
 }
 
-func (p *CommandParser) parseNextCommand(input []byte) (*CommandParseResult, []byte, error) {
+func (p *CommandParser) parseNextCommand(input []byte, expansions *int) (*CommandParseResult, []byte, error) {
+	input, err := p.expandCommandAlias(input, expansions)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// Parse the command name
 	name, err := p.parseCommandName(input)
 	if err != nil {
@@ -210,11 +252,16 @@ func (p *CommandParser) parseNextCommand(input []byte) (*CommandParseResult, []b
 		inv.Decl = decl
 	} else {
 		p.parseSkip(input, inv, true)
-		return inv, nil, fmt.Errorf("%w", cliutil.ErrUnknownCommand)
+		return inv, nil, fmt.Errorf("%w: %s%s", cliutil.ErrUnknownCommand, name, didYouMean(p.Commands.Suggest(string(name))))
 	}
 
 	input, err = p.parseArgs(input, inv)
 	if err != nil {
+		// Show everything the command expects when arguments are missing
+		if errors.Is(err, cliutil.ErrMissingParam) {
+			err = fmt.Errorf("%w, usage: %s", err, inv.Decl)
+		}
+
 		return inv, input, err
 	}
 
@@ -226,6 +273,134 @@ func (p *CommandParser) parseNextCommand(input []byte) (*CommandParseResult, []b
 	return inv, input, nil
 }
 
+// didYouMean returns the suggestions for an unknown command to append to its error, or nothing if there are none
+func didYouMean(suggestions []string) string {
+	switch len(suggestions) {
+	case 0:
+		return ""
+	case 1:
+		return fmt.Sprintf(", did you mean %s?", suggestions[0])
+	case 2:
+		return fmt.Sprintf(", did you mean %s or %s?", suggestions[0], suggestions[1])
+	}
+
+	return fmt.Sprintf(", did you mean %s, or %s?", strings.Join(suggestions[:len(suggestions)-1], ", "), suggestions[len(suggestions)-1])
+}
+
+// AddCommandAlias makes a name stand for a command line, which may hold several commands. Arguments given after the
+// alias are appended to the command line. An alias cannot hide a command, or expand to itself through other aliases
+func (p *CommandParser) AddCommandAlias(name string, command string) error {
+	if m := p.contractNameRE.FindString(name); m != name {
+		return fmt.Errorf("%w: alias %s", cliutil.ErrInvalidCommandName, name)
+	}
+
+	if _, ok := p.Commands.Name2Command[name]; ok {
+		return fmt.Errorf("%w: alias %s is the name of a command", cliutil.ErrInvalidCommandName, name)
+	}
+
+	if _, err := p.parseCommandName([]byte(strings.TrimSpace(command))); err != nil {
+		return err
+	}
+
+	previous, replaced := p.CommandAliases[name]
+	p.CommandAliases[name] = command
+
+	// Adding the alias fails if any command it runs leads back to it
+	if err := p.checkAliasLoop(name, make(map[string]bool)); err != nil {
+		if replaced {
+			p.CommandAliases[name] = previous
+		} else {
+			delete(p.CommandAliases, name)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// checkAliasLoop returns an error if the alias with the given name leads back to one of the aliases being expanded,
+// through any of the commands of its command line
+func (p *CommandParser) checkAliasLoop(name string, expanding map[string]bool) error {
+	if expanding[name] {
+		return fmt.Errorf("%w: alias %s expands to itself", cliutil.ErrInvalidCommandName, name)
+	}
+
+	command, ok := p.CommandAliases[name]
+	if !ok {
+		return nil
+	}
+
+	expanding[name] = true
+	defer delete(expanding, name)
+
+	for _, next := range p.commandLineNames(command) {
+		if err := p.checkAliasLoop(next, expanding); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// commandLineNames returns the name of each command of a command line. Quoted semicolons do not separate commands
+func (p *CommandParser) commandLineNames(line string) []string {
+	names := make([]string, 0)
+	start := 0
+	var quote byte
+	for i := 0; i <= len(line); i++ {
+		if i < len(line) {
+			switch c := line[i]; {
+			case quote != 0 && c == '\\':
+				i++
+				continue
+			case quote != 0:
+				if c == quote {
+					quote = 0
+				}
+				continue
+			case c == '"' || c == '\'':
+				quote = c
+				continue
+			case c != CommandTerminator:
+				continue
+			}
+		}
+
+		if name := p.commandNameRE.FindString(strings.TrimSpace(line[start:i])); name != "" {
+			names = append(names, name)
+		}
+		start = i + 1
+	}
+
+	return names
+}
+
+// expandCommandAlias replaces a command alias at the start of the input with the command line it stands for, repeatedly
+// if that begins with another alias. The expansions made while parsing a command line are counted and limited, so that
+// parsing always ends, even for a loop that checkAliasLoop did not catch
+func (p *CommandParser) expandCommandAlias(input []byte, expansions *int) ([]byte, error) {
+	seen := make(map[string]bool)
+	for {
+		name := p.commandNameRE.Find(input)
+		command, ok := p.CommandAliases[string(name)]
+		if name == nil || !ok {
+			return input, nil
+		}
+
+		if seen[string(name)] {
+			return nil, fmt.Errorf("%w: alias %s expands to itself", cliutil.ErrInvalidCommandName, name)
+		}
+		seen[string(name)] = true
+
+		*expansions++
+		if *expansions > MaxAliasExpansions {
+			return nil, fmt.Errorf("%w: more than %d aliases expanded, alias %s may expand to itself", cliutil.ErrInvalidCommandName, MaxAliasExpansions, name)
+		}
+
+		input = append([]byte(strings.TrimSpace(command)), input[len(name):]...)
+	}
+}
+
 // Returns the matched command name
 func (p *CommandParser) parseCommandName(input []byte) ([]byte, error) {
 	m := p.commandNameRE.Find(input)
@@ -244,9 +419,27 @@ func (p *CommandParser) parseArgs(input []byte, inv *CommandParseResult) ([]byte
 		var t TerminationStatus
 		var skip bool
 		input, t, skip = p.parseSkip(input, inv, true)
+
+		// Options may be given before any argument
+		for t == NoTermination && skip {
+			var option bool
+			var err error
+			input, option, err = p.parseOption(input, inv)
+			if err != nil {
+				return input, err
+			}
+			if !option {
+				break
+			}
+			input, t, skip = p.parseSkip(input, inv, true)
+		}
+
 		if t != NoTermination {
 			if arg.Optional {
-				inv.Args[arg.Name] = nil
+				// This and any later arguments take their default values
+				for _, rest := range inv.Decl.Args[i:] {
+					inv.Args[rest.Name] = rest.defaultValue()
+				}
 				return input, nil
 			}
 
@@ -262,35 +455,7 @@ func (p *CommandParser) parseArgs(input []byte, inv *CommandParseResult) ([]byte
 			return input, fmt.Errorf("%w: %s", cliutil.ErrInvalidParam, inv.Decl.Args[i-1].Name)
 		}
 
-		var match []byte
-		var err error
-		var l int
-
-		// Match the argument based on type
-		switch arg.ArgType {
-		case AddressArg:
-			match, l, err = p.parseAddress(input)
-		case StringArg:
-			match, l, err = p.parseString(input)
-		case AmountArg:
-			match, l, err = p.parseAmount(input)
-		case CmdNameArg:
-			match, l, err = p.parseString(input)
-		case ContractNameArg:
-			match, l, err = p.parseContractName(input)
-		case FileArg:
-			match, l, err = p.parseString(input)
-		case UIntArg:
-			match, l, err = p.parseUInt(input)
-		case IntArg:
-			match, l, err = p.parseInt(input)
-		case BytesArg:
-			match, l, err = p.parseBytes(input)
-		case BoolArg:
-			match, l, err = p.parseBool(input)
-		case HexArg:
-			match, l, err = p.parseHex(input)
-		}
+		match, l, err := p.parseArgValue(arg.ArgType, input)
 		input = input[l:] // Consume the match
 
 		// Check for error during match
@@ -298,22 +463,226 @@ func (p *CommandParser) parseArgs(input []byte, inv *CommandParseResult) ([]byte
 			return input, fmt.Errorf("%w: %s", err, arg.Name)
 		}
 
+		if arg.Variadic {
+			return p.parseVariadicArg(input, inv, arg, string(match))
+		}
+
 		// Store the argument value in the invocation
 		val := string(match)
 		inv.Args[arg.Name] = &val
 	}
 
+	// Options may also follow the last argument
+	for {
+		rest, t, skip := p.parseSkip(input, nil, false)
+		if t != NoTermination || !skip {
+			return input, nil
+		}
+
+		rest, option, err := p.parseOption(rest, inv)
+		if err != nil {
+			return rest, err
+		}
+		if !option {
+			return input, nil
+		}
+		input = rest
+	}
+}
+
+// parseOption parses a declared option of the command and its value, if the input starts with one. Returns the
+// unconsumed input, and whether there was an option
+func (p *CommandParser) parseOption(input []byte, inv *CommandParseResult) ([]byte, bool, error) {
+	for _, option := range inv.Decl.Options {
+		name := OptionPrefix + option.Name
+		if !strings.HasPrefix(string(input), name) || (len(input) > len(name) && !p.isArgBoundary(input[len(name)])) {
+			continue
+		}
+
+		rest, t, skip := p.parseSkip(input[len(name):], nil, false)
+		if t != NoTermination || !skip {
+			return input, true, fmt.Errorf("%w: %s", cliutil.ErrMissingParam, name)
+		}
+
+		match, l, err := p.parseArgValue(option.ArgType, rest)
+		if err != nil {
+			return rest[l:], true, fmt.Errorf("%w: %s", err, name)
+		}
+
+		val := string(match)
+		inv.Options[option.Name] = &val
+		return rest[l:], true, nil
+	}
+
+	return input, false, nil
+}
+
+// ParseArgInput parses the value of a single argument given on its own, such as when prompted for it. A string or file
+// name is taken as it is, without quotes. The values of a variadic argument are separated by whitespace. Blank input
+// gives an optional argument its default value, if any
+func (p *CommandParser) ParseArgInput(arg *CommandArg, input string) ([]string, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		if !arg.Optional {
+			return nil, fmt.Errorf("%w: %s", cliutil.ErrMissingParam, arg.Name)
+		}
+
+		if arg.Default == nil {
+			return nil, nil
+		}
+
+		return []string{*arg.Default}, nil
+	}
+
+	if !arg.Variadic && (arg.ArgType == StringArg || arg.ArgType == FileArg) {
+		return []string{input}, nil
+	}
+
+	values := make([]string, 0)
+	rest := []byte(input)
+	for len(rest) > 0 {
+		match, l, err := p.parseArgValue(arg.ArgType, rest)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", err, arg.Name)
+		}
+
+		rest = rest[l:]
+		if l == 0 || (len(rest) > 0 && !unicode.IsSpace(rune(rest[0]))) {
+			return nil, fmt.Errorf("%w: %s", cliutil.ErrInvalidParam, arg.Name)
+		}
+
+		values = append(values, string(match))
+		rest = []byte(strings.TrimLeftFunc(string(rest), unicode.IsSpace))
+		if !arg.Variadic && len(rest) > 0 {
+			return nil, fmt.Errorf("%w: %s takes a single value", cliutil.ErrInvalidParam, arg.Name)
+		}
+	}
+
+	return values, nil
+}
+
+// parsePayer parses the payer of transactions, which is SelfPayer for the open wallet, or else an address
+func (p *CommandParser) parsePayer(input []byte) ([]byte, int, error) {
+	if strings.HasPrefix(string(input), SelfPayer) && (len(input) == len(SelfPayer) || p.isArgBoundary(input[len(SelfPayer)])) {
+		return []byte(SelfPayer), len(SelfPayer), nil
+	}
+
+	// The keywords for the open wallet also make it the payer
+	match, l, err := p.parseAddress(input)
+	if err == nil && string(match) == SelfKeyword {
+		return []byte(SelfPayer), l, nil
+	}
+
+	return match, l, err
+}
+
+// Parse a single argument value based on type. Returns matched value, consumed length, and error
+func (p *CommandParser) parseArgValue(argType CommandArgType, input []byte) ([]byte, int, error) {
+	switch argType {
+	case AddressArg:
+		return p.parseAddress(input)
+	case StringArg:
+		return p.parseString(input)
+	case AmountArg:
+		return p.parseAmount(input)
+	case CmdNameArg:
+		return p.parseString(input)
+	case ContractNameArg:
+		return p.parseContractName(input)
+	case FileArg:
+		return p.parseString(input)
+	case UIntArg:
+		return p.parseUInt(input)
+	case IntArg:
+		return p.parseInt(input)
+	case FloatArg:
+		return p.parseFloat(input)
+	case PayerArg:
+		return p.parsePayer(input)
+	case BytesArg:
+		return p.parseBytes(input)
+	case BoolArg:
+		return p.parseBool(input)
+	case HexArg:
+		return p.parseHex(input)
+	}
+
+	return nil, 0, nil
+}
+
+// Parse the remaining values of a variadic argument, given its first value. Values are consumed until the end of the command
+func (p *CommandParser) parseVariadicArg(input []byte, inv *CommandParseResult, arg CommandArg, first string) ([]byte, error) {
+	values := []string{first}
+
+	for {
+		// Look ahead without consuming a terminator, it is handled after the arguments
+		rest, t, skip := p.parseSkip(input, nil, false)
+		if t != NoTermination {
+			break
+		}
+
+		// If there was no skip here, then values have been melded together
+		if !skip {
+			return input, fmt.Errorf("%w: %s", cliutil.ErrInvalidParam, arg.Name)
+		}
+
+		rest, option, err := p.parseOption(rest, inv)
+		if err != nil {
+			return rest, err
+		}
+		if option {
+			input = rest
+			continue
+		}
+
+		match, l, err := p.parseArgValue(arg.ArgType, rest)
+		input = rest[l:]
+		if err != nil {
+			return input, fmt.Errorf("%w: %s", err, arg.Name)
+		}
+
+		values = append(values, string(match))
+	}
+
+	inv.Args[arg.Name] = &values[0]
+	inv.VariadicArgs[arg.Name] = values
+
 	return input, nil
 }
 
-// Parse an address. Returns matched address consumed length, and error
+// Parse an address, or an address book alias which is replaced by its address. Returns matched address consumed length, and error
 func (p *CommandParser) parseAddress(input []byte) ([]byte, int, error) {
+	// The keywords for the open wallet are kept as SelfKeyword, and replaced when the command runs, since the wallet
+	// may be opened by an earlier command
+	for _, keyword := range []string{SelfKeyword, MeKeyword} {
+		if strings.HasPrefix(string(input), keyword) && (len(input) == len(keyword) || p.isArgBoundary(input[len(keyword)])) {
+			return []byte(SelfKeyword), len(keyword), nil
+		}
+	}
+
+	// Check for an alias
+	if m := p.contractNameRE.Find(input); m != nil && (len(input) == len(m) || p.isArgBoundary(input[len(m)])) {
+		if address, ok := p.Aliases[string(m)]; ok {
+			return []byte(address), len(m), nil
+		}
+	}
+
 	// Parse address
 	m := p.addressRE.Find(input)
 	if m == nil {
 		return nil, 0, fmt.Errorf("%w", cliutil.ErrInvalidParam)
 	}
 
+	if len(input) > len(m) && !p.isArgBoundary(input[len(m)]) {
+		return nil, 0, fmt.Errorf("%w (invalid address characters)", cliutil.ErrInvalidParam)
+	}
+
+	// Check that the address is in the Koinos format, a versioned 20 byte hash with a checksum
+	err := cliutil.ValidateAddress(string(m))
+	if err != nil {
+		return nil, 0, err
+	}
+
 	return m, len(m), nil
 }
 
@@ -345,6 +714,11 @@ func (p *CommandParser) parseBool(input []byte) ([]byte, int, error) {
 		return nil, 0, fmt.Errorf("%w", cliutil.ErrInvalidParam)
 	}
 
+	// Reject values such as "truest" or "10" rather than matching their prefix
+	if len(input) > len(m[0]) && !p.isArgBoundary(input[len(m[0])]) {
+		return nil, 0, fmt.Errorf("%w (not a boolean)", cliutil.ErrInvalidParam)
+	}
+
 	falseIndex := p.boolRE.SubexpIndex("false")
 	trueIndex := p.boolRE.SubexpIndex("true")
 	if len(m[falseIndex]) > 0 {
@@ -363,9 +737,19 @@ func (p *CommandParser) parseAmount(input []byte) ([]byte, int, error) {
 		return nil, 0, fmt.Errorf("%w", cliutil.ErrInvalidParam)
 	}
 
+	// Reject malformed amounts such as "1.2.3" or "5abc" rather than silently truncating them
+	if len(input) > len(m) && !p.isArgBoundary(input[len(m)]) {
+		return nil, 0, fmt.Errorf("%w (malformed amount)", cliutil.ErrInvalidParam)
+	}
+
 	return m, len(m), nil
 }
 
+// Returns true if the given character ends an argument
+func (p *CommandParser) isArgBoundary(c byte) bool {
+	return c == CommandTerminator || unicode.IsSpace(rune(c))
+}
+
 func (p *CommandParser) parseUInt(input []byte) ([]byte, int, error) {
 	// Parse uint
 	m := p.uintRE.Find(input)
@@ -373,6 +757,15 @@ func (p *CommandParser) parseUInt(input []byte) ([]byte, int, error) {
 		return nil, 0, fmt.Errorf("%w", cliutil.ErrInvalidParam)
 	}
 
+	if len(input) > len(m) && !p.isArgBoundary(input[len(m)]) {
+		return nil, 0, fmt.Errorf("%w (not an unsigned integer)", cliutil.ErrInvalidParam)
+	}
+
+	// Ensure the value fits in 64 bits
+	if _, err := strconv.ParseUint(strings.TrimPrefix(string(m), "+"), 10, 64); err != nil {
+		return nil, 0, fmt.Errorf("%w (unsigned integer out of range)", cliutil.ErrInvalidParam)
+	}
+
 	return m, len(m), nil
 }
 
@@ -383,6 +776,34 @@ func (p *CommandParser) parseInt(input []byte) ([]byte, int, error) {
 		return nil, 0, fmt.Errorf("%w", cliutil.ErrInvalidParam)
 	}
 
+	if len(input) > len(m) && !p.isArgBoundary(input[len(m)]) {
+		return nil, 0, fmt.Errorf("%w (not an integer)", cliutil.ErrInvalidParam)
+	}
+
+	// Ensure the value fits in 64 bits
+	if _, err := strconv.ParseInt(string(m), 10, 64); err != nil {
+		return nil, 0, fmt.Errorf("%w (integer out of range)", cliutil.ErrInvalidParam)
+	}
+
+	return m, len(m), nil
+}
+
+func (p *CommandParser) parseFloat(input []byte) ([]byte, int, error) {
+	// Parse float
+	m := p.floatRE.Find(input)
+	if m == nil {
+		return nil, 0, fmt.Errorf("%w", cliutil.ErrInvalidParam)
+	}
+
+	if len(input) > len(m) && !p.isArgBoundary(input[len(m)]) {
+		return nil, 0, fmt.Errorf("%w (not a number)", cliutil.ErrInvalidParam)
+	}
+
+	// Ensure the value fits in 64 bits, the precision of the field is checked when the message is built
+	if _, err := strconv.ParseFloat(string(m), 64); err != nil {
+		return nil, 0, fmt.Errorf("%w (number out of range)", cliutil.ErrInvalidParam)
+	}
+
 	return m, len(m), nil
 }
 
@@ -437,9 +858,11 @@ func (p *CommandParser) parseQuotedString(input []byte) ([]byte, int, error) {
 		output = append(output, c)
 	}
 
-	return nil, 0, fmt.Errorf("%w (missing closing quote)", cliutil.ErrInvalidParam)
+	return nil, 0, fmt.Errorf("%w: missing closing quote %c", cliutil.ErrInvalidString, quote)
 }
 
+// Parse an unquoted string, which ends at whitespace, a quote, or a semicolon. Backslashes are only escapes inside
+// quotes, so they are kept as they are here, and paths such as C:\wallets or \\server\share work unquoted
 func (p *CommandParser) parseSimpleString(input []byte) ([]byte, int, error) {
 	m := p.simpleStringRE.Find(input)
 	if m == nil {
@@ -491,5 +914,20 @@ func (p *CommandParser) parseHex(input []byte) ([]byte, int, error) {
 		return nil, 0, fmt.Errorf("%w", cliutil.ErrInvalidParam)
 	}
 
-	return m, len(m), nil
+	if len(input) > len(m) && !p.isArgBoundary(input[len(m)]) {
+		return nil, 0, fmt.Errorf("%w (not a hex string)", cliutil.ErrInvalidParam)
+	}
+
+	// The 0x prefix is optional, but always included in the result
+	digits := m
+	if len(m) > 1 && (m[1] == 'x' || m[1] == 'X') {
+		digits = m[2:]
+	}
+
+	// Every byte takes two digits
+	if len(digits)%2 != 0 {
+		return nil, 0, fmt.Errorf("%w (odd-length hex)", cliutil.ErrInvalidParam)
+	}
+
+	return append([]byte("0x"), digits...), len(m), nil
 }
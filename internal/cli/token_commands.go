@@ -1,10 +1,14 @@
 package cli
 
 import (
+	"bytes"
 	"context"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"strconv"
+	"sync"
+	"time"
 
 	"github.com/btcsuite/btcutil/base58"
 	"github.com/koinos/koinos-cli/internal/cliutil"
@@ -15,6 +19,9 @@ import (
 	"google.golang.org/protobuf/proto"
 )
 
+// timestampFormat is the format of the timestamps shown when watching a balance or listing the history
+const timestampFormat = "2006-01-02 15:04:05"
+
 const (
 	TokenBalanceOfEntry   = uint32(0x5c721497)
 	TokenTransferEntry    = uint32(0x27f576ca)
@@ -69,7 +76,8 @@ func retrieveDecimals(ctx context.Context, client *cliutil.KoinosRPCClient, cont
 	return &value, nil
 }
 
-func retrieveBalance(ctx context.Context, client *cliutil.KoinosRPCClient, contractID []byte, address []byte) (*uint64, error) {
+// retrieveBalance reads the balance of an address from a token contract, calling the given balanceOf entry point
+func retrieveBalance(ctx context.Context, client *cliutil.KoinosRPCClient, contractID []byte, address []byte, entryPoint uint32) (*uint64, error) {
 	balanceOfArguments := token.BalanceOfArguments{}
 	balanceOfArguments.Owner = address
 
@@ -78,7 +86,7 @@ func retrieveBalance(ctx context.Context, client *cliutil.KoinosRPCClient, contr
 		return nil, err
 	}
 
-	resp, err := client.ReadContract(ctx, args, contractID, TokenBalanceOfEntry)
+	resp, err := client.ReadContract(ctx, args, contractID, entryPoint)
 	if err != nil {
 		return nil, err
 	}
@@ -124,10 +132,10 @@ func (c *RegisterTokenCommand) Execute(ctx context.Context, ee *ExecutionEnviron
 		return nil, fmt.Errorf("%w: invalid characters in token name %s", cliutil.ErrContract, err)
 	}
 
-	contractID := base58.Decode(c.Address)
-	if len(contractID) == 0 {
-		return nil, errors.New("could not parse contract ID")
+	if err := cliutil.ValidateAddress(c.Address); err != nil {
+		return nil, fmt.Errorf("%w: token %s, %s", cliutil.ErrContract, c.Name, err)
 	}
+	contractID := base58.Decode(c.Address)
 
 	var symbol *string
 	if c.Symbol == nil {
@@ -153,32 +161,51 @@ func (c *RegisterTokenCommand) Execute(ctx context.Context, ee *ExecutionEnviron
 		}
 	}
 
+	err = registerToken(ee, c.Name, c.Address, *precision, *symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	er := NewExecutionResult()
+	er.AddMessage(fmt.Sprintf("Token '%s' at address %s registered", c.Name, c.Address))
+	ee.saveContracts(er)
+	return er, nil
+}
+
+// registerToken registers a token, along with its balance_of, total_supply, and transfer commands
+func registerToken(ee *ExecutionEnvironment, name string, address string, precision int, symbol string) error {
+	err := ee.Contracts.Add(name, address, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	err = ee.Contracts.SetUnits(name, &ABIUnit{Precision: precision, Symbol: symbol})
+	if err != nil {
+		return err
+	}
+
+	// The commands read the address when they are run, so that it can be changed with set_contract_address
+	contract, _ := ee.Contracts.Get(name)
+
 	NewBalanceOfCommand := func(inv *CommandParseResult) Command {
-		return NewTokenBalanceCommand(inv, contractID, *precision, *symbol)
+		return NewTokenBalanceCommand(inv, base58.Decode(contract.GetAddress()), precision, symbol)
 	}
-	cmd := NewCommandDeclaration(fmt.Sprintf("%s.balance_of", c.Name), "Checks the balance at an address", false, NewBalanceOfCommand, *NewOptionalCommandArg("address", AddressArg))
+	cmd := NewCommandDeclaration(fmt.Sprintf("%s.balance_of", name), "Checks the balance at an address", false, NewBalanceOfCommand, *NewOptionalCommandArg("address", AddressArg))
 	ee.Parser.Commands.AddCommand(cmd)
 
 	NewTotalSupplyCommand := func(inv *CommandParseResult) Command {
-		return NewTokenTotalSupplyCommand(inv, contractID, *precision, *symbol)
+		return NewTokenTotalSupplyCommand(inv, base58.Decode(contract.GetAddress()), precision, symbol)
 	}
-	cmd = NewCommandDeclaration(fmt.Sprintf("%s.total_supply", c.Name), "Checks the token total supply", false, NewTotalSupplyCommand)
+	cmd = NewCommandDeclaration(fmt.Sprintf("%s.total_supply", name), "Checks the token total supply", false, NewTotalSupplyCommand)
 	ee.Parser.Commands.AddCommand(cmd)
 
 	NewTransferCommand := func(inv *CommandParseResult) Command {
-		return NewTokenTransferCommand(inv, contractID, *precision, *symbol)
+		return NewTokenTransferCommand(inv, base58.Decode(contract.GetAddress()), precision, symbol)
 	}
-	cmd = NewCommandDeclaration(fmt.Sprintf("%s.transfer", c.Name), "Transfers the token", false, NewTransferCommand, *NewCommandArg("to", AddressArg), *NewCommandArg("amount", AmountArg))
+	cmd = NewCommandDeclaration(fmt.Sprintf("%s.transfer", name), "Transfers the token", false, NewTransferCommand, *NewCommandArg("to", AddressArg), *NewCommandArg("amount", AmountArg))
 	ee.Parser.Commands.AddCommand(cmd)
 
-	err = ee.Contracts.Add(c.Name, c.Address, nil, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	er := NewExecutionResult()
-	er.AddMessage(fmt.Sprintf("Token '%s' at address %s registered", c.Name, c.Address))
-	return er, nil
+	return nil
 }
 
 // ----------------------------------------------------------------------------
@@ -191,6 +218,7 @@ type TokenBalanceCommand struct {
 	ContractID []byte
 	Precision  int
 	Symbol     string
+	Watch      time.Duration // If set, the balance is re-queried at this interval until interrupted
 }
 
 // NewTokenBalanceCommand instantiates the command to retrieve a token balance
@@ -207,7 +235,7 @@ func (c *TokenBalanceCommand) Execute(ctx context.Context, ee *ExecutionEnvironm
 	var address []byte
 	if c.Address == nil {
 		if !ee.IsWalletOpen() {
-			return nil, fmt.Errorf("%w: must give an address", cliutil.ErrWalletClosed)
+			return nil, fmt.Errorf("%w: open a wallet to check its balance, or give an address", cliutil.ErrWalletClosed)
 		}
 
 		address = ee.Key.AddressBytes()
@@ -218,7 +246,11 @@ func (c *TokenBalanceCommand) Execute(ctx context.Context, ee *ExecutionEnvironm
 		}
 	}
 
-	balance, err := retrieveBalance(ctx, ee.RPCClient, c.ContractID, address)
+	if c.Watch > 0 {
+		return c.watch(ee, address)
+	}
+
+	balance, err := retrieveBalance(ctx, ee.RPCClient, c.ContractID, address, ee.balanceOfEntry(c.ContractID))
 	if err != nil {
 		return nil, err
 	}
@@ -229,11 +261,438 @@ func (c *TokenBalanceCommand) Execute(ctx context.Context, ee *ExecutionEnvironm
 	}
 
 	er := NewExecutionResult()
-	er.AddMessage(fmt.Sprintf("%v %s", dec, c.Symbol))
+	er.AddMessage(ee.formatAmount(dec, c.Symbol))
+	er.SetField("address", base58.Encode(address))
+	er.SetField("balance", dec.String())
+	er.SetField("symbol", c.Symbol)
+
+	return er, nil
+}
+
+// watch re-queries the balance at the watch interval until interrupted with Ctrl-C or the maximum duration is up, printing it with
+// a timestamp whenever it changes. It runs until stopped, so each query is bounded by the rpc timeout rather than the whole command
+func (c *TokenBalanceCommand) watch(ee *ExecutionEnvironment, address []byte) (*ExecutionResult, error) {
+	ctx := ee.interruptibleContext()
+
+	fmt.Fprintf(ee.Output(), "Watching the %s balance of %s every %v, press Ctrl-C to stop\n", c.Symbol, base58.Encode(address), c.Watch)
+
+	ticker := time.NewTicker(c.Watch)
+	defer ticker.Stop()
+
+	var last *decimal.Decimal
+	for {
+		balance, err := retrieveBalance(ctx, ee.RPCClient, c.ContractID, address, ee.balanceOfEntry(c.ContractID))
+
+		// Keep watching through errors, the node may only be briefly unavailable
+		if err != nil && ctx.Err() == nil {
+			fmt.Fprintf(ee.Output(), "%s error: %s\n", time.Now().Format(timestampFormat), err)
+		} else if err == nil {
+			dec, err := util.SatoshiToDecimal(*balance, c.Precision)
+			if err != nil {
+				return nil, err
+			}
+
+			if last == nil || !dec.Equal(*last) {
+				fmt.Fprintf(ee.Output(), "%s %s\n", time.Now().Format(timestampFormat), ee.formatAmount(dec, c.Symbol))
+				last = dec
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			er := NewExecutionResult()
+			if ee.MaxDurationExceeded() {
+				er.AddMessage("Stopped watching, the maximum duration is up")
+			} else {
+				er.AddMessage("Stopped watching")
+			}
+			er.SetField("address", base58.Encode(address))
+			er.SetField("symbol", c.Symbol)
+			if last != nil {
+				er.SetField("balance", last.String())
+			}
+			return er, nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// ----------------------------------------------------------------------------
+// Balance
+// ----------------------------------------------------------------------------
+
+// BalanceCommand is a command that retrieves the balance of KOIN or a registered token
+type BalanceCommand struct {
+	Address  *string
+	Contract *string
+	Watch    time.Duration // If set, the balance is re-queried at this interval until interrupted
+}
+
+// NewBalanceCommand instantiates the command to retrieve a balance
+func NewBalanceCommand(inv *CommandParseResult) Command {
+	return &BalanceCommand{Address: inv.Args["address"], Contract: inv.Args["token"]}
+}
+
+// NewSelfBalanceCommand instantiates the command to retrieve the balance of the open wallet
+func NewSelfBalanceCommand(inv *CommandParseResult) Command {
+	return &BalanceCommand{Contract: inv.Args["token"]}
+}
+
+// NewWatchBalanceCommand instantiates the command to watch a balance
+func NewWatchBalanceCommand(inv *CommandParseResult) Command {
+	seconds, err := strconv.ParseFloat(*inv.Args["seconds"], 64)
+	if err != nil {
+		return nil
+	}
+
+	return &BalanceCommand{Address: inv.Args["address"], Contract: inv.Args["token"], Watch: time.Duration(seconds * float64(time.Second))}
+}
+
+// Execute retrieves the balance
+func (c *BalanceCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	// Without an address, the balance is the open wallet's, so check for one before asking the node anything
+	if c.Address == nil && !ee.IsWalletOpen() {
+		return nil, fmt.Errorf("%w: open a wallet to check its balance, or give an address", cliutil.ErrWalletClosed)
+	}
+
+	if !ee.IsOnline() {
+		return nil, fmt.Errorf("%w: cannot check balance", cliutil.ErrOffline)
+	}
+
+	// Default to KOIN
+	if c.Contract == nil {
+		cmd := &TokenBalanceCommand{Address: c.Address, ContractID: base58.Decode(cliutil.KoinContractID), Precision: ee.koinPrecision, Symbol: ee.koinSymbol, Watch: c.Watch}
+		return cmd.Execute(ctx, ee)
+	}
+
+	contract, ok := ee.Contracts.Get(*c.Contract)
+	if !ok {
+		return nil, fmt.Errorf("%w: contract %s is not registered", cliutil.ErrContract, *c.Contract)
+	}
+
+	contractID := base58.Decode(contract.GetAddress())
+	if len(contractID) == 0 {
+		return nil, errors.New("could not parse contract ID")
+	}
+
+	units := balanceUnits(contract)
+	if units == nil {
+		return nil, fmt.Errorf("%w: %s has no symbol or precision, give its balance_of method units in the ABI, or register it with register_token", cliutil.ErrContract, *c.Contract)
+	}
+
+	cmd := &TokenBalanceCommand{Address: c.Address, ContractID: contractID, Precision: units.Precision, Symbol: units.Symbol, Watch: c.Watch}
+	return cmd.Execute(ctx, ee)
+}
+
+// balanceUnits returns how balances of a registered contract are displayed, taken from register_token or from the units
+// of the value returned by balance_of in its ABI, or nil if neither gives them
+func balanceUnits(contract *ContractInfo) *ABIUnit {
+	if contract.Units != nil {
+		return contract.Units
+	}
+
+	if contract.ABI == nil {
+		return nil
+	}
+
+	method := contract.ABI.GetMethod("balance_of")
+	if method == nil {
+		return nil
+	}
+
+	return method.Units["value"]
+}
+
+// ----------------------------------------------------------------------------
+// Balances
+// ----------------------------------------------------------------------------
+
+// BalancesCommand is a command that retrieves the KOIN balances of several addresses at once
+type BalancesCommand struct {
+	Addresses []string
+}
+
+// NewBalancesCommand instantiates the command to retrieve several balances
+func NewBalancesCommand(inv *CommandParseResult) Command {
+	return &BalancesCommand{Addresses: inv.VariadicArgs["addresses"]}
+}
+
+// maxBalanceQueries is the most balances queried at once by the balances command
+const maxBalanceQueries = 8
+
+// addressBalance is the balance of a single address, or the error retrieving it
+type addressBalance struct {
+	balance *decimal.Decimal
+	err     error
+}
+
+// Execute retrieves the balances
+func (c *BalancesCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	if !ee.IsOnline() {
+		return nil, fmt.Errorf("%w: cannot check balances", cliutil.ErrOffline)
+	}
+
+	contractID := base58.Decode(cliutil.KoinContractID)
+
+	// Query the balances concurrently, each into its own slot to preserve the input order, with at most
+	// maxBalanceQueries in flight so that a long list does not flood the node
+	balances := make([]addressBalance, len(c.Addresses))
+	sem := make(chan struct{}, maxBalanceQueries)
+	var wg sync.WaitGroup
+	for i, address := range c.Addresses {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, address string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			balance, err := retrieveBalance(ctx, ee.RPCClient, contractID, base58.Decode(address), ee.koinBalanceOfEntry)
+			if err != nil {
+				balances[i].err = err
+				return
+			}
+
+			balances[i].balance, balances[i].err = util.SatoshiToDecimal(*balance, ee.koinPrecision)
+		}(i, address)
+	}
+	wg.Wait()
+
+	er := NewExecutionResult()
+	fields := make([]map[string]string, len(c.Addresses))
+	table := cliutil.NewTable("ADDRESS", "BALANCE")
+	for i, address := range c.Addresses {
+		fields[i] = map[string]string{"address": address}
+		if balances[i].err != nil {
+			table.AddRow(address, fmt.Sprintf("error: %s", balances[i].err))
+			fields[i]["error"] = balances[i].err.Error()
+			continue
+		}
+
+		table.AddRow(address, ee.formatAmount(balances[i].balance, ee.koinSymbol))
+		fields[i]["balance"] = balances[i].balance.String()
+	}
+
+	er.AddTable(table, ee.color)
+
+	er.SetField("balances", fields)
+	er.SetField("symbol", ee.koinSymbol)
 
 	return er, nil
 }
 
+// ----------------------------------------------------------------------------
+// AccountHistory
+// ----------------------------------------------------------------------------
+
+// DefaultAccountHistoryLimit is the number of entries account_history lists when no limit is given
+const DefaultAccountHistoryLimit = 10
+
+// MaxAccountHistoryLimit is the most entries account_history lists at once
+const MaxAccountHistoryLimit = 100
+
+// LimitOption is the option of account_history setting how many entries to list
+const LimitOption = "limit"
+
+// AccountHistoryCommand is a command that lists the recent transactions involving an address
+type AccountHistoryCommand struct {
+	Address *string
+	Start   *string // The sequence number of the newest entry to list, or nil for the most recent one
+	Limit   *string
+}
+
+// NewAccountHistoryCommand instantiates the command to list the history of an address
+func NewAccountHistoryCommand(inv *CommandParseResult) Command {
+	return &AccountHistoryCommand{Address: inv.Args["address"], Start: inv.Args["start"], Limit: inv.Options[LimitOption]}
+}
+
+// AccountTransfer is a token transfer to or from an address
+type AccountTransfer struct {
+	Direction    string `json:"direction"` // Either sent or received
+	Counterparty string `json:"counterparty"`
+	Amount       string `json:"amount"`
+	Symbol       string `json:"symbol,omitempty"`
+	Token        string `json:"token"` // The address of the token contract
+}
+
+// String describes the transfer, showing the raw amount of unregistered tokens
+func (t *AccountTransfer) String() string {
+	verb, preposition := "Sent", "to"
+	if t.Direction == TransferReceived {
+		verb, preposition = "Received", "from"
+	}
+
+	amount := t.Amount + " " + t.Symbol
+	if t.Symbol == "" {
+		amount = fmt.Sprintf("%s of token %s", t.Amount, t.Token)
+	}
+
+	return fmt.Sprintf("%s %s %s %s", verb, amount, preposition, t.Counterparty)
+}
+
+// Directions of an account transfer
+const (
+	TransferSent     = "sent"
+	TransferReceived = "received"
+)
+
+// AccountHistoryItem is a single entry of an account's history, as shown in the JSON output
+type AccountHistoryItem struct {
+	SeqNum        uint64             `json:"seq_num"`
+	TransactionID string             `json:"transaction_id,omitempty"`
+	ProducedBlock bool               `json:"produced_block,omitempty"`
+	BlockHeight   uint64             `json:"block_height,omitempty"`
+	Timestamp     string             `json:"timestamp,omitempty"`
+	Reverted      bool               `json:"reverted,omitempty"`
+	Transfers     []*AccountTransfer `json:"transfers,omitempty"`
+	Operations    []string           `json:"operations,omitempty"` // Descriptions of the operations that are not transfers of the address
+}
+
+// Execute lists the history
+func (c *AccountHistoryCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	if c.Address == nil && !ee.IsWalletOpen() {
+		return nil, fmt.Errorf("%w: open a wallet to list its history, or give an address", cliutil.ErrWalletClosed)
+	}
+
+	limit := uint64(DefaultAccountHistoryLimit)
+	if c.Limit != nil {
+		var err error
+		limit, err = strconv.ParseUint(*c.Limit, 10, 64)
+		if err != nil || limit == 0 || limit > MaxAccountHistoryLimit {
+			return nil, fmt.Errorf("%w: %s%s must be from 1 to %d, got %s", cliutil.ErrInvalidParam, OptionPrefix, LimitOption, MaxAccountHistoryLimit, *c.Limit)
+		}
+	}
+
+	var start *uint64
+	if c.Start != nil {
+		seqNum, err := strconv.ParseUint(*c.Start, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: start, %s", cliutil.ErrInvalidParam, err)
+		}
+		start = &seqNum
+	}
+
+	if !ee.IsOnline() {
+		return nil, fmt.Errorf("%w: cannot list account history", cliutil.ErrOffline)
+	}
+
+	var address []byte
+	if c.Address == nil {
+		address = ee.Key.AddressBytes()
+	} else {
+		address = base58.Decode(*c.Address)
+		if len(address) == 0 {
+			return nil, errors.New("could not parse address")
+		}
+	}
+
+	entries, err := ee.RPCClient.GetAccountHistory(ctx, address, start, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	result := NewExecutionResult()
+	items := make([]*AccountHistoryItem, 0, len(entries))
+
+	if len(entries) == 0 {
+		result.AddMessage(fmt.Sprintf("No history found for %s", base58.Encode(address)))
+	}
+
+	for _, entry := range entries {
+		item := &AccountHistoryItem{SeqNum: entry.SeqNum}
+		when := "unknown time"
+		if entry.Block != nil {
+			item.BlockHeight = entry.Block.GetHeight()
+			when = time.Unix(0, int64(entry.Block.GetTimestamp())*int64(time.Millisecond)).Format(timestampFormat)
+			item.Timestamp = when
+		}
+
+		// Entries without a transaction are blocks the address produced
+		if entry.Transaction == nil {
+			item.ProducedBlock = true
+			result.AddMessage(fmt.Sprintf("%s produced block %d", when, item.BlockHeight))
+			items = append(items, item)
+			continue
+		}
+
+		item.TransactionID = "0x" + hex.EncodeToString(entry.Transaction.GetId())
+		item.Reverted = entry.Receipt.GetReverted()
+		for _, op := range entry.Transaction.GetOperations() {
+			if transfer := ee.decodeTransfer(op, address); transfer != nil {
+				item.Transfers = append(item.Transfers, transfer)
+			} else {
+				item.Operations = append(item.Operations, describeOperation(op, ee.Contracts))
+			}
+		}
+
+		status := ""
+		if item.Reverted {
+			status = " (reverted)"
+		}
+
+		result.AddMessage(fmt.Sprintf("%s %s%s", when, item.TransactionID, status))
+		for _, transfer := range item.Transfers {
+			result.AddMessage(fmt.Sprintf("   %s", transfer))
+		}
+		for _, op := range item.Operations {
+			result.AddMessage(fmt.Sprintf("   %s", op))
+		}
+
+		items = append(items, item)
+	}
+
+	// A full page may be followed by older entries, which are listed by starting below the oldest one shown
+	if uint64(len(entries)) == limit && entries[len(entries)-1].SeqNum > 0 {
+		result.AddMessage(fmt.Sprintf("Older entries may follow, list them with start %d", entries[len(entries)-1].SeqNum-1))
+	}
+
+	result.SetField("address", base58.Encode(address))
+	result.SetField("history", items)
+
+	return result, nil
+}
+
+// decodeTransfer returns the token transfer to or from the address made by an operation, or nil if it is not one.
+// Amounts of KOIN and registered tokens are shown in their units
+func (ee *ExecutionEnvironment) decodeTransfer(op *protocol.Operation, address []byte) *AccountTransfer {
+	call := op.GetCallContract()
+	if call == nil || call.GetEntryPoint() != TokenTransferEntry {
+		return nil
+	}
+
+	args := &token.TransferArguments{}
+	if err := proto.Unmarshal(call.GetArgs(), args); err != nil {
+		return nil
+	}
+
+	transfer := &AccountTransfer{Token: base58.Encode(call.GetContractId()), Amount: strconv.FormatUint(args.GetValue(), 10)}
+	switch {
+	case bytes.Equal(args.GetFrom(), address):
+		transfer.Direction = TransferSent
+		transfer.Counterparty = base58.Encode(args.GetTo())
+	case bytes.Equal(args.GetTo(), address):
+		transfer.Direction = TransferReceived
+		transfer.Counterparty = base58.Encode(args.GetFrom())
+	default:
+		return nil
+	}
+
+	var units *ABIUnit
+	if transfer.Token == cliutil.KoinContractID {
+		units = &ABIUnit{Precision: ee.koinPrecision, Symbol: ee.koinSymbol}
+	} else {
+		units = ee.Contracts.GetTokenUnits(transfer.Token)
+	}
+
+	if units != nil {
+		if dec, err := util.SatoshiToDecimal(args.GetValue(), units.Precision); err == nil {
+			transfer.Amount = dec.String()
+			transfer.Symbol = units.Symbol
+		}
+	}
+
+	return transfer
+}
+
 // ----------------------------------------------------------------------------
 // TokenTotalSupply
 // ----------------------------------------------------------------------------
@@ -280,7 +739,9 @@ func (c *TokenTotalSupplyCommand) Execute(ctx context.Context, ee *ExecutionEnvi
 	}
 
 	er := NewExecutionResult()
-	er.AddMessage(fmt.Sprintf("%v %s", dec, c.Symbol))
+	er.AddMessage(ee.formatAmount(dec, c.Symbol))
+	er.SetField("total_supply", dec.String())
+	er.SetField("symbol", c.Symbol)
 
 	return er, nil
 }
@@ -303,13 +764,30 @@ func NewTokenTransferCommand(inv *CommandParseResult, contractID []byte, precisi
 	return &TokenTransferCommand{Address: *inv.Args["to"], Amount: *inv.Args["amount"], ContractID: contractID, Precision: precision, Symbol: symbol}
 }
 
+// TransferCommand is a command that transfers KOIN, using the environment's KOIN symbol and precision
+type TransferCommand struct {
+	Address string
+	Amount  string
+}
+
+// NewTransferCommand instantiates the command to transfer KOIN
+func NewTransferCommand(inv *CommandParseResult) Command {
+	return &TransferCommand{Address: *inv.Args["address"], Amount: *inv.Args["amount"]}
+}
+
+// Execute the KOIN transfer
+func (c *TransferCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
+	cmd := &TokenTransferCommand{Address: c.Address, Amount: c.Amount, ContractID: base58.Decode(cliutil.KoinContractID), Precision: ee.koinPrecision, Symbol: ee.koinSymbol}
+	return cmd.Execute(ctx, ee)
+}
+
 // Execute the token transfer
 func (c *TokenTransferCommand) Execute(ctx context.Context, ee *ExecutionEnvironment) (*ExecutionResult, error) {
 	if !ee.IsWalletOpen() {
 		return nil, fmt.Errorf("%w: cannot transfer", cliutil.ErrWalletClosed)
 	}
 
-	if !ee.IsOnline() && !ee.Session.IsValid() {
+	if !ee.CanSubmit() {
 		return nil, fmt.Errorf("%w: cannot transfer", cliutil.ErrOffline)
 	}
 
@@ -330,8 +808,8 @@ func (c *TokenTransferCommand) Execute(ctx context.Context, ee *ExecutionEnviron
 
 	walletAddress := ee.Key.AddressBytes()
 
-	if ee.IsOnline() {
-		balance, err := retrieveBalance(ctx, ee.RPCClient, c.ContractID, walletAddress)
+	if ee.IsOnline() && !ee.parseOnly {
+		balance, err := retrieveBalance(ctx, ee.RPCClient, c.ContractID, walletAddress, ee.balanceOfEntry(c.ContractID))
 		if err != nil {
 			return nil, err
 		}
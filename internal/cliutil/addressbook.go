@@ -0,0 +1,93 @@
+package cliutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/btcsuite/btcutil/base58"
+)
+
+// ValidateAddress returns an error if the given string is not a Koinos address, a versioned 20 byte hash with a checksum
+func ValidateAddress(address string) error {
+	payload, version, err := base58.CheckDecode(address)
+	if err != nil || version != 0 || len(payload) != 20 {
+		return fmt.Errorf("%w (invalid address %s)", ErrInvalidParam, address)
+	}
+
+	return nil
+}
+
+// LoadAddressBook reads the aliases stored in an address book file. A missing file is an empty address book
+func LoadAddressBook(filename string) (map[string]string, error) {
+	aliases, err := readStringMap(filename)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address book %s, %w", filename, err)
+	}
+
+	for name, address := range aliases {
+		if err := ValidateAddress(address); err != nil {
+			return nil, fmt.Errorf("invalid address book %s, alias %s: %w", filename, name, err)
+		}
+	}
+
+	return aliases, nil
+}
+
+// SaveAddressBook writes the aliases to an address book file
+func SaveAddressBook(filename string, aliases map[string]string) error {
+	return writeStringMap(filename, aliases, 0644)
+}
+
+// LoadCommandAliases reads the command aliases stored in a file, mapping each alias to the command line it stands for.
+// A missing file has no aliases
+func LoadCommandAliases(filename string) (map[string]string, error) {
+	aliases, err := readStringMap(filename)
+	if err != nil {
+		return nil, fmt.Errorf("invalid command alias file %s, %w", filename, err)
+	}
+
+	return aliases, nil
+}
+
+// SaveCommandAliases writes the command aliases to a file only the user can read, like the history file, since an
+// alias may hold a password. A file created readable by others is restricted as well
+func SaveCommandAliases(filename string, aliases map[string]string) error {
+	err := writeStringMap(filename, aliases, 0600)
+	if err != nil {
+		return err
+	}
+
+	return os.Chmod(filename, 0600)
+}
+
+// readStringMap reads a JSON object of strings from a file, empty if the file does not exist
+func readStringMap(filename string) (map[string]string, error) {
+	values := make(map[string]string)
+
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return values, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &values)
+	if err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// writeStringMap writes a JSON object of strings to a file, created with the given permissions
+func writeStringMap(filename string, values map[string]string, perm os.FileMode) error {
+	data, err := json.MarshalIndent(values, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filename, data, perm)
+}
@@ -0,0 +1,29 @@
+package cliutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveCommandAliases(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "commands")
+
+	// An alias may hold a password, so the file is only readable by the user, even if it already existed
+	assert.NoError(t, ioutil.WriteFile(filename, []byte("{}"), 0644))
+	assert.NoError(t, SaveCommandAliases(filename, map[string]string{"u": "open w.wallet secret"}))
+
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(filename)
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	}
+
+	aliases, err := LoadCommandAliases(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"u": "open w.wallet secret"}, aliases)
+}
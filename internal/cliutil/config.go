@@ -0,0 +1,206 @@
+package cliutil
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+)
+
+// ConfigEnvPrefix prefixes the names of the environment variables that set command line options
+const ConfigEnvPrefix = "KOINOS_CLI_"
+
+// Sources of a resolved option value, from lowest to highest priority
+const (
+	ConfigSourceDefault     = "default"
+	ConfigSourceFile        = "config file"
+	ConfigSourceEnvironment = "environment"
+	ConfigSourceCommandLine = "command line"
+)
+
+// ConfigOption is the resolved value of a command line option, and where it came from
+type ConfigOption struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// ConfigEnvVar returns the name of the environment variable that sets the given option
+func ConfigEnvVar(name string) string {
+	return ConfigEnvPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// ConfigNetworksKey is the config file key holding custom network presets
+const ConfigNetworksKey = "networks"
+
+// Config is the contents of a config file
+type Config struct {
+	Options  map[string]string         // Values of command line options, by option name
+	Networks map[string]*NetworkPreset // Custom network presets, by name
+}
+
+// LoadConfigFile reads a JSON config file, an object of option names to string, number, or boolean values. Custom
+// network presets may be given as an object under the networks key. A missing file is an empty config
+func LoadConfigFile(filename string) (*Config, error) {
+	config := &Config{Options: make(map[string]string), Networks: make(map[string]*NetworkPreset)}
+
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %s, %w", filename, err)
+	}
+
+	values := make(map[string]json.RawMessage)
+	err = json.Unmarshal(data, &values)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %s, %w", filename, err)
+	}
+
+	for name, raw := range values {
+		if name == ConfigNetworksKey {
+			err = loadConfigNetworks(raw, config.Networks)
+			if err != nil {
+				return nil, fmt.Errorf("invalid config file %s, %w", filename, err)
+			}
+			continue
+		}
+
+		var value interface{}
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+		err = decoder.Decode(&value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid config file %s, %w", filename, err)
+		}
+
+		switch v := value.(type) {
+		case string:
+			config.Options[name] = v
+		case json.Number:
+			config.Options[name] = v.String()
+		case bool:
+			config.Options[name] = fmt.Sprint(v)
+		default:
+			return nil, fmt.Errorf("invalid config file %s, option %s: %w (must be a string, number, or boolean)", filename, name, ErrInvalidParam)
+		}
+	}
+
+	return config, nil
+}
+
+// loadConfigNetworks reads custom network presets. Symbol and precision default to those of KOIN
+func loadConfigNetworks(data []byte, networks map[string]*NetworkPreset) error {
+	presets := make(map[string]json.RawMessage)
+	err := json.Unmarshal(data, &presets)
+	if err != nil {
+		return err
+	}
+
+	for name, raw := range presets {
+		preset := NewNetworkPreset()
+		err = json.Unmarshal(raw, preset)
+		if err != nil {
+			return fmt.Errorf("network %s, %w", name, err)
+		}
+
+		if preset.RPC == "" {
+			return fmt.Errorf("network %s: %w (rpc is required)", name, ErrInvalidParam)
+		}
+
+		if preset.ChainID != "" {
+			if id, err := base64.URLEncoding.DecodeString(preset.ChainID); err != nil || len(id) == 0 {
+				return fmt.Errorf("network %s: %w (chain_id must be base64)", name, ErrInvalidParam)
+			}
+		}
+
+		networks[name] = preset
+	}
+
+	return nil
+}
+
+// ApplyConfigDefaults sets the options that still have their default values, recording the given source for them
+func ApplyConfigDefaults(flags *flag.FlagSet, options []*ConfigOption, values map[string]string, source string) error {
+	for _, option := range options {
+		value, ok := values[option.Name]
+		if !ok || option.Source != ConfigSourceDefault {
+			continue
+		}
+
+		err := flags.Set(option.Name, value)
+		if err != nil {
+			return fmt.Errorf("%w: %s %s, %s", ErrInvalidParam, option.Name, value, err)
+		}
+
+		option.Value = flags.Lookup(option.Name).Value.String()
+		option.Source = source
+	}
+
+	return nil
+}
+
+// ResolveConfig sets each option not given on the command line from its environment variable, or else from the config,
+// and returns the resolved value of each option. Options can be given a different environment variable than ConfigEnvVar.
+// Excluded options cannot be configured, and are not returned
+func ResolveConfig(flags *flag.FlagSet, config map[string]string, envVars map[string]string, excluded ...string) ([]*ConfigOption, error) {
+	isExcluded := func(name string) bool {
+		for _, e := range excluded {
+			if e == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	for name := range config {
+		if flags.Lookup(name) == nil || isExcluded(name) {
+			return nil, fmt.Errorf("%w: unknown config option %s", ErrInvalidParam, name)
+		}
+	}
+
+	options := make([]*ConfigOption, 0)
+	var err error
+
+	flags.VisitAll(func(f *flag.Flag) {
+		if err != nil || isExcluded(f.Name) {
+			return
+		}
+
+		envVar, ok := envVars[f.Name]
+		if !ok {
+			envVar = ConfigEnvVar(f.Name)
+		}
+
+		source := ConfigSourceDefault
+		if f.Changed {
+			source = ConfigSourceCommandLine
+		} else if value, ok := os.LookupEnv(envVar); ok {
+			source = ConfigSourceEnvironment
+			if e := flags.Set(f.Name, value); e != nil {
+				err = fmt.Errorf("%w: %s %s, %s", ErrInvalidParam, envVar, value, e)
+				return
+			}
+		} else if value, ok := config[f.Name]; ok {
+			source = ConfigSourceFile
+			if e := flags.Set(f.Name, value); e != nil {
+				err = fmt.Errorf("%w: config option %s %s, %s", ErrInvalidParam, f.Name, value, e)
+				return
+			}
+		}
+
+		options = append(options, &ConfigOption{Name: f.Name, Value: f.Value.String(), Source: source})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return options, nil
+}
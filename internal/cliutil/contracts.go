@@ -0,0 +1,52 @@
+package cliutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// SavedContract is a registered contract as stored in a contracts file. A token registered with register_token has
+// no ABI, and is stored with its units instead
+type SavedContract struct {
+	Address string          `json:"address"`
+	ABI     json.RawMessage `json:"abi,omitempty"`
+	Token   *SavedToken     `json:"token,omitempty"`
+}
+
+// SavedToken is how the amounts of a saved token are displayed
+type SavedToken struct {
+	Precision int    `json:"precision"`
+	Symbol    string `json:"symbol"`
+}
+
+// LoadContracts reads the contracts stored in a file, by name. A missing file has no contracts
+func LoadContracts(filename string) (map[string]*SavedContract, error) {
+	contracts := make(map[string]*SavedContract)
+
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return contracts, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid contracts file %s, %w", filename, err)
+	}
+
+	err = json.Unmarshal(data, &contracts)
+	if err != nil {
+		return nil, fmt.Errorf("invalid contracts file %s, %w", filename, err)
+	}
+
+	return contracts, nil
+}
+
+// SaveContracts writes the contracts to a file
+func SaveContracts(filename string, contracts map[string]*SavedContract) error {
+	data, err := json.MarshalIndent(contracts, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filename, data, 0644)
+}
@@ -20,6 +20,9 @@ var (
 	// ErrInvalidParam is returned when a parameter is invalid.
 	ErrInvalidParam = errors.New("invalid value given for parameter")
 
+	// ErrInvalidString is returned when a string argument is malformed, such as a missing closing quote
+	ErrInvalidString = errors.New("invalid string")
+
 	// ErrInvalidResponse is returned when a response from the RPC endpoint is invalid
 	ErrInvalidResponse = errors.New("invalid response")
 
@@ -38,14 +41,23 @@ var (
 	// ErrWalletDecrypt is returned when a wallet file does not decrypt properly
 	ErrWalletDecrypt = errors.New("wallet decryption failed")
 
+	// ErrWalletVersion is returned when a wallet file is in a format version this CLI cannot read
+	ErrWalletVersion = errors.New("unsupported wallet file version")
+
 	// ErrInvalidPrivateKey is returned when an imported private key is invalid
 	ErrInvalidPrivateKey = errors.New("invalid private key")
 
+	// ErrInvalidMnemonic is returned when a mnemonic phrase is invalid
+	ErrInvalidMnemonic = errors.New("invalid mnemonic")
+
 	// ErrInvalidAmount is returned when an amount is invalid
 	ErrInvalidAmount = errors.New("invalid amount")
 
-	// ErrOffline is returned when a wallet is not online
-	ErrOffline = errors.New("wallet is offline")
+	// ErrOffline is returned when there is no connection to an RPC endpoint
+	ErrOffline = errors.New("not connected to an RPC endpoint")
+
+	// ErrUnreachable is returned when a request does not reach the RPC endpoint
+	ErrUnreachable = errors.New("rpc endpoint unreachable")
 
 	// ErrFileNotFound is returned when the file is not found
 	ErrFileNotFound = errors.New("file not found")
@@ -53,6 +65,9 @@ var (
 	// ErrBlankPassword is returned when the user supplies a blank password
 	ErrBlankPassword = errors.New("blank password")
 
+	// ErrPasswordMismatch is returned when a password and its confirmation do not match
+	ErrPasswordMismatch = errors.New("passwords do not match")
+
 	// ErrInvalidABI is returned when an ABI is invalid
 	ErrInvalidABI = errors.New("invalid ABI")
 
@@ -62,6 +77,21 @@ var (
 	// ErrContract is returned when a contract is already registered
 	ErrContract = errors.New("contract error")
 
+	// ErrTransactionNotIncluded is returned when a transaction is not included in a block before the wait timeout
+	ErrTransactionNotIncluded = errors.New("transaction not included in a block before timeout")
+
 	// ErrInsufficientRC is returned when not enough resource credits can be used to cover a transaction
 	ErrInsufficientRC = errors.New("insufficient rc")
+
+	// ErrMaxRcExceeded is returned when a transaction would use more mana than the most allowed for it
+	ErrMaxRcExceeded = errors.New("transaction exceeds the maximum rc")
+
+	// ErrNotConfirmed is returned when the user does not confirm a transaction before it is submitted
+	ErrNotConfirmed = errors.New("transaction not confirmed")
+
+	// ErrInterrupted is returned when a command is cancelled with Ctrl-C
+	ErrInterrupted = errors.New("interrupted")
+
+	// ErrMaxDuration is returned when commands are stopped because the CLI ran for its maximum duration
+	ErrMaxDuration = errors.New("maximum duration exceeded")
 )
@@ -0,0 +1,209 @@
+package cliutil
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// KDFParams are the scrypt cost parameters used to derive the key of an encrypted file from its password
+type KDFParams struct {
+	N int `json:"n"` // CPU and memory cost, a power of two
+	R int `json:"r"` // Block size
+	P int `json:"p"` // Parallelization
+}
+
+// Limits on the scrypt cost parameters. The minimums keep wallet files from being made dangerously weak, and the
+// maximum memory keeps a crafted file from exhausting memory when opened
+const (
+	MinKDFN      = 1 << 14
+	MinKDFR      = 8
+	MinKDFP      = 1
+	MaxKDFP      = 16
+	MaxKDFMemory = 1 << 30 // In bytes, scrypt uses 128 * N * R
+)
+
+// DefaultKDFParams is the cost used for new wallet files when none is given
+var DefaultKDFParams = KDFParams{N: 1 << 15, R: 8, P: 1}
+
+// String shows the parameters and the memory they use
+func (k KDFParams) String() string {
+	return fmt.Sprintf("scrypt N=%d r=%d p=%d (%d MiB)", k.N, k.R, k.P, k.memory()>>20)
+}
+
+func (k KDFParams) memory() int64 {
+	return 128 * int64(k.N) * int64(k.R)
+}
+
+// Validate checks that the parameters are neither too weak to protect a wallet, nor too costly to open it
+func (k KDFParams) Validate() error {
+	if err := k.checkBounds(); err != nil {
+		return err
+	}
+
+	if k.N < MinKDFN || k.R < MinKDFR {
+		return fmt.Errorf("%w: %s is too weak, N must be at least %d and r at least %d", ErrInvalidParam, k, MinKDFN, MinKDFR)
+	}
+
+	return nil
+}
+
+// checkBounds checks that the parameters are usable, without requiring a minimum strength
+func (k KDFParams) checkBounds() error {
+	if k.N <= 1 || k.N&(k.N-1) != 0 {
+		return fmt.Errorf("%w: scrypt N must be a power of two greater than 1, got %d", ErrInvalidParam, k.N)
+	}
+
+	if k.R < 1 || k.P < MinKDFP || k.P > MaxKDFP {
+		return fmt.Errorf("%w: scrypt r must be positive and p from %d to %d, got r=%d p=%d", ErrInvalidParam, MinKDFP, MaxKDFP, k.R, k.P)
+	}
+
+	if k.memory() > MaxKDFMemory {
+		return fmt.Errorf("%w: %s uses more than %d MiB", ErrInvalidParam, k, MaxKDFMemory>>20)
+	}
+
+	return nil
+}
+
+// Versions of the encrypted file format used by wallet files. Version 1 files have no header, and their key is the
+// sha256 hash of the password. Version 2 files start with a header line naming the key derivation. Files written
+// before the version was recorded in the header are version 2
+const (
+	WalletFileV1             = 1
+	WalletFileV2             = 2
+	CurrentWalletFileVersion = WalletFileV2
+)
+
+// kdfHeaderMagic starts the first line of an encrypted file whose key is derived with scrypt. Files without it are
+// version 1 files
+var kdfHeaderMagic = []byte("KOINOS-KDF ")
+
+// kdfHeader is the JSON rest of the header line, holding the file version and what is needed to derive the key again
+type kdfHeader struct {
+	Version int    `json:"version,omitempty"`
+	KDF     string `json:"kdf"`
+	KDFParams
+	Salt []byte `json:"salt"`
+}
+
+const (
+	scryptKDF     = "scrypt"
+	kdfSaltLength = 16
+	kdfKeyLength  = 32
+)
+
+// writeKDFHeader writes a header with a new random salt, returning the key derived from the passphrase
+func writeKDFHeader(w io.Writer, passphrase string, params KDFParams) ([]byte, error) {
+	if err := params.checkBounds(); err != nil {
+		return nil, err
+	}
+
+	header := kdfHeader{Version: CurrentWalletFileVersion, KDF: scryptKDF, KDFParams: params, Salt: make([]byte, kdfSaltLength)}
+	if _, err := rand.Read(header.Salt); err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(&header)
+	if err != nil {
+		return nil, err
+	}
+
+	line := append(append(append([]byte{}, kdfHeaderMagic...), data...), '\n')
+	if _, err = w.Write(line); err != nil {
+		return nil, err
+	}
+
+	return scrypt.Key([]byte(passphrase), header.Salt, params.N, params.R, params.P, kdfKeyLength)
+}
+
+// readKDFHeader reads the header of an encrypted file, if it has one. The returned reader holds the rest of the file
+func readKDFHeader(r io.Reader) (*kdfHeader, io.Reader, error) {
+	reader := bufio.NewReader(r)
+
+	// Legacy files, including those shorter than the magic, have no header
+	magic, err := reader.Peek(len(kdfHeaderMagic))
+	if err != nil || !bytes.Equal(magic, kdfHeaderMagic) {
+		return nil, reader, nil
+	}
+
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: truncated key derivation header", ErrWalletDecrypt)
+	}
+
+	header := &kdfHeader{}
+	err = json.Unmarshal(line[len(kdfHeaderMagic):], header)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: invalid key derivation header, %s", ErrWalletDecrypt, err)
+	}
+
+	if header.Version == 0 {
+		header.Version = WalletFileV2
+	}
+
+	if header.Version > CurrentWalletFileVersion {
+		return nil, nil, fmt.Errorf("%w: version %d, a newer version of the CLI is needed to open it", ErrWalletVersion, header.Version)
+	}
+
+	// Only files without a header are version 1
+	if header.Version < WalletFileV2 {
+		return nil, nil, fmt.Errorf("%w: invalid version %d in header", ErrWalletVersion, header.Version)
+	}
+
+	if header.KDF != scryptKDF {
+		return nil, nil, fmt.Errorf("%w: unsupported key derivation %s", ErrWalletDecrypt, header.KDF)
+	}
+
+	if err = header.checkBounds(); err != nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrWalletDecrypt, err)
+	}
+
+	return header, reader, nil
+}
+
+// walletFileVersion returns the format version of an encrypted file given its header, which is nil for version 1
+func walletFileVersion(header *kdfHeader) int {
+	if header == nil {
+		return WalletFileV1
+	}
+
+	return header.Version
+}
+
+// ReadWalletFileVersion returns the format version of an encrypted file
+func ReadWalletFileVersion(filename string) (int, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	header, _, err := readKDFHeader(file)
+	if err != nil {
+		return 0, err
+	}
+
+	return walletFileVersion(header), nil
+}
+
+// ReadWalletFileKDF returns the key derivation parameters of an encrypted file, or nil if it is a legacy file
+func ReadWalletFileKDF(filename string) (*KDFParams, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	header, _, err := readKDFHeader(file)
+	if err != nil || header == nil {
+		return nil, err
+	}
+
+	return &header.KDFParams, nil
+}
@@ -0,0 +1,106 @@
+package cliutil
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// LogLevel is the verbosity of the diagnostic log
+type LogLevel int32
+
+// Log levels, each including the messages of the levels before it
+const (
+	LogLevelNone LogLevel = iota
+	LogLevelInfo
+	LogLevelDebug
+)
+
+// RedactedValue replaces the value of sensitive fields in logged data
+const RedactedValue = "<redacted>"
+
+// sensitiveFields are the names of fields whose values are never logged
+var sensitiveFields = []string{"private_key", "privatekey", "password", "passphrase", "mnemonic", "seed", "wif"}
+
+var (
+	logLevel int32
+	logger   = log.New(os.Stderr, "", log.LstdFlags|log.Lmicroseconds)
+)
+
+// SetLogLevel sets the verbosity of the diagnostic log
+func SetLogLevel(level LogLevel) {
+	atomic.StoreInt32(&logLevel, int32(level))
+}
+
+// SetLogOutput sets the destination of the diagnostic log, which is stderr by default
+func SetLogOutput(w io.Writer) {
+	logger.SetOutput(w)
+}
+
+// LogEnabled returns true if messages at the given level are logged
+func LogEnabled(level LogLevel) bool {
+	return level != LogLevelNone && LogLevel(atomic.LoadInt32(&logLevel)) >= level
+}
+
+// Infof logs a message when verbose logging is enabled
+func Infof(format string, v ...interface{}) {
+	if LogEnabled(LogLevelInfo) {
+		logger.Printf("INFO  "+format, v...)
+	}
+}
+
+// Debugf logs a message when debug logging is enabled
+func Debugf(format string, v ...interface{}) {
+	if LogEnabled(LogLevelDebug) {
+		logger.Printf("DEBUG "+format, v...)
+	}
+}
+
+// Redact returns a copy of the given JSON with the values of sensitive fields replaced.
+// Data that is not valid JSON is replaced entirely, since it cannot be inspected
+func Redact(data []byte) string {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return RedactedValue
+	}
+
+	b, err := json.Marshal(redactValue(v))
+	if err != nil {
+		return RedactedValue
+	}
+
+	return string(b)
+}
+
+func redactValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for key, value := range t {
+			if isSensitiveField(key) {
+				t[key] = RedactedValue
+			} else {
+				t[key] = redactValue(value)
+			}
+		}
+	case []interface{}:
+		for i, value := range t {
+			t[i] = redactValue(value)
+		}
+	}
+
+	return v
+}
+
+func isSensitiveField(name string) bool {
+	lower := strings.ToLower(name)
+	for _, field := range sensitiveFields {
+		if strings.Contains(lower, field) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,56 @@
+package cliutil
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"strconv"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/base58"
+)
+
+// MessagePrefix starts every signed message, so that a message signature can never be a valid transaction signature
+const MessagePrefix = "\x19Koinos Signed Message:\n"
+
+// messageDigest returns the sha256 hash of a message, after MessagePrefix and the message's length in bytes
+func messageDigest(message string) [32]byte {
+	return sha256.Sum256([]byte(MessagePrefix + strconv.Itoa(len(message)) + message))
+}
+
+// SignMessage signs the digest of a message with the given private key, returning a compact recoverable signature
+func SignMessage(key []byte, message string) ([]byte, error) {
+	privateKey, _ := btcec.PrivKeyFromBytes(btcec.S256(), key)
+	digest := messageDigest(message)
+
+	return btcec.SignCompact(btcec.S256(), privateKey, digest[:], true)
+}
+
+// VerifyMessage returns true if the signature of the message was made by the key of the given address
+func VerifyMessage(address []byte, message string, signature []byte) (bool, error) {
+	digest := messageDigest(message)
+
+	publicKey, _, err := btcec.RecoverCompact(btcec.S256(), signature, digest[:])
+	if err != nil {
+		return false, fmt.Errorf("%w: could not recover a key from the signature, %s", ErrInvalidParam, err)
+	}
+
+	signer, err := PublicKeyToAddress(publicKey)
+	if err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(signer, address), nil
+}
+
+// PublicKeyToAddress returns the address of a public key
+func PublicKeyToAddress(publicKey *btcec.PublicKey) ([]byte, error) {
+	address, err := btcutil.NewAddressPubKey(publicKey.SerializeCompressed(), &chaincfg.MainNetParams)
+	if err != nil {
+		return nil, err
+	}
+
+	return base58.Decode(address.EncodeAddress()), nil
+}
@@ -0,0 +1,127 @@
+package cliutil
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	util "github.com/koinos/koinos-util-golang"
+	"github.com/tyler-smith/go-bip39"
+)
+
+// KoinosDerivationPath is the BIP-44 path used to derive a Koinos key from a mnemonic (m/44'/659'/0'/0/0)
+var KoinosDerivationPath = KoinosAccountPath(0)
+
+// KoinosAccountPath returns the BIP-44 path of the Koinos account with the given index (m/44'/659'/account'/0/0)
+func KoinosAccountPath(account uint32) []uint32 {
+	return []uint32{
+		hdkeychain.HardenedKeyStart + 44,
+		hdkeychain.HardenedKeyStart + 659,
+		hdkeychain.HardenedKeyStart + account,
+		0,
+		0,
+	}
+}
+
+// ParseDerivationPath parses a BIP-32 derivation path such as m/44'/659'/1'/0/0, where a trailing ' or h marks a
+// hardened index. A plain number is taken as an account index on the Koinos path
+func ParseDerivationPath(path string) ([]uint32, error) {
+	path = strings.TrimSpace(path)
+	if account, err := strconv.ParseUint(path, 10, 31); err == nil {
+		return KoinosAccountPath(uint32(account)), nil
+	}
+
+	parts := strings.Split(path, "/")
+	if parts[0] != "m" || len(parts) < 2 {
+		return nil, fmt.Errorf("%w: derivation path must start with m/, not %s", ErrInvalidParam, path)
+	}
+
+	indices := make([]uint32, 0, len(parts)-1)
+	for _, part := range parts[1:] {
+		var offset uint32
+		if strings.HasSuffix(part, "'") || strings.HasSuffix(part, "h") {
+			part = part[:len(part)-1]
+			offset = hdkeychain.HardenedKeyStart
+		}
+
+		index, err := strconv.ParseUint(part, 10, 31)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid index %s in derivation path %s", ErrInvalidParam, part, path)
+		}
+
+		indices = append(indices, uint32(index)+offset)
+	}
+
+	return indices, nil
+}
+
+// FormatDerivationPath returns the text form of a derivation path, marking hardened indices with '
+func FormatDerivationPath(path []uint32) string {
+	var b strings.Builder
+	b.WriteString("m")
+	for _, i := range path {
+		if i >= hdkeychain.HardenedKeyStart {
+			fmt.Fprintf(&b, "/%d'", i-hdkeychain.HardenedKeyStart)
+		} else {
+			fmt.Fprintf(&b, "/%d", i)
+		}
+	}
+
+	return b.String()
+}
+
+// GenerateMnemonic generates a new BIP-39 mnemonic with the given number of words (12 or 24)
+func GenerateMnemonic(words int) (string, error) {
+	var bits int
+	switch words {
+	case 12:
+		bits = 128
+	case 24:
+		bits = 256
+	default:
+		return "", fmt.Errorf("%w: mnemonic must be 12 or 24 words, not %d", ErrInvalidMnemonic, words)
+	}
+
+	entropy, err := bip39.NewEntropy(bits)
+	if err != nil {
+		return "", err
+	}
+
+	return bip39.NewMnemonic(entropy)
+}
+
+// KeyFromMnemonic validates a BIP-39 mnemonic and derives its Koinos key
+func KeyFromMnemonic(mnemonic string) (*util.KoinosKey, error) {
+	return KeyFromMnemonicPath(mnemonic, KoinosDerivationPath)
+}
+
+// KeyFromMnemonicPath validates a BIP-39 mnemonic and derives the key at the given path
+func KeyFromMnemonicPath(mnemonic string, path []uint32) (*util.KoinosKey, error) {
+	mnemonic = strings.Join(strings.Fields(mnemonic), " ")
+
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, "")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidMnemonic, err)
+	}
+
+	extKey, err := hdkeychain.NewMaster(seed, &chaincfg.MainNetParams)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, i := range path {
+		extKey, err = extKey.Child(i)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	privKey, err := extKey.ECPrivKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return util.NewKoinosKeyFromBytes(privKey.Serialize())
+}
@@ -0,0 +1,55 @@
+package cliutil
+
+import (
+	"fmt"
+	"sort"
+)
+
+// NetworkPreset holds the settings for connecting to a Koinos network
+type NetworkPreset struct {
+	RPC       string `json:"rpc"`
+	ChainID   string `json:"chain_id,omitempty"` // Base64 chain id, or empty to ask the node for it
+	Symbol    string `json:"koin_symbol"`
+	Precision int    `json:"koin_precision"`
+}
+
+// Networks are the built in network presets, by name
+var Networks = map[string]*NetworkPreset{
+	"mainnet": {RPC: "https://api.koinos.io/", Symbol: KoinSymbol, Precision: KoinPrecision},
+	"testnet": {RPC: "https://harbinger-api.koinos.io/", Symbol: "tKOIN", Precision: KoinPrecision},
+}
+
+// NewNetworkPreset returns a preset with the default KOIN symbol and precision, to be filled in from a config file
+func NewNetworkPreset() *NetworkPreset {
+	return &NetworkPreset{Symbol: KoinSymbol, Precision: KoinPrecision}
+}
+
+// GetNetwork returns the named network preset. Custom presets take priority over the built in ones
+func GetNetwork(name string, custom map[string]*NetworkPreset) (*NetworkPreset, error) {
+	if preset, ok := custom[name]; ok {
+		return preset, nil
+	}
+
+	if preset, ok := Networks[name]; ok {
+		return preset, nil
+	}
+
+	return nil, fmt.Errorf("%w: unknown network %s", ErrInvalidParam, name)
+}
+
+// NetworkNames returns the sorted names of the built in and custom network presets
+func NetworkNames(custom map[string]*NetworkPreset) []string {
+	names := make([]string, 0, len(Networks)+len(custom))
+	for name := range Networks {
+		names = append(names, name)
+	}
+
+	for name := range custom {
+		if _, ok := Networks[name]; !ok {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,151 @@
+package cliutil
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// AutoDecimals shows as many decimal places as an amount needs
+const AutoDecimals = -1
+
+// MaxDecimals is the most decimal places that may be fixed, the most a token precision may have
+const MaxDecimals = 18
+
+// Words of a number format, given separated by commas such as "separators,decimals=2"
+const (
+	PlainNumberFormat      = "plain"
+	SeparatorsNumberFormat = "separators"
+	DecimalsNumberFormat   = "decimals"
+	ScientificNumberFormat = "scientific"
+)
+
+// scientificThreshold is the amount below which amounts are shown in scientific notation, when enabled
+var scientificThreshold = decimal.New(1, -3)
+
+// NumberFormat controls how amounts such as balances are shown
+type NumberFormat struct {
+	Separators bool // Group the digits of the integer part by thousands with commas
+	Decimals   int  // Fixed number of decimal places, truncated toward zero, or AutoDecimals
+	Scientific bool // Show nonzero amounts below 0.001 in scientific notation
+}
+
+// DefaultNumberFormat shows amounts as they are, with as many decimal places as needed
+var DefaultNumberFormat = NumberFormat{Decimals: AutoDecimals}
+
+// ParseNumberFormat parses a number format given as words separated by commas. Plain is the default format,
+// separators groups digits by thousands, decimals=N fixes the decimal places, and scientific shows tiny amounts in
+// scientific notation
+func ParseNumberFormat(format string) (NumberFormat, error) {
+	nf := DefaultNumberFormat
+	if strings.TrimSpace(format) == "" {
+		return nf, fmt.Errorf("%w: empty number format", ErrInvalidParam)
+	}
+
+	for _, word := range strings.Split(format, ",") {
+		word = strings.TrimSpace(word)
+		switch {
+		case word == PlainNumberFormat:
+			nf = DefaultNumberFormat
+		case word == SeparatorsNumberFormat:
+			nf.Separators = true
+		case word == ScientificNumberFormat:
+			nf.Scientific = true
+		case strings.HasPrefix(word, DecimalsNumberFormat+"="):
+			decimals, err := strconv.ParseUint(strings.TrimPrefix(word, DecimalsNumberFormat+"="), 10, 8)
+			if err != nil || decimals > MaxDecimals {
+				return nf, fmt.Errorf("%w: decimals must be between 0 and %d", ErrInvalidParam, MaxDecimals)
+			}
+			nf.Decimals = int(decimals)
+		default:
+			return nf, fmt.Errorf("%w: unknown number format %s, expected %s, %s, %s=N, or %s", ErrInvalidParam, word, PlainNumberFormat, SeparatorsNumberFormat, DecimalsNumberFormat, ScientificNumberFormat)
+		}
+	}
+
+	return nf, nil
+}
+
+// String returns the format in the form ParseNumberFormat accepts
+func (nf NumberFormat) String() string {
+	words := make([]string, 0, 3)
+	if nf.Separators {
+		words = append(words, SeparatorsNumberFormat)
+	}
+	if nf.Decimals != AutoDecimals {
+		words = append(words, fmt.Sprintf("%s=%d", DecimalsNumberFormat, nf.Decimals))
+	}
+	if nf.Scientific {
+		words = append(words, ScientificNumberFormat)
+	}
+
+	if len(words) == 0 {
+		return PlainNumberFormat
+	}
+
+	return strings.Join(words, ",")
+}
+
+// Format shows an amount in the format
+func (nf NumberFormat) Format(amount decimal.Decimal) string {
+	if nf.Scientific && !amount.IsZero() && amount.Abs().LessThan(scientificThreshold) {
+		return scientific(amount)
+	}
+
+	// Fixed decimal places are truncated, so an amount is never shown as more than it is
+	s := amount.String()
+	if nf.Decimals != AutoDecimals {
+		decimals := int32(nf.Decimals)
+		s = amount.Truncate(decimals).StringFixed(decimals)
+	}
+
+	if nf.Separators {
+		s = groupThousands(s)
+	}
+
+	return s
+}
+
+// scientific shows a nonzero amount exactly, as its significant digits and a decimal exponent such as 1.5e-07
+func scientific(amount decimal.Decimal) string {
+	sign := ""
+	if amount.Sign() < 0 {
+		sign, amount = "-", amount.Neg()
+	}
+
+	// The exponent is that of the first significant digit, and trailing zeros are not significant
+	coefficient := amount.Coefficient().String()
+	digits := strings.TrimRight(coefficient, "0")
+	exponent := int(amount.Exponent()) + len(coefficient) - 1
+
+	mantissa := digits[:1]
+	if len(digits) > 1 {
+		mantissa += "." + digits[1:]
+	}
+
+	return fmt.Sprintf("%s%se%+03d", sign, mantissa, exponent)
+}
+
+// groupThousands inserts commas between the thousands of the integer part of a number
+func groupThousands(s string) string {
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+
+	integer, fraction := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		integer, fraction = s[:i], s[i:]
+	}
+
+	var b strings.Builder
+	for i, digit := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(digit)
+	}
+
+	return sign + b.String() + fraction
+}
@@ -0,0 +1,47 @@
+package cliutil
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNumberFormat(t *testing.T) {
+	format := func(spec string, amount string) string {
+		nf, err := ParseNumberFormat(spec)
+		assert.NoError(t, err)
+		return nf.Format(decimal.RequireFromString(amount))
+	}
+
+	assert.Equal(t, "1234567.891", format("plain", "1234567.891"))
+	assert.Equal(t, "1,234,567.89", format("separators,decimals=2", "1234567.891"))
+	assert.Equal(t, "-1,234.5", format("separators", "-1234.5"))
+	assert.Equal(t, "123,456", format("separators", "123456"))
+	assert.Equal(t, "123", format("separators", "123"))
+	assert.Equal(t, "1.50000000", format("decimals=8", "1.5"))
+	assert.Equal(t, "1234.5", format("separators,plain", "1234.5"))
+
+	// Fixed decimal places are truncated toward zero, rather than rounded
+	assert.Equal(t, "1.99", format("decimals=2", "1.999"))
+	assert.Equal(t, "-1.99", format("decimals=2", "-1.999"))
+	assert.Equal(t, "0", format("decimals=0", "0.9"))
+
+	// Tiny amounts are shown exactly, with all of their significant digits
+	assert.Equal(t, "1.5e-07", format("scientific", "0.00000015"))
+	assert.Equal(t, "-1.5e-07", format("scientific", "-0.00000015"))
+	assert.Equal(t, "1e-18", format("scientific", "0.000000000000000001"))
+	assert.Equal(t, "1.23456789012345678e-04", format("scientific", "0.000123456789012345678"))
+	assert.Equal(t, "0.5", format("scientific", "0.5"))
+	assert.Equal(t, "0", format("scientific", "0"))
+
+	for _, spec := range []string{"", "decimals=19", "decimals=-1", "bold"} {
+		_, err := ParseNumberFormat(spec)
+		assert.ErrorIs(t, err, ErrInvalidParam, spec)
+	}
+
+	nf, err := ParseNumberFormat("scientific, decimals=2,separators")
+	assert.NoError(t, err)
+	assert.Equal(t, "separators,decimals=2,scientific", nf.String())
+	assert.Equal(t, "plain", DefaultNumberFormat.String())
+}
@@ -1,15 +1,23 @@
 package cliutil
 
 import (
+	"bytes"
 	"context"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
 
+	"github.com/btcsuite/btcutil/base58"
 	kjson "github.com/koinos/koinos-proto-golang/encoding/json"
 	"github.com/koinos/koinos-proto-golang/koinos/contract_meta_store"
 	"github.com/koinos/koinos-proto-golang/koinos/contracts/token"
 	"github.com/koinos/koinos-proto-golang/koinos/protocol"
+	block_store_rpc "github.com/koinos/koinos-proto-golang/koinos/rpc/block_store"
 	"github.com/koinos/koinos-proto-golang/koinos/rpc/chain"
 	contract_meta_store_rpc "github.com/koinos/koinos-proto-golang/koinos/rpc/contract_meta_store"
+	transaction_store_rpc "github.com/koinos/koinos-proto-golang/koinos/rpc/transaction_store"
 	util "github.com/koinos/koinos-util-golang"
 	jsonrpc "github.com/ybbus/jsonrpc/v3"
 	"google.golang.org/protobuf/proto"
@@ -22,7 +30,20 @@ const (
 	GetAccountRcCall      = "chain.get_account_rc"
 	SubmitTransactionCall = "chain.submit_transaction"
 	GetChainIDCall        = "chain.get_chain_id"
+	GetHeadInfoCall       = "chain.get_head_info"
 	GetContractMetaCall   = "contract_meta_store.get_contract_meta"
+	GetTransactionsByID   = "transaction_store.get_transactions_by_id"
+	GetBlocksByIDCall     = "block_store.get_blocks_by_id"
+	GetAccountHistoryCall = "account_history.get_account_history"
+)
+
+// Retry settings used by newly created rpc clients
+var (
+	// DefaultRPCRetries is the number of times a failed rpc request is retried
+	DefaultRPCRetries = 3
+
+	// DefaultRPCRetryDelay is the delay before the first retry, doubling with each subsequent retry
+	DefaultRPCRetryDelay = time.Millisecond * 500
 )
 
 // SubmissionParams is the parameters for a transaction submission
@@ -43,15 +64,89 @@ func (e KoinosRPCError) Error() string {
 	return e.message
 }
 
+// rpcCaller is the transport used to make jsonrpc calls, either HTTP or WebSocket
+type rpcCaller interface {
+	Call(ctx context.Context, method string, params ...interface{}) (*jsonrpc.RPCResponse, error)
+}
+
 // KoinosRPCClient is a wrapper around the jsonrpc client
 type KoinosRPCClient struct {
-	client jsonrpc.RPCClient
+	client rpcCaller
+	url    string
+
+	// Retries is the number of times a request that failed to reach the node is retried
+	Retries int
+
+	// RetryDelay is the delay before the first retry, it doubles with each subsequent retry
+	RetryDelay time.Duration
 }
 
-// NewKoinosRPCClient creates a new koinos rpc client
+// NewKoinosRPCClient creates a new koinos rpc client. A ws:// or wss:// url uses a persistent WebSocket connection, anything else uses HTTP
 func NewKoinosRPCClient(url string) *KoinosRPCClient {
-	client := jsonrpc.NewClient(url)
-	return &KoinosRPCClient{client: client}
+	var client rpcCaller
+	if isWebSocketURL(url) {
+		client = newWSClient(url)
+	} else {
+		client = jsonrpc.NewClient(url)
+	}
+
+	return &KoinosRPCClient{client: client, url: url, Retries: DefaultRPCRetries, RetryDelay: DefaultRPCRetryDelay}
+}
+
+// URL returns the url of the RPC endpoint
+func (c *KoinosRPCClient) URL() string {
+	return c.url
+}
+
+// Close releases the connection to the node, if the transport keeps one open
+func (c *KoinosRPCClient) Close() error {
+	if ws, ok := c.client.(*wsClient); ok {
+		return ws.Close()
+	}
+
+	return nil
+}
+
+// rpcTimeoutKey is the context key of the rpc timeout
+type rpcTimeoutKey struct{}
+
+// WithRPCTimeout returns a context in which each rpc call, including its retries, may wait on the node for at most
+// the given time. Zero disables the timeout
+func WithRPCTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, rpcTimeoutKey{}, timeout)
+}
+
+// callContext bounds a single rpc call by the rpc timeout of the context, if it has one
+func callContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if timeout, ok := ctx.Value(rpcTimeoutKey{}).(time.Duration); ok && timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+
+	return context.WithCancel(ctx)
+}
+
+// callWithRetry makes the rpc call, retrying with exponential backoff if the request fails to reach the node
+func (c *KoinosRPCClient) callWithRetry(ctx context.Context, method string, params interface{}) (*jsonrpc.RPCResponse, error) {
+	delay := c.RetryDelay
+	for attempt := 0; ; attempt++ {
+		resp, err := c.client.Call(ctx, method, params)
+		if err == nil || attempt >= c.Retries || ctx.Err() != nil {
+			return resp, err
+		}
+
+		Infof("rpc %s attempt %d failed, retrying in %v: %v", method, attempt+1, delay, err)
+
+		// Wait before retrying, aborting if the context is done
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		delay *= 2
+	}
 }
 
 // Call wraps the rpc client call and handles some of the boilerplate
@@ -61,12 +156,35 @@ func (c *KoinosRPCClient) Call(ctx context.Context, method string, params proto.
 		return err
 	}
 
-	// Make the rpc call
-	resp, err := c.client.Call(ctx, method, json.RawMessage(req))
+	raw, err := c.callJSON(ctx, method, json.RawMessage(req))
 	if err != nil {
 		return err
 	}
+
+	return kjson.Unmarshal([]byte(raw), returnType)
+}
+
+// callJSON makes the rpc call with JSON params, returning the raw JSON result. It is used directly for calls
+// whose messages are not in the proto package
+func (c *KoinosRPCClient) callJSON(ctx context.Context, method string, req json.RawMessage) (json.RawMessage, error) {
+	ctx, cancel := callContext(ctx)
+	defer cancel()
+
+	// Make the rpc call
+	Debugf("rpc %s request: %s", method, Redact(req))
+	start := time.Now()
+	resp, err := c.callWithRetry(ctx, method, req)
+	elapsed := time.Since(start)
+	if err != nil {
+		Infof("rpc %s failed after %v: %v", method, elapsed, err)
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("%w: no response from rpc endpoint for %s", ctx.Err(), method)
+		}
+		return nil, err
+	}
 	if resp.Error != nil {
+		Infof("rpc %s returned error after %v: %s", method, elapsed, resp.Error.Message)
+		Debugf("rpc %s error data: %v", method, resp.Error.Data)
 		err := KoinosRPCError{message: resp.Error.Message}
 
 		if data, ok := resp.Error.Data.(string); ok {
@@ -79,7 +197,7 @@ func (c *KoinosRPCClient) Call(ctx context.Context, method string, params proto.
 			}
 		}
 
-		return err
+		return nil, err
 	}
 
 	// Fetch the contract response
@@ -87,15 +205,13 @@ func (c *KoinosRPCClient) Call(ctx context.Context, method string, params proto.
 
 	err = resp.GetObject(&raw)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	err = kjson.Unmarshal([]byte(raw), returnType)
-	if err != nil {
-		return err
-	}
+	Infof("rpc %s completed in %v", method, elapsed)
+	Debugf("rpc %s response: %s", method, Redact(raw))
 
-	return nil
+	return raw, nil
 }
 
 // GetAccountBalance gets the balance of a given account
@@ -127,6 +243,7 @@ func (c *KoinosRPCClient) GetAccountBalance(ctx context.Context, address []byte,
 func (c *KoinosRPCClient) ReadContract(ctx context.Context, args []byte, contractID []byte, entryPoint uint32) (*chain.ReadContractResponse, error) {
 	// Build the contract request
 	params := chain.ReadContractRequest{ContractId: contractID, EntryPoint: entryPoint, Args: args}
+	Infof("reading contract %s entry point 0x%08x", base58.Encode(contractID), entryPoint)
 
 	// Make the rpc call
 	var cResp chain.ReadContractResponse
@@ -195,14 +312,26 @@ func (c *KoinosRPCClient) GetContractMeta(ctx context.Context, contractID []byte
 }
 
 // SubmitTransaction creates and submits a transaction from a list of operations
-func (c *KoinosRPCClient) SubmitTransactionOps(ctx context.Context, ops []*protocol.Operation, key *util.KoinosKey, subParams *SubmissionParams, broadcast bool) (*protocol.TransactionReceipt, error) {
-	return c.SubmitTransactionOpsWithPayer(ctx, ops, key, subParams, key.AddressBytes(), broadcast)
+func (c *KoinosRPCClient) SubmitTransactionOps(ctx context.Context, ops []*protocol.Operation, signer Signer, subParams *SubmissionParams, broadcast bool) (*protocol.TransactionReceipt, error) {
+	return c.SubmitTransactionOpsWithPayer(ctx, ops, signer, subParams, signer.AddressBytes(), broadcast)
 }
 
 // SubmitTransaction creates and submits a transaction from a list of operations with a specified payer
-func (c *KoinosRPCClient) SubmitTransactionOpsWithPayer(ctx context.Context, ops []*protocol.Operation, key *util.KoinosKey, subParams *SubmissionParams, payer []byte, broadcast bool) (*protocol.TransactionReceipt, error) {
+func (c *KoinosRPCClient) SubmitTransactionOpsWithPayer(ctx context.Context, ops []*protocol.Operation, signer Signer, subParams *SubmissionParams, payer []byte, broadcast bool) (*protocol.TransactionReceipt, error) {
+	transaction, err := c.CreateTransactionOpsWithPayer(ctx, ops, signer, subParams, payer)
+	if err != nil {
+		return nil, err
+	}
+
+	// Submit the transaction
+	return c.SubmitTransaction(ctx, transaction, broadcast)
+}
+
+// CreateTransactionOpsWithPayer creates a signed transaction from a list of operations with a specified payer, asking
+// the chain for the submission parameters that are not provided
+func (c *KoinosRPCClient) CreateTransactionOpsWithPayer(ctx context.Context, ops []*protocol.Operation, signer Signer, subParams *SubmissionParams, payer []byte) (*protocol.Transaction, error) {
 	// Cache the public address
-	address := key.AddressBytes()
+	address := signer.AddressBytes()
 
 	var err error
 	var nonce uint64 = 0
@@ -240,13 +369,7 @@ func (c *KoinosRPCClient) SubmitTransactionOpsWithPayer(ctx context.Context, ops
 	}
 
 	// Create the transaction
-	transaction, err := CreateSignedTransaction(ctx, ops, key, nonce, rcLimit, chainID, payer)
-	if err != nil {
-		return nil, err
-	}
-
-	// Submit the transaction
-	return c.SubmitTransaction(ctx, transaction, broadcast)
+	return CreateSignedTransaction(ctx, ops, signer, nonce, rcLimit, chainID, payer)
 }
 
 // SubmitTransaction creates and submits a transaction from a list of operations
@@ -254,6 +377,7 @@ func (c *KoinosRPCClient) SubmitTransaction(ctx context.Context, transaction *pr
 	params := chain.SubmitTransactionRequest{}
 	params.Transaction = transaction
 	params.Broadcast = broadcast
+	Infof("submitting transaction 0x%s with %d operations", hex.EncodeToString(transaction.GetId()), len(transaction.GetOperations()))
 
 	// Make the rpc call
 	var cResp chain.SubmitTransactionResponse
@@ -265,6 +389,307 @@ func (c *KoinosRPCClient) SubmitTransaction(ctx context.Context, transaction *pr
 	return cResp.Receipt, nil
 }
 
+// GetTransactionBlocks gets the ids of the blocks containing a given transaction, empty if it has not been included yet
+func (c *KoinosRPCClient) GetTransactionBlocks(ctx context.Context, transactionID []byte) ([][]byte, error) {
+	params := transaction_store_rpc.GetTransactionsByIdRequest{
+		TransactionIds: [][]byte{transactionID},
+	}
+
+	// Make the rpc call
+	var cResp transaction_store_rpc.GetTransactionsByIdResponse
+	err := c.Call(ctx, GetTransactionsByID, &params, &cResp)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range cResp.Transactions {
+		if item != nil && bytes.Equal(item.Transaction.GetId(), transactionID) {
+			return item.ContainingBlocks, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// GetTransactionReceipt gets the receipt of a transaction and the id of the block that includes it.
+// The receipt is nil if the transaction has not been included in a block
+func (c *KoinosRPCClient) GetTransactionReceipt(ctx context.Context, transactionID []byte) (*protocol.TransactionReceipt, []byte, error) {
+	blocks, err := c.GetTransactionBlocks(ctx, transactionID)
+	if err != nil || len(blocks) == 0 {
+		return nil, nil, err
+	}
+
+	params := block_store_rpc.GetBlocksByIdRequest{
+		BlockIds:      blocks[:1],
+		ReturnReceipt: true,
+	}
+
+	// Make the rpc call
+	var cResp block_store_rpc.GetBlocksByIdResponse
+	err = c.Call(ctx, GetBlocksByIDCall, &params, &cResp)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, item := range cResp.BlockItems {
+		for _, receipt := range item.GetReceipt().GetTransactionReceipts() {
+			if bytes.Equal(receipt.GetId(), transactionID) {
+				return receipt, blocks[0], nil
+			}
+		}
+	}
+
+	return nil, nil, fmt.Errorf("%w: block 0x%s has no receipt for transaction 0x%s", ErrInvalidResponse, hex.EncodeToString(blocks[0]), hex.EncodeToString(transactionID))
+}
+
+// GetBlockHeaders gets the headers of the blocks with the given ids in a single call, by block id. Blocks that are
+// not found are left out
+func (c *KoinosRPCClient) GetBlockHeaders(ctx context.Context, blockIDs [][]byte) (map[string]*protocol.BlockHeader, error) {
+	params := block_store_rpc.GetBlocksByIdRequest{
+		BlockIds:    blockIDs,
+		ReturnBlock: true,
+	}
+
+	// Make the rpc call
+	var cResp block_store_rpc.GetBlocksByIdResponse
+	err := c.Call(ctx, GetBlocksByIDCall, &params, &cResp)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := make(map[string]*protocol.BlockHeader)
+	for _, item := range cResp.BlockItems {
+		if item.GetBlock().GetHeader() != nil {
+			headers[string(item.GetBlockId())] = item.GetBlock().GetHeader()
+		}
+	}
+
+	return headers, nil
+}
+
+// getIncludingBlocks gets the id of the first block containing each of the given transactions in a single call, by
+// transaction id. Transactions that have not been included yet are left out
+func (c *KoinosRPCClient) getIncludingBlocks(ctx context.Context, transactionIDs [][]byte) (map[string][]byte, error) {
+	params := transaction_store_rpc.GetTransactionsByIdRequest{
+		TransactionIds: transactionIDs,
+	}
+
+	// Make the rpc call
+	var cResp transaction_store_rpc.GetTransactionsByIdResponse
+	err := c.Call(ctx, GetTransactionsByID, &params, &cResp)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := make(map[string][]byte)
+	for _, item := range cResp.Transactions {
+		if item != nil && len(item.ContainingBlocks) > 0 {
+			blocks[string(item.Transaction.GetId())] = item.ContainingBlocks[0]
+		}
+	}
+
+	return blocks, nil
+}
+
+// AccountHistoryEntry is a transaction involving an account, or a block it produced
+type AccountHistoryEntry struct {
+	SeqNum      uint64 // The position of the entry in the account's history
+	Transaction *protocol.Transaction
+	Receipt     *protocol.TransactionReceipt
+	Block       *protocol.BlockHeader // The produced block, or the block including the transaction if it was found
+}
+
+// accountHistoryResponse is the JSON result of the account history call, whose messages are not in the proto package
+type accountHistoryResponse struct {
+	Values []struct {
+		SeqNum uint64 `json:"seq_num,string"`
+		Trx    *struct {
+			Transaction json.RawMessage `json:"transaction"`
+			Receipt     json.RawMessage `json:"receipt"`
+		} `json:"trx"`
+		Block *struct {
+			Header json.RawMessage `json:"header"`
+		} `json:"block"`
+	} `json:"values"`
+}
+
+// GetAccountHistory gets entries in the history of an account, newest first, starting from the entry with the given
+// sequence number, or from the most recent one if it is nil. This requires the node to run the account history
+// microservice
+func (c *KoinosRPCClient) GetAccountHistory(ctx context.Context, address []byte, start *uint64, limit uint64) ([]*AccountHistoryEntry, error) {
+	params := map[string]interface{}{
+		"address":   base58.Encode(address),
+		"limit":     fmt.Sprint(limit),
+		"ascending": false,
+	}
+	if start != nil {
+		params["seq_num"] = fmt.Sprint(*start)
+	}
+
+	req, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := c.callJSON(ctx, GetAccountHistoryCall, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp accountHistoryResponse
+	err = json.Unmarshal(raw, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidResponse, err)
+	}
+
+	entries := make([]*AccountHistoryEntry, 0, len(resp.Values))
+	for _, value := range resp.Values {
+		entry := &AccountHistoryEntry{SeqNum: value.SeqNum}
+
+		switch {
+		case value.Trx != nil:
+			entry.Transaction = &protocol.Transaction{}
+			if err := kjson.Unmarshal(value.Trx.Transaction, entry.Transaction); err != nil {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidResponse, err)
+			}
+
+			if len(value.Trx.Receipt) > 0 {
+				entry.Receipt = &protocol.TransactionReceipt{}
+				if err := kjson.Unmarshal(value.Trx.Receipt, entry.Receipt); err != nil {
+					return nil, fmt.Errorf("%w: %s", ErrInvalidResponse, err)
+				}
+			}
+		case value.Block != nil:
+			entry.Block = &protocol.BlockHeader{}
+			if err := kjson.Unmarshal(value.Block.Header, entry.Block); err != nil {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidResponse, err)
+			}
+		default:
+			continue
+		}
+
+		entries = append(entries, entry)
+	}
+
+	// The history does not say when transactions were included, so look up their blocks
+	err = c.addIncludingBlocks(ctx, entries)
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// addIncludingBlocks sets the block of each transaction entry to the block including it, if it was found. All of the
+// transactions are looked up in one call, and all of their blocks in another, however many entries there are
+func (c *KoinosRPCClient) addIncludingBlocks(ctx context.Context, entries []*AccountHistoryEntry) error {
+	transactionIDs := make([][]byte, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Transaction != nil {
+			transactionIDs = append(transactionIDs, entry.Transaction.GetId())
+		}
+	}
+
+	if len(transactionIDs) == 0 {
+		return nil
+	}
+
+	blocks, err := c.getIncludingBlocks(ctx, transactionIDs)
+	if err != nil || len(blocks) == 0 {
+		return err
+	}
+
+	blockIDs := make([][]byte, 0, len(blocks))
+	seen := make(map[string]bool)
+	for _, blockID := range blocks {
+		if !seen[string(blockID)] {
+			seen[string(blockID)] = true
+			blockIDs = append(blockIDs, blockID)
+		}
+	}
+
+	headers, err := c.GetBlockHeaders(ctx, blockIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.Transaction == nil {
+			continue
+		}
+
+		if blockID, ok := blocks[string(entry.Transaction.GetId())]; ok {
+			entry.Block = headers[string(blockID)]
+		}
+	}
+
+	return nil
+}
+
+// WaitForTransaction polls until the given transaction is included in a block, returning the id of that block.
+// It gives up when the context is done
+func (c *KoinosRPCClient) WaitForTransaction(ctx context.Context, transactionID []byte, interval time.Duration) ([]byte, error) {
+	for {
+		blocks, err := c.GetTransactionBlocks(ctx, transactionID)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(blocks) > 0 {
+			return blocks[0], nil
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("%w: transaction 0x%s", ErrTransactionNotIncluded, hex.EncodeToString(transactionID))
+		case <-timer.C:
+		}
+	}
+}
+
+// GetHeadInfo gets information about the head block of the node
+func (c *KoinosRPCClient) GetHeadInfo(ctx context.Context) (*chain.GetHeadInfoResponse, error) {
+	params := chain.GetHeadInfoRequest{}
+
+	// Make the rpc call
+	var cResp chain.GetHeadInfoResponse
+	err := c.Call(ctx, GetHeadInfoCall, &params, &cResp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cResp, nil
+}
+
+// Ping makes a single head info call, without retrying, and returns its round trip time. The node is reachable unless
+// the error is ErrUnreachable, since a KoinosRPCError is a response from the node
+func (c *KoinosRPCClient) Ping(ctx context.Context) (time.Duration, error) {
+	req, err := kjson.Marshal(&chain.GetHeadInfoRequest{})
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := callContext(ctx)
+	defer cancel()
+
+	start := time.Now()
+	resp, err := c.client.Call(ctx, GetHeadInfoCall, json.RawMessage(req))
+	elapsed := time.Since(start)
+	if err != nil {
+		// The dial error is logged rather than shown, since it rarely says more than that the node is unreachable
+		Infof("rpc ping of %s failed after %v: %v", c.url, elapsed, err)
+		return elapsed, fmt.Errorf("%w: %s", ErrUnreachable, c.url)
+	}
+
+	if resp.Error != nil {
+		return elapsed, KoinosRPCError{message: resp.Error.Message}
+	}
+
+	return elapsed, nil
+}
+
 // GetChainID gets the chain id
 func (c *KoinosRPCClient) GetChainID(ctx context.Context) ([]byte, error) {
 	// Build the contract request
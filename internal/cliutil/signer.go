@@ -0,0 +1,123 @@
+package cliutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"os"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/koinos/koinos-proto-golang/koinos/protocol"
+	util "github.com/koinos/koinos-util-golang"
+	"github.com/multiformats/go-multihash"
+)
+
+// Signer signs transactions for an address. It separates building a transaction from custody of the key,
+// so that the key can be held in memory, on a hardware wallet, or by a remote signer
+type Signer interface {
+	// AddressBytes returns the address whose key makes the signatures
+	AddressBytes() []byte
+
+	// SignTransaction adds a signature of the transaction id to the transaction
+	SignTransaction(ctx context.Context, tx *protocol.Transaction) error
+}
+
+// KeySigner signs with a private key held in memory, such as one read from a wallet file
+type KeySigner struct {
+	Key *util.KoinosKey
+}
+
+// NewKeySigner creates a signer for a private key
+func NewKeySigner(key *util.KoinosKey) *KeySigner {
+	return &KeySigner{Key: key}
+}
+
+// AddressBytes returns the address of the key
+func (s *KeySigner) AddressBytes() []byte {
+	return s.Key.AddressBytes()
+}
+
+// SignTransaction signs the transaction with the key
+func (s *KeySigner) SignTransaction(ctx context.Context, tx *protocol.Transaction) error {
+	return SignTransaction(s.Key.PrivateBytes(), tx)
+}
+
+// SignDigestFunc signs a sha256 digest with a key held outside the CLI, returning a compact recoverable signature
+type SignDigestFunc func(ctx context.Context, digest []byte) ([]byte, error)
+
+// ExternalSigner signs with a key held outside the CLI, such as on a hardware wallet or by a remote signer.
+// The device is reached through its Sign function, which should wait for the user to approve the transaction
+type ExternalSigner struct {
+	Name    string // Shown when asking the user to approve the transaction, e.g. "Ledger"
+	Address []byte
+	Sign    SignDigestFunc
+}
+
+// NewExternalSigner creates a signer for the key of an address held by an external device
+func NewExternalSigner(name string, address []byte, sign SignDigestFunc) *ExternalSigner {
+	return &ExternalSigner{Name: name, Address: address, Sign: sign}
+}
+
+// AddressBytes returns the address of the device's key
+func (s *ExternalSigner) AddressBytes() []byte {
+	return s.Address
+}
+
+// SignTransaction asks the device to sign the transaction id, and checks that the signature was made by its address
+func (s *ExternalSigner) SignTransaction(ctx context.Context, tx *protocol.Transaction) error {
+	idBytes, err := multihash.Decode(tx.Id)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Approve transaction 0x%s on %s\n", hex.EncodeToString(tx.Id), s.Name)
+	signatureBytes, err := s.Sign(ctx, idBytes.Digest)
+	if err != nil {
+		return fmt.Errorf("%s could not sign the transaction, %w", s.Name, err)
+	}
+
+	publicKey, _, err := btcec.RecoverCompact(btcec.S256(), signatureBytes, idBytes.Digest)
+	if err != nil {
+		return fmt.Errorf("%w: %s returned an invalid signature, %s", ErrInvalidResponse, s.Name, err)
+	}
+
+	signer, err := PublicKeyToAddress(publicKey)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(signer, s.Address) {
+		return fmt.Errorf("%w: %s signed with a different key than that of its address", ErrInvalidResponse, s.Name)
+	}
+
+	tx.Signatures = append(tx.Signatures, signatureBytes)
+	return nil
+}
+
+// CoSigner signs a transaction with each of its signers in turn, such as the account sending it and the separate
+// payer of its mana. Its address is that of the first signer, which the transaction is sent from
+type CoSigner struct {
+	Signers []Signer
+}
+
+// NewCoSigner creates a signer from the sender's signer followed by the other signers
+func NewCoSigner(sender Signer, others ...Signer) *CoSigner {
+	return &CoSigner{Signers: append([]Signer{sender}, others...)}
+}
+
+// AddressBytes returns the address of the first signer
+func (s *CoSigner) AddressBytes() []byte {
+	return s.Signers[0].AddressBytes()
+}
+
+// SignTransaction adds the signature of each signer to the transaction
+func (s *CoSigner) SignTransaction(ctx context.Context, tx *protocol.Transaction) error {
+	for _, signer := range s.Signers {
+		if err := signer.SignTransaction(ctx, tx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
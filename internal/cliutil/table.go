@@ -0,0 +1,87 @@
+package cliutil
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// ANSI escape codes used to emphasize table headers on color terminals
+const (
+	boldStyle  = "\x1b[1m"
+	resetStyle = "\x1b[0m"
+)
+
+// columnSeparator is the space between the columns of a table
+const columnSeparator = "  "
+
+// Table holds rows of text to be shown as aligned columns under a header
+type Table struct {
+	Header []string
+	Rows   [][]string
+}
+
+// NewTable creates a table with the given column headers
+func NewTable(header ...string) *Table {
+	return &Table{Header: header, Rows: make([][]string, 0)}
+}
+
+// AddRow adds a row to the table. Missing cells are left blank, and cells beyond the header are ignored
+func (t *Table) AddRow(cells ...string) {
+	row := make([]string, len(t.Header))
+	copy(row, cells)
+	t.Rows = append(t.Rows, row)
+}
+
+// Lines returns the table as lines of aligned columns. With color, the header is shown in bold, otherwise it is
+// underlined with dashes so that the output stays readable when it is not a terminal
+func (t *Table) Lines(color bool) []string {
+	widths := make([]int, len(t.Header))
+	for i, h := range t.Header {
+		widths[i] = utf8.RuneCountInString(h)
+	}
+
+	for _, row := range t.Rows {
+		for i, cell := range row {
+			if n := utf8.RuneCountInString(cell); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+
+	lines := make([]string, 0, len(t.Rows)+2)
+	if color {
+		header := make([]string, len(t.Header))
+		for i, h := range t.Header {
+			header[i] = boldStyle + h + resetStyle
+		}
+		lines = append(lines, t.formatRow(header, t.Header, widths))
+	} else {
+		dashes := make([]string, len(t.Header))
+		for i, h := range t.Header {
+			dashes[i] = strings.Repeat("-", utf8.RuneCountInString(h))
+		}
+		lines = append(lines, t.formatRow(t.Header, t.Header, widths), t.formatRow(dashes, dashes, widths))
+	}
+
+	for _, row := range t.Rows {
+		lines = append(lines, t.formatRow(row, row, widths))
+	}
+
+	return lines
+}
+
+// formatRow pads each cell to the width of its column. The text is measured separately from the cells, which may
+// contain escape codes
+func (t *Table) formatRow(cells []string, text []string, widths []int) string {
+	var b strings.Builder
+	for i, cell := range cells {
+		if i > 0 {
+			b.WriteString(columnSeparator)
+		}
+
+		b.WriteString(cell)
+		b.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(text[i])))
+	}
+
+	return strings.TrimRight(b.String(), " ")
+}
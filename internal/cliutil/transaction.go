@@ -12,16 +12,16 @@ import (
 	"github.com/multiformats/go-multihash"
 )
 
-// CreateSignedTransaction creates a signed transaction
-func CreateSignedTransaction(ctx context.Context, ops []*protocol.Operation, key *util.KoinosKey, nonce uint64, rcLimit uint64, chainID []byte, payer []byte) (*protocol.Transaction, error) {
+// CreateSignedTransaction creates a transaction from the signer's address and signs it
+func CreateSignedTransaction(ctx context.Context, ops []*protocol.Operation, signer Signer, nonce uint64, rcLimit uint64, chainID []byte, payer []byte) (*protocol.Transaction, error) {
 	// Create the transaction
-	transaction, err := CreateTransaction(ctx, ops, key.AddressBytes(), nonce, rcLimit, chainID, payer)
+	transaction, err := CreateTransaction(ctx, ops, signer.AddressBytes(), nonce, rcLimit, chainID, payer)
 	if err != nil {
 		return nil, err
 	}
 
 	// Sign the transaction
-	err = SignTransaction(key.PrivateBytes(), transaction)
+	err = signer.SignTransaction(ctx, transaction)
 	if err != nil {
 		return nil, err
 	}
@@ -4,17 +4,22 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
 
 	"github.com/koinos/koinos-proto-golang/koinos/protocol"
 	util "github.com/koinos/koinos-util-golang"
 	"github.com/minio/sio"
-)
-
-const (
-	// Version number (this should probably not live here)
-	Version = "v2.0.0"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/term"
 )
 
 // Hardcoded Koin contract constants
@@ -35,8 +40,8 @@ const (
 	RIPEMD320 = 0x1055
 )
 
-// TransactionReceiptToString creates a string from a receipt
-func TransactionReceiptToString(receipt *protocol.TransactionReceipt, operations int) string {
+// TransactionReceiptToString creates a string from a receipt, showing mana with the given precision
+func TransactionReceiptToString(receipt *protocol.TransactionReceipt, operations int, precision int) string {
 	s := fmt.Sprintf("Transaction with ID 0x%s containing %d operations", hex.EncodeToString(receipt.Id), operations)
 	if receipt.Reverted {
 		s += " reverted."
@@ -45,7 +50,7 @@ func TransactionReceiptToString(receipt *protocol.TransactionReceipt, operations
 	}
 
 	// Build the mana result
-	manaDec, err := util.SatoshiToDecimal(receipt.RcUsed, KoinPrecision)
+	manaDec, err := util.SatoshiToDecimal(receipt.RcUsed, precision)
 	if err != nil {
 		s += "\n" + err.Error()
 		return s
@@ -74,65 +79,367 @@ func walletConfig(password []byte) sio.Config {
 	}
 }
 
-// CreateWalletFile creates a new wallet file on disk
+// CreateWalletFile creates a new wallet file on disk, deriving its key with the default cost
 func CreateWalletFile(file *os.File, passphrase string, privateKey []byte) error {
-	hasher := sha256.New()
-	bytesWritten, err := hasher.Write([]byte(passphrase))
+	return CreateWalletFileWithKDF(file, passphrase, privateKey, DefaultKDFParams)
+}
+
+// CreateWalletFileWithKDF creates a new wallet file on disk, deriving its key from the passphrase with the given cost
+func CreateWalletFileWithKDF(file *os.File, passphrase string, privateKey []byte, params KDFParams) error {
+	if passphrase == "" {
+		return ErrEmptyPassphrase
+	}
 
+	key, err := writeKDFHeader(file, passphrase, params)
 	if err != nil {
 		return err
 	}
 
-	if bytesWritten <= 0 {
+	source := bytes.NewReader(privateKey)
+	_, err = sio.Encrypt(file, source, walletConfig(key))
+
+	return err
+}
+
+// ReadWalletFile extracts the private key from the provided wallet file
+func ReadWalletFile(file *os.File, passphrase string) ([]byte, error) {
+	data, _, err := readWalletFile(file, passphrase)
+	return data, err
+}
+
+// readWalletFile decrypts a wallet file with the key its format version derives from the passphrase, returning the
+// data and the version
+func readWalletFile(file *os.File, passphrase string) ([]byte, int, error) {
+	if passphrase == "" {
+		return nil, 0, ErrEmptyPassphrase
+	}
+
+	header, source, err := readKDFHeader(file)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	version := walletFileVersion(header)
+
+	var key []byte
+	switch version {
+	case WalletFileV1:
+		// Version 1 files use the hash of the passphrase as the key
+		passwordHash := sha256.Sum256([]byte(passphrase))
+		key = passwordHash[:]
+	case WalletFileV2:
+		key, err = scrypt.Key([]byte(passphrase), header.Salt, header.N, header.R, header.P, kdfKeyLength)
+		if err != nil {
+			return nil, 0, err
+		}
+	default:
+		return nil, 0, fmt.Errorf("%w: version %d", ErrWalletVersion, version)
+	}
+
+	if len(key) != 32 {
+		return nil, 0, ErrUnexpectedHashLength
+	}
+
+	var destination bytes.Buffer
+	_, err = sio.Decrypt(&destination, source, walletConfig(key))
+
+	return destination.Bytes(), version, err
+}
+
+// EncryptFile encrypts the input file with a passphrase into a new output file, using the same scheme as wallet files.
+// An existing output file is never overwritten
+func EncryptFile(input string, output string, passphrase string) error {
+	if passphrase == "" {
 		return ErrEmptyPassphrase
 	}
 
-	passwordHash := hasher.Sum(nil)
+	data, err := ioutil.ReadFile(input)
+	if err != nil {
+		return err
+	}
+
+	return writeNewFile(output, func(file *os.File) error {
+		return CreateWalletFile(file, passphrase, data)
+	})
+}
 
-	if len(passwordHash) != 32 {
-		return ErrUnexpectedHashLength
+// DecryptFile decrypts a file made by EncryptFile into a new output file. An existing output file is never overwritten
+func DecryptFile(input string, output string, passphrase string) error {
+	if passphrase == "" {
+		return ErrEmptyPassphrase
 	}
 
-	source := bytes.NewReader(privateKey)
-	_, err = sio.Encrypt(file, source, walletConfig(passwordHash))
+	file, err := os.Open(input)
+	if err != nil {
+		return err
+	}
 
-	return err
+	data, err := ReadWalletFile(file, passphrase)
+	file.Close()
+	if err != nil {
+		return fmt.Errorf("%w: check your password, %s", ErrWalletDecrypt, err)
+	}
+
+	return writeNewFile(output, func(file *os.File) error {
+		_, err := file.Write(data)
+		return err
+	})
 }
 
-// ReadWalletFile extracts the private key from the provided wallet file
-func ReadWalletFile(file *os.File, passphrase string) ([]byte, error) {
-	hasher := sha256.New()
-	bytesWritten, err := hasher.Write([]byte(passphrase))
+// writeNewFile creates a file readable only by its owner and writes to it, removing the file if writing fails
+func writeNewFile(filename string, write func(file *os.File) error) error {
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+
+	err = write(file)
+	if err == nil {
+		err = file.Close()
+	} else {
+		file.Close()
+	}
+
+	if err != nil {
+		os.Remove(filename)
+		return err
+	}
+
+	return nil
+}
+
+// DefaultKeyLabel is the label given to the key of a wallet file holding a single key
+const DefaultKeyLabel = "default"
+
+// WalletKey is a labeled private key stored in a wallet file
+type WalletKey struct {
+	Label      string `json:"label"`
+	PrivateKey []byte `json:"private_key"`
+
+	// DerivationPath is the path the key was derived at, for keys imported from a mnemonic
+	DerivationPath string `json:"derivation_path,omitempty"`
+}
+
+type walletKeys struct {
+	Keys []WalletKey `json:"keys"`
+}
+
+// CreateWalletKeysFile creates a new wallet file on disk holding the given labeled keys
+func CreateWalletKeysFile(file *os.File, passphrase string, keys []WalletKey) error {
+	return createWalletKeysFile(file, passphrase, keys, DefaultKDFParams)
+}
 
+func createWalletKeysFile(file *os.File, passphrase string, keys []WalletKey, params KDFParams) error {
+	data, err := json.Marshal(&walletKeys{Keys: keys})
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	if bytesWritten <= 0 {
-		return nil, ErrEmptyPassphrase
+	return CreateWalletFileWithKDF(file, passphrase, data, params)
+}
+
+// ReadWalletKeysFile extracts the labeled keys from the provided wallet file.
+// A file holding a single bare private key is returned as one key with the default label. Legacy is set to true for
+// such files, and for files in an older format version, so that they can be migrated by writing them again
+func ReadWalletKeysFile(file *os.File, passphrase string) (keys []WalletKey, legacy bool, err error) {
+	data, version, err := readWalletFile(file, passphrase)
+	if err != nil {
+		return nil, false, err
 	}
 
-	passwordHash := hasher.Sum(nil)
+	if len(data) == 32 {
+		return []WalletKey{{Label: DefaultKeyLabel, PrivateKey: data}}, true, nil
+	}
 
-	if len(passwordHash) != 32 {
-		return nil, ErrUnexpectedHashLength
+	var wk walletKeys
+	err = json.Unmarshal(data, &wk)
+	if err != nil || len(wk.Keys) == 0 {
+		return nil, false, fmt.Errorf("%w: unrecognized wallet format", ErrWalletDecrypt)
 	}
 
-	var destination bytes.Buffer
-	_, err = sio.Decrypt(&destination, file, walletConfig(passwordHash))
+	return wk.Keys, version < CurrentWalletFileVersion, nil
+}
+
+// WriteWalletKeysFile replaces the wallet file with one holding the given labeled keys. The key derivation cost of an
+// existing file is kept, while new and legacy files get the default cost
+func WriteWalletKeysFile(filename string, passphrase string, keys []WalletKey) error {
+	params, err := ReadWalletFileKDF(filename)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if params == nil {
+		params = &DefaultKDFParams
+	}
+
+	return WriteWalletKeysFileWithKDF(filename, passphrase, keys, *params)
+}
+
+// WriteWalletKeysFileWithKDF replaces the wallet file with one holding the given labeled keys, with the given key derivation cost.
+// The keys are written to a temporary file which is renamed into place, so the existing file is never partially overwritten
+func WriteWalletKeysFileWithKDF(filename string, passphrase string, keys []WalletKey, params KDFParams) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(filename), "."+filepath.Base(filename)+".tmp*")
+	if err != nil {
+		return err
+	}
+
+	// Clean up the temporary file unless it was renamed into place
+	renamed := false
+	defer func() {
+		if !renamed {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	err = createWalletKeysFile(tmp, passphrase, keys, params)
+	if err != nil {
+		return err
+	}
+
+	err = tmp.Sync()
+	if err != nil {
+		return err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
 
-	return destination.Bytes(), err
+	err = os.Rename(tmp.Name(), filename)
+	if err != nil {
+		return err
+	}
+
+	renamed = true
+	return nil
+}
+
+// PrivateKeyLength is the length in bytes of a raw private key
+const PrivateKeyLength = 32
+
+// DecodePrivateKey decodes a private key given either in WIF or as hex (with or without a 0x prefix)
+func DecodePrivateKey(privateKey string) ([]byte, error) {
+	var keyBytes []byte
+	var err error
+
+	// A hex key is exactly twice the key length once the prefix is removed, which a WIF key never is
+	hexKey := strings.TrimPrefix(strings.TrimPrefix(privateKey, "0x"), "0X")
+	if len(hexKey) == 2*PrivateKeyLength {
+		keyBytes, err = hex.DecodeString(hexKey)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidPrivateKey, err)
+		}
+	} else {
+		keyBytes, err = util.DecodeWIF(privateKey)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidPrivateKey, err)
+		}
+	}
+
+	if len(keyBytes) != PrivateKeyLength {
+		return nil, fmt.Errorf("%w: expected %d bytes, got %d", ErrInvalidPrivateKey, PrivateKeyLength, len(keyBytes))
+	}
+
+	return keyBytes, nil
+}
+
+// ParseEntryPoint parses a contract entry point given as hex with a 0x prefix, as decimal, or as hex without a prefix
+// when it contains a hex letter
+func ParseEntryPoint(entryPoint string) (uint32, error) {
+	s := strings.TrimSpace(entryPoint)
+	base := 10
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		s = s[2:]
+		base = 16
+	} else if strings.ContainsAny(s, "abcdefABCDEF") {
+		base = 16
+	}
+
+	value, err := strconv.ParseUint(s, base, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid entry point %q", ErrInvalidParam, entryPoint)
+	}
+
+	return uint32(value), nil
+}
+
+// Environment variables holding the password used when a command is not given one
+const (
+	WalletPassEnvVar     = "WALLET_PASS"
+	WalletPassFileEnvVar = "WALLET_PASS_FILE"
+)
+
+// ReadPasswordFile reads a password from a file, which is its whole content without any trailing line endings.
+// The file must not be accessible to other users of the machine
+func ReadPasswordFile(filename string) (string, error) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return "", err
+	}
+
+	// Windows does not report unix permissions
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0007 != 0 {
+		return "", fmt.Errorf("password file %s must not be accessible by other users (mode %04o), use chmod 600", filename, info.Mode().Perm())
+	}
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(string(data), "\r\n"), nil
 }
 
 // GetPassword takes the password input from a command, and returns the string password which should be used
+// If no password is given, it is taken from WALLET_PASS, then the file named by WALLET_PASS_FILE, then read from stdin
 func GetPassword(password *string) (string, error) {
+	return getPassword(password, false)
+}
+
+// GetNewPassword is like GetPassword, but asks for the password twice when prompting
+func GetNewPassword(password *string) (string, error) {
+	return getPassword(password, true)
+}
+
+func getPassword(password *string, confirm bool) (string, error) {
 	// Get the password
 	result := ""
 	if password == nil { // If no password is provided, check the environment variable
-		result = os.Getenv("WALLET_PASS")
-		// Advise about the environment variable
+		result = os.Getenv(WalletPassEnvVar)
+
+		// Then check for a password file
+		if filename := os.Getenv(WalletPassFileEnvVar); result == "" && filename != "" {
+			var err error
+			result, err = ReadPasswordFile(filename)
+			if err != nil {
+				return "", err
+			}
+
+			if result == "" {
+				return "", fmt.Errorf("%w: password file %s is empty", ErrBlankPassword, filename)
+			}
+		}
+
+		// Fall back to prompting for the password
 		if result == "" {
-			return result, fmt.Errorf("%w: no password was provided and env variable WALLET_PASS is empty", ErrBlankPassword)
+			var err error
+			result, err = ReadPassword("Password: ")
+			if err != nil {
+				return result, err
+			}
+
+			if confirm && result != "" {
+				again, err := ReadPassword("Confirm password: ")
+				if err != nil {
+					return "", err
+				}
+
+				if again != result {
+					return "", ErrPasswordMismatch
+				}
+			}
 		}
 	} else {
 		result = *password
@@ -145,3 +452,104 @@ func GetPassword(password *string) (string, error) {
 
 	return result, nil
 }
+
+// IsTerminal returns whether the file, such as standard output, is a terminal
+func IsTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// ReadPassword prints the prompt and reads a line from stdin, masking the input if stdin is a terminal
+func ReadPassword(prompt string) (string, error) {
+	fmt.Print(prompt)
+
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		pass, err := term.ReadPassword(fd)
+		fmt.Println()
+		return string(pass), err
+	}
+
+	return readLine()
+}
+
+// ReadConfirmation prints the prompt and reads a line from stdin, returning true only if the answer is yes
+func ReadConfirmation(prompt string) (bool, error) {
+	fmt.Print(prompt)
+
+	answer, err := readLine()
+	if err != nil {
+		return false, err
+	}
+
+	return strings.EqualFold(strings.TrimSpace(answer), "yes"), nil
+}
+
+// ReadInput prints the prompt and reads a line from stdin
+func ReadInput(prompt string) (string, error) {
+	fmt.Print(prompt)
+	return readLine()
+}
+
+// readLine reads a line from stdin without its line ending
+func readLine() (string, error) {
+	return readLineFrom(os.Stdin)
+}
+
+// readLineFrom reads a line without its line ending. It reads a single byte at a time, without buffering, so that
+// nothing past the line is consumed, and piped input after it is left for whatever reads next
+func readLineFrom(r io.Reader) (string, error) {
+	line := make([]byte, 0)
+	b := make([]byte, 1)
+	for {
+		n, err := r.Read(b)
+		if n > 0 {
+			if b[0] == '\n' {
+				break
+			}
+			line = append(line, b[0])
+		}
+
+		if err != nil {
+			if errors.Is(err, io.EOF) && len(line) > 0 {
+				break
+			}
+			return "", err
+		}
+	}
+
+	return strings.TrimRight(string(line), "\r"), nil
+}
+
+// EditDistance returns the Levenshtein distance between two strings, the number of single character insertions,
+// deletions, or substitutions needed to turn one into the other
+func EditDistance(a string, b string) int {
+	s, t := []rune(a), []rune(b)
+
+	// Only the previous row of the distance matrix is needed to compute the next
+	prev := make([]int, len(t)+1)
+	curr := make([]int, len(t)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(s); i++ {
+		curr[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+
+			curr[j] = prev[j-1] + cost
+			if prev[j]+1 < curr[j] {
+				curr[j] = prev[j] + 1
+			}
+			if curr[j-1]+1 < curr[j] {
+				curr[j] = curr[j-1] + 1
+			}
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(t)]
+}
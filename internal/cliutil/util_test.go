@@ -0,0 +1,23 @@
+package cliutil
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadLine(t *testing.T) {
+	// Each read takes one line, leaving the rest of the input for the next
+	r := strings.NewReader("first\r\nsecond\n\nlast")
+
+	for _, expected := range []string{"first", "second", "", "last"} {
+		line, err := readLineFrom(r)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, line)
+	}
+
+	_, err := readLineFrom(r)
+	assert.ErrorIs(t, err, io.EOF)
+}
@@ -0,0 +1,47 @@
+package cliutil
+
+import (
+	"runtime/debug"
+)
+
+// Build information. These may be set at build time, for example:
+// go build -ldflags "-X github.com/koinos/koinos-cli/internal/cliutil.Commit=$(git rev-parse --short HEAD)" ./cmd/cli
+var (
+	// Version number
+	Version = "v2.0.0"
+
+	// Commit is the git commit the CLI was built from, empty if unknown
+	Commit = ""
+)
+
+// ProtoModule is the module providing the Koinos protocol types
+const ProtoModule = "github.com/koinos/koinos-proto-golang"
+
+// VersionString returns the version, along with the commit if known
+func VersionString() string {
+	if Commit == "" {
+		return Version
+	}
+
+	return Version + " (" + Commit + ")"
+}
+
+// DependencyVersion returns the version of a module the CLI was built with, empty if unknown
+func DependencyVersion(path string) string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+
+	for _, dep := range info.Deps {
+		if dep.Path == path {
+			// A replaced module reports the version actually used
+			if dep.Replace != nil {
+				return dep.Replace.Version
+			}
+			return dep.Version
+		}
+	}
+
+	return ""
+}
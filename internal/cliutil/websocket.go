@@ -0,0 +1,127 @@
+package cliutil
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	jsonrpc "github.com/ybbus/jsonrpc/v3"
+)
+
+// isWebSocketURL returns true if the url uses a WebSocket scheme
+func isWebSocketURL(url string) bool {
+	lower := strings.ToLower(url)
+	return strings.HasPrefix(lower, "ws://") || strings.HasPrefix(lower, "wss://")
+}
+
+// wsRequest is a jsonrpc request sent over a WebSocket
+type wsRequest struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+	ID      int         `json:"id"`
+}
+
+// wsClient is a jsonrpc client that keeps a persistent WebSocket connection to the node.
+// The connection is dialed on first use, and redialed on the next call if it drops
+type wsClient struct {
+	url    string
+	mu     sync.Mutex
+	conn   *websocket.Conn
+	nextID int
+}
+
+// newWSClient creates a new WebSocket jsonrpc client
+func newWSClient(url string) *wsClient {
+	return &wsClient{url: url}
+}
+
+// Call sends a jsonrpc request over the WebSocket and waits for its response
+func (c *wsClient) Call(ctx context.Context, method string, params ...interface{}) (*jsonrpc.RPCResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		conn, _, err := websocket.DefaultDialer.DialContext(ctx, c.url, nil)
+		if err != nil {
+			return nil, err
+		}
+		c.conn = conn
+	}
+
+	c.nextID++
+	req := wsRequest{JSONRPC: "2.0", Method: method, ID: c.nextID}
+	if len(params) == 1 {
+		req.Params = params[0]
+	} else if len(params) > 1 {
+		req.Params = params
+	}
+
+	// Respect the context deadline, or wait indefinitely if there is none
+	deadline, _ := ctx.Deadline()
+	c.conn.SetWriteDeadline(deadline)
+	c.conn.SetReadDeadline(deadline)
+
+	// Unblock reads and writes if the context is cancelled without a deadline
+	done := make(chan struct{})
+	defer close(done)
+	go func(conn *websocket.Conn) {
+		select {
+		case <-ctx.Done():
+			conn.SetReadDeadline(time.Now())
+			conn.SetWriteDeadline(time.Now())
+		case <-done:
+		}
+	}(c.conn)
+
+	err := c.conn.WriteJSON(&req)
+	if err != nil {
+		c.drop()
+		return nil, err
+	}
+
+	// Skip any responses left over from earlier requests that timed out
+	for {
+		resp := &jsonrpc.RPCResponse{}
+		err = c.conn.ReadJSON(resp)
+		if err != nil {
+			// A malformed message does not break the connection
+			if _, ok := err.(*json.SyntaxError); !ok {
+				c.drop()
+			}
+			return nil, err
+		}
+
+		if resp.ID == req.ID {
+			return resp, nil
+		}
+	}
+}
+
+// Close closes the WebSocket connection, if open
+func (c *wsClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		return nil
+	}
+
+	err := c.conn.Close()
+	c.conn = nil
+	if err != nil {
+		return fmt.Errorf("closing connection to %s: %w", c.url, err)
+	}
+
+	return nil
+}
+
+// drop closes a broken connection so that the next call redials
+func (c *wsClient) drop() {
+	c.conn.Close()
+	c.conn = nil
+}